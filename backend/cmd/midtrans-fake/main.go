@@ -0,0 +1,262 @@
+// Command midtrans-fake is a local stand-in for Midtrans, for trying payments end to end
+// without the sandbox. Point the API at it with the midtrans_api_base_url global config,
+// create a payment as usual, then trigger a signed notification for the order:
+//
+//	go run ./cmd/midtrans-fake -server-key SB-Mid-server-xxx -notify-url http://localhost:8080/api/v1/payment/notification
+//	curl -X POST 'http://localhost:9090/fake/orders/ORD-123/notify?status=settlement'
+//
+// status is a Midtrans transaction status: settlement, capture, pending, deny, cancel,
+// expire, failure, refund or partial_refund. ?fraud= sets the fraud status of a capture,
+// ?amount= overrides the amount and ?signature=bad sends a wrongly signed notification.
+// The Core API status and refund endpoints answer for the orders it knows.
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/codapos/backend/internal/domain"
+	"github.com/codapos/backend/internal/payment"
+	"github.com/google/uuid"
+)
+
+type order struct {
+	OrderID       string `json:"order_id"`
+	GrossAmount   int64  `json:"gross_amount"`
+	TransactionID string `json:"transaction_id"`
+	Status        string `json:"transaction_status"`
+}
+
+type fake struct {
+	serverKey string
+	notifyURL string
+	baseURL   string
+	client    *http.Client
+
+	mu     sync.Mutex
+	orders map[string]*order
+}
+
+func main() {
+	addr := flag.String("addr", ":9090", "address to listen on")
+	serverKey := flag.String("server-key", "", "server key notifications are signed with (midtrans_server_key)")
+	notifyURL := flag.String("notify-url", "http://localhost:8080/api/v1/payment/notification", "payment notification URL of the API")
+	flag.Parse()
+	if *serverKey == "" {
+		log.Fatal("-server-key is required")
+	}
+
+	f := &fake{
+		serverKey: *serverKey,
+		notifyURL: *notifyURL,
+		baseURL:   "http://localhost" + *addr,
+		client:    &http.Client{Timeout: 10 * time.Second},
+		orders:    make(map[string]*order),
+	}
+	log.Printf("🧪 Fake Midtrans listening on %s, notifying %s", *addr, *notifyURL)
+	log.Fatal(http.ListenAndServe(*addr, f.routes()))
+}
+
+func (f *fake) routes() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /snap/v1/transactions", f.createTransaction)
+	mux.HandleFunc("GET /snap/v2/vtweb/{token}", f.paymentPage)
+	mux.HandleFunc("GET /v2/{orderID}/status", f.status)
+	mux.HandleFunc("POST /v2/{orderID}/refund", f.refund)
+	mux.HandleFunc("GET /fake/orders", f.listOrders)
+	mux.HandleFunc("POST /fake/orders/{orderID}/notify", f.notify)
+	return mux
+}
+
+func (f *fake) authorized(w http.ResponseWriter, r *http.Request) bool {
+	if r.Header.Get("Authorization") != "Basic "+base64.StdEncoding.EncodeToString([]byte(f.serverKey+":")) {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"error_messages": []string{"Access denied due to unauthorized transaction, please check client or server key"}})
+		return false
+	}
+	return true
+}
+
+// createTransaction answers a Snap transaction request like Midtrans does
+func (f *fake) createTransaction(w http.ResponseWriter, r *http.Request) {
+	if !f.authorized(w, r) {
+		return
+	}
+	var req struct {
+		TransactionDetails struct {
+			OrderID     string `json:"order_id"`
+			GrossAmount int64  `json:"gross_amount"`
+		} `json:"transaction_details"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.TransactionDetails.OrderID == "" || req.TransactionDetails.GrossAmount <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error_messages": []string{"transaction_details.order_id and gross_amount are required"}})
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, exists := f.orders[req.TransactionDetails.OrderID]; exists {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error_messages": []string{"transaction_details.order_id has already been taken"}})
+		return
+	}
+	token := uuid.NewString()
+	f.orders[req.TransactionDetails.OrderID] = &order{
+		OrderID:       req.TransactionDetails.OrderID,
+		GrossAmount:   req.TransactionDetails.GrossAmount,
+		TransactionID: uuid.NewString(),
+		Status:        "pending",
+	}
+	log.Printf("💳 Order %s created for Rp %d", req.TransactionDetails.OrderID, req.TransactionDetails.GrossAmount)
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"token":        token,
+		"redirect_url": f.baseURL + "/snap/v2/vtweb/" + token,
+	})
+}
+
+// status answers a Core API status request; like Midtrans, errors come with HTTP 200
+func (f *fake) status(w http.ResponseWriter, r *http.Request) {
+	if !f.authorized(w, r) {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	o, ok := f.orders[r.PathValue("orderID")]
+	if !ok {
+		writeJSON(w, http.StatusOK, map[string]string{"status_code": "404", "status_message": "Transaction doesn't exist."})
+		return
+	}
+	writeJSON(w, http.StatusOK, f.notification(o, ""))
+}
+
+// refund refunds a settled order in full or in part
+func (f *fake) refund(w http.ResponseWriter, r *http.Request) {
+	if !f.authorized(w, r) {
+		return
+	}
+	var req struct {
+		Amount int64 `json:"amount"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&req)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	o, ok := f.orders[r.PathValue("orderID")]
+	if !ok {
+		writeJSON(w, http.StatusOK, map[string]string{"status_code": "404", "status_message": "Transaction doesn't exist."})
+		return
+	}
+	if o.Status != "settlement" && o.Status != "capture" && o.Status != "partial_refund" {
+		writeJSON(w, http.StatusOK, map[string]string{"status_code": "412", "status_message": "Merchant cannot modify the status of the transaction"})
+		return
+	}
+	o.Status = "refund"
+	if req.Amount > 0 && req.Amount < o.GrossAmount {
+		o.Status = "partial_refund"
+	}
+	log.Printf("↩️ Order %s refunded (%s)", o.OrderID, o.Status)
+	writeJSON(w, http.StatusOK, f.notification(o, ""))
+}
+
+func (f *fake) paymentPage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, `<!DOCTYPE html><html><body style="font-family:sans-serif;padding:32px">`+
+		`<p>Fake Midtrans payment page. Pay the order with POST /fake/orders/{order_id}/notify?status=settlement.</p></body></html>`)
+}
+
+func (f *fake) listOrders(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	orders := make([]*order, 0, len(f.orders))
+	for _, o := range f.orders {
+		orders = append(orders, o)
+	}
+	writeJSON(w, http.StatusOK, orders)
+}
+
+// notify moves an order to a status and posts the signed notification to the API,
+// answering with what the API answered
+func (f *fake) notify(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	status := q.Get("status")
+	if status == "" {
+		status = "settlement"
+	}
+
+	f.mu.Lock()
+	o, ok := f.orders[r.PathValue("orderID")]
+	if !ok {
+		// Orders created elsewhere can be notified too, given their amount
+		amount, _ := strconv.ParseInt(q.Get("amount"), 10, 64)
+		o = &order{OrderID: r.PathValue("orderID"), GrossAmount: amount, TransactionID: uuid.NewString()}
+		f.orders[o.OrderID] = o
+	}
+	o.Status = status
+	n := f.notification(o, q.Get("fraud"))
+	f.mu.Unlock()
+
+	if amount := q.Get("amount"); amount != "" {
+		n.GrossAmount = amount + ".00"
+	}
+	n.SignatureKey = payment.MidtransSignature(n.OrderID, n.StatusCode, n.GrossAmount, f.serverKey)
+	if q.Get("signature") == "bad" {
+		n.SignatureKey = strings.Repeat("0", len(n.SignatureKey))
+	}
+
+	body, _ := json.Marshal(n)
+	resp, err := f.client.Post(f.notifyURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		writeJSON(w, http.StatusBadGateway, map[string]string{"error": err.Error()})
+		return
+	}
+	defer resp.Body.Close()
+	answer, _ := io.ReadAll(resp.Body)
+	log.Printf("📨 %s %s → %d %s", n.OrderID, status, resp.StatusCode, answer)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"notification":  n,
+		"response_code": resp.StatusCode,
+		"response":      json.RawMessage(answer),
+	})
+}
+
+// notification builds the notification Midtrans sends for an order in its current status
+func (f *fake) notification(o *order, fraudStatus string) domain.MidtransNotification {
+	statusCode := "200"
+	switch o.Status {
+	case "pending":
+		statusCode = "201"
+	case "deny", "cancel", "expire", "failure":
+		statusCode = "202"
+	}
+	if o.Status == "capture" && fraudStatus == "" {
+		fraudStatus = "accept"
+	}
+	n := domain.MidtransNotification{
+		TransactionID:     o.TransactionID,
+		OrderID:           o.OrderID,
+		StatusCode:        statusCode,
+		GrossAmount:       strconv.FormatInt(o.GrossAmount, 10) + ".00",
+		TransactionStatus: o.Status,
+		FraudStatus:       fraudStatus,
+		PaymentType:       "qris",
+		TransactionTime:   time.Now().Format("2006-01-02 15:04:05"),
+	}
+	if o.Status == "settlement" || o.Status == "capture" {
+		n.SettlementTime = n.TransactionTime
+	}
+	return n
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
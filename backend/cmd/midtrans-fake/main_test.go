@@ -0,0 +1,183 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/codapos/backend/internal/domain"
+	"github.com/codapos/backend/internal/payment"
+	"gorm.io/gorm"
+)
+
+const testServerKey = "SB-Mid-server-test"
+
+type configRepo struct {
+	domain.GlobalConfigRepository
+	values map[string]string
+}
+
+func (r configRepo) FindByKey(key string) (*domain.GlobalConfig, error) {
+	v, ok := r.values[key]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &domain.GlobalConfig{Key: key, Value: v}, nil
+}
+
+// received is a notification as the API's webhook verified it
+type received struct {
+	payment *domain.GatewayPayment
+	err     error
+}
+
+// harness runs the fake and a webhook that verifies what the fake posts to it with
+// the Midtrans gateway, pointed at the fake
+type harness struct {
+	server  *httptest.Server
+	gateway *payment.Midtrans
+
+	mu       sync.Mutex
+	received []received
+}
+
+func newHarness(t *testing.T) *harness {
+	t.Helper()
+	h := &harness{}
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		p, err := h.gateway.VerifyNotification(body)
+		h.mu.Lock()
+		h.received = append(h.received, received{payment: p, err: err})
+		h.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"success":true}`))
+	}))
+	t.Cleanup(webhook.Close)
+
+	f := &fake{
+		serverKey: testServerKey,
+		notifyURL: webhook.URL,
+		client:    &http.Client{Timeout: 5 * time.Second},
+		orders:    make(map[string]*order),
+	}
+	server := httptest.NewServer(f.routes())
+	t.Cleanup(server.Close)
+	f.baseURL = server.URL
+
+	h.gateway = payment.NewMidtrans(configRepo{values: map[string]string{
+		domain.ConfigMidtransServerKey:  testServerKey,
+		domain.ConfigMidtransAPIBaseURL: server.URL,
+	}})
+	h.server = server
+	return h
+}
+
+// notify has the fake move an order to a status and returns the notification the
+// webhook received for it
+func (h *harness) notify(t *testing.T, orderID, query string) received {
+	t.Helper()
+	resp, err := http.Post(h.server.URL+"/fake/orders/"+orderID+"/notify?"+query, "application/json", nil)
+	if err != nil {
+		t.Fatalf("notify %s: %v", query, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("notify %s answered HTTP %d", query, resp.StatusCode)
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.received) == 0 {
+		t.Fatalf("notify %s: webhook received nothing", query)
+	}
+	return h.received[len(h.received)-1]
+}
+
+func (h *harness) charge(t *testing.T, orderID string, amount domain.Money) {
+	t.Helper()
+	charge, err := h.gateway.CreateCharge(domain.ChargeRequest{OrderID: orderID, Amount: amount})
+	if err != nil {
+		t.Fatalf("CreateCharge: %v", err)
+	}
+	if charge.Token == "" || charge.RedirectURL == "" {
+		t.Fatalf("CreateCharge returned %+v", charge)
+	}
+}
+
+func TestNotificationTransitions(t *testing.T) {
+	h := newHarness(t)
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"status=pending", domain.GatewayStatusPending},
+		{"status=settlement", domain.GatewayStatusPaid},
+		{"status=capture", domain.GatewayStatusPaid},
+		{"status=capture&fraud=challenge", domain.GatewayStatusPending},
+		{"status=capture&fraud=deny", domain.GatewayStatusFailed},
+		{"status=deny", domain.GatewayStatusFailed},
+		{"status=cancel", domain.GatewayStatusFailed},
+		{"status=expire", domain.GatewayStatusExpired},
+		{"status=refund", domain.GatewayStatusRefunded},
+		{"status=partial_refund", domain.GatewayStatusPartiallyRefunded},
+	}
+	for i, tt := range tests {
+		orderID := "ORD-T" + string(rune('A'+i))
+		h.charge(t, orderID, domain.Rupiah(150000))
+		got := h.notify(t, orderID, tt.query)
+		if got.err != nil {
+			t.Errorf("%s: VerifyNotification error %v", tt.query, got.err)
+			continue
+		}
+		if got.payment.OrderID != orderID || got.payment.Status != tt.want || got.payment.Amount != domain.Rupiah(150000) {
+			t.Errorf("%s: got %s %s for %s, want %s Rp150000", tt.query, got.payment.OrderID, got.payment.Status, got.payment.Amount, tt.want)
+		}
+	}
+}
+
+func TestNotificationSignature(t *testing.T) {
+	h := newHarness(t)
+	h.charge(t, "ORD-SIG", domain.Rupiah(150000))
+
+	if got := h.notify(t, "ORD-SIG", "status=settlement&signature=bad"); !errors.Is(got.err, payment.ErrInvalidSignature) {
+		t.Errorf("badly signed notification: error %v, want ErrInvalidSignature", got.err)
+	}
+	// A changed amount is re-signed by the fake, so it verifies but carries the new amount
+	got := h.notify(t, "ORD-SIG", "status=settlement&amount=1")
+	if got.err != nil || got.payment.Amount != domain.Rupiah(1) {
+		t.Errorf("re-signed notification: %v, amount %v; want it verified with Rp1", got.err, got.payment)
+	}
+}
+
+func TestStatusAndRefund(t *testing.T) {
+	h := newHarness(t)
+	h.charge(t, "ORD-REFUND", domain.Rupiah(150000))
+
+	if _, err := h.gateway.Refund("ORD-REFUND", domain.Rupiah(150000), "test"); err == nil {
+		t.Error("refund of an unpaid order succeeded, want an error")
+	}
+	h.notify(t, "ORD-REFUND", "status=settlement")
+	status, err := h.gateway.GetStatus("ORD-REFUND")
+	if err != nil || status.Status != domain.GatewayStatusPaid {
+		t.Fatalf("GetStatus after settlement = %+v, %v; want paid", status, err)
+	}
+
+	refund, err := h.gateway.Refund("ORD-REFUND", domain.Rupiah(50000), "test")
+	if err != nil || refund.Status != domain.GatewayStatusPartiallyRefunded {
+		t.Fatalf("partial Refund = %+v, %v; want partially refunded", refund, err)
+	}
+	refund, err = h.gateway.Refund("ORD-REFUND", domain.Rupiah(150000), "test")
+	if err != nil || refund.Status != domain.GatewayStatusRefunded {
+		t.Fatalf("full Refund = %+v, %v; want refunded", refund, err)
+	}
+	if status, err := h.gateway.GetStatus("ORD-REFUND"); err != nil || status.Status != domain.GatewayStatusRefunded {
+		t.Errorf("GetStatus after refund = %+v, %v; want refunded", status, err)
+	}
+	if _, err := h.gateway.GetStatus("ORD-MISSING"); !errors.Is(err, payment.ErrOrderNotFound) {
+		t.Errorf("GetStatus of an unknown order: error %v, want ErrOrderNotFound", err)
+	}
+}
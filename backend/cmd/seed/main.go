@@ -135,48 +135,48 @@ func main() {
 	// Seed Products - Makanan (10 items)
 	// ==========================================
 	makananProducts := []domain.Product{
-		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catMakanan.ID, Name: "Nasi Goreng Bali", SKU: "MKN-001", Description: "Nasi goreng khas Bali dengan bumbu lengkap", BasePrice: 28000, CostPrice: 12000, TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 1},
-		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catMakanan.ID, Name: "Mie Goreng Spesial", SKU: "MKN-002", Description: "Mie goreng dengan telur dan sayuran segar", BasePrice: 25000, CostPrice: 10000, TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 2},
-		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catMakanan.ID, Name: "Ayam Betutu", SKU: "MKN-003", Description: "Ayam betutu khas Bali dengan bumbu rempah", BasePrice: 45000, CostPrice: 22000, TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 3},
-		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catMakanan.ID, Name: "Sate Lilit", SKU: "MKN-004", Description: "Sate lilit ikan khas Bali", BasePrice: 30000, CostPrice: 15000, TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 4},
-		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catMakanan.ID, Name: "Bebek Goreng Crispy", SKU: "MKN-005", Description: "Bebek goreng renyah dengan sambal matah", BasePrice: 42000, CostPrice: 20000, TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 5},
-		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catMakanan.ID, Name: "Nasi Campur Bali", SKU: "MKN-006", Description: "Nasi campur dengan lauk tradisional Bali", BasePrice: 32000, CostPrice: 14000, TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 6},
-		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catMakanan.ID, Name: "Lawar Bali", SKU: "MKN-007", Description: "Lawar sayur campur daging khas Bali", BasePrice: 25000, CostPrice: 11000, TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 7},
-		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catMakanan.ID, Name: "Bakso Urat Jumbo", SKU: "MKN-008", Description: "Bakso urat jumbo dengan kuah kaldu sapi", BasePrice: 22000, CostPrice: 9000, TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 8},
-		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catMakanan.ID, Name: "Gado-Gado", SKU: "MKN-009", Description: "Gado-gado sayuran segar dengan bumbu kacang", BasePrice: 20000, CostPrice: 8000, TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 9},
-		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catMakanan.ID, Name: "Nasi Jinggo", SKU: "MKN-010", Description: "Nasi bungkus khas Bali dengan lauk lengkap", BasePrice: 10000, CostPrice: 4000, TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 10},
+		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catMakanan.ID, Name: "Nasi Goreng Bali", SKU: "MKN-001", Description: "Nasi goreng khas Bali dengan bumbu lengkap", BasePrice: domain.Rupiah(28000), CostPrice: domain.Rupiah(12000), TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 1},
+		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catMakanan.ID, Name: "Mie Goreng Spesial", SKU: "MKN-002", Description: "Mie goreng dengan telur dan sayuran segar", BasePrice: domain.Rupiah(25000), CostPrice: domain.Rupiah(10000), TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 2},
+		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catMakanan.ID, Name: "Ayam Betutu", SKU: "MKN-003", Description: "Ayam betutu khas Bali dengan bumbu rempah", BasePrice: domain.Rupiah(45000), CostPrice: domain.Rupiah(22000), TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 3},
+		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catMakanan.ID, Name: "Sate Lilit", SKU: "MKN-004", Description: "Sate lilit ikan khas Bali", BasePrice: domain.Rupiah(30000), CostPrice: domain.Rupiah(15000), TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 4},
+		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catMakanan.ID, Name: "Bebek Goreng Crispy", SKU: "MKN-005", Description: "Bebek goreng renyah dengan sambal matah", BasePrice: domain.Rupiah(42000), CostPrice: domain.Rupiah(20000), TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 5},
+		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catMakanan.ID, Name: "Nasi Campur Bali", SKU: "MKN-006", Description: "Nasi campur dengan lauk tradisional Bali", BasePrice: domain.Rupiah(32000), CostPrice: domain.Rupiah(14000), TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 6},
+		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catMakanan.ID, Name: "Lawar Bali", SKU: "MKN-007", Description: "Lawar sayur campur daging khas Bali", BasePrice: domain.Rupiah(25000), CostPrice: domain.Rupiah(11000), TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 7},
+		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catMakanan.ID, Name: "Bakso Urat Jumbo", SKU: "MKN-008", Description: "Bakso urat jumbo dengan kuah kaldu sapi", BasePrice: domain.Rupiah(22000), CostPrice: domain.Rupiah(9000), TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 8},
+		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catMakanan.ID, Name: "Gado-Gado", SKU: "MKN-009", Description: "Gado-gado sayuran segar dengan bumbu kacang", BasePrice: domain.Rupiah(20000), CostPrice: domain.Rupiah(8000), TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 9},
+		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catMakanan.ID, Name: "Nasi Jinggo", SKU: "MKN-010", Description: "Nasi bungkus khas Bali dengan lauk lengkap", BasePrice: domain.Rupiah(10000), CostPrice: domain.Rupiah(4000), TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 10},
 	}
 
 	// ==========================================
 	// Seed Products - Minuman (10 items)
 	// ==========================================
 	minumanProducts := []domain.Product{
-		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catMinuman.ID, Name: "Es Teh Manis", SKU: "MNM-001", Description: "Es teh manis segar", BasePrice: 8000, CostPrice: 2000, TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 1},
-		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catMinuman.ID, Name: "Jus Alpukat", SKU: "MNM-002", Description: "Jus alpukat segar dengan susu coklat", BasePrice: 18000, CostPrice: 7000, TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 2},
-		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catMinuman.ID, Name: "Kopi Bali", SKU: "MNM-003", Description: "Kopi tubruk khas Bali", BasePrice: 12000, CostPrice: 3000, TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 3},
-		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catMinuman.ID, Name: "Es Jeruk Segar", SKU: "MNM-004", Description: "Es jeruk peras segar", BasePrice: 10000, CostPrice: 3000, TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 4},
-		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catMinuman.ID, Name: "Lemon Tea", SKU: "MNM-005", Description: "Lemon tea dingin menyegarkan", BasePrice: 12000, CostPrice: 3500, TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 5},
-		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catMinuman.ID, Name: "Jus Mangga", SKU: "MNM-006", Description: "Jus mangga harum manis segar", BasePrice: 15000, CostPrice: 5000, TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 6},
-		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catMinuman.ID, Name: "Milkshake Coklat", SKU: "MNM-007", Description: "Milkshake coklat premium", BasePrice: 22000, CostPrice: 8000, TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 7},
-		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catMinuman.ID, Name: "Es Kelapa Muda", SKU: "MNM-008", Description: "Es kelapa muda asli", BasePrice: 15000, CostPrice: 5000, TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 8},
-		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catMinuman.ID, Name: "Matcha Latte", SKU: "MNM-009", Description: "Matcha latte dengan susu segar", BasePrice: 25000, CostPrice: 9000, TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 9},
-		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catMinuman.ID, Name: "Air Mineral", SKU: "MNM-010", Description: "Air mineral kemasan 600ml", BasePrice: 5000, CostPrice: 1500, TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 10},
+		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catMinuman.ID, Name: "Es Teh Manis", SKU: "MNM-001", Description: "Es teh manis segar", BasePrice: domain.Rupiah(8000), CostPrice: domain.Rupiah(2000), TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 1},
+		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catMinuman.ID, Name: "Jus Alpukat", SKU: "MNM-002", Description: "Jus alpukat segar dengan susu coklat", BasePrice: domain.Rupiah(18000), CostPrice: domain.Rupiah(7000), TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 2},
+		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catMinuman.ID, Name: "Kopi Bali", SKU: "MNM-003", Description: "Kopi tubruk khas Bali", BasePrice: domain.Rupiah(12000), CostPrice: domain.Rupiah(3000), TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 3},
+		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catMinuman.ID, Name: "Es Jeruk Segar", SKU: "MNM-004", Description: "Es jeruk peras segar", BasePrice: domain.Rupiah(10000), CostPrice: domain.Rupiah(3000), TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 4},
+		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catMinuman.ID, Name: "Lemon Tea", SKU: "MNM-005", Description: "Lemon tea dingin menyegarkan", BasePrice: domain.Rupiah(12000), CostPrice: domain.Rupiah(3500), TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 5},
+		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catMinuman.ID, Name: "Jus Mangga", SKU: "MNM-006", Description: "Jus mangga harum manis segar", BasePrice: domain.Rupiah(15000), CostPrice: domain.Rupiah(5000), TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 6},
+		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catMinuman.ID, Name: "Milkshake Coklat", SKU: "MNM-007", Description: "Milkshake coklat premium", BasePrice: domain.Rupiah(22000), CostPrice: domain.Rupiah(8000), TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 7},
+		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catMinuman.ID, Name: "Es Kelapa Muda", SKU: "MNM-008", Description: "Es kelapa muda asli", BasePrice: domain.Rupiah(15000), CostPrice: domain.Rupiah(5000), TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 8},
+		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catMinuman.ID, Name: "Matcha Latte", SKU: "MNM-009", Description: "Matcha latte dengan susu segar", BasePrice: domain.Rupiah(25000), CostPrice: domain.Rupiah(9000), TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 9},
+		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catMinuman.ID, Name: "Air Mineral", SKU: "MNM-010", Description: "Air mineral kemasan 600ml", BasePrice: domain.Rupiah(5000), CostPrice: domain.Rupiah(1500), TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 10},
 	}
 
 	// ==========================================
 	// Seed Products - Snack (10 items)
 	// ==========================================
 	snackProducts := []domain.Product{
-		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catSnack.ID, Name: "Pisang Goreng", SKU: "SNK-001", Description: "Pisang goreng crispy dengan madu", BasePrice: 12000, CostPrice: 4000, TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 1},
-		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catSnack.ID, Name: "Kentang Goreng", SKU: "SNK-002", Description: "Kentang goreng renyah dengan saus", BasePrice: 18000, CostPrice: 6000, TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 2},
-		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catSnack.ID, Name: "Tahu Crispy", SKU: "SNK-003", Description: "Tahu goreng crispy dengan sambal kecap", BasePrice: 10000, CostPrice: 3000, TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 3},
-		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catSnack.ID, Name: "Lumpia Sayur", SKU: "SNK-004", Description: "Lumpia sayur goreng renyah", BasePrice: 12000, CostPrice: 4000, TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 4},
-		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catSnack.ID, Name: "Cireng Isi", SKU: "SNK-005", Description: "Cireng isi ayam dengan bumbu rujak", BasePrice: 10000, CostPrice: 3500, TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 5},
-		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catSnack.ID, Name: "Dimsum Ayam", SKU: "SNK-006", Description: "Dimsum ayam kukus isi 5 pcs", BasePrice: 20000, CostPrice: 8000, TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 6},
-		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catSnack.ID, Name: "Roti Bakar", SKU: "SNK-007", Description: "Roti bakar coklat keju", BasePrice: 15000, CostPrice: 5000, TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 7},
-		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catSnack.ID, Name: "Nachos Cheese", SKU: "SNK-008", Description: "Nachos dengan saus keju premium", BasePrice: 22000, CostPrice: 8000, TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 8},
-		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catSnack.ID, Name: "Singkong Keju", SKU: "SNK-009", Description: "Singkong goreng dengan taburan keju", BasePrice: 12000, CostPrice: 4000, TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 9},
-		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catSnack.ID, Name: "Onde-Onde", SKU: "SNK-010", Description: "Onde-onde isi kacang hijau", BasePrice: 8000, CostPrice: 2500, TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 10},
+		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catSnack.ID, Name: "Pisang Goreng", SKU: "SNK-001", Description: "Pisang goreng crispy dengan madu", BasePrice: domain.Rupiah(12000), CostPrice: domain.Rupiah(4000), TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 1},
+		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catSnack.ID, Name: "Kentang Goreng", SKU: "SNK-002", Description: "Kentang goreng renyah dengan saus", BasePrice: domain.Rupiah(18000), CostPrice: domain.Rupiah(6000), TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 2},
+		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catSnack.ID, Name: "Tahu Crispy", SKU: "SNK-003", Description: "Tahu goreng crispy dengan sambal kecap", BasePrice: domain.Rupiah(10000), CostPrice: domain.Rupiah(3000), TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 3},
+		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catSnack.ID, Name: "Lumpia Sayur", SKU: "SNK-004", Description: "Lumpia sayur goreng renyah", BasePrice: domain.Rupiah(12000), CostPrice: domain.Rupiah(4000), TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 4},
+		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catSnack.ID, Name: "Cireng Isi", SKU: "SNK-005", Description: "Cireng isi ayam dengan bumbu rujak", BasePrice: domain.Rupiah(10000), CostPrice: domain.Rupiah(3500), TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 5},
+		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catSnack.ID, Name: "Dimsum Ayam", SKU: "SNK-006", Description: "Dimsum ayam kukus isi 5 pcs", BasePrice: domain.Rupiah(20000), CostPrice: domain.Rupiah(8000), TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 6},
+		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catSnack.ID, Name: "Roti Bakar", SKU: "SNK-007", Description: "Roti bakar coklat keju", BasePrice: domain.Rupiah(15000), CostPrice: domain.Rupiah(5000), TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 7},
+		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catSnack.ID, Name: "Nachos Cheese", SKU: "SNK-008", Description: "Nachos dengan saus keju premium", BasePrice: domain.Rupiah(22000), CostPrice: domain.Rupiah(8000), TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 8},
+		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catSnack.ID, Name: "Singkong Keju", SKU: "SNK-009", Description: "Singkong goreng dengan taburan keju", BasePrice: domain.Rupiah(12000), CostPrice: domain.Rupiah(4000), TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 9},
+		{BaseModel: domain.BaseModel{ID: uuid.New()}, TenantID: tenantID, CategoryID: &catSnack.ID, Name: "Onde-Onde", SKU: "SNK-010", Description: "Onde-onde isi kacang hijau", BasePrice: domain.Rupiah(8000), CostPrice: domain.Rupiah(2500), TaxRate: 11, IsActive: true, TrackStock: true, SortOrder: 10},
 	}
 
 	// Insert all products
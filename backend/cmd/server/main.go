@@ -1,13 +1,9 @@
 package main
 
 import (
-	"bytes"
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
@@ -18,6 +14,7 @@ import (
 	"github.com/codapos/backend/internal/handler"
 	"github.com/codapos/backend/internal/middleware"
 	"github.com/codapos/backend/internal/migration"
+	"github.com/codapos/backend/internal/payment"
 	"github.com/codapos/backend/internal/repository"
 	"github.com/codapos/backend/internal/usecase"
 	"github.com/codapos/backend/internal/util"
@@ -89,12 +86,37 @@ func main() {
 	businessUnitRepo := repository.NewBusinessUnitRepository(db)
 	tenantBillingRepo := repository.NewTenantBillingRepository(db)
 	superAdminRepo := repository.NewSuperAdminRepository(db)
+	promotionRepo := repository.NewPromotionRepository(db)
+	splitBillRepo := repository.NewSplitBillRepository(db)
+	outletPriceRepo := repository.NewOutletPriceRepository(db)
+	modifierGroupRepo := repository.NewModifierGroupRepository(db)
+	sequenceRepo := repository.NewSequenceRepository(db)
+	taxRateRepo := repository.NewTaxRateRepository(db)
+	shiftRepo := repository.NewShiftRepository(db)
+	tableRepo := repository.NewTableRepository(db)
+	kitchenRepo := repository.NewKitchenRepository(db)
+	receiptTemplateRepo := repository.NewReceiptTemplateRepository(db)
+	receiptLinkRepo := repository.NewReceiptLinkRepository(db)
+	paymentNotificationRepo := repository.NewPaymentNotificationRepository(db)
+	loyaltyRepo := repository.NewLoyaltyRepository(db)
+	giftCardRepo := repository.NewGiftCardRepository(db)
+	unitOfWork := repository.NewUnitOfWork(db)
 
 	// Initialize usecases
 	authUsecase := usecase.NewAuthUsecase(tenantRepo, userRepo, merchantTypeRepo, featureFlagRepo, cfg)
-	posUsecase := usecase.NewPOSUsecase(transactionRepo, productRepo, inventoryRepo, accountingRepo, tenantBillingRepo)
-	productUsecase := usecase.NewProductUsecase(productRepo, categoryRepo)
+	sequenceUsecase := usecase.NewSequenceUsecase(sequenceRepo, outletRepo)
+	taxUsecase := usecase.NewTaxUsecase(taxRateRepo, accountingRepo, outletRepo)
+	kitchenUsecase := usecase.NewKitchenUsecase(kitchenRepo, productRepo, categoryRepo, outletRepo)
+	loyaltyUsecase := usecase.NewLoyaltyUsecase(loyaltyRepo, customerRepo, productRepo, categoryRepo, accountingRepo, unitOfWork)
+	giftCardUsecase := usecase.NewGiftCardUsecase(giftCardRepo, productRepo, unitOfWork, sequenceUsecase)
+	posUsecase := usecase.NewPOSUsecase(transactionRepo, productRepo, inventoryRepo, accountingRepo, tenantBillingRepo, promotionRepo, userRepo, splitBillRepo, outletPriceRepo, outletRepo, shiftRepo, tableRepo, unitOfWork, sequenceUsecase, taxUsecase, kitchenUsecase, loyaltyUsecase, giftCardUsecase)
+	shiftUsecase := usecase.NewShiftUsecase(shiftRepo, transactionRepo, outletRepo, unitOfWork)
+	tableUsecase := usecase.NewTableUsecase(tableRepo, transactionRepo, outletRepo, unitOfWork, kitchenUsecase)
+	receiptUsecase := usecase.NewReceiptUsecase(receiptTemplateRepo, receiptLinkRepo, transactionRepo, tenantRepo, outletRepo, featureFlagRepo, cfg.JWT.Secret, publicBaseURL(cfg))
+	promotionUsecase := usecase.NewPromotionUsecase(promotionRepo)
+	modifierUsecase := usecase.NewModifierUsecase(modifierGroupRepo, productRepo)
 	inventoryUsecase := usecase.NewInventoryUsecase(inventoryRepo)
+	productUsecase := usecase.NewProductUsecase(productRepo, categoryRepo, outletRepo, outletPriceRepo, inventoryUsecase)
 	outletUsecase := usecase.NewOutletUsecase(outletRepo, brandRepo, regionRepo)
 	accountingUsecase := usecase.NewAccountingUsecase(accountingRepo)
 	// Phase 1 usecases
@@ -107,12 +129,27 @@ func main() {
 	// Phase 6 usecases
 	forecastUsecase := usecase.NewForecastUsecase(transactionRepo)
 	// Phase 7 usecases
-	deliveryUsecase := usecase.NewDeliveryUsecase(deliveryRepo)
+	deliveryUsecase := usecase.NewDeliveryUsecase(deliveryRepo, unitOfWork, sequenceUsecase, posUsecase)
+	// Payment gateways; the fake one is for development and demos only
+	paymentGateways := []domain.PaymentGateway{payment.NewMidtrans(globalConfigRepo)}
+	var fakeGateway *payment.Fake
+	if cfg.AppEnv != "production" {
+		fakeGateway = payment.NewFake(cfg.JWT.Secret, publicBaseURL(cfg)+"/api/v1/payment/fake")
+		paymentGateways = append(paymentGateways, fakeGateway)
+	}
+	paymentUsecase := usecase.NewPaymentUsecase(globalConfigRepo, paymentNotificationRepo, tenantRepo, deliveryRepo, transactionRepo, subscriptionRepo, tenantBillingRepo, posUsecase, paymentGateways...)
+	storefrontUsecase := usecase.NewStorefrontUsecase(tenantRepo, outletRepo, deliveryRepo, customerUsecase, deliveryUsecase, posUsecase, paymentUsecase)
 
 	// Initialize handlers
 	authHandler := handler.NewAuthHandler(authUsecase, accountingUsecase, tenantRepo)
 	posHandler := handler.NewPOSHandler(posUsecase)
+	shiftHandler := handler.NewShiftHandler(shiftUsecase)
+	tableHandler := handler.NewTableHandler(tableUsecase, posUsecase)
+	kitchenHandler := handler.NewKitchenHandler(kitchenUsecase)
+	receiptHandler := handler.NewReceiptHandler(receiptUsecase)
 	productHandler := handler.NewProductHandler(productUsecase)
+	promotionHandler := handler.NewPromotionHandler(promotionUsecase)
+	modifierHandler := handler.NewModifierHandler(modifierUsecase)
 	outletHandler := handler.NewOutletHandler(outletUsecase)
 	accountingHandler := handler.NewAccountingHandler(accountingUsecase)
 	inventoryHandler := handler.NewInventoryHandler(inventoryUsecase)
@@ -127,7 +164,13 @@ func main() {
 	forecastHandler := handler.NewForecastHandler(forecastUsecase)
 	// Phase 7 handlers
 	deliveryHandler := handler.NewDeliveryHandler(deliveryUsecase)
+	paymentHandler := handler.NewPaymentHandler(paymentUsecase, fakeGateway)
+	storefrontHandler := handler.NewStorefrontHandler(storefrontUsecase, chatRepo)
 	chatHandler := handler.NewChatHandler(chatRepo, deliveryRepo)
+	sequenceHandler := handler.NewSequenceHandler(sequenceUsecase)
+	taxHandler := handler.NewTaxHandler(taxUsecase)
+	loyaltyHandler := handler.NewLoyaltyHandler(loyaltyUsecase)
+	giftCardHandler := handler.NewGiftCardHandler(giftCardUsecase)
 	// AI handler
 	aiHandler := handler.NewAIHandler(db)
 
@@ -214,81 +257,8 @@ func main() {
 		return c.JSON(fiber.Map{"success": true, "data": fiber.Map{"mode": mode, "client_key": clientKey}})
 	})
 
-	// Public: Midtrans payment creation
-	api.Post("/payment/create", func(c *fiber.Ctx) error {
-		var body struct {
-			OrderID     string  `json:"order_id"`
-			GrossAmount float64 `json:"gross_amount"`
-			FirstName   string  `json:"first_name"`
-			Email       string  `json:"email"`
-			Phone       string  `json:"phone"`
-		}
-		if err := c.BodyParser(&body); err != nil {
-			return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid request"})
-		}
-
-		// Load Midtrans config from DB
-		modeConfig, _ := globalConfigRepo.FindByKey(domain.ConfigMidtransMode)
-		serverKeyConfig, _ := globalConfigRepo.FindByKey(domain.ConfigMidtransServerKey)
-
-		if serverKeyConfig == nil || serverKeyConfig.Value == "" {
-			return c.Status(500).JSON(fiber.Map{"success": false, "error": "Midtrans belum dikonfigurasi"})
-		}
-
-		mode := "sandbox"
-		if modeConfig != nil && modeConfig.Value == "production" {
-			mode = "production"
-		}
-
-		// Midtrans Snap API URL
-		snapURL := "https://app.sandbox.midtrans.com/snap/v1/transactions"
-		if mode == "production" {
-			snapURL = "https://app.midtrans.com/snap/v1/transactions"
-		}
-
-		// Build Snap request
-		snapReq := map[string]interface{}{
-			"transaction_details": map[string]interface{}{
-				"order_id":     body.OrderID,
-				"gross_amount": int64(body.GrossAmount),
-			},
-			"customer_details": map[string]interface{}{
-				"first_name": body.FirstName,
-				"email":      body.Email,
-				"phone":      body.Phone,
-			},
-			"enabled_payments": []string{
-				"credit_card", "bca_va", "bni_va", "bri_va", "permata_va",
-				"other_va", "gopay", "shopeepay", "qris",
-			},
-			"credit_card": map[string]interface{}{
-				"secure": true,
-			},
-		}
-
-		jsonBody, _ := json.Marshal(snapReq)
-		req, _ := http.NewRequest("POST", snapURL, bytes.NewBuffer(jsonBody))
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("Accept", "application/json")
-		req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(serverKeyConfig.Value+":")))
-
-		client := &http.Client{}
-		resp, err := client.Do(req)
-		if err != nil {
-			return c.Status(500).JSON(fiber.Map{"success": false, "error": "Gagal menghubungi Midtrans"})
-		}
-		defer resp.Body.Close()
-
-		respBody, _ := io.ReadAll(resp.Body)
-		var snapResp map[string]interface{}
-		json.Unmarshal(respBody, &snapResp)
-
-		if resp.StatusCode != 201 {
-			return c.Status(resp.StatusCode).JSON(fiber.Map{"success": false, "error": "Midtrans error", "details": snapResp})
-		}
-
-		return c.JSON(fiber.Map{"success": true, "data": snapResp})
-	})
+	// Public: payment creation and gateway notifications
+	paymentHandler.RegisterPublicRoutes(api)
 
 	// Public: store page (mini app for each merchant)
 	api.Get("/store/:slug", func(c *fiber.Ctx) error {
@@ -311,8 +281,24 @@ func main() {
 			tenant.MerchantType = mt
 		}
 
+		// ?outlet_id shows the prices and stock of the outlet the order will be fulfilled
+		// from, by default the outlet the storefront sells from
+		var outletID *uuid.UUID
+		if oid := c.Query("outlet_id"); oid != "" {
+			parsed, err := uuid.Parse(oid)
+			if err != nil {
+				return c.Status(400).JSON(fiber.Map{"success": false, "error": "invalid outlet_id"})
+			}
+			outletID = &parsed
+		} else if outlet, err := storefrontUsecase.FulfillingOutlet(tenant.ID); err == nil {
+			outletID = &outlet.ID
+		}
+
 		categories, _ := categoryRepo.FindByTenantID(tenant.ID)
-		products, _ := productRepo.FindByTenantID(tenant.ID, "", nil)
+		products, err := productUsecase.GetProducts(tenant.ID, "", nil, outletID)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"success": false, "error": err.Error()})
+		}
 
 		return c.JSON(fiber.Map{
 			"success": true,
@@ -324,108 +310,8 @@ func main() {
 		})
 	})
 
-	// Public: store checkout — create customer + delivery order (after Midtrans payment)
-	api.Post("/store/:slug/checkout", func(c *fiber.Ctx) error {
-		slug := c.Params("slug")
-		if slug == "" {
-			return c.Status(400).JSON(fiber.Map{"success": false, "error": "slug is required"})
-		}
-
-		tenant, err := tenantRepo.FindBySlug(slug)
-		if err != nil || !tenant.IsEnabled {
-			return c.Status(404).JSON(fiber.Map{"success": false, "error": "Toko tidak ditemukan"})
-		}
-
-		// Parse checkout request
-		var req struct {
-			Name          string  `json:"name"`
-			Phone         string  `json:"phone"`
-			FullAddress   string  `json:"full_address"`
-			Latitude      float64 `json:"latitude"`
-			Longitude     float64 `json:"longitude"`
-			Notes         string  `json:"notes"`
-			MidtransOrder string  `json:"midtrans_order_id"`
-			TotalAmount   float64 `json:"total_amount"`
-			ItemsSummary  string  `json:"items_summary"`
-		}
-		if err := c.BodyParser(&req); err != nil {
-			return c.Status(400).JSON(fiber.Map{"success": false, "error": "Invalid request body"})
-		}
-		if req.Name == "" || req.Phone == "" || req.FullAddress == "" {
-			return c.Status(400).JSON(fiber.Map{"success": false, "error": "Nama, No HP, dan Alamat wajib diisi"})
-		}
-
-		// 1) Create or find customer (upsert by phone)
-		customer, err := customerUsecase.CreateCustomer(tenant.ID, domain.CreateCustomerRequest{
-			Name:        req.Name,
-			Phone:       req.Phone,
-			FullAddress: req.FullAddress,
-			Latitude:    req.Latitude,
-			Longitude:   req.Longitude,
-		})
-		if err != nil {
-			return c.Status(500).JSON(fiber.Map{"success": false, "error": "Gagal menyimpan data pelanggan"})
-		}
-
-		// 2) Build store pickup address from tenant settings
-		var storeSettings map[string]interface{}
-		if tenant.Settings != nil {
-			json.Unmarshal(tenant.Settings, &storeSettings)
-		}
-		storeAddress := tenant.Name
-		storePhone := ""
-		if addr, ok := storeSettings["store_address"].(string); ok && addr != "" {
-			storeAddress = addr
-		}
-		if ph, ok := storeSettings["store_phone"].(string); ok && ph != "" {
-			storePhone = ph
-		}
-
-		// 3) Create delivery order
-		deliveryOrder, err := deliveryUsecase.CreateOrder(tenant.ID, domain.CreateDeliveryRequest{
-			CustomerID:     customer.ID.String(),
-			PickupAddress:  storeAddress,
-			PickupContact:  tenant.Name,
-			PickupPhone:    storePhone,
-			DropoffAddress: req.FullAddress,
-			DropoffLat:     req.Latitude,
-			DropoffLng:     req.Longitude,
-			DropoffContact: req.Name,
-			DropoffPhone:   req.Phone,
-			PackageDesc:    req.ItemsSummary,
-			Notes:          req.Notes,
-		})
-		if err != nil {
-			return c.Status(500).JSON(fiber.Map{"success": false, "error": "Gagal membuat pesanan pengiriman"})
-		}
-
-		// Save extra fields on the delivery order
-		deliveryOrder.MidtransOrderID = req.MidtransOrder
-		deliveryOrder.TotalAmount = req.TotalAmount
-		deliveryOrder.ItemsSummary = req.ItemsSummary
-		deliveryRepo.UpdateOrder(deliveryOrder)
-
-		// 4) Auto-create ChatRoom for this delivery
-		chatRoom := &domain.ChatRoom{
-			BaseModel:  domain.BaseModel{ID: uuid.New(), CreatedAt: time.Now(), UpdatedAt: time.Now()},
-			TenantID:   tenant.ID,
-			DeliveryID: deliveryOrder.ID,
-			CustomerID: customer.ID,
-			Status:     domain.ChatRoomStatusOpen,
-		}
-		chatRepo.CreateRoom(chatRoom)
-
-		return c.Status(201).JSON(fiber.Map{
-			"success": true,
-			"data": fiber.Map{
-				"customer_id":       customer.ID,
-				"delivery_order_id": deliveryOrder.ID,
-				"order_number":      deliveryOrder.OrderNumber,
-				"chat_room_id":      chatRoom.ID,
-				"message":           "Pesanan berhasil! Kurir akan segera mengantar pesanan Anda.",
-			},
-		})
-	})
+	// Public: storefront quotes and checkout, priced by the server and paid through the gateway
+	storefrontHandler.RegisterPublicRoutes(api)
 
 	// Public: customer order tracking
 	api.Get("/store/:slug/orders/:orderId", func(c *fiber.Ctx) error {
@@ -459,6 +345,9 @@ func main() {
 	// Public: customer self-registration (no auth required)
 	customerHandler.RegisterPublicRoutes(api)
 
+	// Public: digital receipts opened from links shared with customers
+	receiptHandler.RegisterPublicRoutes(api)
+
 	// Public: subscription plans listing
 	subscriptionHandler.RegisterPublicRoutes(api)
 
@@ -478,9 +367,17 @@ func main() {
 		return c.JSON(fiber.Map{"data": result})
 	})
 
+	// Kitchen display stream, authenticated by a short-lived stream token in the query
+	// string (EventSource cannot send headers). Registered ahead of the protected group
+	// so the bearer token check does not run for it.
+	kitchenHandler.RegisterStreamRoutes(api, middleware.StreamAuthMiddleware(cfg))
+
 	// Protected routes (authentication required)
 	protected := api.Group("", middleware.AuthMiddleware(cfg))
 
+	// Stream tokens for event streams opened from the browser
+	protected.Post("/stream-token", middleware.StreamToken(cfg))
+
 	// Phase 2: User management + profile
 	userHandler.RegisterRoutes(protected)
 
@@ -630,6 +527,24 @@ func main() {
 	categories.Put("/:id", middleware.PermissionMiddleware(middleware.ActionManageProducts), productHandler.UpdateCategory)
 	categories.Delete("/:id", middleware.PermissionMiddleware(middleware.ActionManageProducts), productHandler.DeleteCategory)
 
+	// Outlet price overrides — managed in bulk per outlet
+	outletPrices := protected.Group("/outlet-prices")
+	outletPrices.Get("/:outlet_id", middleware.PermissionMiddleware(middleware.ActionReadProducts), productHandler.GetOutletPrices)
+	outletPrices.Put("/:outlet_id", middleware.PermissionMiddleware(middleware.ActionManageProducts), productHandler.SetOutletPrices)
+	outletPrices.Delete("/:outlet_id", middleware.PermissionMiddleware(middleware.ActionManageProducts), productHandler.DeleteOutletPrices)
+
+	// Promotions — applied server-side at checkout
+	promotionHandler.RegisterRoutes(protected)
+
+	// Modifier groups — priced and validated server-side at checkout
+	modifierHandler.RegisterRoutes(protected)
+
+	// Document numbering — per-outlet sequences for receipts, refunds, deliveries and journals
+	sequenceHandler.RegisterRoutes(protected)
+
+	// Tax rates — PPN/PB1, inclusive or exclusive, chosen per outlet and charged at checkout
+	taxHandler.RegisterRoutes(protected)
+
 	// Inventory — stock management (same permission as products)
 	inventory := protected.Group("/inventory", middleware.PermissionMiddleware(middleware.ActionManageProducts))
 	inventory.Get("", inventoryHandler.GetStock)
@@ -641,13 +556,36 @@ func main() {
 	// POS — checkout: broader, refund: restricted
 	pos := protected.Group("/pos")
 	pos.Post("/checkout", middleware.PermissionMiddleware(middleware.ActionPOSCheckout), posHandler.Checkout)
+	pos.Post("/quote", middleware.PermissionMiddleware(middleware.ActionPOSCheckout), posHandler.Quote)
 	pos.Post("/refund/:id", middleware.PermissionMiddleware(middleware.ActionPOSRefund), posHandler.Refund)
+	pos.Post("/void/:id", middleware.PermissionMiddleware(middleware.ActionPOSCheckout), posHandler.Void)
 	pos.Get("/transactions", middleware.PermissionMiddleware(middleware.ActionReadTransactions), posHandler.GetTransactions)
 	pos.Get("/transactions/:id", middleware.PermissionMiddleware(middleware.ActionReadTransactions), posHandler.GetTransaction)
 	pos.Post("/transactions/:id/reprint", middleware.PermissionMiddleware(middleware.ActionReadTransactions), posHandler.ReprintTransaction)
+	pos.Post("/transactions/:id/split", middleware.PermissionMiddleware(middleware.ActionPOSCheckout), posHandler.SplitBill)
+	pos.Post("/transactions/:id/splits/:split_id/pay", middleware.PermissionMiddleware(middleware.ActionPOSCheckout), posHandler.PaySplit)
+	pos.Post("/orders", middleware.PermissionMiddleware(middleware.ActionPOSCheckout), posHandler.HoldOrder)
+	pos.Get("/orders", middleware.PermissionMiddleware(middleware.ActionPOSCheckout), posHandler.GetHeldOrders)
+	pos.Put("/orders/:id", middleware.PermissionMiddleware(middleware.ActionPOSCheckout), posHandler.UpdateHeldOrder)
+	pos.Post("/orders/:id/settle", middleware.PermissionMiddleware(middleware.ActionPOSCheckout), posHandler.SettleHeldOrder)
 	pos.Get("/billings", posHandler.GetTenantBillings)
 	pos.Post("/billings/:id/pay", posHandler.PayTenantBilling)
 
+	// Cashier shifts — drawer sessions with opening float, petty cash, blind count and Z-report
+	shiftHandler.RegisterRoutes(protected)
+
+	// Floor plans and tables — dine-in seating, move and merge
+	tableHandler.RegisterRoutes(protected)
+
+	// Kitchen stations and tickets; the display stream is registered above
+	kitchenHandler.RegisterRoutes(protected)
+
+	// Receipt templates and server-rendered receipts (ESC/POS, text, HTML)
+	receiptHandler.RegisterRoutes(protected)
+
+	// Gateway payments started by the cashier
+	paymentHandler.RegisterRoutes(protected)
+
 	// Accounting (owner + finance only)
 	accounting := protected.Group("/accounting", middleware.PermissionMiddleware(middleware.ActionManageAccounting))
 	accounting.Get("/coa", accountingHandler.GetCOA)
@@ -655,24 +593,56 @@ func main() {
 	accounting.Get("/reports/trial-balance", accountingHandler.GetTrialBalance)
 	accounting.Get("/reports/profit-loss", accountingHandler.GetProfitLoss)
 	accounting.Get("/reports/balance-sheet", accountingHandler.GetBalanceSheet)
+	accounting.Get("/failed-postings", posHandler.GetFailedPostings)
+	accounting.Post("/failed-postings/:id/repost", posHandler.RepostJournal)
 
 	// Customers (owner, admin, outlet_manager, cashier)
 	customerHandler.RegisterRoutes(protected)
 
+	// Loyalty points — earn rules, tiers, balances and the points ledger per customer
+	loyaltyHandler.RegisterRoutes(protected)
+
+	// Gift cards — sold as products, looked up by code at the till
+	giftCardHandler.RegisterRoutes(protected)
+
 	// Super Admin routes (super_admin only)
 	adminProtected := api.Group("", middleware.AuthMiddleware(cfg), middleware.RoleMiddleware(domain.RoleSuperAdmin))
 	superAdminHandler.RegisterRoutes(adminProtected)
+	paymentHandler.RegisterAdminRoutes(adminProtected)
 
 	// Admin manual trigger for generating monthly bills
 	adminBills := adminProtected.Group("/bills")
 	adminBills.Post("/generate", posHandler.GenerateMonthlyBillings)
 
+	// Expire lapsed gift cards hourly so their breakage is booked when they lapse
+	go runEvery(time.Hour, "gift card expiry", giftCardUsecase.ExpireDue)
+	// Expire lapsed held orders every minute so their tables and kitchen tickets are freed
+	go runEvery(time.Minute, "held order expiry", posUsecase.ExpireHeldOrders)
+
 	// Start server
 	port := fmt.Sprintf(":%s", cfg.AppPort)
 	log.Printf("🚀 CODAPOS API starting on port %s", port)
 	log.Fatal(app.Listen(port))
 }
 
+// publicBaseURL is the address customers reach the API on, used in links sent to them
+func publicBaseURL(cfg *config.Config) string {
+	if baseURL := os.Getenv("BASE_URL"); baseURL != "" {
+		return baseURL
+	}
+	return "http://localhost:" + cfg.AppPort
+}
+
+// runEvery runs a background job now and then at every interval, logging its failures
+func runEvery(interval time.Duration, name string, job func() error) {
+	for {
+		if err := job(); err != nil {
+			log.Printf("⚠️  %s failed: %v", name, err)
+		}
+		time.Sleep(interval)
+	}
+}
+
 func customErrorHandler(c *fiber.Ctx, err error) error {
 	code := fiber.StatusInternalServerError
 	if e, ok := err.(*fiber.Error); ok {
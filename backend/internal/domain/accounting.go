@@ -17,7 +17,7 @@ type ChartOfAccount struct {
 	SubType  string     `json:"sub_type,omitempty" gorm:"size:50"`
 	IsSystem bool       `json:"is_system" gorm:"default:false"`
 	IsActive bool       `json:"is_active" gorm:"default:true"`
-	Balance  float64    `json:"balance" gorm:"type:decimal(15,2);default:0"`
+	Balance  Money      `json:"balance" gorm:"type:decimal(15,2);default:0"`
 
 	// Relations
 	Parent   *ChartOfAccount  `json:"parent,omitempty" gorm:"foreignKey:ParentID"`
@@ -37,14 +37,19 @@ const (
 
 // COA SubType constants
 const (
-	AccountSubTypeCash       = "cash"
-	AccountSubTypeBank       = "bank"
-	AccountSubTypeReceivable = "receivable"
-	AccountSubTypePayable    = "payable"
-	AccountSubTypeInventory  = "inventory"
-	AccountSubTypeCOGS       = "cogs"
-	AccountSubTypeSales      = "sales"
-	AccountSubTypeTax        = "tax"
+	AccountSubTypeCash          = "cash"
+	AccountSubTypeBank          = "bank"
+	AccountSubTypeReceivable    = "receivable"
+	AccountSubTypePayable       = "payable"
+	AccountSubTypeInventory     = "inventory"
+	AccountSubTypeCOGS          = "cogs"
+	AccountSubTypeSales         = "sales"
+	AccountSubTypeTax           = "tax"
+	AccountSubTypeServiceCharge = "service_charge"
+	AccountSubTypeTips          = "tips_payable"
+	AccountSubTypeLoyalty       = "loyalty"            // cost of sales paid with loyalty points
+	AccountSubTypeGiftCard      = "gift_card"          // gift card balances owed to holders
+	AccountSubTypeBreakage      = "gift_card_breakage" // gift card balances left to expire
 )
 
 // JournalEntry represents a journal entry header
@@ -52,15 +57,15 @@ type JournalEntry struct {
 	BaseModel
 	TenantID      uuid.UUID  `json:"tenant_id" gorm:"type:uuid;not null;index"`
 	OutletID      *uuid.UUID `json:"outlet_id,omitempty" gorm:"type:uuid"`
-	EntryNumber   string     `json:"entry_number" gorm:"size:50;not null"`
+	EntryNumber   string     `json:"entry_number" gorm:"size:100;not null"`
 	Date          time.Time  `json:"date" gorm:"type:date;not null"`
 	Description   string     `json:"description,omitempty"`
 	Source        string     `json:"source,omitempty" gorm:"size:50"`
 	ReferenceType string     `json:"reference_type,omitempty" gorm:"size:50"`
 	ReferenceID   *uuid.UUID `json:"reference_id,omitempty" gorm:"type:uuid"`
 	Status        string     `json:"status" gorm:"size:20;default:'posted'"`
-	TotalDebit    float64    `json:"total_debit" gorm:"type:decimal(15,2);not null;default:0"`
-	TotalCredit   float64    `json:"total_credit" gorm:"type:decimal(15,2);not null;default:0"`
+	TotalDebit    Money      `json:"total_debit" gorm:"type:decimal(15,2);not null;default:0"`
+	TotalCredit   Money      `json:"total_credit" gorm:"type:decimal(15,2);not null;default:0"`
 	CreatedBy     *uuid.UUID `json:"created_by,omitempty" gorm:"type:uuid"`
 	ApprovedBy    *uuid.UUID `json:"approved_by,omitempty" gorm:"type:uuid"`
 
@@ -74,9 +79,11 @@ func (JournalEntry) TableName() string { return "journal_entries" }
 const (
 	JournalSourcePOSSale   = "pos_sale"
 	JournalSourcePOSRefund = "pos_refund"
+	JournalSourcePOSVoid   = "pos_void"
 	JournalSourceInventory = "inventory"
 	JournalSourceManual    = "manual"
 	JournalSourceRoyalty   = "royalty"
+	JournalSourceGiftCard  = "gift_card"
 )
 
 // JournalEntryLine represents a debit/credit line in a journal
@@ -85,8 +92,8 @@ type JournalEntryLine struct {
 	JournalEntryID uuid.UUID `json:"journal_entry_id" gorm:"type:uuid;not null;index"`
 	AccountID      uuid.UUID `json:"account_id" gorm:"type:uuid;not null"`
 	Description    string    `json:"description,omitempty"`
-	Debit          float64   `json:"debit" gorm:"type:decimal(15,2);default:0"`
-	Credit         float64   `json:"credit" gorm:"type:decimal(15,2);default:0"`
+	Debit          Money     `json:"debit" gorm:"type:decimal(15,2);default:0"`
+	Credit         Money     `json:"credit" gorm:"type:decimal(15,2);default:0"`
 	CreatedAt      time.Time `json:"created_at"`
 
 	// Relations
@@ -108,20 +115,52 @@ type FiscalPeriod struct {
 
 func (FiscalPeriod) TableName() string { return "fiscal_periods" }
 
-// TaxRate represents a tax configuration
+// TaxRate represents a tax configuration.
+// Default rates apply to every outlet that has no tax rates of its own. Inclusive
+// rates are already contained in the selling price; compound rates are charged on
+// the price plus the taxes before them (in SortOrder).
 type TaxRate struct {
-	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
-	TenantID  uuid.UUID `json:"tenant_id" gorm:"type:uuid;not null;index"`
-	Name      string    `json:"name" gorm:"size:100;not null"`
-	Rate      float64   `json:"rate" gorm:"type:decimal(5,2);not null"`
-	Type      string    `json:"type" gorm:"size:20;default:'ppn'"`
-	IsDefault bool      `json:"is_default" gorm:"default:false"`
-	IsActive  bool      `json:"is_active" gorm:"default:true"`
-	CreatedAt time.Time `json:"created_at"`
+	ID          uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	TenantID    uuid.UUID  `json:"tenant_id" gorm:"type:uuid;not null;index"`
+	Name        string     `json:"name" gorm:"size:100;not null"`
+	Rate        float64    `json:"rate" gorm:"type:decimal(5,2);not null"`
+	Type        string     `json:"type" gorm:"size:20;default:'ppn'"`
+	IsInclusive bool       `json:"is_inclusive" gorm:"default:false"`
+	IsCompound  bool       `json:"is_compound" gorm:"default:false"`
+	SortOrder   int        `json:"sort_order" gorm:"default:0"`
+	AccountID   *uuid.UUID `json:"account_id,omitempty" gorm:"type:uuid"` // liability account the tax is posted to
+	IsDefault   bool       `json:"is_default" gorm:"default:false"`
+	IsActive    bool       `json:"is_active" gorm:"default:true"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+
+	Account *ChartOfAccount `json:"account,omitempty" gorm:"foreignKey:AccountID"`
 }
 
 func (TaxRate) TableName() string { return "tax_rates" }
 
+// Tax type constants
+const (
+	TaxTypePPN   = "ppn"   // Pajak Pertambahan Nilai (VAT)
+	TaxTypePB1   = "pb1"   // Pajak Barang dan Jasa Tertentu, e.g. restaurant tax
+	TaxTypeOther = "other" // any other tax, including the legacy per-product rate
+)
+
+// OutletTaxRate assigns a tax rate to an outlet. Outlets without any assignment
+// charge the tenant's default rates.
+type OutletTaxRate struct {
+	OutletID  uuid.UUID `json:"outlet_id" gorm:"type:uuid;primaryKey"`
+	TaxRateID uuid.UUID `json:"tax_rate_id" gorm:"type:uuid;primaryKey;index"`
+}
+
+func (OutletTaxRate) TableName() string { return "outlet_tax_rates" }
+
+// OutletTaxRatesRequest is the DTO for choosing the tax rates of an outlet.
+// An empty list makes the outlet fall back to the tenant's default rates.
+type OutletTaxRatesRequest struct {
+	TaxRateIDs []uuid.UUID `json:"tax_rate_ids"`
+}
+
 // AccountingRepository defines the interface for accounting data access
 type AccountingRepository interface {
 	// Chart of Accounts
@@ -129,11 +168,12 @@ type AccountingRepository interface {
 	FindAccountByID(id uuid.UUID) (*ChartOfAccount, error)
 	FindAccountsByTenantID(tenantID uuid.UUID) ([]ChartOfAccount, error)
 	UpdateAccount(account *ChartOfAccount) error
-	UpdateAccountBalance(accountID uuid.UUID, amount float64) error
+	UpdateAccountBalance(accountID uuid.UUID, amount Money) error
 
 	// Journal Entries
 	CreateJournal(entry *JournalEntry) error
 	FindJournalByID(id uuid.UUID) (*JournalEntry, error)
+	FindJournalsByReference(referenceType string, referenceID uuid.UUID) ([]JournalEntry, error)
 	FindJournalsByTenantID(tenantID uuid.UUID, startDate, endDate *time.Time, limit, offset int) ([]JournalEntry, int64, error)
 
 	// Reports
@@ -141,3 +181,14 @@ type AccountingRepository interface {
 	GetProfitLoss(tenantID uuid.UUID, startDate, endDate time.Time) ([]ChartOfAccount, error)
 	GetBalanceSheet(tenantID uuid.UUID, asOfDate time.Time) ([]ChartOfAccount, error)
 }
+
+// TaxRateRepository defines the interface for tax rate data access
+type TaxRateRepository interface {
+	Create(rate *TaxRate) error
+	FindByID(id uuid.UUID) (*TaxRate, error)
+	FindByTenantID(tenantID uuid.UUID, activeOnly bool) ([]TaxRate, error)
+	FindByOutletID(outletID uuid.UUID) ([]TaxRate, error)
+	Update(rate *TaxRate) error
+	Delete(id uuid.UUID) error
+	SetOutletTaxRates(outletID uuid.UUID, rateIDs []uuid.UUID) error
+}
@@ -9,13 +9,17 @@ import (
 // DeliveryOrder represents a delivery/courier order (GoSend-style)
 type DeliveryOrder struct {
 	BaseModel
-	TenantID      uuid.UUID  `json:"tenant_id" gorm:"type:uuid;not null;index"`
+	TenantID      uuid.UUID  `json:"tenant_id" gorm:"type:uuid;not null;index;uniqueIndex:idx_delivery_orders_tenant_idempotency"`
+	OutletID      *uuid.UUID `json:"outlet_id,omitempty" gorm:"type:uuid;index"` // outlet a storefront order is fulfilled from
 	TransactionID *uuid.UUID `json:"transaction_id,omitempty" gorm:"type:uuid;index"`
 	CustomerID    *uuid.UUID `json:"customer_id,omitempty" gorm:"type:uuid;index"`
 	DriverID      *uuid.UUID `json:"driver_id,omitempty" gorm:"type:uuid;index"`
-	OrderNumber   string     `json:"order_number" gorm:"size:50;uniqueIndex;not null"`
+	OrderNumber   string     `json:"order_number" gorm:"size:100;uniqueIndex;not null"`
 	Status        string     `json:"status" gorm:"size:30;not null;default:'pending';index"`
 
+	// Storefront checkout retries with the same key return the original order
+	IdempotencyKey *string `json:"-" gorm:"size:100;uniqueIndex:idx_delivery_orders_tenant_idempotency"`
+
 	// Pickup
 	PickupAddress string  `json:"pickup_address" gorm:"not null"`
 	PickupLat     float64 `json:"pickup_lat" gorm:"type:decimal(10,7)"`
@@ -33,14 +37,23 @@ type DeliveryOrder struct {
 	// Delivery details
 	PackageDesc     string  `json:"package_desc" gorm:"size:255"`
 	DistanceKm      float64 `json:"distance_km" gorm:"type:decimal(10,2)"`
-	DeliveryFee     float64 `json:"delivery_fee" gorm:"type:decimal(15,2);default:0"`
+	DeliveryFee     Money   `json:"delivery_fee" gorm:"type:decimal(15,2);default:0"`
 	Notes           string  `json:"notes,omitempty"`
 	EstimatedTime   int     `json:"estimated_time"` // minutes
 	CourierName     string  `json:"courier_name" gorm:"size:100"`
-	MidtransOrderID string  `json:"midtrans_order_id" gorm:"size:100;index"`
-	TotalAmount     float64 `json:"total_amount" gorm:"type:decimal(15,2);default:0"`
+	MidtransOrderID string  `json:"midtrans_order_id" gorm:"size:100;index"` // gateway order ID, whichever the gateway
+	TotalAmount     Money   `json:"total_amount" gorm:"type:decimal(15,2);default:0"`
 	ItemsSummary    string  `json:"items_summary" gorm:"type:text"`
 
+	// Sale is the priced Transaction of a storefront order, booked as a sale of the outlet
+	// once the order is paid and linked through TransactionID
+	Sale JSON `json:"-" gorm:"type:jsonb"`
+
+	// Gateway payment of a storefront order, handed back to the customer on a retried checkout
+	PaymentProvider string `json:"payment_provider,omitempty" gorm:"size:20"`
+	PaymentToken    string `json:"-" gorm:"size:255"`
+	PaymentURL      string `json:"-" gorm:"size:500"`
+
 	// Timestamps
 	AssignedAt   *time.Time `json:"assigned_at,omitempty"`
 	PickedUpAt   *time.Time `json:"picked_up_at,omitempty"`
@@ -106,6 +119,61 @@ type CreateDeliveryRequest struct {
 	DropoffPhone   string  `json:"dropoff_phone"`
 	PackageDesc    string  `json:"package_desc"`
 	Notes          string  `json:"notes"`
+	IdempotencyKey string  `json:"-"`
+	// Set by the storefront: the order waits for its payment before the merchant sees it as new
+	OutletID     *uuid.UUID `json:"-"`
+	AwaitPayment bool       `json:"-"`
+}
+
+// DeliveryQuote is the distance, fee and travel time of a delivery
+type DeliveryQuote struct {
+	DistanceKm    float64 `json:"distance_km"`
+	DeliveryFee   Money   `json:"delivery_fee"`
+	EstimatedTime int     `json:"estimated_time"` // minutes
+}
+
+// StoreCheckoutRequest is a storefront order. Only the products are taken from the
+// customer; prices, fees and the total are worked out by the server.
+type StoreCheckoutRequest struct {
+	OutletID    *uuid.UUID            `json:"outlet_id,omitempty"` // defaults to the first active outlet
+	Name        string                `json:"name"`
+	Phone       string                `json:"phone"`
+	FullAddress string                `json:"full_address"`
+	Latitude    float64               `json:"latitude"`
+	Longitude   float64               `json:"longitude"`
+	Notes       string                `json:"notes"`
+	Items       []CheckoutItemRequest `json:"items"`
+	// ClientOrderID is generated by the storefront once per order so retries are not ordered twice
+	ClientOrderID string `json:"client_order_id"`
+	// IdempotencyKey is resolved by the handler from the Idempotency-Key header or ClientOrderID
+	IdempotencyKey string `json:"-"`
+}
+
+// StoreQuote is the price of a storefront order
+type StoreQuote struct {
+	OutletID            uuid.UUID         `json:"outlet_id"`
+	Items               []TransactionItem `json:"items"`
+	Subtotal            Money             `json:"subtotal"`
+	DiscountAmount      Money             `json:"discount_amount"`
+	TaxAmount           Money             `json:"tax_amount"`
+	ServiceChargeAmount Money             `json:"service_charge_amount"`
+	DeliveryQuote
+	TotalAmount Money `json:"total_amount"`
+
+	Sale *Transaction `json:"-"` // the priced sale, delivery fee included
+}
+
+// StoreCheckoutResult is a storefront order waiting for its payment
+type StoreCheckoutResult struct {
+	StoreQuote
+	TenantID        uuid.UUID  `json:"-"`
+	CustomerID      uuid.UUID  `json:"customer_id"`
+	DeliveryOrderID uuid.UUID  `json:"delivery_order_id"`
+	OrderNumber     string     `json:"order_number"`
+	Status          string     `json:"status"`
+	ChatRoomID      *uuid.UUID `json:"chat_room_id"`
+	Payment         *Charge    `json:"payment"`
+	Message         string     `json:"message"`
 }
 
 type UpdateDeliveryStatusRequest struct {
@@ -131,6 +199,9 @@ type DeliveryRepository interface {
 	// Orders
 	CreateOrder(order *DeliveryOrder) error
 	FindOrderByID(id uuid.UUID) (*DeliveryOrder, error)
+	LockOrderByID(id uuid.UUID) (*DeliveryOrder, error)
+	FindOrderByIdempotencyKey(tenantID uuid.UUID, key string) (*DeliveryOrder, error)
+	FindOrderByMidtransOrderID(midtransOrderID string) (*DeliveryOrder, error)
 	FindOrdersByTenantID(tenantID uuid.UUID, status string, limit, offset int) ([]DeliveryOrder, int64, error)
 	FindOrdersByDriverID(driverID uuid.UUID, status string) ([]DeliveryOrder, error)
 	UpdateOrder(order *DeliveryOrder) error
@@ -0,0 +1,79 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// GiftCard is a prepaid voucher sold at the POS through a gift card product. Its
+// balance is owed to whoever holds the code until it is spent or the card expires,
+// when what is left is recognised as breakage revenue.
+type GiftCard struct {
+	BaseModel
+	TenantID      uuid.UUID  `json:"tenant_id" gorm:"type:uuid;not null;index"`
+	Code          string     `json:"code" gorm:"size:30;not null;uniqueIndex"`
+	InitialAmount Money      `json:"initial_amount" gorm:"type:decimal(15,2);not null"`
+	Balance       Money      `json:"balance" gorm:"type:decimal(15,2);not null"`
+	Status        string     `json:"status" gorm:"size:20;not null;default:'active';index"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty" gorm:"index"`
+	ExpiredAt     *time.Time `json:"expired_at,omitempty"`
+	ProductID     *uuid.UUID `json:"product_id,omitempty" gorm:"type:uuid"`
+	OutletID      *uuid.UUID `json:"outlet_id,omitempty" gorm:"type:uuid"` // outlet that sold it, whose books carry the liability
+	CustomerID    *uuid.UUID `json:"customer_id,omitempty" gorm:"type:uuid;index"`
+	// The sale line the card was bought on
+	TransactionID     *uuid.UUID `json:"transaction_id,omitempty" gorm:"type:uuid;index"`
+	TransactionItemID *uuid.UUID `json:"transaction_item_id,omitempty" gorm:"type:uuid;index"`
+
+	// Relations
+	Movements []GiftCardMovement `json:"movements,omitempty" gorm:"foreignKey:GiftCardID"`
+}
+
+func (GiftCard) TableName() string { return "gift_cards" }
+
+// Gift card status constants
+const (
+	GiftCardStatusActive  = "active"
+	GiftCardStatusSpent   = "spent" // balance used up; a refund can top it up again
+	GiftCardStatusExpired = "expired"
+	GiftCardStatusVoided  = "voided" // the sale that issued it was refunded or voided
+)
+
+// GiftCardMovement is one change of a gift card's balance
+type GiftCardMovement struct {
+	ID            uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	GiftCardID    uuid.UUID  `json:"gift_card_id" gorm:"type:uuid;not null;index"`
+	TransactionID *uuid.UUID `json:"transaction_id,omitempty" gorm:"type:uuid;index"`
+	Type          string     `json:"type" gorm:"size:20;not null"`
+	Amount        Money      `json:"amount" gorm:"type:decimal(15,2);not null"` // signed
+	BalanceAfter  Money      `json:"balance_after" gorm:"type:decimal(15,2);not null"`
+	CreatedBy     *uuid.UUID `json:"created_by,omitempty" gorm:"type:uuid"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+func (GiftCardMovement) TableName() string { return "gift_card_movements" }
+
+// Gift card movement type constants
+const (
+	GiftCardMovementIssue  = "issue"
+	GiftCardMovementRedeem = "redeem"
+	GiftCardMovementRefund = "refund" // a refund paid back onto the card
+	GiftCardMovementExpire = "expire"
+	GiftCardMovementVoid   = "void"
+)
+
+// GiftCardRepository defines the interface for gift card data access
+type GiftCardRepository interface {
+	Create(card *GiftCard) error
+	Update(card *GiftCard) error
+	FindByID(id uuid.UUID) (*GiftCard, error)
+	FindByCode(tenantID uuid.UUID, code string) (*GiftCard, error)
+	FindByTenantID(tenantID uuid.UUID, status string, limit, offset int) ([]GiftCard, int64, error)
+	FindByTransactionItem(itemID uuid.UUID) ([]GiftCard, error)
+	// FindExpiring returns the active or spent cards whose expiry has passed, of one
+	// tenant or of all tenants when tenantID is nil
+	FindExpiring(tenantID *uuid.UUID, now time.Time) ([]GiftCard, error)
+	// Lock locks a card until the database transaction ends
+	Lock(id uuid.UUID) (*GiftCard, error)
+	CreateMovement(movement *GiftCardMovement) error
+}
@@ -19,11 +19,15 @@ const (
 	ConfigSMTPPass               = "smtp_pass"
 	ConfigGoogleMapsAPIKey       = "google_maps_api_key"
 
+	// Payment gateway used by tenants that have none of their own
+	ConfigPaymentGateway = "payment_gateway"
+
 	// Midtrans payment gateway
 	ConfigMidtransMode       = "midtrans_mode"
 	ConfigMidtransMerchantID = "midtrans_merchant_id"
 	ConfigMidtransClientKey  = "midtrans_client_key"
 	ConfigMidtransServerKey  = "midtrans_server_key"
+	ConfigMidtransAPIBaseURL = "midtrans_api_base_url" // optional; overrides the Snap URL, e.g. for a local fake
 )
 
 // GlobalConfigRepository defines the interface for global config data access
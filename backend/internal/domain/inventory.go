@@ -15,6 +15,10 @@ type Inventory struct {
 	Quantity  float64    `json:"quantity" gorm:"type:decimal(15,2);not null;default:0"`
 	MinStock  float64    `json:"min_stock" gorm:"type:decimal(15,2);default:0"`
 
+	// Stock held for unpaid online orders, and what is left to sell (on hand - reserved)
+	Reserved  float64 `json:"reserved" gorm:"-"`
+	Available float64 `json:"available" gorm:"-"`
+
 	// Relations
 	Outlet  *Outlet         `json:"outlet,omitempty" gorm:"foreignKey:OutletID"`
 	Product *Product        `json:"product,omitempty" gorm:"foreignKey:ProductID"`
@@ -47,6 +51,34 @@ const (
 	MovementTransferIn  = "transfer_in"
 	MovementTransferOut = "transfer_out"
 	MovementAdjustment  = "adjustment"
+	MovementRefund      = "refund"
+	MovementVoid        = "void"
+)
+
+// StockReservation holds stock at an outlet for an unpaid online order. It becomes a
+// sale movement when the order is paid and is released when the order is cancelled
+// or the reservation expires.
+type StockReservation struct {
+	BaseModel
+	TenantID      uuid.UUID  `json:"tenant_id" gorm:"type:uuid;not null;index"`
+	OutletID      uuid.UUID  `json:"outlet_id" gorm:"type:uuid;not null;index:idx_stock_reservations_outlet_status"`
+	ProductID     uuid.UUID  `json:"product_id" gorm:"type:uuid;not null"`
+	VariantID     *uuid.UUID `json:"variant_id,omitempty" gorm:"type:uuid"`
+	Quantity      float64    `json:"quantity" gorm:"type:decimal(15,2);not null"`
+	ReferenceType string     `json:"reference_type" gorm:"size:50;not null"`
+	ReferenceID   uuid.UUID  `json:"reference_id" gorm:"type:uuid;not null;index"`
+	Status        string     `json:"status" gorm:"size:20;not null;default:'active';index:idx_stock_reservations_outlet_status"`
+	ExpiresAt     time.Time  `json:"expires_at" gorm:"not null;index"`
+}
+
+func (StockReservation) TableName() string { return "stock_reservations" }
+
+// Reservation status constants
+const (
+	ReservationStatusActive    = "active"
+	ReservationStatusConverted = "converted" // the order was paid and the stock sold
+	ReservationStatusReleased  = "released"  // the order was cancelled
+	ReservationStatusExpired   = "expired"
 )
 
 // StockTransfer represents a transfer between outlets
@@ -89,4 +121,14 @@ type InventoryRepository interface {
 	CreateMovement(movement *InventoryMovement) error
 	FindMovements(outletID uuid.UUID, productID *uuid.UUID, limit int) ([]InventoryMovement, error)
 	GetLowStock(outletID uuid.UUID) ([]Inventory, error)
+
+	// Reservations
+	// LockStock locks the stock row of a product until the database transaction ends and
+	// returns the quantity on hand, 0 when the product has no stock at the outlet
+	LockStock(outletID, productID uuid.UUID, variantID *uuid.UUID) (float64, error)
+	CreateReservation(reservation *StockReservation) error
+	FindActiveReservations(outletID uuid.UUID, now time.Time) ([]StockReservation, error)
+	// SetReservationStatus closes the active reservations of an order
+	SetReservationStatus(referenceType string, referenceID uuid.UUID, status string) error
+	ExpireReservations(now time.Time) error
 }
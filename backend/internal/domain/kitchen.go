@@ -0,0 +1,144 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// KitchenStation is a preparation area of an outlet (kitchen, bar, pastry) with its own
+// display. Items are routed to the station that serves their category; items of other
+// categories go to the default station, or nowhere when the outlet has none.
+type KitchenStation struct {
+	BaseModel
+	TenantID  uuid.UUID `json:"tenant_id" gorm:"type:uuid;not null;index"`
+	OutletID  uuid.UUID `json:"outlet_id" gorm:"type:uuid;not null;index"`
+	Name      string    `json:"name" gorm:"size:100;not null"`
+	IsDefault bool      `json:"is_default" gorm:"default:false"`
+	IsActive  bool      `json:"is_active" gorm:"default:true"`
+
+	// Relations
+	Categories []Category `json:"categories,omitempty" gorm:"many2many:kitchen_station_categories"`
+}
+
+func (KitchenStation) TableName() string { return "kitchen_stations" }
+
+// KitchenTicket is what one station has to prepare for one round of an order. Items
+// added to an open order later arrive on a new ticket.
+type KitchenTicket struct {
+	BaseModel
+	TenantID          uuid.UUID `json:"tenant_id" gorm:"type:uuid;not null;index"`
+	OutletID          uuid.UUID `json:"outlet_id" gorm:"type:uuid;not null;index"`
+	StationID         uuid.UUID `json:"station_id" gorm:"type:uuid;not null;index"`
+	TransactionID     uuid.UUID `json:"transaction_id" gorm:"type:uuid;not null;index"`
+	TransactionNumber string    `json:"transaction_number" gorm:"size:100"`
+	OrderType         string    `json:"order_type" gorm:"size:20"`
+	TabName           string    `json:"tab_name,omitempty" gorm:"size:100"`
+	Status            string    `json:"status" gorm:"size:20;not null;default:'open';index"`
+
+	// Relations
+	Station *KitchenStation     `json:"station,omitempty" gorm:"foreignKey:StationID"`
+	Items   []KitchenTicketItem `json:"items,omitempty" gorm:"foreignKey:TicketID"`
+}
+
+func (KitchenTicket) TableName() string { return "kitchen_tickets" }
+
+// Kitchen ticket status constants: a ticket is done once all its items are served or cancelled
+const (
+	KitchenTicketOpen = "open"
+	KitchenTicketDone = "done"
+)
+
+// KitchenTicketItem is one line to prepare. Its timestamps give the prep-time metrics:
+// waiting is queued to cooking, preparation is cooking to ready, serving is ready to served.
+type KitchenTicketItem struct {
+	ID           uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	TicketID     uuid.UUID  `json:"ticket_id" gorm:"type:uuid;not null;index"`
+	ProductID    uuid.UUID  `json:"product_id" gorm:"type:uuid;not null;index"`
+	VariantID    *uuid.UUID `json:"variant_id,omitempty" gorm:"type:uuid"`
+	ProductName  string     `json:"product_name" gorm:"size:255;not null"`
+	VariantName  string     `json:"variant_name,omitempty" gorm:"size:255"`
+	Quantity     float64    `json:"quantity" gorm:"type:decimal(15,2);not null"`
+	Modifiers    JSON       `json:"modifiers" gorm:"type:jsonb;default:'[]'"` // []SelectedModifier
+	Notes        string     `json:"notes,omitempty"`
+	SeatNumber   int        `json:"seat_number,omitempty" gorm:"default:0"`
+	Status       string     `json:"status" gorm:"size:20;not null;default:'queued';index"`
+	StartedAt    *time.Time `json:"started_at,omitempty"`
+	ReadyAt      *time.Time `json:"ready_at,omitempty" gorm:"index"`
+	ServedAt     *time.Time `json:"served_at,omitempty"`
+	WaitSeconds  int        `json:"wait_seconds" gorm:"default:0"`
+	PrepSeconds  int        `json:"prep_seconds" gorm:"default:0"`
+	ServeSeconds int        `json:"serve_seconds" gorm:"default:0"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+func (KitchenTicketItem) TableName() string { return "kitchen_ticket_items" }
+
+// Kitchen item status constants, in the order an item moves through them
+const (
+	KitchenItemQueued    = "queued"
+	KitchenItemCooking   = "cooking"
+	KitchenItemReady     = "ready"
+	KitchenItemServed    = "served"
+	KitchenItemCancelled = "cancelled" // removed from the order before it was cooked
+)
+
+// Kitchen event type constants, sent on the kitchen display stream
+const (
+	KitchenEventTicketCreated = "ticket_created"
+	KitchenEventTicketUpdated = "ticket_updated"
+)
+
+// KitchenEvent is a change pushed to the kitchen displays of an outlet
+type KitchenEvent struct {
+	Type   string         `json:"type"`
+	Ticket *KitchenTicket `json:"ticket"`
+}
+
+// KitchenStationRequest is the DTO for creating or updating a station
+type KitchenStationRequest struct {
+	OutletID    uuid.UUID   `json:"outlet_id"`
+	Name        string      `json:"name" validate:"required"`
+	IsDefault   bool        `json:"is_default"`
+	IsActive    *bool       `json:"is_active,omitempty"`
+	CategoryIDs []uuid.UUID `json:"category_ids"`
+}
+
+// KitchenStatusRequest is the DTO for moving a ticket or ticket item to a later status
+type KitchenStatusRequest struct {
+	Status string `json:"status" validate:"required"`
+}
+
+// KitchenPrepMetric is the prep-time summary of one product at one station
+type KitchenPrepMetric struct {
+	StationID       uuid.UUID `json:"station_id"`
+	StationName     string    `json:"station_name"`
+	ProductID       uuid.UUID `json:"product_id"`
+	ProductName     string    `json:"product_name"`
+	Items           int64     `json:"items"`
+	Quantity        float64   `json:"quantity"`
+	AvgWaitSeconds  float64   `json:"avg_wait_seconds"`
+	AvgPrepSeconds  float64   `json:"avg_prep_seconds"`
+	MaxPrepSeconds  int       `json:"max_prep_seconds"`
+	AvgServeSeconds float64   `json:"avg_serve_seconds"`
+}
+
+// KitchenRepository defines the interface for kitchen station and ticket data access
+type KitchenRepository interface {
+	CreateStation(station *KitchenStation) error
+	FindStationByID(id uuid.UUID) (*KitchenStation, error)
+	FindStationsByOutlet(outletID uuid.UUID) ([]KitchenStation, error)
+	UpdateStation(station *KitchenStation) error
+	DeleteStation(id uuid.UUID) error
+
+	CreateTicket(ticket *KitchenTicket) error
+	FindTicketByID(id uuid.UUID) (*KitchenTicket, error)
+	FindTickets(outletID uuid.UUID, stationID *uuid.UUID, status string) ([]KitchenTicket, error)
+	FindTicketsByTransaction(transactionID uuid.UUID) ([]KitchenTicket, error)
+	UpdateTicket(ticket *KitchenTicket) error
+	UpdateItem(item *KitchenTicketItem) error
+	// MoveTickets hands the tickets of a merged order to the order it was merged into
+	MoveTickets(fromTransactionID, toTransactionID uuid.UUID) error
+	PrepMetrics(tenantID uuid.UUID, outletID *uuid.UUID, from, to time.Time) ([]KitchenPrepMetric, error)
+}
@@ -0,0 +1,147 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LoyaltyProgram is the points program of a tenant. Customers earn a point for every
+// SpendPerPoint spent (before tax, service charge and tip) and redeem points at
+// PointValue each, as a discount or as a payment.
+type LoyaltyProgram struct {
+	BaseModel
+	TenantID        uuid.UUID `json:"tenant_id" gorm:"type:uuid;not null;uniqueIndex"`
+	IsActive        bool      `json:"is_active" gorm:"default:false"`
+	SpendPerPoint   Money     `json:"spend_per_point" gorm:"type:decimal(15,2);not null;default:0"`
+	PointValue      Money     `json:"point_value" gorm:"type:decimal(15,2);not null;default:0"`
+	MinRedeemPoints int64     `json:"min_redeem_points" gorm:"default:0"`
+	ExpiryDays      int       `json:"expiry_days" gorm:"default:0"` // points lapse this many days after they are earned; 0 = never
+}
+
+func (LoyaltyProgram) TableName() string { return "loyalty_programs" }
+
+// LoyaltyEarnRule multiplies the points earned on items of a category, on a day of the
+// week, or on items of a category on that day. Where several rules match an item the
+// highest multiplier wins.
+type LoyaltyEarnRule struct {
+	BaseModel
+	TenantID   uuid.UUID  `json:"tenant_id" gorm:"type:uuid;not null;index"`
+	Name       string     `json:"name" gorm:"size:100;not null"`
+	CategoryID *uuid.UUID `json:"category_id,omitempty" gorm:"type:uuid"`
+	Weekday    *int       `json:"weekday,omitempty"` // 0 = Sunday … 6 = Saturday
+	Multiplier float64    `json:"multiplier" gorm:"type:decimal(5,2);not null;default:1"`
+	IsActive   bool       `json:"is_active" gorm:"default:true"`
+
+	// Relations
+	Category *Category `json:"category,omitempty" gorm:"foreignKey:CategoryID"`
+}
+
+func (LoyaltyEarnRule) TableName() string { return "loyalty_earn_rules" }
+
+// LoyaltyTier is a membership level reached with MinPoints lifetime points. Members earn
+// EarnMultiplier times the points and get DiscountPercent off every sale.
+type LoyaltyTier struct {
+	BaseModel
+	TenantID        uuid.UUID `json:"tenant_id" gorm:"type:uuid;not null;index"`
+	Name            string    `json:"name" gorm:"size:100;not null"`
+	MinPoints       int64     `json:"min_points" gorm:"not null;default:0"`
+	EarnMultiplier  float64   `json:"earn_multiplier" gorm:"type:decimal(5,2);not null;default:1"`
+	DiscountPercent float64   `json:"discount_percent" gorm:"type:decimal(5,2);default:0"`
+}
+
+func (LoyaltyTier) TableName() string { return "loyalty_tiers" }
+
+// LoyaltyAccount is the points balance of a customer. The balance can go below zero
+// when points already spent are clawed back by a refund.
+type LoyaltyAccount struct {
+	BaseModel
+	TenantID       uuid.UUID  `json:"tenant_id" gorm:"type:uuid;not null;index"`
+	CustomerID     uuid.UUID  `json:"customer_id" gorm:"type:uuid;not null;uniqueIndex"`
+	Balance        int64      `json:"balance" gorm:"not null;default:0"`
+	LifetimePoints int64      `json:"lifetime_points" gorm:"not null;default:0"` // earned less clawed back, decides the tier
+	TierID         *uuid.UUID `json:"tier_id,omitempty" gorm:"type:uuid"`
+
+	// Relations
+	Tier     *LoyaltyTier `json:"tier,omitempty" gorm:"foreignKey:TierID"`
+	Customer *Customer    `json:"customer,omitempty" gorm:"foreignKey:CustomerID"`
+}
+
+func (LoyaltyAccount) TableName() string { return "loyalty_accounts" }
+
+// LoyaltyEntry is one movement of a customer's points. Entries that add points are lots
+// with Remaining points left to spend; redemptions and expiry use up the lots that
+// lapse first. Entries of a sale and of its refunds all point at the sale.
+type LoyaltyEntry struct {
+	BaseModel
+	TenantID      uuid.UUID  `json:"tenant_id" gorm:"type:uuid;not null;index"`
+	CustomerID    uuid.UUID  `json:"customer_id" gorm:"type:uuid;not null;index"`
+	TransactionID *uuid.UUID `json:"transaction_id,omitempty" gorm:"type:uuid;index"`
+	Type          string     `json:"type" gorm:"size:20;not null"`
+	Points        int64      `json:"points" gorm:"not null"`
+	Remaining     int64      `json:"remaining" gorm:"not null;default:0"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty" gorm:"index"`
+	Notes         string     `json:"notes,omitempty"`
+	CreatedBy     *uuid.UUID `json:"created_by,omitempty" gorm:"type:uuid"`
+}
+
+func (LoyaltyEntry) TableName() string { return "loyalty_entries" }
+
+// Loyalty entry type constants
+const (
+	LoyaltyEntryEarn     = "earn"     // earned on a sale
+	LoyaltyEntryRedeem   = "redeem"   // spent on a sale, as a discount or a payment
+	LoyaltyEntryClawback = "clawback" // earned points taken back by a refund or void
+	LoyaltyEntryReturn   = "return"   // spent points given back by a refund or void
+	LoyaltyEntryExpire   = "expire"
+	LoyaltyEntryAdjust   = "adjust" // manual correction
+)
+
+// DTOs
+
+// LoyaltyBalance is what a customer has in the program
+type LoyaltyBalance struct {
+	Customer       *Customer    `json:"customer"`
+	Balance        int64        `json:"balance"`
+	Value          Money        `json:"value"` // the balance redeemed at today's point value
+	LifetimePoints int64        `json:"lifetime_points"`
+	Tier           *LoyaltyTier `json:"tier,omitempty"`
+	NextTier       *LoyaltyTier `json:"next_tier,omitempty"`
+	// Points that lapse within the next 30 days, and when the first of them do
+	ExpiringPoints int64      `json:"expiring_points"`
+	ExpiringAt     *time.Time `json:"expiring_at,omitempty"`
+}
+
+// LoyaltyAdjustRequest adds (or with negative points removes) points by hand
+type LoyaltyAdjustRequest struct {
+	Points int64  `json:"points"`
+	Notes  string `json:"notes"`
+}
+
+// LoyaltyRepository defines the interface for loyalty data access
+type LoyaltyRepository interface {
+	// Program, earn rules and tiers
+	FindProgram(tenantID uuid.UUID) (*LoyaltyProgram, error)
+	SaveProgram(program *LoyaltyProgram) error
+	FindRules(tenantID uuid.UUID) ([]LoyaltyEarnRule, error)
+	FindRuleByID(id uuid.UUID) (*LoyaltyEarnRule, error)
+	SaveRule(rule *LoyaltyEarnRule) error
+	DeleteRule(id uuid.UUID) error
+	FindTiers(tenantID uuid.UUID) ([]LoyaltyTier, error) // lowest MinPoints first
+	FindTierByID(id uuid.UUID) (*LoyaltyTier, error)
+	SaveTier(tier *LoyaltyTier) error
+	DeleteTier(id uuid.UUID) error
+
+	// Accounts and ledger
+	FindAccount(customerID uuid.UUID) (*LoyaltyAccount, error)
+	// LockAccount locks the account of a customer until the database transaction ends,
+	// opening it when the customer has none
+	LockAccount(tenantID, customerID uuid.UUID) (*LoyaltyAccount, error)
+	UpdateAccount(account *LoyaltyAccount) error
+	CreateEntry(entry *LoyaltyEntry) error
+	UpdateEntry(entry *LoyaltyEntry) error
+	FindEntries(customerID uuid.UUID, limit, offset int) ([]LoyaltyEntry, int64, error)
+	FindEntriesByTransaction(transactionID uuid.UUID) ([]LoyaltyEntry, error)
+	// FindOpenLots returns the entries of a customer with points left, first to lapse first
+	FindOpenLots(customerID uuid.UUID) ([]LoyaltyEntry, error)
+}
@@ -0,0 +1,196 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Money is an exact rupiah amount stored as an integer number of sen (1/100 rupiah),
+// matching the decimal(15,2) columns it is saved in. Sums and differences use plain
+// integer arithmetic; every operation that can produce fractions of a sen (quantities,
+// percentages, ratios) rounds half away from zero to the nearest sen.
+//
+// Money marshals to a JSON number (e.g. 15000 or 15000.5), so API payloads keep their shape.
+type Money int64
+
+const senPerRupiah = 100
+
+// NewMoney converts a float amount of rupiah, rounding to the nearest sen.
+// Use it only at boundaries where floats come in (legacy inputs, rates, reports).
+func NewMoney(rupiah float64) Money {
+	return Money(math.Round(rupiah * senPerRupiah))
+}
+
+// Rupiah returns a whole rupiah amount
+func Rupiah(rupiah int64) Money {
+	return Money(rupiah * senPerRupiah)
+}
+
+// ParseMoney parses a decimal string such as "15000", "15000.5" or "-12.345",
+// rounding to the nearest sen
+func ParseMoney(s string) (Money, error) {
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+	if !ok {
+		return 0, fmt.Errorf("invalid money amount %q", s)
+	}
+	return roundRat(r.Mul(r, big.NewRat(senPerRupiah, 1)))
+}
+
+// Float64 returns the amount in rupiah as a float, for display and statistics only
+func (m Money) Float64() float64 {
+	return float64(m) / senPerRupiah
+}
+
+// WholeRupiah rounds the amount to a whole rupiah, as payment gateways expect
+func (m Money) WholeRupiah() int64 {
+	return int64(math.Round(float64(m) / senPerRupiah))
+}
+
+// String formats the amount with two decimals, e.g. "15000.50"
+func (m Money) String() string {
+	sign := ""
+	v := int64(m)
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, v/senPerRupiah, v%senPerRupiah)
+}
+
+// Mul multiplies the amount by a quantity
+func (m Money) Mul(quantity float64) Money {
+	v, _ := roundRat(new(big.Rat).Mul(big.NewRat(int64(m), 1), decimalRat(quantity)))
+	return v
+}
+
+// Percent returns rate percent of the amount, e.g. m.Percent(11) for 11% PPN
+func (m Money) Percent(rate float64) Money {
+	r := new(big.Rat).Mul(big.NewRat(int64(m), 1), decimalRat(rate))
+	v, _ := roundRat(r.Quo(r, big.NewRat(100, 1)))
+	return v
+}
+
+// MulRatio returns m * num / den without intermediate overflow, used to spread an
+// amount in proportion to line values. A zero den returns zero.
+func (m Money) MulRatio(num, den Money) Money {
+	if den == 0 {
+		return 0
+	}
+	r := new(big.Rat).SetFrac(
+		new(big.Int).Mul(big.NewInt(int64(m)), big.NewInt(int64(num))),
+		big.NewInt(int64(den)),
+	)
+	v, _ := roundRat(r)
+	return v
+}
+
+// Split divides the amount into n shares that differ by at most one sen; the remainder
+// is spread one sen at a time over the first shares
+func (m Money) Split(n int) []Money {
+	if n <= 0 {
+		return nil
+	}
+	shares := make([]Money, n)
+	share, rem := m/Money(n), m%Money(n)
+	unit := Money(1)
+	if rem < 0 {
+		unit, rem = -1, -rem
+	}
+	for i := range shares {
+		shares[i] = share
+		if Money(i) < rem {
+			shares[i] += unit
+		}
+	}
+	return shares
+}
+
+// MarshalJSON encodes the amount as a JSON number
+func (m Money) MarshalJSON() ([]byte, error) {
+	if m%senPerRupiah == 0 {
+		return []byte(strconv.FormatInt(int64(m/senPerRupiah), 10)), nil
+	}
+	return []byte(strings.TrimSuffix(m.String(), "0")), nil
+}
+
+// UnmarshalJSON accepts a JSON number or a numeric string
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	v, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// Value stores the amount as an exact decimal string
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// Scan reads a decimal column
+func (m *Money) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*m = 0
+	case []byte:
+		return m.scanString(string(v))
+	case string:
+		return m.scanString(v)
+	case float64:
+		*m = NewMoney(v)
+	case int64:
+		*m = Rupiah(v)
+	default:
+		return fmt.Errorf("cannot scan %T into Money", value)
+	}
+	return nil
+}
+
+func (m *Money) scanString(s string) error {
+	v, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// decimalRat converts a float quantity or rate to the decimal it was written as, so
+// e.g. 1.1 is exactly 11/10 rather than the nearest binary fraction
+func decimalRat(f float64) *big.Rat {
+	r, ok := new(big.Rat).SetString(strconv.FormatFloat(f, 'g', -1, 64))
+	if !ok {
+		return new(big.Rat)
+	}
+	return r
+}
+
+// roundRat rounds a rational number of sen half away from zero
+func roundRat(r *big.Rat) (Money, error) {
+	num := new(big.Int).Set(r.Num())
+	den := r.Denom()
+	q, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if rem.Sign() != 0 && new(big.Int).Abs(new(big.Int).Mul(rem, big.NewInt(2))).Cmp(den) >= 0 {
+		if num.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	if !q.IsInt64() {
+		return 0, fmt.Errorf("money amount out of range")
+	}
+	return Money(q.Int64()), nil
+}
@@ -0,0 +1,193 @@
+package domain
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestNewMoney(t *testing.T) {
+	tests := []struct {
+		rupiah float64
+		want   Money
+	}{
+		{0, 0},
+		{15000, 1500000},
+		{15000.5, 1500050},
+		{0.005, 1},
+		{-0.005, -1},
+		{0.004, 0},
+	}
+	for _, tt := range tests {
+		if got := NewMoney(tt.rupiah); got != tt.want {
+			t.Errorf("NewMoney(%v) = %d, want %d", tt.rupiah, got, tt.want)
+		}
+	}
+}
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Money
+		wantErr bool
+	}{
+		{in: "15000", want: 1500000},
+		{in: " 15000.5 ", want: 1500050},
+		{in: "12.345", want: 1235},
+		{in: "-12.345", want: -1235},
+		{in: "12.344", want: 1234},
+		{in: "0.005", want: 1},
+		{in: "abc", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseMoney(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseMoney(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+	tests := []struct {
+		name string
+		got  Money
+		want Money
+	}{
+		{"mul by quantity", Rupiah(1000).Mul(1.5), Rupiah(1500)},
+		{"mul rounds half up", Money(333).Mul(0.5), 167},
+		{"mul by a decimal quantity", Money(105).Mul(0.1), 11},
+		{"mul of a large amount", Money(9_000_000_000_000_000).Mul(0.5), 4_500_000_000_000_000},
+		{"percent", Rupiah(10000).Percent(11), Rupiah(1100)},
+		{"percent rounds half up", Money(5).Percent(10), 1},
+		{"percent rounds negative half away from zero", Money(-5).Percent(10), -1},
+		{"percent below half a sen", Money(4).Percent(10), 0},
+		{"fractional percent", Money(1000).Percent(0.7), 7},
+		{"fractional percent rounds half up", Money(50).Percent(1.1), 1},
+		{"ratio rounds down", Rupiah(100).MulRatio(1, 3), 3333},
+		{"ratio rounds up", Rupiah(100).MulRatio(2, 3), 6667},
+		{"ratio half a sen", Money(1).MulRatio(1, 2), 1},
+		{"negative ratio half a sen", Money(-1).MulRatio(1, 2), -1},
+		{"ratio of zero", Rupiah(100).MulRatio(1, 0), 0},
+		{"ratio without overflow", Money(4_000_000_000_000_000).MulRatio(3_000_000, 6_000_000), 2_000_000_000_000_000},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestMoneySplit(t *testing.T) {
+	tests := []struct {
+		amount Money
+		n      int
+		want   []Money
+	}{
+		{Rupiah(100), 3, []Money{3334, 3333, 3333}},
+		{Money(10), 4, []Money{3, 3, 2, 2}},
+		{Money(-10), 3, []Money{-4, -3, -3}},
+		{Money(2), 5, []Money{1, 1, 0, 0, 0}},
+		{Rupiah(50), 1, []Money{Rupiah(50)}},
+		{Rupiah(50), 0, nil},
+	}
+	for _, tt := range tests {
+		got := tt.amount.Split(tt.n)
+		if len(got) != len(tt.want) {
+			t.Errorf("%d.Split(%d) = %v, want %v", tt.amount, tt.n, got, tt.want)
+			continue
+		}
+		var sum Money
+		for i := range got {
+			sum += got[i]
+			if got[i] != tt.want[i] {
+				t.Errorf("%d.Split(%d) = %v, want %v", tt.amount, tt.n, got, tt.want)
+				break
+			}
+		}
+		if tt.n > 0 && sum != tt.amount {
+			t.Errorf("%d.Split(%d) sums to %d", tt.amount, tt.n, sum)
+		}
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	tests := []struct {
+		amount Money
+		json   string
+	}{
+		{Rupiah(15000), "15000"},
+		{1500050, "15000.5"},
+		{1500055, "15000.55"},
+		{10, "0.1"},
+		{5, "0.05"},
+		{-50, "-0.5"},
+		{0, "0"},
+	}
+	for _, tt := range tests {
+		data, err := json.Marshal(tt.amount)
+		if err != nil || string(data) != tt.json {
+			t.Errorf("Marshal(%d) = %s, %v, want %s", tt.amount, data, err, tt.json)
+			continue
+		}
+		var back Money
+		if err := json.Unmarshal(data, &back); err != nil || back != tt.amount {
+			t.Errorf("Unmarshal(%s) = %d, %v, want %d", data, back, err, tt.amount)
+		}
+	}
+
+	var quoted Money
+	if err := json.Unmarshal([]byte(`"12.34"`), &quoted); err != nil || quoted != 1234 {
+		t.Errorf(`Unmarshal("12.34") = %d, %v, want 1234`, quoted, err)
+	}
+	kept := Money(7)
+	if err := json.Unmarshal([]byte("null"), &kept); err != nil || kept != 7 {
+		t.Errorf("Unmarshal(null) = %d, %v, want the value kept", kept, err)
+	}
+	var bad Money
+	if err := json.Unmarshal([]byte(`"x"`), &bad); err == nil {
+		t.Error(`Unmarshal("x") succeeded, want an error`)
+	}
+}
+
+func TestMoneyDatabase(t *testing.T) {
+	for _, amount := range []Money{0, 1, 1500050, -1235, Rupiah(2_000_000)} {
+		v, err := amount.Value()
+		if err != nil {
+			t.Fatalf("Value(%d) error: %v", amount, err)
+		}
+		var fromString, fromBytes Money
+		if err := fromString.Scan(v); err != nil || fromString != amount {
+			t.Errorf("Scan(%v) = %d, %v, want %d", v, fromString, err, amount)
+		}
+		if err := fromBytes.Scan([]byte(v.(string))); err != nil || fromBytes != amount {
+			t.Errorf("Scan([]byte(%v)) = %d, %v, want %d", v, fromBytes, err, amount)
+		}
+	}
+
+	tests := []struct {
+		value   interface{}
+		want    Money
+		wantErr bool
+	}{
+		{value: nil, want: 0},
+		{value: 1.5, want: 150},
+		{value: int64(2), want: 200},
+		{value: "abc", wantErr: true},
+		{value: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		m := Money(99)
+		err := m.Scan(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Scan(%v) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && m != tt.want {
+			t.Errorf("Scan(%v) = %d, want %d", tt.value, m, tt.want)
+		}
+	}
+}
@@ -51,7 +51,11 @@ type Outlet struct {
 	FranchiseOwnerID *uuid.UUID `json:"franchise_owner_id,omitempty" gorm:"type:uuid"`
 	OpeningDate      *time.Time `json:"opening_date,omitempty" gorm:"type:date"`
 	Settings         JSON       `json:"settings" gorm:"type:jsonb;default:'{}'"`
-
+	// Service charge added to every sale at the outlet, in percent of the net sales
+	ServiceChargeRate    float64 `json:"service_charge_rate" gorm:"type:decimal(5,2);default:0"`
+	ServiceChargeTaxable bool    `json:"service_charge_taxable" gorm:"default:false"`
+	// Unpaid held orders and open bills expire after this many minutes without changes; 0 keeps them open
+	HeldOrderExpiryMinutes int `json:"held_order_expiry_minutes" gorm:"default:720"`
 	// Relations
 	Brand          *Brand  `json:"brand,omitempty" gorm:"foreignKey:BrandID"`
 	Region         *Region `json:"region,omitempty" gorm:"foreignKey:RegionID"`
@@ -0,0 +1,152 @@
+package domain
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// PaymentNotification is a payment status notification received from a payment gateway.
+// Every notification is kept, whether it was applied, ignored or rejected.
+type PaymentNotification struct {
+	BaseModel
+	Provider             string     `json:"provider" gorm:"size:20;not null;index"`
+	OrderID              string     `json:"order_id" gorm:"size:100;not null;index"`
+	GatewayTransactionID string     `json:"gateway_transaction_id,omitempty" gorm:"size:100"`
+	TransactionStatus    string     `json:"transaction_status" gorm:"size:30"` // as sent by the gateway
+	FraudStatus          string     `json:"fraud_status,omitempty" gorm:"size:20"`
+	StatusCode           string     `json:"status_code,omitempty" gorm:"size:10"`
+	PaymentType          string     `json:"payment_type,omitempty" gorm:"size:50"`
+	GrossAmount          Money      `json:"gross_amount" gorm:"type:decimal(15,2);default:0"`
+	Status               string     `json:"status" gorm:"size:20"` // the gateway status mapped to a GatewayStatus
+	SignatureValid       bool       `json:"signature_valid"`
+	TenantID             *uuid.UUID `json:"tenant_id,omitempty" gorm:"type:uuid;index"`
+	TargetType           string     `json:"target_type,omitempty" gorm:"size:30"`
+	TargetID             *uuid.UUID `json:"target_id,omitempty" gorm:"type:uuid;index"`
+	Result               string     `json:"result" gorm:"size:20;not null;index"`
+	Message              string     `json:"message,omitempty"`
+	Payload              JSON       `json:"payload" gorm:"type:jsonb;default:'{}'"`
+}
+
+func (PaymentNotification) TableName() string { return "payment_notifications" }
+
+// Payment provider constants
+const (
+	PaymentProviderMidtrans = "midtrans"
+	PaymentProviderFake     = "fake" // in-process gateway for tests and demos, never in production
+)
+
+// Gateway status constants: the state of a gateway payment, whichever gateway it went through
+const (
+	GatewayStatusPending  = "pending"
+	GatewayStatusPaid     = "paid"
+	GatewayStatusFailed   = "failed" // denied, cancelled or failed
+	GatewayStatusExpired  = "expired"
+	GatewayStatusRefunded = "refunded"
+
+	GatewayStatusPartiallyRefunded = "partially_refunded"
+)
+
+// Payment target constants: what a gateway order pays for
+const (
+	PaymentTargetDeliveryOrder  = "delivery_order"
+	PaymentTargetTransaction    = "transaction"
+	PaymentTargetBillingInvoice = "billing_invoice"
+	PaymentTargetTenantBilling  = "tenant_billing"
+)
+
+// Notification result constants
+const (
+	NotificationApplied        = "applied"         // the notification changed its target
+	NotificationUnchanged      = "unchanged"       // already applied, or older than the target's state
+	NotificationRejected       = "rejected"        // invalid payload or signature
+	NotificationUnmatched      = "unmatched"       // no order with this ID
+	NotificationAmountMismatch = "amount_mismatch" // paid amount differs from what was due; needs review
+	NotificationRefundRequired = "refund_required" // paid for a target that was cancelled; the payment needs refunding
+	NotificationFailed         = "failed"
+)
+
+// tenantBillingOrderPrefix starts the gateway order ID of an MDR invoice payment
+const tenantBillingOrderPrefix = "MDR-"
+
+// TenantBillingOrderID is the gateway order ID used to pay an MDR invoice
+func TenantBillingOrderID(billingID uuid.UUID) string {
+	return tenantBillingOrderPrefix + billingID.String()
+}
+
+// ParseTenantBillingOrderID returns the MDR invoice paid by a gateway order, if it pays one
+func ParseTenantBillingOrderID(orderID string) (uuid.UUID, bool) {
+	if !strings.HasPrefix(orderID, tenantBillingOrderPrefix) {
+		return uuid.Nil, false
+	}
+	id, err := uuid.Parse(strings.TrimPrefix(orderID, tenantBillingOrderPrefix))
+	return id, err == nil
+}
+
+// MidtransNotification is the HTTP notification Midtrans posts when a payment changes
+type MidtransNotification struct {
+	TransactionID     string `json:"transaction_id"`
+	OrderID           string `json:"order_id"`
+	StatusCode        string `json:"status_code"`
+	GrossAmount       string `json:"gross_amount"` // e.g. "10000.00", signed exactly as sent
+	SignatureKey      string `json:"signature_key"`
+	TransactionStatus string `json:"transaction_status"`
+	FraudStatus       string `json:"fraud_status"`
+	PaymentType       string `json:"payment_type"`
+	TransactionTime   string `json:"transaction_time"`
+	SettlementTime    string `json:"settlement_time,omitempty"`
+}
+
+// PaymentGateway charges customers through a payment provider. Gateway-specific states
+// are mapped to the GatewayStatus constants, so callers never see provider terms.
+type PaymentGateway interface {
+	Provider() string
+	// CreateCharge starts a payment the customer completes on the gateway's payment page
+	CreateCharge(req ChargeRequest) (*Charge, error)
+	// GetStatus asks the gateway for the current state of a payment
+	GetStatus(orderID string) (*GatewayPayment, error)
+	// Refund refunds a paid payment in full, or partly when amount is less than was paid
+	Refund(orderID string, amount Money, reason string) (*GatewayPayment, error)
+	// VerifyNotification reads a notification posted by the gateway and checks it was sent by
+	// the gateway. What could be read is returned with the error of an invalid notification.
+	VerifyNotification(payload []byte) (*GatewayPayment, error)
+}
+
+// ChargeRequest is a payment to collect through a gateway
+type ChargeRequest struct {
+	OrderID      string `json:"order_id"`
+	Amount       Money  `json:"gross_amount"`
+	CustomerName string `json:"first_name"`
+	Email        string `json:"email"`
+	Phone        string `json:"phone"`
+	// ExpiryMinutes closes the payment when it is not made in time; 0 keeps the gateway's default
+	ExpiryMinutes int `json:"expiry_minutes,omitempty"`
+}
+
+// Charge is a payment started at a gateway
+type Charge struct {
+	Provider    string `json:"provider"`
+	OrderID     string `json:"order_id"`
+	Token       string `json:"token"`
+	RedirectURL string `json:"redirect_url"`
+}
+
+// GatewayPayment is the state of a payment as reported by a gateway
+type GatewayPayment struct {
+	Provider             string `json:"provider"`
+	OrderID              string `json:"order_id"`
+	GatewayTransactionID string `json:"gateway_transaction_id,omitempty"`
+	TransactionStatus    string `json:"transaction_status"` // as named by the gateway
+	FraudStatus          string `json:"fraud_status,omitempty"`
+	StatusCode           string `json:"status_code,omitempty"`
+	PaymentType          string `json:"payment_type,omitempty"`
+	Status               string `json:"status"` // a GatewayStatus
+	Amount               Money  `json:"amount"`
+}
+
+// PaymentNotificationRepository defines the interface for payment notification data access
+type PaymentNotificationRepository interface {
+	Create(notification *PaymentNotification) error
+	FindByOrderID(orderID string) ([]PaymentNotification, error)
+	FindAll(result string, limit, offset int) ([]PaymentNotification, int64, error)
+}
@@ -1,6 +1,8 @@
 package domain
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 )
 
@@ -32,17 +34,25 @@ type Product struct {
 	Name        string     `json:"name" gorm:"size:255;not null"`
 	Description string     `json:"description,omitempty"`
 	ImageURL    string     `json:"image_url,omitempty"`
-	BasePrice   float64    `json:"base_price" gorm:"type:decimal(15,2);not null;default:0"`
-	CostPrice   float64    `json:"cost_price" gorm:"type:decimal(15,2);default:0"`
+	BasePrice   Money      `json:"base_price" gorm:"type:decimal(15,2);not null;default:0"`
+	CostPrice   Money      `json:"cost_price" gorm:"type:decimal(15,2);default:0"`
 	TaxRate     float64    `json:"tax_rate" gorm:"type:decimal(5,2);default:0"`
 	IsActive    bool       `json:"is_active" gorm:"default:true"`
 	TrackStock  bool       `json:"track_stock" gorm:"default:true"`
 	IsLocked    bool       `json:"is_locked" gorm:"default:false"`
 	SortOrder   int        `json:"sort_order" gorm:"default:0"`
 	Unit        string     `json:"unit" gorm:"size:50;default:'pcs'"`
+	// A gift card product issues a card worth its price for every unit sold, valid for
+	// GiftCardValidDays days (0 = no expiry)
+	IsGiftCard        bool `json:"is_gift_card" gorm:"default:false"`
+	GiftCardValidDays int  `json:"gift_card_valid_days,omitempty" gorm:"default:0"`
 
 	// Virtual field — not stored in products table, used for create/update convenience
 	StockQuantity *float64 `json:"stock_quantity,omitempty" gorm:"-"`
+	// Virtual field — price at a specific outlet after OutletPrice overrides
+	EffectivePrice *Money `json:"effective_price,omitempty" gorm:"-"`
+	// Virtual field — stock left to sell at a specific outlet (on hand - reserved), stock-tracked products only
+	Available *float64 `json:"available,omitempty" gorm:"-"`
 
 	// Relations
 	Category       *Category        `json:"category,omitempty" gorm:"foreignKey:CategoryID"`
@@ -59,14 +69,20 @@ type ProductVariant struct {
 	Name            string    `json:"name" gorm:"size:255;not null"`
 	SKU             string    `json:"sku,omitempty" gorm:"size:100"`
 	Barcode         string    `json:"barcode,omitempty" gorm:"size:100"`
-	AdditionalPrice float64   `json:"additional_price" gorm:"type:decimal(15,2);default:0"`
-	CostPrice       float64   `json:"cost_price" gorm:"type:decimal(15,2);default:0"`
+	AdditionalPrice Money     `json:"additional_price" gorm:"type:decimal(15,2);default:0"`
+	CostPrice       Money     `json:"cost_price" gorm:"type:decimal(15,2);default:0"`
 	IsActive        bool      `json:"is_active" gorm:"default:true"`
+
+	// Virtual field — full unit price of the variant at a specific outlet
+	EffectivePrice *Money `json:"effective_price,omitempty" gorm:"-"`
+	// Virtual field — stock of the variant left to sell at a specific outlet
+	Available *float64 `json:"available,omitempty" gorm:"-"`
 }
 
 func (ProductVariant) TableName() string { return "product_variants" }
 
-// ModifierGroup represents a group of modifiers (e.g. Toppings, Add-ons)
+// ModifierGroup represents a group of modifiers (e.g. Toppings, Add-ons).
+// A required group needs at least max(1, MinSelect) selections; MaxSelect 0 means no limit.
 type ModifierGroup struct {
 	BaseModel
 	TenantID   uuid.UUID `json:"tenant_id" gorm:"type:uuid;not null;index"`
@@ -74,6 +90,7 @@ type ModifierGroup struct {
 	IsRequired bool      `json:"is_required" gorm:"default:false"`
 	MinSelect  int       `json:"min_select" gorm:"default:0"`
 	MaxSelect  int       `json:"max_select" gorm:"default:1"`
+	SortOrder  int       `json:"sort_order" gorm:"default:0"`
 
 	// Relations
 	Modifiers []Modifier `json:"modifiers,omitempty" gorm:"foreignKey:GroupID"`
@@ -86,42 +103,81 @@ type Modifier struct {
 	BaseModel
 	GroupID   uuid.UUID `json:"group_id" gorm:"type:uuid;not null;index"`
 	Name      string    `json:"name" gorm:"size:255;not null"`
-	Price     float64   `json:"price" gorm:"type:decimal(15,2);default:0"`
+	Price     Money     `json:"price" gorm:"type:decimal(15,2);default:0"`
 	IsActive  bool      `json:"is_active" gorm:"default:true"`
 	SortOrder int       `json:"sort_order" gorm:"default:0"`
 }
 
 func (Modifier) TableName() string { return "modifiers" }
 
-// OutletPrice represents a price override for a specific outlet
+// AttachModifierGroupsRequest is the DTO for setting the modifier groups of a product
+type AttachModifierGroupsRequest struct {
+	GroupIDs []uuid.UUID `json:"group_ids"`
+}
+
+// OutletPrice represents a price override for a specific outlet.
+// Without VariantID it replaces the product's BasePrice (variant additional prices
+// still apply); with VariantID it is the full unit price of that variant.
 type OutletPrice struct {
 	BaseModel
 	OutletID  uuid.UUID  `json:"outlet_id" gorm:"type:uuid;not null;uniqueIndex:idx_outlet_product_variant"`
 	ProductID uuid.UUID  `json:"product_id" gorm:"type:uuid;not null;uniqueIndex:idx_outlet_product_variant"`
 	VariantID *uuid.UUID `json:"variant_id,omitempty" gorm:"type:uuid;uniqueIndex:idx_outlet_product_variant"`
-	Price     float64    `json:"price" gorm:"type:decimal(15,2);not null"`
+	Price     Money      `json:"price" gorm:"type:decimal(15,2);not null"`
 }
 
 func (OutletPrice) TableName() string { return "outlet_prices" }
 
+// OutletPriceRequest is one entry of a bulk outlet price update
+type OutletPriceRequest struct {
+	ProductID uuid.UUID  `json:"product_id" validate:"required"`
+	VariantID *uuid.UUID `json:"variant_id,omitempty"`
+	Price     Money      `json:"price"`
+}
+
+// OutletPriceBulkRequest is the DTO for setting or removing several outlet prices at once
+type OutletPriceBulkRequest struct {
+	Prices []OutletPriceRequest `json:"prices" validate:"required,min=1"`
+}
+
 // Promotion represents a promo/discount
 type Promotion struct {
 	BaseModel
-	TenantID           uuid.UUID `json:"tenant_id" gorm:"type:uuid;not null;index"`
-	Name               string    `json:"name" gorm:"size:255;not null"`
-	Type               string    `json:"type" gorm:"size:50;not null"`
-	Value              float64   `json:"value" gorm:"type:decimal(15,2);not null"`
-	MinPurchase        float64   `json:"min_purchase" gorm:"type:decimal(15,2);default:0"`
-	MaxDiscount        *float64  `json:"max_discount,omitempty" gorm:"type:decimal(15,2)"`
-	StartDate          *string   `json:"start_date,omitempty" gorm:"type:timestamptz"`
-	EndDate            *string   `json:"end_date,omitempty" gorm:"type:timestamptz"`
-	IsActive           bool      `json:"is_active" gorm:"default:true"`
-	ApplicableOutlets  JSON      `json:"applicable_outlets" gorm:"type:jsonb;default:'[]'"`
-	ApplicableProducts JSON      `json:"applicable_products" gorm:"type:jsonb;default:'[]'"`
+	TenantID           uuid.UUID  `json:"tenant_id" gorm:"type:uuid;not null;index"`
+	Name               string     `json:"name" gorm:"size:255;not null"`
+	Type               string     `json:"type" gorm:"size:50;not null"`
+	Value              Money      `json:"value" gorm:"type:decimal(15,2);not null;default:0"` // fixed: amount off; bundle: bundle price
+	Rate               float64    `json:"rate,omitempty" gorm:"type:decimal(5,2);default:0"`  // percentage: percent off
+	MinPurchase        Money      `json:"min_purchase" gorm:"type:decimal(15,2);default:0"`
+	MaxDiscount        *Money     `json:"max_discount,omitempty" gorm:"type:decimal(15,2)"`
+	BuyQuantity        int        `json:"buy_quantity,omitempty" gorm:"default:0"` // buy_x_get_y: units to buy
+	GetQuantity        int        `json:"get_quantity,omitempty" gorm:"default:0"` // buy_x_get_y: units given free
+	StartDate          *time.Time `json:"start_date,omitempty" gorm:"type:timestamptz"`
+	EndDate            *time.Time `json:"end_date,omitempty" gorm:"type:timestamptz"`
+	IsActive           bool       `json:"is_active" gorm:"default:true"`
+	ApplicableOutlets  JSON       `json:"applicable_outlets" gorm:"type:jsonb;default:'[]'"`
+	ApplicableProducts JSON       `json:"applicable_products" gorm:"type:jsonb;default:'[]'"`
 }
 
 func (Promotion) TableName() string { return "promotions" }
 
+// Promotion type constants
+const (
+	PromotionTypePercentage = "percentage"  // Rate is a percentage of the eligible subtotal
+	PromotionTypeFixed      = "fixed"       // Value is a fixed rupiah amount
+	PromotionTypeBuyXGetY   = "buy_x_get_y" // buy BuyQuantity, the cheapest GetQuantity units are free
+	PromotionTypeBundle     = "bundle"      // Value is the bundle price for one of each ApplicableProducts
+)
+
+// PromotionRepository defines the interface for promotion data access
+type PromotionRepository interface {
+	Create(promotion *Promotion) error
+	FindByID(id uuid.UUID) (*Promotion, error)
+	FindByTenantID(tenantID uuid.UUID, activeOnly bool) ([]Promotion, error)
+	Update(promotion *Promotion) error
+	Delete(id uuid.UUID) error
+}
+
 // ProductRepository defines the interface for product data access
 type ProductRepository interface {
 	Create(product *Product) error
@@ -131,6 +187,27 @@ type ProductRepository interface {
 	Delete(id uuid.UUID) error
 }
 
+// ModifierGroupRepository defines the interface for modifier group data access
+type ModifierGroupRepository interface {
+	Create(group *ModifierGroup) error
+	FindByID(id uuid.UUID) (*ModifierGroup, error)
+	FindByTenantID(tenantID uuid.UUID) ([]ModifierGroup, error)
+	Update(group *ModifierGroup) error
+	Delete(id uuid.UUID) error
+	CreateModifier(modifier *Modifier) error
+	FindModifierByID(id uuid.UUID) (*Modifier, error)
+	UpdateModifier(modifier *Modifier) error
+	DeleteModifier(id uuid.UUID) error
+	SetProductGroups(product *Product, groups []ModifierGroup) error
+}
+
+// OutletPriceRepository defines the interface for outlet price override data access
+type OutletPriceRepository interface {
+	FindByOutletID(outletID uuid.UUID) ([]OutletPrice, error)
+	Upsert(prices []OutletPrice) error
+	Delete(outletID, productID uuid.UUID, variantID *uuid.UUID) error
+}
+
 // CategoryRepository defines the interface for category data access
 type CategoryRepository interface {
 	Create(category *Category) error
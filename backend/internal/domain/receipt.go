@@ -0,0 +1,105 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReceiptTemplate is the tenant-editable layout of a printed receipt. A template
+// without an outlet is the tenant default; an outlet template overrides it.
+type ReceiptTemplate struct {
+	BaseModel
+	TenantID    uuid.UUID  `json:"tenant_id" gorm:"type:uuid;not null;index"`
+	OutletID    *uuid.UUID `json:"outlet_id,omitempty" gorm:"type:uuid;index"`
+	PaperSize   string     `json:"paper_size" gorm:"size:10;not null;default:'58mm'"`
+	ShowLogo    bool       `json:"show_logo"`
+	LogoURL     string     `json:"logo_url,omitempty"`                   // empty uses the tenant logo
+	Header      string     `json:"header,omitempty"`                     // lines under the business name; empty shows the outlet address and phone
+	Footer      string     `json:"footer,omitempty"`                     // empty shows the default thank-you line
+	QRContent   string     `json:"qr_content,omitempty" gorm:"size:500"` // printed as a QR code; {id}, {number}, {total} and {outlet} are filled in
+	ShowCashier bool       `json:"show_cashier"`
+}
+
+func (ReceiptTemplate) TableName() string { return "receipt_templates" }
+
+// Receipt format constants
+const (
+	ReceiptFormatESCPOS = "escpos"
+	ReceiptFormatText   = "text"
+	ReceiptFormatHTML   = "html"
+)
+
+// Receipt paper size constants
+const (
+	Paper58mm = "58mm"
+	Paper80mm = "80mm"
+)
+
+// DefaultReceiptTemplate is the layout used when a tenant has not set up its own
+func DefaultReceiptTemplate(tenantID uuid.UUID) ReceiptTemplate {
+	return ReceiptTemplate{
+		TenantID:    tenantID,
+		PaperSize:   Paper58mm,
+		ShowLogo:    true,
+		ShowCashier: true,
+	}
+}
+
+// ReceiptTemplateRequest is the DTO for saving a receipt template
+type ReceiptTemplateRequest struct {
+	OutletID    *uuid.UUID `json:"outlet_id,omitempty"`
+	PaperSize   string     `json:"paper_size"`
+	ShowLogo    *bool      `json:"show_logo,omitempty"`
+	LogoURL     string     `json:"logo_url"`
+	Header      string     `json:"header"`
+	Footer      string     `json:"footer"`
+	QRContent   string     `json:"qr_content"`
+	ShowCashier *bool      `json:"show_cashier,omitempty"`
+}
+
+// ReceiptLink is a public link to the digital receipt of a transaction, sent to the
+// customer by WhatsApp or email. Its token is random and signed with the server secret;
+// the link stops working once it expires or is revoked, and counts how often it is opened.
+type ReceiptLink struct {
+	BaseModel
+	TenantID      uuid.UUID  `json:"tenant_id" gorm:"type:uuid;not null;index"`
+	TransactionID uuid.UUID  `json:"transaction_id" gorm:"type:uuid;not null;index"`
+	Token         string     `json:"-" gorm:"size:64;not null;uniqueIndex"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	RevokedAt     *time.Time `json:"revoked_at,omitempty"`
+	ViewCount     int        `json:"view_count" gorm:"default:0"`
+	LastViewedAt  *time.Time `json:"last_viewed_at,omitempty"`
+	CreatedBy     uuid.UUID  `json:"created_by" gorm:"type:uuid;not null"`
+
+	URL string `json:"url,omitempty" gorm:"-"` // the signed public address, filled in when the link is returned
+}
+
+func (ReceiptLink) TableName() string { return "receipt_links" }
+
+// ReceiptLinkRequest is the DTO for creating a receipt link
+type ReceiptLinkRequest struct {
+	ExpiresInDays int `json:"expires_in_days"` // 0 uses the default of 30 days
+}
+
+// ReceiptTemplateRepository defines the interface for receipt template data access
+type ReceiptTemplateRepository interface {
+	Create(template *ReceiptTemplate) error
+	FindByID(id uuid.UUID) (*ReceiptTemplate, error)
+	// FindByOutlet returns the template of exactly this outlet, or the tenant default when outletID is nil
+	FindByOutlet(tenantID uuid.UUID, outletID *uuid.UUID) (*ReceiptTemplate, error)
+	FindByTenant(tenantID uuid.UUID) ([]ReceiptTemplate, error)
+	Update(template *ReceiptTemplate) error
+	Delete(id uuid.UUID) error
+}
+
+// ReceiptLinkRepository defines the interface for receipt link data access
+type ReceiptLinkRepository interface {
+	Create(link *ReceiptLink) error
+	FindByID(id uuid.UUID) (*ReceiptLink, error)
+	FindByToken(token string) (*ReceiptLink, error)
+	FindByTransaction(transactionID uuid.UUID) ([]ReceiptLink, error)
+	Update(link *ReceiptLink) error
+	// RecordView counts one more view of a link
+	RecordView(id uuid.UUID, at time.Time) error
+}
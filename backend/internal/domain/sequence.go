@@ -0,0 +1,63 @@
+package domain
+
+import (
+	"github.com/google/uuid"
+)
+
+// SequenceConfig configures how the numbers of one document type are formatted
+type SequenceConfig struct {
+	BaseModel
+	TenantID     uuid.UUID `json:"tenant_id" gorm:"type:uuid;not null;uniqueIndex:idx_sequence_configs_tenant_type"`
+	DocumentType string    `json:"document_type" gorm:"size:30;not null;uniqueIndex:idx_sequence_configs_tenant_type"`
+	Prefix       string    `json:"prefix" gorm:"size:10;not null"`
+	ResetPeriod  string    `json:"reset_period" gorm:"size:20;not null;default:'daily'"`
+	Padding      int       `json:"padding" gorm:"default:4"`
+}
+
+func (SequenceConfig) TableName() string { return "sequence_configs" }
+
+// SequenceCounter holds the last number issued for a tenant, outlet, document type
+// and reset period. OutletID is uuid.Nil for tenant-wide sequences.
+type SequenceCounter struct {
+	BaseModel
+	TenantID     uuid.UUID `json:"tenant_id" gorm:"type:uuid;not null;uniqueIndex:idx_sequence_counters_scope"`
+	OutletID     uuid.UUID `json:"outlet_id" gorm:"type:uuid;not null;uniqueIndex:idx_sequence_counters_scope"`
+	DocumentType string    `json:"document_type" gorm:"size:30;not null;uniqueIndex:idx_sequence_counters_scope"`
+	Period       string    `json:"period" gorm:"size:8;not null;uniqueIndex:idx_sequence_counters_scope"`
+	LastValue    int64     `json:"last_value" gorm:"not null;default:0"`
+}
+
+func (SequenceCounter) TableName() string { return "sequence_counters" }
+
+// Sequence document type constants
+const (
+	SequenceTransaction = "transaction"
+	SequenceRefund      = "refund"
+	SequenceDelivery    = "delivery"
+	SequenceJournal     = "journal"
+)
+
+// Sequence reset period constants
+const (
+	SequenceResetDaily   = "daily"
+	SequenceResetMonthly = "monthly"
+	SequenceResetNever   = "never"
+)
+
+// SequenceConfigRequest is the payload for changing the numbering of a document type
+type SequenceConfigRequest struct {
+	Prefix      string `json:"prefix"`
+	ResetPeriod string `json:"reset_period"`
+	Padding     int    `json:"padding"`
+}
+
+// SequenceRepository defines the interface for document number sequences
+type SequenceRepository interface {
+	// Next atomically increments the counter of a scope and returns the new value.
+	// Inside a unit of work the counter row stays locked until commit, so concurrent
+	// cashiers are serialised and a rolled back document does not leave a gap.
+	Next(tenantID, outletID uuid.UUID, documentType, period string) (int64, error)
+	FindConfigs(tenantID uuid.UUID) ([]SequenceConfig, error)
+	FindConfig(tenantID uuid.UUID, documentType string) (*SequenceConfig, error)
+	SaveConfig(config *SequenceConfig) error
+}
@@ -0,0 +1,138 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Shift is a cashier's session at the cash drawer of an outlet, from the opening
+// float to the blind cash count at close. A cashier has at most one open shift.
+type Shift struct {
+	BaseModel
+	TenantID     uuid.UUID  `json:"tenant_id" gorm:"type:uuid;not null;index"`
+	OutletID     uuid.UUID  `json:"outlet_id" gorm:"type:uuid;not null;index"`
+	CashierID    uuid.UUID  `json:"cashier_id" gorm:"type:uuid;not null;uniqueIndex:idx_shifts_open_cashier,where:status = 'open'"`
+	Status       string     `json:"status" gorm:"size:20;not null;default:'open';index"`
+	OpeningFloat Money      `json:"opening_float" gorm:"type:decimal(15,2);not null;default:0"`
+	OpenedAt     time.Time  `json:"opened_at" gorm:"not null"`
+	ClosedAt     *time.Time `json:"closed_at,omitempty"`
+	ClosedBy     *uuid.UUID `json:"closed_by,omitempty" gorm:"type:uuid"`
+	// Filled in at close; the cashier counts the drawer without seeing the expected cash
+	CountedCash  Money  `json:"counted_cash" gorm:"type:decimal(15,2);default:0"`
+	ExpectedCash Money  `json:"expected_cash" gorm:"type:decimal(15,2);default:0"`
+	CashVariance Money  `json:"cash_variance" gorm:"type:decimal(15,2);default:0"`
+	Notes        string `json:"notes,omitempty"`
+
+	// Relations
+	Outlet        *Outlet             `json:"outlet,omitempty" gorm:"foreignKey:OutletID"`
+	Cashier       *User               `json:"cashier,omitempty" gorm:"foreignKey:CashierID"`
+	CashMovements []ShiftCashMovement `json:"cash_movements,omitempty" gorm:"foreignKey:ShiftID"`
+}
+
+func (Shift) TableName() string { return "shifts" }
+
+// Shift status constants
+const (
+	ShiftStatusOpen   = "open"
+	ShiftStatusClosed = "closed"
+)
+
+// ShiftCashMovement is petty cash put into or taken out of the drawer during a shift
+type ShiftCashMovement struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ShiftID   uuid.UUID `json:"shift_id" gorm:"type:uuid;not null;index"`
+	Type      string    `json:"type" gorm:"size:20;not null"`
+	Amount    Money     `json:"amount" gorm:"type:decimal(15,2);not null"`
+	Reason    string    `json:"reason" gorm:"not null"`
+	CreatedBy uuid.UUID `json:"created_by" gorm:"type:uuid;not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (ShiftCashMovement) TableName() string { return "shift_cash_movements" }
+
+// Cash movement type constants
+const (
+	CashMovementIn  = "cash_in"
+	CashMovementOut = "cash_out"
+)
+
+// OpenShiftRequest is the DTO for opening a shift
+type OpenShiftRequest struct {
+	OutletID     uuid.UUID `json:"outlet_id" validate:"required"`
+	OpeningFloat Money     `json:"opening_float"`
+	Notes        string    `json:"notes,omitempty"`
+}
+
+// CashMovementRequest is the DTO for a cash-in or cash-out entry
+type CashMovementRequest struct {
+	Type   string `json:"type" validate:"required"`
+	Amount Money  `json:"amount" validate:"required"`
+	Reason string `json:"reason" validate:"required"`
+}
+
+// CloseShiftRequest is the DTO for closing a shift with the blind cash count
+type CloseShiftRequest struct {
+	CountedCash Money  `json:"counted_cash"`
+	Notes       string `json:"notes,omitempty"`
+}
+
+// ZReport is the end-of-shift summary of a cash drawer
+type ZReport struct {
+	Shift *Shift `json:"shift"`
+
+	SalesCount     int   `json:"sales_count"`
+	GrossSales     Money `json:"gross_sales"`
+	Discounts      Money `json:"discounts"`
+	Tax            Money `json:"tax"`
+	ServiceCharge  Money `json:"service_charge"`
+	Tips           Money `json:"tips"`
+	NetSales       Money `json:"net_sales"`
+	RefundCount    int   `json:"refund_count"`
+	RefundAmount   Money `json:"refund_amount"`
+	VoidCount      int   `json:"void_count"`
+	VoidAmount     Money `json:"void_amount"`
+	OpenBillCount  int   `json:"open_bill_count"`
+	OpenBillAmount Money `json:"open_bill_amount"`
+
+	Payments []ZReportPayment `json:"payments"`
+	// Net sales per order type, after refunds
+	OrderTypes []ZReportOrderType `json:"order_types"`
+
+	OpeningFloat Money `json:"opening_float"`
+	CashSales    Money `json:"cash_sales"`
+	CashRefunds  Money `json:"cash_refunds"`
+	CashIn       Money `json:"cash_in"`
+	CashOut      Money `json:"cash_out"`
+	ExpectedCash Money `json:"expected_cash"`
+	CountedCash  Money `json:"counted_cash"`
+	Variance     Money `json:"variance"`
+}
+
+// ZReportPayment is the net amount taken with one payment method during a shift
+type ZReportPayment struct {
+	PaymentMethod string `json:"payment_method"`
+	Count         int    `json:"count"`
+	Amount        Money  `json:"amount"`
+}
+
+// ZReportOrderType is the net amount sold with one order type during a shift
+type ZReportOrderType struct {
+	OrderType string `json:"order_type"`
+	Count     int    `json:"count"`
+	Amount    Money  `json:"amount"`
+}
+
+// ShiftRepository defines the interface for shift data access
+type ShiftRepository interface {
+	Create(shift *Shift) error
+	FindByID(id uuid.UUID) (*Shift, error)
+	// LockByID locks the shift row for closing; LockSharedByID for taking a payment in it,
+	// so payments do not wait for each other but closing waits for them all
+	LockByID(id uuid.UUID) (*Shift, error)
+	LockSharedByID(id uuid.UUID) (*Shift, error)
+	FindOpenByCashier(cashierID uuid.UUID) (*Shift, error)
+	FindByTenantID(tenantID uuid.UUID, outletID *uuid.UUID, limit, offset int) ([]Shift, int64, error)
+	Update(shift *Shift) error
+	CreateCashMovement(movement *ShiftCashMovement) error
+}
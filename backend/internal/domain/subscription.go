@@ -11,8 +11,8 @@ type SubscriptionPlan struct {
 	ID           uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
 	Name         string    `json:"name" gorm:"size:100;not null"`
 	Slug         string    `json:"slug" gorm:"size:100;uniqueIndex;not null"`
-	PriceMonthly float64   `json:"price_monthly" gorm:"type:decimal(15,2);not null"`
-	PriceYearly  *float64  `json:"price_yearly,omitempty" gorm:"type:decimal(15,2)"`
+	PriceMonthly Money     `json:"price_monthly" gorm:"type:decimal(15,2);not null"`
+	PriceYearly  *Money    `json:"price_yearly,omitempty" gorm:"type:decimal(15,2)"`
 	MaxOutlets   int       `json:"max_outlets" gorm:"default:1"`
 	MaxUsers     int       `json:"max_users" gorm:"default:5"`
 	MaxProducts  int       `json:"max_products" gorm:"default:100"`
@@ -47,7 +47,7 @@ type BillingInvoice struct {
 	TenantID       uuid.UUID  `json:"tenant_id" gorm:"type:uuid;not null;index"`
 	SubscriptionID *uuid.UUID `json:"subscription_id,omitempty" gorm:"type:uuid"`
 	InvoiceNumber  string     `json:"invoice_number" gorm:"size:50;uniqueIndex;not null"`
-	Amount         float64    `json:"amount" gorm:"type:decimal(15,2);not null"`
+	Amount         Money      `json:"amount" gorm:"type:decimal(15,2);not null"`
 	Status         string     `json:"status" gorm:"size:20;default:'pending'"`
 	DueDate        time.Time  `json:"due_date" gorm:"type:date;not null"`
 	PaidAt         *time.Time `json:"paid_at,omitempty"`
@@ -56,6 +56,15 @@ type BillingInvoice struct {
 
 func (BillingInvoice) TableName() string { return "billing_invoices" }
 
+// Billing invoice status constants; the invoice number is the gateway order ID of its payment
+const (
+	InvoiceStatusPending  = "pending"
+	InvoiceStatusPaid     = "paid"
+	InvoiceStatusFailed   = "failed"
+	InvoiceStatusExpired  = "expired"
+	InvoiceStatusRefunded = "refunded"
+)
+
 // SubscriptionRepository defines the interface for subscription data access
 type SubscriptionRepository interface {
 	// Plans
@@ -67,4 +76,8 @@ type SubscriptionRepository interface {
 	CreateSubscription(sub *Subscription) error
 	FindByTenantID(tenantID uuid.UUID) (*Subscription, error)
 	UpdateSubscription(sub *Subscription) error
+
+	// Invoices
+	FindInvoiceByNumber(invoiceNumber string) (*BillingInvoice, error)
+	UpdateInvoice(invoice *BillingInvoice) error
 }
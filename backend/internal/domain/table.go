@@ -0,0 +1,104 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Floor is an area of an outlet's floor plan, e.g. indoor, terrace or the second floor
+type Floor struct {
+	BaseModel
+	TenantID  uuid.UUID `json:"tenant_id" gorm:"type:uuid;not null;index"`
+	OutletID  uuid.UUID `json:"outlet_id" gorm:"type:uuid;not null;index"`
+	Name      string    `json:"name" gorm:"size:100;not null"`
+	SortOrder int       `json:"sort_order" gorm:"default:0"`
+
+	// Relations
+	Tables []DiningTable `json:"tables,omitempty" gorm:"foreignKey:FloorID"`
+}
+
+func (Floor) TableName() string { return "floors" }
+
+// DiningTable is a table on a floor plan. Position and size are in the grid units of
+// the floor plan editor. While guests are seated the table points at their open order;
+// merged tables share one order.
+type DiningTable struct {
+	BaseModel
+	TenantID uuid.UUID `json:"tenant_id" gorm:"type:uuid;not null;index"`
+	OutletID uuid.UUID `json:"outlet_id" gorm:"type:uuid;not null;index"`
+	FloorID  uuid.UUID `json:"floor_id" gorm:"type:uuid;not null;index"`
+	Name     string    `json:"name" gorm:"size:50;not null"`
+	Capacity int       `json:"capacity" gorm:"not null;default:4"`
+	Shape    string    `json:"shape" gorm:"size:20;default:'square'"`
+	PosX     int       `json:"pos_x" gorm:"default:0"`
+	PosY     int       `json:"pos_y" gorm:"default:0"`
+	Width    int       `json:"width" gorm:"default:1"`
+	Height   int       `json:"height" gorm:"default:1"`
+	IsActive bool      `json:"is_active" gorm:"default:true"`
+
+	Status        string     `json:"status" gorm:"size:20;not null;default:'free';index"`
+	TransactionID *uuid.UUID `json:"transaction_id,omitempty" gorm:"type:uuid;index"` // open order of the seated guests
+	OccupiedAt    *time.Time `json:"occupied_at,omitempty"`
+
+	// Relations
+	Transaction *Transaction `json:"transaction,omitempty" gorm:"foreignKey:TransactionID"`
+}
+
+func (DiningTable) TableName() string { return "dining_tables" }
+
+// Table status constants
+const (
+	TableStatusFree     = "free"
+	TableStatusOccupied = "occupied"
+	TableStatusBilling  = "billing" // the bill has been presented
+)
+
+// Table shape constants
+const (
+	TableShapeSquare = "square"
+	TableShapeRound  = "round"
+)
+
+// TableStatusRequest is the DTO for changing a table's status by hand, e.g. seating
+// walk-in guests before they order or freeing a table after it has been cleared
+type TableStatusRequest struct {
+	Status string `json:"status" validate:"required"`
+}
+
+// MoveTableRequest is the DTO for moving seated guests and their order to a free table
+type MoveTableRequest struct {
+	ToTableID uuid.UUID `json:"to_table_id" validate:"required"`
+}
+
+// MergeTablesRequest is the DTO for merging tables into the order of the target table.
+// Open orders on the merged tables are moved into that order.
+type MergeTablesRequest struct {
+	TableIDs []uuid.UUID `json:"table_ids" validate:"required,min=1"`
+}
+
+// TableRepository defines the interface for floor plan and table data access
+type TableRepository interface {
+	CreateFloor(floor *Floor) error
+	FindFloorByID(id uuid.UUID) (*Floor, error)
+	FindFloorsByOutlet(outletID uuid.UUID) ([]Floor, error)
+	UpdateFloor(floor *Floor) error
+	DeleteFloor(id uuid.UUID) error
+	CountByFloor(floorID uuid.UUID) (int64, error)
+
+	Create(table *DiningTable) error
+	FindByID(id uuid.UUID) (*DiningTable, error)
+	FindByOutlet(outletID uuid.UUID, status string) ([]DiningTable, error)
+	FindByTransactionID(transactionID uuid.UUID) ([]DiningTable, error)
+	Update(table *DiningTable) error
+	Delete(id uuid.UUID) error
+	// Seat takes a table that is free, or whose order is no longer open, for the given
+	// order; it fails when the table is taken
+	Seat(id uuid.UUID, status string, transactionID *uuid.UUID, since time.Time) error
+	// SetStatusByTransaction changes the status of the tables seated on an order
+	SetStatusByTransaction(transactionID uuid.UUID, status string) error
+	// ReleaseByTransaction frees the tables seated on an order
+	ReleaseByTransaction(transactionID uuid.UUID) error
+	// ReleaseClosed frees the tables whose order has been paid, merged or has expired
+	ReleaseClosed(tenantID uuid.UUID) error
+}
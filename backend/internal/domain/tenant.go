@@ -34,6 +34,9 @@ type Tenant struct {
 	RevenueSharePct float64    `json:"revenue_share_pct" gorm:"type:decimal(5,2);default:10"`
 	IsEnabled       bool       `json:"is_enabled" gorm:"default:true"`
 
+	// Payment gateway customers pay through; empty uses the platform default
+	PaymentGateway string `json:"payment_gateway,omitempty" gorm:"size:20"`
+
 	// Bank account info
 	BankName          string `json:"bank_name,omitempty" gorm:"size:100"`
 	BankAccountNumber string `json:"bank_account_number,omitempty" gorm:"size:50"`
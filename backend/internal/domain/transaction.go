@@ -9,39 +9,68 @@ import (
 // Transaction represents a POS transaction
 type Transaction struct {
 	BaseModel
-	TenantID              uuid.UUID  `json:"tenant_id" gorm:"type:uuid;not null;index"`
+	TenantID              uuid.UUID  `json:"tenant_id" gorm:"type:uuid;not null;index;uniqueIndex:idx_transactions_tenant_idempotency"`
 	OutletID              uuid.UUID  `json:"outlet_id" gorm:"type:uuid;not null;index"`
 	CashierID             uuid.UUID  `json:"cashier_id" gorm:"type:uuid;not null"`
 	CustomerID            *uuid.UUID `json:"customer_id,omitempty" gorm:"type:uuid;index"`
-	TransactionNumber     string     `json:"transaction_number" gorm:"size:50;uniqueIndex;not null"`
+	ShiftID               *uuid.UUID `json:"shift_id,omitempty" gorm:"type:uuid;index"`
+	TransactionNumber     string     `json:"transaction_number" gorm:"size:100;uniqueIndex;not null"`
+	IdempotencyKey        *string    `json:"idempotency_key,omitempty" gorm:"size:100;uniqueIndex:idx_transactions_tenant_idempotency"`
 	Type                  string     `json:"type" gorm:"size:20;not null;default:'sale'"`
 	Status                string     `json:"status" gorm:"size:20;not null;default:'completed'"`
-	Subtotal              float64    `json:"subtotal" gorm:"type:decimal(15,2);not null;default:0"`
-	DiscountAmount        float64    `json:"discount_amount" gorm:"type:decimal(15,2);default:0"`
-	TaxAmount             float64    `json:"tax_amount" gorm:"type:decimal(15,2);default:0"`
-	TotalAmount           float64    `json:"total_amount" gorm:"type:decimal(15,2);not null;default:0"`
+	OrderType             string     `json:"order_type" gorm:"size:20;not null;default:'takeaway';index"`
+	Channel               string     `json:"channel" gorm:"size:20;not null;default:'pos';index"`
+	TableID               *uuid.UUID `json:"table_id,omitempty" gorm:"type:uuid;index"`
+	GuestCount            int        `json:"guest_count,omitempty" gorm:"default:0"`
+	MergedIntoID          *uuid.UUID `json:"merged_into_id,omitempty" gorm:"type:uuid"` // order this one was merged into with its table
+	Subtotal              Money      `json:"subtotal" gorm:"type:decimal(15,2);not null;default:0"`
+	DiscountAmount        Money      `json:"discount_amount" gorm:"type:decimal(15,2);default:0"`
+	TaxAmount             Money      `json:"tax_amount" gorm:"type:decimal(15,2);default:0"`
+	ServiceChargeRate     float64    `json:"service_charge_rate,omitempty" gorm:"type:decimal(5,2);default:0"`
+	ServiceChargeAmount   Money      `json:"service_charge_amount" gorm:"type:decimal(15,2);default:0"`
+	ServiceChargeTax      Money      `json:"service_charge_tax" gorm:"type:decimal(15,2);default:0"` // part of TaxAmount charged on the service charge
+	ServiceChargeTaxes    JSON       `json:"service_charge_taxes" gorm:"type:jsonb;default:'[]'"`    // []ItemTax
+	TipAmount             Money      `json:"tip_amount" gorm:"type:decimal(15,2);default:0"`
+	TipStaffID            *uuid.UUID `json:"tip_staff_id,omitempty" gorm:"type:uuid;index"`
+	DeliveryFee           Money      `json:"delivery_fee,omitempty" gorm:"type:decimal(15,2);default:0"` // charged on storefront orders
+	TotalAmount           Money      `json:"total_amount" gorm:"type:decimal(15,2);not null;default:0"`
 	PromotionID           *uuid.UUID `json:"promotion_id,omitempty" gorm:"type:uuid"`
+	PointsEarned          int64      `json:"points_earned,omitempty" gorm:"default:0"`   // loyalty points the customer earned
+	PointsRedeemed        int64      `json:"points_redeemed,omitempty" gorm:"default:0"` // loyalty points spent, as a discount or a payment
 	Notes                 string     `json:"notes,omitempty"`
+	TabName               string     `json:"tab_name,omitempty" gorm:"size:100"` // table or customer name of a held order
+	ExpiresAt             *time.Time `json:"expires_at,omitempty" gorm:"index"`  // when an unpaid held order lapses
 	RefundReason          string     `json:"refund_reason,omitempty"`
 	OriginalTransactionID *uuid.UUID `json:"original_transaction_id,omitempty" gorm:"type:uuid"`
+	VoidReason            string     `json:"void_reason,omitempty"`
+	VoidedBy              *uuid.UUID `json:"voided_by,omitempty" gorm:"type:uuid"`
+	VoidApprovedBy        *uuid.UUID `json:"void_approved_by,omitempty" gorm:"type:uuid"`
+	VoidedAt              *time.Time `json:"voided_at,omitempty"`
 	ReprintCount          int        `json:"reprint_count" gorm:"default:0"`
 	LastReprintAt         *time.Time `json:"last_reprint_at,omitempty"`
 	PaymentMethod         string     `json:"payment_method,omitempty" gorm:"size:50"`
 	MDRRatePercentage     float64    `json:"mdr_rate_percentage,omitempty" gorm:"type:decimal(5,2);default:0"`
-	MDRRateFlat           float64    `json:"mdr_rate_flat,omitempty" gorm:"type:decimal(15,2);default:0"`
-	FeeMidtrans           float64    `json:"fee_midtrans,omitempty" gorm:"type:decimal(15,2);default:0"`
-	FeeCodapos            float64    `json:"fee_codapos,omitempty" gorm:"type:decimal(15,2);default:0"`
-	TotalMDRMerchant      float64    `json:"total_mdr_merchant,omitempty" gorm:"type:decimal(15,2);default:0"`
-	NetProfit             float64    `json:"net_profit,omitempty" gorm:"type:decimal(15,2);default:0"`
+	MDRRateFlat           Money      `json:"mdr_rate_flat,omitempty" gorm:"type:decimal(15,2);default:0"`
+	FeeMidtrans           Money      `json:"fee_midtrans,omitempty" gorm:"type:decimal(15,2);default:0"`
+	FeeCodapos            Money      `json:"fee_codapos,omitempty" gorm:"type:decimal(15,2);default:0"`
+	TotalMDRMerchant      Money      `json:"total_mdr_merchant,omitempty" gorm:"type:decimal(15,2);default:0"`
+	NetProfit             Money      `json:"net_profit,omitempty" gorm:"type:decimal(15,2);default:0"`
+	JournalStatus         string     `json:"journal_status,omitempty" gorm:"size:20;index"`
+	JournalError          string     `json:"journal_error,omitempty"`
 
 	// Relations
 	Outlet              *Outlet              `json:"outlet,omitempty" gorm:"foreignKey:OutletID"`
 	Cashier             *User                `json:"cashier,omitempty" gorm:"foreignKey:CashierID"`
+	TipStaff            *User                `json:"tip_staff,omitempty" gorm:"foreignKey:TipStaffID"`
+	Table               *DiningTable         `json:"table,omitempty" gorm:"foreignKey:TableID"`
 	Customer            *Customer            `json:"customer,omitempty" gorm:"foreignKey:CustomerID"`
 	Items               []TransactionItem    `json:"items,omitempty" gorm:"foreignKey:TransactionID"`
 	Payments            []TransactionPayment `json:"payments,omitempty" gorm:"foreignKey:TransactionID"`
 	OriginalTransaction *Transaction         `json:"original_transaction,omitempty" gorm:"foreignKey:OriginalTransactionID"`
 	Promotion           *Promotion           `json:"promotion,omitempty" gorm:"foreignKey:PromotionID"`
+	Splits              []SplitBill          `json:"splits,omitempty" gorm:"foreignKey:TransactionID"`
+	Taxes               []TransactionTax     `json:"taxes,omitempty" gorm:"foreignKey:TransactionID"`
+	GiftCards           []GiftCard           `json:"gift_cards,omitempty" gorm:"foreignKey:TransactionID"` // cards the sale issued
 }
 
 func (Transaction) TableName() string { return "transactions" }
@@ -55,12 +84,53 @@ const (
 
 // Transaction status constants
 const (
-	TransactionStatusPending   = "pending"
-	TransactionStatusCompleted = "completed"
-	TransactionStatusVoided    = "voided"
-	TransactionStatusRefunded  = "refunded"
+	TransactionStatusPending           = "pending"
+	TransactionStatusCompleted         = "completed"
+	TransactionStatusVoided            = "voided"
+	TransactionStatusRefunded          = "refunded"
+	TransactionStatusPartiallyRefunded = "partially_refunded"
+	TransactionStatusExpired           = "expired"
+	TransactionStatusMerged            = "merged"
 )
 
+// Order type constants: how the customer is served, so sales can be split by channel
+const (
+	OrderTypeDineIn   = "dine_in"
+	OrderTypeTakeaway = "takeaway"
+	OrderTypeDelivery = "delivery"
+)
+
+// Channel constants: where the sale was taken
+const (
+	ChannelPOS    = "pos"    // at the cashier
+	ChannelOnline = "online" // on the storefront, paid through the payment gateway
+)
+
+// Journal status constants: whether the automatic journal of a transaction was posted
+const (
+	JournalStatusPosted = "posted"
+	JournalStatusFailed = "failed"
+)
+
+// ReportableTransactionStatuses are the statuses counted in sales reports.
+// Refund transactions carry negative quantities and amounts, so a refunded sale
+// stays in the report and is netted off by its refund transactions.
+var ReportableTransactionStatuses = []string{
+	TransactionStatusCompleted,
+	TransactionStatusPartiallyRefunded,
+	TransactionStatusRefunded,
+}
+
+// IsReportableStatus reports whether a transaction with the given status counts in sales reports
+func IsReportableStatus(status string) bool {
+	for _, s := range ReportableTransactionStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
 // TransactionItem represents an item in a transaction
 type TransactionItem struct {
 	ID             uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
@@ -70,30 +140,79 @@ type TransactionItem struct {
 	ProductName    string     `json:"product_name" gorm:"size:255;not null"`
 	VariantName    string     `json:"variant_name,omitempty" gorm:"size:255"`
 	Quantity       float64    `json:"quantity" gorm:"type:decimal(15,2);not null"`
-	UnitPrice      float64    `json:"unit_price" gorm:"type:decimal(15,2);not null"`
-	DiscountAmount float64    `json:"discount_amount" gorm:"type:decimal(15,2);default:0"`
-	TaxAmount      float64    `json:"tax_amount" gorm:"type:decimal(15,2);default:0"`
-	Subtotal       float64    `json:"subtotal" gorm:"type:decimal(15,2);not null"`
+	UnitPrice      Money      `json:"unit_price" gorm:"type:decimal(15,2);not null"`
+	DiscountAmount Money      `json:"discount_amount" gorm:"type:decimal(15,2);default:0"`
+	TaxAmount      Money      `json:"tax_amount" gorm:"type:decimal(15,2);default:0"`
+	TaxIncluded    Money      `json:"tax_included" gorm:"type:decimal(15,2);default:0"` // part of TaxAmount already inside Subtotal
+	Subtotal       Money      `json:"subtotal" gorm:"type:decimal(15,2);not null"`
+	Taxes          JSON       `json:"taxes" gorm:"type:jsonb;default:'[]'"` // []ItemTax
 	Modifiers      JSON       `json:"modifiers" gorm:"type:jsonb;default:'[]'"`
 	Notes          string     `json:"notes,omitempty"`
+	SeatNumber     int        `json:"seat_number,omitempty" gorm:"default:0"`      // 0 = shared by the table
+	IsGiftCard     bool       `json:"is_gift_card,omitempty" gorm:"default:false"` // sells gift cards: untaxed, owed until spent
 	CreatedAt      time.Time  `json:"created_at"`
+
+	// Refund tracking: sale items count what has been refunded so far,
+	// refund items point back at the sale item they reverse
+	RefundedQuantity float64    `json:"refunded_quantity" gorm:"type:decimal(15,2);default:0"`
+	OriginalItemID   *uuid.UUID `json:"original_item_id,omitempty" gorm:"type:uuid;index"`
 }
 
 func (TransactionItem) TableName() string { return "transaction_items" }
 
 // TransactionPayment represents a payment for a transaction
 type TransactionPayment struct {
-	ID              uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
-	TransactionID   uuid.UUID `json:"transaction_id" gorm:"type:uuid;not null;index"`
-	PaymentMethod   string    `json:"payment_method" gorm:"size:50;not null"`
-	Amount          float64   `json:"amount" gorm:"type:decimal(15,2);not null"`
-	ReferenceNumber string    `json:"reference_number,omitempty" gorm:"size:255"`
-	Status          string    `json:"status" gorm:"size:20;default:'completed'"`
-	CreatedAt       time.Time `json:"created_at"`
+	ID              uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	TransactionID   uuid.UUID  `json:"transaction_id" gorm:"type:uuid;not null;index"`
+	PaymentMethod   string     `json:"payment_method" gorm:"size:50;not null"`
+	Amount          Money      `json:"amount" gorm:"type:decimal(15,2);not null"`
+	ReferenceNumber string     `json:"reference_number,omitempty" gorm:"size:255"`
+	GiftCardID      *uuid.UUID `json:"gift_card_id,omitempty" gorm:"type:uuid;index"` // card paid with (or refunded to)
+	ShiftID         *uuid.UUID `json:"shift_id,omitempty" gorm:"type:uuid;index"`     // shift whose drawer took the payment
+	SplitID         *uuid.UUID `json:"split_id,omitempty" gorm:"type:uuid"`           // share of a split bill it paid
+	Status          string     `json:"status" gorm:"size:20;default:'completed'"`
+	CreatedAt       time.Time  `json:"created_at"`
 }
 
 func (TransactionPayment) TableName() string { return "transaction_payments" }
 
+// Transaction payment status constants: gateway payments follow the gateway's notifications
+const (
+	PaymentStatusCompleted = "completed"
+	PaymentStatusFailed    = "failed"
+	PaymentStatusRefunded  = "refunded"
+)
+
+// ItemTax is one tax charged on a transaction item, stored in TransactionItem.Taxes
+type ItemTax struct {
+	TaxRateID     *uuid.UUID `json:"tax_rate_id,omitempty"`
+	Name          string     `json:"name"`
+	Type          string     `json:"type"`
+	Rate          float64    `json:"rate"`
+	IsInclusive   bool       `json:"is_inclusive"`
+	AccountID     *uuid.UUID `json:"account_id,omitempty"`
+	TaxableAmount Money      `json:"taxable_amount"`
+	Amount        Money      `json:"amount"`
+}
+
+// TransactionTax is the tax summary of a transaction: one row per tax charged.
+// Taxes without a TaxRateID come from the legacy per-product tax rate.
+type TransactionTax struct {
+	ID            uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	TransactionID uuid.UUID  `json:"transaction_id" gorm:"type:uuid;not null;index"`
+	TaxRateID     *uuid.UUID `json:"tax_rate_id,omitempty" gorm:"type:uuid"`
+	Name          string     `json:"name" gorm:"size:100;not null"`
+	Type          string     `json:"type" gorm:"size:20"`
+	Rate          float64    `json:"rate" gorm:"type:decimal(5,2);not null"`
+	IsInclusive   bool       `json:"is_inclusive" gorm:"default:false"`
+	AccountID     *uuid.UUID `json:"account_id,omitempty" gorm:"type:uuid"`
+	TaxableAmount Money      `json:"taxable_amount" gorm:"type:decimal(15,2);not null;default:0"`
+	Amount        Money      `json:"amount" gorm:"type:decimal(15,2);not null;default:0"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+func (TransactionTax) TableName() string { return "transaction_taxes" }
+
 // Payment method constants
 const (
 	PaymentCash         = "cash"
@@ -102,28 +221,57 @@ const (
 	PaymentBankTransfer = "bank_transfer"
 	PaymentCreditCard   = "credit_card"
 	PaymentWhatsApp     = "whatsapp"
+	PaymentLoyalty      = "loyalty_points" // points of the customer's loyalty account, at the program's point value
+	PaymentGiftCard     = "gift_card"      // balance of a gift card, given by its code
+
+	// PaymentSplit marks a sale settled through split bills paid with different methods
+	PaymentSplit = "split"
 )
 
 // SplitBill represents split billing for a transaction
 type SplitBill struct {
-	ID            uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
-	TransactionID uuid.UUID `json:"transaction_id" gorm:"type:uuid;not null;index"`
-	SplitNumber   int       `json:"split_number" gorm:"not null"`
-	Amount        float64   `json:"amount" gorm:"type:decimal(15,2);not null"`
-	PaymentMethod string    `json:"payment_method,omitempty" gorm:"size:50"`
-	Status        string    `json:"status" gorm:"size:20;default:'pending'"`
+	ID                uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	TransactionID     uuid.UUID  `json:"transaction_id" gorm:"type:uuid;not null;index"`
+	SplitNumber       int        `json:"split_number" gorm:"not null"`
+	Mode              string     `json:"mode" gorm:"size:20;not null;default:'equal'"`
+	Label             string     `json:"label,omitempty" gorm:"size:100"`
+	ItemIDs           JSON       `json:"item_ids" gorm:"type:jsonb;default:'[]'"`
+	Amount            Money      `json:"amount" gorm:"type:decimal(15,2);not null"`
+	PaymentMethod     string     `json:"payment_method,omitempty" gorm:"size:50"`
+	ReferenceNumber   string     `json:"reference_number,omitempty" gorm:"size:255"`
+	MDRRatePercentage float64    `json:"mdr_rate_percentage,omitempty" gorm:"type:decimal(5,2);default:0"`
+	MDRRateFlat       Money      `json:"mdr_rate_flat,omitempty" gorm:"type:decimal(15,2);default:0"`
+	FeeMidtrans       Money      `json:"fee_midtrans,omitempty" gorm:"type:decimal(15,2);default:0"`
+	FeeCodapos        Money      `json:"fee_codapos,omitempty" gorm:"type:decimal(15,2);default:0"`
+	TotalMDRMerchant  Money      `json:"total_mdr_merchant,omitempty" gorm:"type:decimal(15,2);default:0"`
+	Status            string     `json:"status" gorm:"size:20;default:'pending'"`
+	PaidBy            *uuid.UUID `json:"paid_by,omitempty" gorm:"type:uuid"`
+	PaidAt            *time.Time `json:"paid_at,omitempty"`
 }
 
 func (SplitBill) TableName() string { return "split_bills" }
 
+// Split bill mode constants
+const (
+	SplitModeItem  = "item"
+	SplitModeSeat  = "seat"
+	SplitModeEqual = "equal"
+)
+
+// Split bill status constants
+const (
+	SplitStatusPending = "pending"
+	SplitStatusPaid    = "paid"
+)
+
 // TenantBilling represents monthly MDR invoice for a tenant
 type TenantBilling struct {
 	BaseModel
 	TenantID          uuid.UUID `json:"tenant_id" gorm:"type:uuid;not null;index"`
 	BillingMonth      string    `json:"billing_month" gorm:"size:10;not null;index"` // Format: MM-YYYY
 	TotalTransactions int       `json:"total_transactions" gorm:"not null;default:0"`
-	TotalMDR          float64   `json:"total_mdr" gorm:"type:decimal(15,2);not null;default:0"`
-	PenaltyFee        float64   `json:"penalty_fee" gorm:"type:decimal(15,2);not null;default:0"`
+	TotalMDR          Money     `json:"total_mdr" gorm:"type:decimal(15,2);not null;default:0"`
+	PenaltyFee        Money     `json:"penalty_fee" gorm:"type:decimal(15,2);not null;default:0"`
 	Status            string    `json:"status" gorm:"size:20;not null;default:'unpaid'"` // unpaid, paid, past_due, suspended
 
 	Tenant *Tenant `json:"tenant,omitempty" gorm:"foreignKey:TenantID"`
@@ -131,6 +279,14 @@ type TenantBilling struct {
 
 func (TenantBilling) TableName() string { return "tenant_billings" }
 
+// Tenant billing status constants
+const (
+	BillingStatusUnpaid    = "unpaid"
+	BillingStatusPaid      = "paid"
+	BillingStatusPastDue   = "past_due"
+	BillingStatusSuspended = "suspended"
+)
+
 // CheckoutRequest is the DTO for creating a transaction
 type CheckoutRequest struct {
 	OutletID    uuid.UUID             `json:"outlet_id" validate:"required"`
@@ -139,44 +295,174 @@ type CheckoutRequest struct {
 	Payments    []PaymentRequest      `json:"payments" validate:"required,min=1"`
 	PromotionID *uuid.UUID            `json:"promotion_id,omitempty"`
 	Notes       string                `json:"notes,omitempty"`
+	// OrderType defaults to dine_in when a table is given and to takeaway otherwise
+	OrderType  string     `json:"order_type,omitempty"`
+	TableID    *uuid.UUID `json:"table_id,omitempty"`
+	GuestCount int        `json:"guest_count,omitempty"`
+	// TipAmount is added on top of the bill and owed to TipStaffID (the cashier when empty)
+	TipAmount  Money      `json:"tip_amount,omitempty"`
+	TipStaffID *uuid.UUID `json:"tip_staff_id,omitempty"`
+	// OpenBill keeps the transaction pending without payments so it can be split
+	OpenBill bool `json:"open_bill,omitempty"`
+	// RedeemPoints spends loyalty points of the customer as a discount before tax.
+	// Points can also pay for the sale through a loyalty_points payment.
+	RedeemPoints int64 `json:"redeem_points,omitempty"`
+	// ClientTransactionID is generated by the client once per sale so retries are not booked twice
+	ClientTransactionID *uuid.UUID `json:"client_transaction_id,omitempty"`
+	// IdempotencyKey is resolved by the handler from the Idempotency-Key header or ClientTransactionID
+	IdempotencyKey string `json:"-"`
+}
+
+// QuoteRequest is the DTO for pricing a POS cart before it is paid. The quote's total
+// is what the payments of the checkout must cover.
+type QuoteRequest struct {
+	OutletID     uuid.UUID             `json:"outlet_id" validate:"required"`
+	CustomerID   *uuid.UUID            `json:"customer_id,omitempty"`
+	Items        []CheckoutItemRequest `json:"items" validate:"required,min=1"`
+	PromotionID  *uuid.UUID            `json:"promotion_id,omitempty"`
+	TipAmount    Money                 `json:"tip_amount,omitempty"`
+	TipStaffID   *uuid.UUID            `json:"tip_staff_id,omitempty"`
+	RedeemPoints int64                 `json:"redeem_points,omitempty"`
+}
+
+// HeldOrderRequest is the DTO for parking a cart as an open tab, or for replacing
+// the contents of one. The outlet of an existing tab cannot change.
+type HeldOrderRequest struct {
+	OutletID    uuid.UUID             `json:"outlet_id"`
+	TabName     string                `json:"tab_name"`
+	Items       []CheckoutItemRequest `json:"items" validate:"required,min=1"`
+	PromotionID *uuid.UUID            `json:"promotion_id,omitempty"`
+	Notes       string                `json:"notes,omitempty"`
+	// A dine-in tab takes its table until it is paid; the table name is the default tab name
+	OrderType  string     `json:"order_type,omitempty"`
+	TableID    *uuid.UUID `json:"table_id,omitempty"`
+	GuestCount int        `json:"guest_count,omitempty"`
+	// ClientTransactionID is generated by the client once per tab so retries are not held twice
+	ClientTransactionID *uuid.UUID `json:"client_transaction_id,omitempty"`
+	IdempotencyKey      string     `json:"-"`
+}
+
+// SettleOrderRequest is the DTO for paying a held order in full
+type SettleOrderRequest struct {
+	Payments   []PaymentRequest `json:"payments" validate:"required,min=1"`
+	TipAmount  Money            `json:"tip_amount,omitempty"`
+	TipStaffID *uuid.UUID       `json:"tip_staff_id,omitempty"`
 }
 
 type CheckoutItemRequest struct {
-	ProductID uuid.UUID         `json:"product_id" validate:"required"`
-	VariantID *uuid.UUID        `json:"variant_id,omitempty"`
-	Quantity  float64           `json:"quantity" validate:"required,gt=0"`
-	Modifiers []ModifierRequest `json:"modifiers,omitempty"`
-	Notes     string            `json:"notes,omitempty"`
+	ProductID  uuid.UUID         `json:"product_id" validate:"required"`
+	VariantID  *uuid.UUID        `json:"variant_id,omitempty"`
+	Quantity   float64           `json:"quantity" validate:"required,gt=0"`
+	Modifiers  []ModifierRequest `json:"modifiers,omitempty"`
+	Notes      string            `json:"notes,omitempty"`
+	SeatNumber int               `json:"seat_number,omitempty"`
 }
 
+// ModifierRequest selects a modifier by ID; its name and price come from the database
 type ModifierRequest struct {
-	Name  string  `json:"name"`
-	Price float64 `json:"price"`
+	ModifierID uuid.UUID `json:"modifier_id" validate:"required"`
+}
+
+// SelectedModifier is a modifier as stored on a transaction item
+type SelectedModifier struct {
+	ModifierID uuid.UUID `json:"modifier_id"`
+	GroupID    uuid.UUID `json:"group_id"`
+	GroupName  string    `json:"group_name"`
+	Name       string    `json:"name"`
+	Price      Money     `json:"price"`
+}
+
+// RefundRequest is the DTO for refunding a transaction.
+// An empty Items list refunds everything that has not been refunded yet.
+type RefundRequest struct {
+	Reason         string              `json:"reason"`
+	Items          []RefundItemRequest `json:"items,omitempty"`
+	ClientRefundID *uuid.UUID          `json:"client_refund_id,omitempty"`
+	IdempotencyKey string              `json:"-"`
+}
+
+type RefundItemRequest struct {
+	ItemID   uuid.UUID `json:"item_id" validate:"required"`
+	Quantity float64   `json:"quantity" validate:"required,gt=0"`
+}
+
+// VoidRequest is the DTO for voiding a sale made in the current shift.
+// When a cashier starts the void, an outlet manager or owner must approve it
+// with either their PIN or their email and password.
+type VoidRequest struct {
+	Reason           string     `json:"reason" validate:"required"`
+	ApproverID       *uuid.UUID `json:"approver_id,omitempty"`
+	ApproverPIN      string     `json:"approver_pin,omitempty"`
+	ApproverEmail    string     `json:"approver_email,omitempty"`
+	ApproverPassword string     `json:"approver_password,omitempty"`
+	// Device identifies the terminal asking, for counting wrong PINs; set by the handler
+	Device string `json:"-"`
+}
+
+// SplitBillRequest is the DTO for splitting an open bill.
+// Mode "item" takes one group of item IDs per share, "seat" creates one share
+// per seat number and "equal" divides the total into Shares parts.
+type SplitBillRequest struct {
+	Mode   string        `json:"mode" validate:"required"`
+	Shares int           `json:"shares,omitempty"`
+	Groups [][]uuid.UUID `json:"groups,omitempty"`
 }
 
 type PaymentRequest struct {
-	PaymentMethod   string  `json:"payment_method" validate:"required"`
-	Amount          float64 `json:"amount" validate:"required,gt=0"`
-	ReferenceNumber string  `json:"reference_number,omitempty"`
+	PaymentMethod   string `json:"payment_method" validate:"required"`
+	Amount          Money  `json:"amount" validate:"required,gt=0"`
+	ReferenceNumber string `json:"reference_number,omitempty"`
+	// GiftCardCode is the code of the card a gift_card payment is taken from
+	GiftCardCode string `json:"gift_card_code,omitempty"`
 }
 
 // TransactionRepository defines the interface for transaction data access
 type TransactionRepository interface {
 	Create(transaction *Transaction) error
 	FindByID(id uuid.UUID) (*Transaction, error)
+	// LockByID loads a transaction with its items and payments and locks it until the
+	// database transaction ends, so concurrent changes to it run one after the other
+	LockByID(id uuid.UUID) (*Transaction, error)
+	FindByIdempotencyKey(tenantID uuid.UUID, key string) (*Transaction, error)
 	FindByTenantID(tenantID uuid.UUID, outletID *uuid.UUID, limit, offset int) ([]Transaction, int64, error)
+	FindRefunds(originalID uuid.UUID) ([]Transaction, error)
+	FindByJournalStatus(tenantID uuid.UUID, status string) ([]Transaction, error)
+	// FindByShiftID returns the transactions of a shift and those with payments taken in it
+	FindByShiftID(shiftID uuid.UUID) ([]Transaction, error)
+	FindHeld(tenantID uuid.UUID, outletID *uuid.UUID) ([]Transaction, error)
+	// ExpireHeld expires the held orders past their expiry, of one tenant or of all with
+	// a nil tenant, and returns them with their items. Orders locked elsewhere are skipped.
+	ExpireHeld(tenantID *uuid.UUID, now time.Time) ([]Transaction, error)
+	ReplaceItems(transaction *Transaction) error
 	GetMDRMonthlyAggregation(month, year int) ([]struct {
 		TenantID    uuid.UUID
 		TotalTrx    int
-		TotalMDRVal float64
+		TotalMDRVal Money
 	}, error)
 	Update(transaction *Transaction) error
+	UpdateItem(item *TransactionItem) error
+	UpdateJournalStatus(id uuid.UUID, status, message string) error
+	UpdatePoints(id uuid.UUID, earned, redeemed int64) error
+	CreatePayment(payment *TransactionPayment) error
+	// FindPaymentByReference returns the payment made with a gateway order ID
+	FindPaymentByReference(reference string) (*TransactionPayment, error)
+	UpdatePayment(payment *TransactionPayment) error
 	Delete(id uuid.UUID) error
 }
 
+// SplitBillRepository defines the interface for split bill data access
+type SplitBillRepository interface {
+	CreateBatch(splits []SplitBill) error
+	FindByID(id uuid.UUID) (*SplitBill, error)
+	FindByTransactionID(transactionID uuid.UUID) ([]SplitBill, error)
+	Update(split *SplitBill) error
+	DeleteByTransactionID(transactionID uuid.UUID) error
+}
+
 // TenantBillingRepository defines the interface for MDR invoice data access
 type TenantBillingRepository interface {
 	Create(billing *TenantBilling) error
+	FindByID(id uuid.UUID) (*TenantBilling, error)
 	GetByTenantAndMonth(tenantID uuid.UUID, month string) (*TenantBilling, error)
 	Update(billing *TenantBilling) error
 	FindAll(limit, offset int) ([]TenantBilling, int64, error)
@@ -0,0 +1,22 @@
+package domain
+
+// Repositories are the repositories available inside a unit of work
+type Repositories struct {
+	Transactions TransactionRepository
+	SplitBills   SplitBillRepository
+	Inventory    InventoryRepository
+	Accounting   AccountingRepository
+	Sequences    SequenceRepository
+	Tables       TableRepository
+	Kitchen      KitchenRepository
+	Deliveries   DeliveryRepository
+	Loyalty      LoyaltyRepository
+	GiftCards    GiftCardRepository
+	Shifts       ShiftRepository
+}
+
+// UnitOfWork runs fn with repositories that share one database transaction.
+// Everything written through them commits when fn returns nil and rolls back otherwise.
+type UnitOfWork interface {
+	Do(fn func(repos Repositories) error) error
+}
@@ -12,6 +12,7 @@ type User struct {
 	TenantID     uuid.UUID  `json:"tenant_id" gorm:"type:uuid;not null;index"`
 	Email        string     `json:"email" gorm:"size:255;not null"`
 	PasswordHash string     `json:"-" gorm:"size:255;not null"`
+	PinHash      string     `json:"-" gorm:"size:255"` // manager override PIN (e.g. POS voids)
 	FullName     string     `json:"full_name" gorm:"size:255;not null"`
 	Phone        string     `json:"phone,omitempty" gorm:"size:50"`
 	AvatarURL    string     `json:"avatar_url,omitempty"`
@@ -27,6 +28,18 @@ type User struct {
 
 func (User) TableName() string { return "users" }
 
+// PINAttempt counts the wrong manager PINs entered for one approver or from one device.
+// Too many in a row lock PIN approval for that approver or device for a while.
+type PINAttempt struct {
+	ID          uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Subject     string     `json:"subject" gorm:"size:150;not null;uniqueIndex"` // "approver:<user id>" or "device:<tenant id>:<address>"
+	Failures    int        `json:"failures" gorm:"not null;default:0"`
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+func (PINAttempt) TableName() string { return "pin_attempts" }
+
 // UserRole constants
 const (
 	RoleSuperAdmin    = "super_admin"
@@ -102,6 +115,12 @@ type ChangePasswordRequest struct {
 	NewPassword string `json:"new_password" validate:"required,min=6"`
 }
 
+// SetPINRequest is the DTO for setting the manager override PIN
+type SetPINRequest struct {
+	Password string `json:"password" validate:"required"`
+	PIN      string `json:"pin" validate:"required,numeric,min=4,max=6"`
+}
+
 // UpdateMerchantRequest is the DTO for updating merchant/tenant info
 type UpdateMerchantRequest struct {
 	Name              string `json:"name"`
@@ -122,6 +141,13 @@ type UserRepository interface {
 	FindByTenantID(tenantID uuid.UUID) ([]User, error)
 	Update(user *User) error
 	Delete(id uuid.UUID) error
+
+	FindPINAttempt(subject string) (*PINAttempt, error)
+	// RecordPINFailure counts one more wrong PIN for subject and returns the count
+	RecordPINFailure(subject string) (int, error)
+	// LockPIN locks PIN approval for subject until the given time and resets its count
+	LockPIN(subject string, until time.Time) error
+	ClearPINAttempts(subjects ...string) error
 }
 
 // AuditLogRepository defines the interface for audit log data access
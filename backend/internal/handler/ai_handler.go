@@ -70,9 +70,9 @@ func (h *AIHandler) GetStockAlerts(c *fiber.Ctx) error {
 		SELECT ti.product_id, COALESCE(SUM(ti.quantity), 0) as total_qty
 		FROM transaction_items ti
 		JOIN transactions t ON t.id = ti.transaction_id
-		WHERE t.tenant_id = ? AND t.created_at >= ? AND t.status = 'completed'
+		WHERE t.tenant_id = ? AND t.created_at >= ? AND t.status IN ?
 		GROUP BY ti.product_id
-	`, tenantID, thirtyDaysAgo).Scan(&salesLast30)
+	`, tenantID, thirtyDaysAgo, domain.ReportableTransactionStatuses).Scan(&salesLast30)
 
 	salesMap30 := make(map[string]float64)
 	for _, s := range salesLast30 {
@@ -85,9 +85,9 @@ func (h *AIHandler) GetStockAlerts(c *fiber.Ctx) error {
 		SELECT ti.product_id, COALESCE(SUM(ti.quantity), 0) as total_qty
 		FROM transaction_items ti
 		JOIN transactions t ON t.id = ti.transaction_id
-		WHERE t.tenant_id = ? AND t.created_at >= ? AND t.status = 'completed'
+		WHERE t.tenant_id = ? AND t.created_at >= ? AND t.status IN ?
 		GROUP BY ti.product_id
-	`, tenantID, sevenDaysAgo).Scan(&salesThisWeek)
+	`, tenantID, sevenDaysAgo, domain.ReportableTransactionStatuses).Scan(&salesThisWeek)
 
 	salesMapThisWeek := make(map[string]float64)
 	for _, s := range salesThisWeek {
@@ -100,9 +100,9 @@ func (h *AIHandler) GetStockAlerts(c *fiber.Ctx) error {
 		SELECT ti.product_id, COALESCE(SUM(ti.quantity), 0) as total_qty
 		FROM transaction_items ti
 		JOIN transactions t ON t.id = ti.transaction_id
-		WHERE t.tenant_id = ? AND t.created_at >= ? AND t.created_at < ? AND t.status = 'completed'
+		WHERE t.tenant_id = ? AND t.created_at >= ? AND t.created_at < ? AND t.status IN ?
 		GROUP BY ti.product_id
-	`, tenantID, fourteenDaysAgo, sevenDaysAgo).Scan(&salesLastWeek)
+	`, tenantID, fourteenDaysAgo, sevenDaysAgo, domain.ReportableTransactionStatuses).Scan(&salesLastWeek)
 
 	salesMapLastWeek := make(map[string]float64)
 	for _, s := range salesLastWeek {
@@ -98,7 +98,7 @@ func (h *DeliveryHandler) UpdateStatus(c *fiber.Ctx) error {
 	if err := c.BodyParser(&req); err != nil {
 		return response.BadRequest(c, "invalid request body")
 	}
-	order, err := h.usecase.UpdateOrderStatus(id, req)
+	order, err := h.usecase.UpdateOrderStatus(id, middleware.GetUserID(c), req)
 	if err != nil {
 		return response.BadRequest(c, err.Error())
 	}
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/codapos/backend/internal/middleware"
+	"github.com/codapos/backend/internal/usecase"
+	"github.com/codapos/backend/pkg/response"
+	"github.com/gofiber/fiber/v2"
+)
+
+type GiftCardHandler struct {
+	usecase *usecase.GiftCardUsecase
+}
+
+func NewGiftCardHandler(uc *usecase.GiftCardUsecase) *GiftCardHandler {
+	return &GiftCardHandler{usecase: uc}
+}
+
+// RegisterRoutes registers the gift card routes. Cards are issued by selling a gift
+// card product; cashiers look them up by code before taking them as payment.
+func (h *GiftCardHandler) RegisterRoutes(api fiber.Router) {
+	giftCards := api.Group("/gift-cards")
+	giftCards.Get("/", middleware.PermissionMiddleware(middleware.ActionManageCustomers), h.List)
+	giftCards.Get("/:code", middleware.PermissionMiddleware(middleware.ActionPOSCheckout), h.Lookup)
+}
+
+// List returns the gift cards of the tenant, optionally filtered by ?status=
+func (h *GiftCardHandler) List(c *fiber.Ctx) error {
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	perPage, _ := strconv.Atoi(c.Query("per_page", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 {
+		perPage = 20
+	}
+
+	cards, total, err := h.usecase.List(middleware.GetTenantID(c), c.Query("status"), page, perPage)
+	if err != nil {
+		return response.InternalError(c, "failed to fetch gift cards")
+	}
+	totalPages := (total + int64(perPage) - 1) / int64(perPage)
+	return response.SuccessWithMeta(c, cards, &response.Meta{
+		Page:       page,
+		PerPage:    perPage,
+		Total:      total,
+		TotalPages: totalPages,
+	})
+}
+
+// Lookup returns a gift card by its code, with its balance and movements
+func (h *GiftCardHandler) Lookup(c *fiber.Ctx) error {
+	card, err := h.usecase.Lookup(middleware.GetTenantID(c), c.Params("code"))
+	if err != nil {
+		return response.NotFound(c, err.Error())
+	}
+	return response.Success(c, card, "")
+}
@@ -0,0 +1,265 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/codapos/backend/internal/domain"
+	"github.com/codapos/backend/internal/middleware"
+	"github.com/codapos/backend/internal/usecase"
+	"github.com/codapos/backend/pkg/response"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// kitchenKeepAlive is how often an idle kitchen display stream is pinged so proxies keep it open
+const kitchenKeepAlive = 20 * time.Second
+
+type KitchenHandler struct {
+	usecase *usecase.KitchenUsecase
+}
+
+func NewKitchenHandler(uc *usecase.KitchenUsecase) *KitchenHandler {
+	return &KitchenHandler{usecase: uc}
+}
+
+// RegisterRoutes registers kitchen routes (stations: outlet management, tickets:
+// checkout permission, prep-time report: transactions)
+func (h *KitchenHandler) RegisterRoutes(api fiber.Router) {
+	kitchen := api.Group("/kitchen")
+	kitchen.Get("/stations", middleware.PermissionMiddleware(middleware.ActionPOSCheckout), h.ListStations)
+	kitchen.Post("/stations", middleware.PermissionMiddleware(middleware.ActionManageOutlets), h.CreateStation)
+	kitchen.Put("/stations/:id", middleware.PermissionMiddleware(middleware.ActionManageOutlets), h.UpdateStation)
+	kitchen.Delete("/stations/:id", middleware.PermissionMiddleware(middleware.ActionManageOutlets), h.DeleteStation)
+	kitchen.Get("/tickets", middleware.PermissionMiddleware(middleware.ActionPOSCheckout), h.ListTickets)
+	kitchen.Put("/tickets/:id/status", middleware.PermissionMiddleware(middleware.ActionPOSCheckout), h.SetTicketStatus)
+	kitchen.Put("/tickets/:id/items/:item_id/status", middleware.PermissionMiddleware(middleware.ActionPOSCheckout), h.SetItemStatus)
+	kitchen.Get("/metrics", middleware.PermissionMiddleware(middleware.ActionReadTransactions), h.Metrics)
+}
+
+// RegisterStreamRoutes registers the display stream (checkout permission). It is
+// authenticated by a stream token in the query string, since EventSource cannot send
+// the Authorization header.
+func (h *KitchenHandler) RegisterStreamRoutes(api fiber.Router, streamAuth fiber.Handler) {
+	api.Get("/kitchen/stream", streamAuth, middleware.PermissionMiddleware(middleware.ActionPOSCheckout), h.Stream)
+}
+
+// ListStations returns the stations of an outlet
+func (h *KitchenHandler) ListStations(c *fiber.Ctx) error {
+	outletID, err := uuid.Parse(c.Query("outlet_id"))
+	if err != nil {
+		return response.BadRequest(c, "outlet_id is required")
+	}
+	stations, err := h.usecase.GetStations(middleware.GetTenantID(c), outletID)
+	if err != nil {
+		return response.NotFound(c, err.Error())
+	}
+	return response.Success(c, stations, "")
+}
+
+// CreateStation creates a kitchen station
+func (h *KitchenHandler) CreateStation(c *fiber.Ctx) error {
+	var req domain.KitchenStationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "invalid request body")
+	}
+	station, err := h.usecase.CreateStation(middleware.GetTenantID(c), req)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Created(c, station, "station created successfully")
+}
+
+// UpdateStation updates a kitchen station and its categories
+func (h *KitchenHandler) UpdateStation(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid station ID")
+	}
+	var req domain.KitchenStationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "invalid request body")
+	}
+	station, err := h.usecase.UpdateStation(middleware.GetTenantID(c), id, req)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Success(c, station, "station updated successfully")
+}
+
+// DeleteStation deletes a kitchen station
+func (h *KitchenHandler) DeleteStation(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid station ID")
+	}
+	if err := h.usecase.DeleteStation(middleware.GetTenantID(c), id); err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Success(c, nil, "station deleted successfully")
+}
+
+// ListTickets returns the open tickets of an outlet; ?station_id= limits them to one
+// station and ?status=done or ?status=all shows finished tickets
+func (h *KitchenHandler) ListTickets(c *fiber.Ctx) error {
+	outletID, err := uuid.Parse(c.Query("outlet_id"))
+	if err != nil {
+		return response.BadRequest(c, "outlet_id is required")
+	}
+	stationID := optionalUUID(c.Query("station_id"))
+	status := c.Query("status", domain.KitchenTicketOpen)
+	if status == "all" {
+		status = ""
+	}
+	tickets, err := h.usecase.GetTickets(middleware.GetTenantID(c), outletID, stationID, status)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Success(c, tickets, "")
+}
+
+// SetTicketStatus moves all items of a ticket on, e.g. bumping a whole ticket to ready
+func (h *KitchenHandler) SetTicketStatus(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid ticket ID")
+	}
+	var req domain.KitchenStatusRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "invalid request body")
+	}
+	ticket, err := h.usecase.SetTicketStatus(middleware.GetTenantID(c), id, req)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Success(c, ticket, "ticket updated successfully")
+}
+
+// SetItemStatus moves one ticket item on
+func (h *KitchenHandler) SetItemStatus(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid ticket ID")
+	}
+	itemID, err := uuid.Parse(c.Params("item_id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid item ID")
+	}
+	var req domain.KitchenStatusRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "invalid request body")
+	}
+	ticket, err := h.usecase.SetItemStatus(middleware.GetTenantID(c), id, itemID, req)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Success(c, ticket, "ticket item updated successfully")
+}
+
+// Stream is the Server-Sent Events feed of a kitchen display. The open tickets are
+// sent first, then every ticket that is created or changes. When the stream ends the
+// display reconnects and starts over from the open tickets, fetching a new stream
+// token first as tokens expire after a minute.
+//
+// Ticket changes are passed on by an in-memory hub, so a display only hears about
+// changes made through the same API instance: run a single instance while kitchen
+// displays are in use.
+func (h *KitchenHandler) Stream(c *fiber.Ctx) error {
+	outletID, err := uuid.Parse(c.Query("outlet_id"))
+	if err != nil {
+		return response.BadRequest(c, "outlet_id is required")
+	}
+	open, events, cancel, err := h.usecase.Subscribe(middleware.GetTenantID(c), outletID, optionalUUID(c.Query("station_id")))
+	if err != nil {
+		return response.NotFound(c, err.Error())
+	}
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer cancel()
+		for i := range open {
+			if writeKitchenEvent(w, domain.KitchenEvent{Type: domain.KitchenEventTicketUpdated, Ticket: &open[i]}) != nil {
+				return
+			}
+		}
+		if w.Flush() != nil {
+			return
+		}
+
+		ping := time.NewTicker(kitchenKeepAlive)
+		defer ping.Stop()
+		for {
+			select {
+			case ev, ok := <-events:
+				if !ok {
+					return
+				}
+				if writeKitchenEvent(w, ev) != nil {
+					return
+				}
+			case <-ping.C:
+				if _, err := w.WriteString(": ping\n\n"); err != nil {
+					return
+				}
+			}
+			// A failed flush means the display has gone
+			if w.Flush() != nil {
+				return
+			}
+		}
+	})
+	return nil
+}
+
+// Metrics returns prep-time metrics per product and station; ?from= and ?to= are
+// dates (YYYY-MM-DD, default the last 7 days) and ?outlet_id= is optional
+func (h *KitchenHandler) Metrics(c *fiber.Ctx) error {
+	to := time.Now()
+	from := to.AddDate(0, 0, -7)
+	if v := c.Query("from"); v != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			return response.BadRequest(c, "invalid from date, use YYYY-MM-DD")
+		}
+		from = parsed
+	}
+	if v := c.Query("to"); v != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			return response.BadRequest(c, "invalid to date, use YYYY-MM-DD")
+		}
+		to = parsed.AddDate(0, 0, 1)
+	}
+
+	metrics, err := h.usecase.PrepMetrics(middleware.GetTenantID(c), optionalUUID(c.Query("outlet_id")), from, to)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Success(c, metrics, "")
+}
+
+func writeKitchenEvent(w *bufio.Writer, ev domain.KitchenEvent) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, data)
+	return err
+}
+
+func optionalUUID(value string) *uuid.UUID {
+	if value == "" {
+		return nil
+	}
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return nil
+	}
+	return &id
+}
@@ -0,0 +1,240 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/codapos/backend/internal/domain"
+	"github.com/codapos/backend/internal/middleware"
+	"github.com/codapos/backend/internal/usecase"
+	"github.com/codapos/backend/pkg/response"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type LoyaltyHandler struct {
+	usecase *usecase.LoyaltyUsecase
+}
+
+func NewLoyaltyHandler(uc *usecase.LoyaltyUsecase) *LoyaltyHandler {
+	return &LoyaltyHandler{usecase: uc}
+}
+
+// RegisterRoutes registers the loyalty program routes. Cashiers look up balances at
+// checkout; the program, its earn rules and tiers are managed with the customers.
+func (h *LoyaltyHandler) RegisterRoutes(api fiber.Router) {
+	loyalty := api.Group("/loyalty")
+	loyalty.Get("/program", middleware.PermissionMiddleware(middleware.ActionPOSCheckout), h.GetProgram)
+	loyalty.Put("/program", middleware.PermissionMiddleware(middleware.ActionManageSettings), h.SaveProgram)
+
+	loyalty.Get("/rules", middleware.PermissionMiddleware(middleware.ActionManageCustomers), h.ListRules)
+	loyalty.Post("/rules", middleware.PermissionMiddleware(middleware.ActionManageSettings), h.CreateRule)
+	loyalty.Put("/rules/:id", middleware.PermissionMiddleware(middleware.ActionManageSettings), h.UpdateRule)
+	loyalty.Delete("/rules/:id", middleware.PermissionMiddleware(middleware.ActionManageSettings), h.DeleteRule)
+
+	loyalty.Get("/tiers", middleware.PermissionMiddleware(middleware.ActionPOSCheckout), h.ListTiers)
+	loyalty.Post("/tiers", middleware.PermissionMiddleware(middleware.ActionManageSettings), h.CreateTier)
+	loyalty.Put("/tiers/:id", middleware.PermissionMiddleware(middleware.ActionManageSettings), h.UpdateTier)
+	loyalty.Delete("/tiers/:id", middleware.PermissionMiddleware(middleware.ActionManageSettings), h.DeleteTier)
+
+	loyalty.Get("/balance", middleware.PermissionMiddleware(middleware.ActionPOSCheckout), h.Balance)
+	loyalty.Get("/customers/:id", middleware.PermissionMiddleware(middleware.ActionPOSCheckout), h.CustomerBalance)
+	loyalty.Get("/customers/:id/ledger", middleware.PermissionMiddleware(middleware.ActionManageCustomers), h.Ledger)
+	loyalty.Post("/customers/:id/adjust", middleware.PermissionMiddleware(middleware.ActionManageCustomers), h.Adjust)
+}
+
+// GetProgram returns the points program of the tenant
+func (h *LoyaltyHandler) GetProgram(c *fiber.Ctx) error {
+	program, err := h.usecase.GetProgram(middleware.GetTenantID(c))
+	if err != nil {
+		return response.InternalError(c, "failed to fetch loyalty program")
+	}
+	return response.Success(c, program, "")
+}
+
+// SaveProgram sets up or changes the points program
+func (h *LoyaltyHandler) SaveProgram(c *fiber.Ctx) error {
+	var req domain.LoyaltyProgram
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "invalid request body")
+	}
+	program, err := h.usecase.SaveProgram(middleware.GetTenantID(c), &req)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Success(c, program, "loyalty program saved successfully")
+}
+
+// ListRules returns the earn rules
+func (h *LoyaltyHandler) ListRules(c *fiber.Ctx) error {
+	rules, err := h.usecase.GetRules(middleware.GetTenantID(c))
+	if err != nil {
+		return response.InternalError(c, "failed to fetch earn rules")
+	}
+	return response.Success(c, rules, "")
+}
+
+// CreateRule creates an earn rule
+func (h *LoyaltyHandler) CreateRule(c *fiber.Ctx) error {
+	var rule domain.LoyaltyEarnRule
+	if err := c.BodyParser(&rule); err != nil {
+		return response.BadRequest(c, "invalid request body")
+	}
+	if err := h.usecase.CreateRule(middleware.GetTenantID(c), &rule); err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Created(c, rule, "earn rule created successfully")
+}
+
+// UpdateRule updates an earn rule
+func (h *LoyaltyHandler) UpdateRule(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid rule ID")
+	}
+	var req domain.LoyaltyEarnRule
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "invalid request body")
+	}
+	rule, err := h.usecase.UpdateRule(middleware.GetTenantID(c), id, &req)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Success(c, rule, "earn rule updated successfully")
+}
+
+// DeleteRule deletes an earn rule
+func (h *LoyaltyHandler) DeleteRule(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid rule ID")
+	}
+	if err := h.usecase.DeleteRule(middleware.GetTenantID(c), id); err != nil {
+		return response.NotFound(c, err.Error())
+	}
+	return response.Success(c, nil, "earn rule deleted successfully")
+}
+
+// ListTiers returns the membership tiers, lowest first
+func (h *LoyaltyHandler) ListTiers(c *fiber.Ctx) error {
+	tiers, err := h.usecase.GetTiers(middleware.GetTenantID(c))
+	if err != nil {
+		return response.InternalError(c, "failed to fetch tiers")
+	}
+	return response.Success(c, tiers, "")
+}
+
+// CreateTier creates a membership tier
+func (h *LoyaltyHandler) CreateTier(c *fiber.Ctx) error {
+	var tier domain.LoyaltyTier
+	if err := c.BodyParser(&tier); err != nil {
+		return response.BadRequest(c, "invalid request body")
+	}
+	if err := h.usecase.CreateTier(middleware.GetTenantID(c), &tier); err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Created(c, tier, "tier created successfully")
+}
+
+// UpdateTier updates a membership tier
+func (h *LoyaltyHandler) UpdateTier(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid tier ID")
+	}
+	var req domain.LoyaltyTier
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "invalid request body")
+	}
+	tier, err := h.usecase.UpdateTier(middleware.GetTenantID(c), id, &req)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Success(c, tier, "tier updated successfully")
+}
+
+// DeleteTier deletes a membership tier
+func (h *LoyaltyHandler) DeleteTier(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid tier ID")
+	}
+	if err := h.usecase.DeleteTier(middleware.GetTenantID(c), id); err != nil {
+		return response.NotFound(c, err.Error())
+	}
+	return response.Success(c, nil, "tier deleted successfully")
+}
+
+// Balance looks up the points of a customer by ?phone=
+func (h *LoyaltyHandler) Balance(c *fiber.Ctx) error {
+	phone := c.Query("phone")
+	if phone == "" {
+		return response.BadRequest(c, "phone is required")
+	}
+	balance, err := h.usecase.Balance(middleware.GetTenantID(c), phone)
+	if err != nil {
+		return response.NotFound(c, err.Error())
+	}
+	return response.Success(c, balance, "")
+}
+
+// CustomerBalance returns the points of a customer
+func (h *LoyaltyHandler) CustomerBalance(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid customer ID")
+	}
+	balance, err := h.usecase.CustomerBalance(middleware.GetTenantID(c), id)
+	if err != nil {
+		return response.NotFound(c, err.Error())
+	}
+	return response.Success(c, balance, "")
+}
+
+// Ledger returns the point movements of a customer, newest first
+func (h *LoyaltyHandler) Ledger(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid customer ID")
+	}
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	perPage, _ := strconv.Atoi(c.Query("per_page", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 {
+		perPage = 20
+	}
+
+	entries, total, err := h.usecase.Ledger(middleware.GetTenantID(c), id, page, perPage)
+	if err != nil {
+		return response.NotFound(c, err.Error())
+	}
+	totalPages := (total + int64(perPage) - 1) / int64(perPage)
+	return response.SuccessWithMeta(c, entries, &response.Meta{
+		Page:       page,
+		PerPage:    perPage,
+		Total:      total,
+		TotalPages: totalPages,
+	})
+}
+
+// Adjust adds or removes points of a customer by hand
+func (h *LoyaltyHandler) Adjust(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid customer ID")
+	}
+	var req domain.LoyaltyAdjustRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "invalid request body")
+	}
+	balance, err := h.usecase.Adjust(middleware.GetTenantID(c), middleware.GetUserID(c), id, req)
+	if err != nil {
+		if errors.Is(err, usecase.ErrInsufficientPoints) {
+			return response.Error(c, fiber.StatusConflict, err.Error())
+		}
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Success(c, balance, "points adjusted successfully")
+}
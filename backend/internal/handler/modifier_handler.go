@@ -0,0 +1,169 @@
+package handler
+
+import (
+	"github.com/codapos/backend/internal/domain"
+	"github.com/codapos/backend/internal/middleware"
+	"github.com/codapos/backend/internal/usecase"
+	"github.com/codapos/backend/pkg/response"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type ModifierHandler struct {
+	usecase *usecase.ModifierUsecase
+}
+
+func NewModifierHandler(uc *usecase.ModifierUsecase) *ModifierHandler {
+	return &ModifierHandler{usecase: uc}
+}
+
+// RegisterRoutes registers modifier group routes (read: same as products, write: restricted)
+func (h *ModifierHandler) RegisterRoutes(api fiber.Router) {
+	read := middleware.PermissionMiddleware(middleware.ActionReadProducts)
+	manage := middleware.PermissionMiddleware(middleware.ActionManageProducts)
+
+	groups := api.Group("/modifier-groups")
+	groups.Get("", read, h.ListGroups)
+	groups.Get("/:id", read, h.GetGroup)
+	groups.Post("", manage, h.CreateGroup)
+	groups.Put("/:id", manage, h.UpdateGroup)
+	groups.Delete("/:id", manage, h.DeleteGroup)
+	groups.Post("/:id/modifiers", manage, h.AddModifier)
+	groups.Put("/:id/modifiers/:modifier_id", manage, h.UpdateModifier)
+	groups.Delete("/:id/modifiers/:modifier_id", manage, h.DeleteModifier)
+
+	api.Put("/products/:id/modifier-groups", manage, h.AttachToProduct)
+}
+
+// ListGroups returns the tenant's modifier groups with their modifiers
+func (h *ModifierHandler) ListGroups(c *fiber.Ctx) error {
+	groups, err := h.usecase.GetGroups(middleware.GetTenantID(c))
+	if err != nil {
+		return response.InternalError(c, "failed to fetch modifier groups")
+	}
+	return response.Success(c, groups, "")
+}
+
+// GetGroup returns a single modifier group
+func (h *ModifierHandler) GetGroup(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid modifier group ID")
+	}
+	group, err := h.usecase.GetGroup(middleware.GetTenantID(c), id)
+	if err != nil {
+		return response.NotFound(c, err.Error())
+	}
+	return response.Success(c, group, "")
+}
+
+// CreateGroup creates a modifier group, optionally with its modifiers
+func (h *ModifierHandler) CreateGroup(c *fiber.Ctx) error {
+	var group domain.ModifierGroup
+	if err := c.BodyParser(&group); err != nil {
+		return response.BadRequest(c, "invalid request body")
+	}
+	if err := h.usecase.CreateGroup(middleware.GetTenantID(c), &group); err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Created(c, group, "modifier group created successfully")
+}
+
+// UpdateGroup updates a modifier group
+func (h *ModifierHandler) UpdateGroup(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid modifier group ID")
+	}
+	var req domain.ModifierGroup
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "invalid request body")
+	}
+	group, err := h.usecase.UpdateGroup(middleware.GetTenantID(c), id, &req)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Success(c, group, "modifier group updated successfully")
+}
+
+// DeleteGroup deletes a modifier group
+func (h *ModifierHandler) DeleteGroup(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid modifier group ID")
+	}
+	if err := h.usecase.DeleteGroup(middleware.GetTenantID(c), id); err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Success(c, nil, "modifier group deleted successfully")
+}
+
+// AddModifier adds a modifier option to a group
+func (h *ModifierHandler) AddModifier(c *fiber.Ctx) error {
+	groupID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid modifier group ID")
+	}
+	var modifier domain.Modifier
+	if err := c.BodyParser(&modifier); err != nil {
+		return response.BadRequest(c, "invalid request body")
+	}
+	if err := h.usecase.AddModifier(middleware.GetTenantID(c), groupID, &modifier); err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Created(c, modifier, "modifier created successfully")
+}
+
+// UpdateModifier updates a modifier option
+func (h *ModifierHandler) UpdateModifier(c *fiber.Ctx) error {
+	groupID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid modifier group ID")
+	}
+	id, err := uuid.Parse(c.Params("modifier_id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid modifier ID")
+	}
+	var req domain.Modifier
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "invalid request body")
+	}
+	modifier, err := h.usecase.UpdateModifier(middleware.GetTenantID(c), groupID, id, &req)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Success(c, modifier, "modifier updated successfully")
+}
+
+// DeleteModifier deletes a modifier option
+func (h *ModifierHandler) DeleteModifier(c *fiber.Ctx) error {
+	groupID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid modifier group ID")
+	}
+	id, err := uuid.Parse(c.Params("modifier_id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid modifier ID")
+	}
+	if err := h.usecase.DeleteModifier(middleware.GetTenantID(c), groupID, id); err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Success(c, nil, "modifier deleted successfully")
+}
+
+// AttachToProduct sets the modifier groups offered on a product
+func (h *ModifierHandler) AttachToProduct(c *fiber.Ctx) error {
+	productID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid product ID")
+	}
+	var req domain.AttachModifierGroupsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "invalid request body")
+	}
+	product, err := h.usecase.AttachToProduct(middleware.GetTenantID(c), productID, req)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Success(c, product, "modifier groups attached successfully")
+}
@@ -26,6 +26,12 @@ func (h *OutletHandler) CreateOutlet(c *fiber.Ctx) error {
 	if outlet.Name == "" || outlet.Code == "" {
 		return response.BadRequest(c, "name and code are required")
 	}
+	if outlet.ServiceChargeRate < 0 || outlet.ServiceChargeRate > 100 {
+		return response.BadRequest(c, "service charge rate must be between 0 and 100")
+	}
+	if outlet.HeldOrderExpiryMinutes < 0 {
+		return response.BadRequest(c, "held order expiry cannot be negative")
+	}
 	if err := h.outletUsecase.CreateOutlet(&outlet); err != nil {
 		return response.InternalError(c, "failed to create outlet")
 	}
@@ -64,6 +70,12 @@ func (h *OutletHandler) UpdateOutlet(c *fiber.Ctx) error {
 	}
 	outlet.ID = id
 	outlet.TenantID = middleware.GetTenantID(c)
+	if outlet.ServiceChargeRate < 0 || outlet.ServiceChargeRate > 100 {
+		return response.BadRequest(c, "service charge rate must be between 0 and 100")
+	}
+	if outlet.HeldOrderExpiryMinutes < 0 {
+		return response.BadRequest(c, "held order expiry cannot be negative")
+	}
 	if err := h.outletUsecase.UpdateOutlet(&outlet); err != nil {
 		return response.InternalError(c, "failed to update outlet")
 	}
@@ -0,0 +1,184 @@
+package handler
+
+import (
+	"errors"
+	"html"
+	"strconv"
+
+	"github.com/codapos/backend/internal/domain"
+	"github.com/codapos/backend/internal/middleware"
+	"github.com/codapos/backend/internal/payment"
+	"github.com/codapos/backend/internal/usecase"
+	"github.com/codapos/backend/pkg/response"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type PaymentHandler struct {
+	usecase *usecase.PaymentUsecase
+	fake    *payment.Fake // nil unless the fake gateway is enabled
+}
+
+func NewPaymentHandler(uc *usecase.PaymentUsecase, fake *payment.Fake) *PaymentHandler {
+	return &PaymentHandler{usecase: uc, fake: fake}
+}
+
+// RegisterPublicRoutes registers the webhooks gateways post notifications to and, with
+// the fake gateway, its payment page. Storefront payments are created by the checkout.
+func (h *PaymentHandler) RegisterPublicRoutes(api fiber.Router) {
+	// Midtrans is set up with the address without provider
+	api.Post("/payment/notification", h.Notification)
+	api.Post("/payment/notification/:provider", h.Notification)
+	if h.fake != nil {
+		api.Get("/payment/fake/:token", h.FakePaymentPage)
+		api.Post("/payment/fake/:token", h.FakePay)
+	}
+}
+
+// RegisterRoutes registers charge creation for the cashier
+func (h *PaymentHandler) RegisterRoutes(api fiber.Router) {
+	api.Post("/payment/charges", middleware.PermissionMiddleware(middleware.ActionPOSCheckout), h.CreateCharge)
+}
+
+// RegisterAdminRoutes registers the notification log and gateway settings for super admins
+func (h *PaymentHandler) RegisterAdminRoutes(api fiber.Router) {
+	api.Get("/admin/payment-notifications", h.ListNotifications)
+	api.Post("/admin/payments/:provider/:orderId/sync", h.SyncPayment)
+	api.Put("/admin/merchants/:id/payment-gateway", h.SetTenantGateway)
+}
+
+// CreateCharge starts a payment through the gateway of the cashier's tenant
+func (h *PaymentHandler) CreateCharge(c *fiber.Ctx) error {
+	var req domain.ChargeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "invalid request body")
+	}
+	tenantID := middleware.GetTenantID(c)
+	charge, err := h.usecase.CreateCharge(&tenantID, req)
+	if err != nil {
+		return chargeError(c, err)
+	}
+	return response.Created(c, charge, "payment created successfully")
+}
+
+func chargeError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, usecase.ErrGatewayUnavailable):
+		return response.Error(c, fiber.StatusServiceUnavailable, usecase.ErrGatewayUnavailable.Error())
+	case errors.Is(err, usecase.ErrChargeFailed):
+		return response.Error(c, fiber.StatusBadGateway, err.Error())
+	default:
+		return response.BadRequest(c, err.Error())
+	}
+}
+
+// Notification receives a payment notification. Gateways send a notification again
+// until it is answered with 200, so only notifications that were handled, including
+// repeats, are answered with 200.
+func (h *PaymentHandler) Notification(c *fiber.Ctx) error {
+	provider := c.Params("provider", domain.PaymentProviderMidtrans)
+	record, err := h.usecase.HandleNotification(provider, c.Body())
+	switch {
+	case err == nil:
+		return response.Success(c, fiber.Map{"result": record.Result}, record.Message)
+	case errors.Is(err, usecase.ErrInvalidNotification):
+		return response.Forbidden(c, record.Message)
+	case errors.Is(err, usecase.ErrPaymentOrderUnknown):
+		return response.NotFound(c, err.Error())
+	default:
+		return response.InternalError(c, "failed to handle payment notification")
+	}
+}
+
+// ListNotifications returns the payment notification log: ?order_id= for one order,
+// or ?result= (e.g. rejected, unmatched, amount_mismatch) with ?page= and ?per_page=
+func (h *PaymentHandler) ListNotifications(c *fiber.Ctx) error {
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	perPage, _ := strconv.Atoi(c.Query("per_page", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 || perPage > 200 {
+		perPage = 50
+	}
+
+	notifications, total, err := h.usecase.GetNotifications(c.Query("order_id"), c.Query("result"), page, perPage)
+	if err != nil {
+		return response.InternalError(c, "failed to fetch payment notifications")
+	}
+
+	totalPages := (total + int64(perPage) - 1) / int64(perPage)
+	return response.SuccessWithMeta(c, notifications, &response.Meta{
+		Page:       page,
+		PerPage:    perPage,
+		Total:      total,
+		TotalPages: totalPages,
+	})
+}
+
+// SyncPayment fetches the state of a payment from its gateway and applies it, for an
+// order whose notification never arrived
+func (h *PaymentHandler) SyncPayment(c *fiber.Ctx) error {
+	record, err := h.usecase.SyncPayment(c.Params("provider"), c.Params("orderId"))
+	if err != nil {
+		if record == nil {
+			return response.BadRequest(c, err.Error())
+		}
+		return response.Error(c, fiber.StatusUnprocessableEntity, err.Error())
+	}
+	return response.Success(c, record, record.Message)
+}
+
+// SetTenantGateway sets the gateway customers of a merchant pay through
+func (h *PaymentHandler) SetTenantGateway(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid merchant ID")
+	}
+	var req struct {
+		PaymentGateway string `json:"payment_gateway"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "invalid request body")
+	}
+	tenant, err := h.usecase.SetTenantGateway(id, req.PaymentGateway)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Success(c, tenant, "payment gateway updated successfully")
+}
+
+// FakePaymentPage is the payment page a fake gateway charge redirects to
+func (h *PaymentHandler) FakePaymentPage(c *fiber.Ctx) error {
+	p, err := h.fake.PaymentByToken(c.Params("token"))
+	if err != nil {
+		return fakePage(c, fiber.StatusNotFound, "Pembayaran tidak ditemukan.", "")
+	}
+	form := ""
+	if p.Status == domain.GatewayStatusPending {
+		form = `<form method="post">` +
+			`<button name="status" value="` + domain.GatewayStatusPaid + `">Bayar</button> ` +
+			`<button name="status" value="` + domain.GatewayStatusFailed + `">Tolak</button> ` +
+			`<button name="status" value="` + domain.GatewayStatusExpired + `">Kedaluwarsa</button></form>`
+	}
+	return fakePage(c, fiber.StatusOK, "Pesanan "+p.OrderID+" · Rp "+strconv.FormatInt(p.Amount.WholeRupiah(), 10)+" · "+p.Status, form)
+}
+
+// FakePay completes a fake gateway payment and delivers its notification to the webhook
+func (h *PaymentHandler) FakePay(c *fiber.Ctx) error {
+	payload, err := h.fake.NotifyByToken(c.Params("token"), c.FormValue("status"))
+	if err != nil {
+		return fakePage(c, fiber.StatusBadRequest, err.Error(), "")
+	}
+	record, err := h.usecase.HandleNotification(domain.PaymentProviderFake, payload)
+	if err != nil {
+		return fakePage(c, fiber.StatusInternalServerError, err.Error(), "")
+	}
+	return fakePage(c, fiber.StatusOK, "Pesanan "+record.OrderID+" · "+record.Status+" · "+record.Result, "")
+}
+
+func fakePage(c *fiber.Ctx, status int, message, form string) error {
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return c.Status(status).SendString(`<!DOCTYPE html><html><head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1"><title>Pembayaran (simulasi)</title></head>` +
+		`<body style="font-family:sans-serif;text-align:center;padding:48px 16px;color:#333"><p><b>Gateway simulasi</b></p><p>` + html.EscapeString(message) + `</p>` + form + `</body></html>`)
+}
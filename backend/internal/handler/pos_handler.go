@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"strconv"
 
 	"github.com/codapos/backend/internal/domain"
@@ -26,6 +27,11 @@ func (h *POSHandler) Checkout(c *fiber.Ctx) error {
 		return response.BadRequest(c, "invalid request body")
 	}
 
+	req.IdempotencyKey = idempotencyKey(c, req.ClientTransactionID)
+	if len(req.IdempotencyKey) > maxIdempotencyKeyLength {
+		return response.BadRequest(c, "idempotency key is too long")
+	}
+
 	tenantID := middleware.GetTenantID(c)
 	cashierID := middleware.GetUserID(c)
 
@@ -37,7 +43,110 @@ func (h *POSHandler) Checkout(c *fiber.Ctx) error {
 	return response.Created(c, tx, "transaction created successfully")
 }
 
-// Refund processes a refund
+// Quote prices a cart without booking it; checkout payments must cover its total
+func (h *POSHandler) Quote(c *fiber.Ctx) error {
+	var req domain.QuoteRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "invalid request body")
+	}
+
+	tenantID := middleware.GetTenantID(c)
+	cashierID := middleware.GetUserID(c)
+
+	tx, err := h.posUsecase.Quote(tenantID, cashierID, req)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Success(c, tx, "cart priced")
+}
+
+// maxIdempotencyKeyLength matches the size of the idempotency_key columns
+const maxIdempotencyKeyLength = 100
+
+// idempotencyKey prefers the Idempotency-Key header and falls back to the client-generated ID
+func idempotencyKey(c *fiber.Ctx, clientID *uuid.UUID) string {
+	if key := c.Get("Idempotency-Key"); key != "" {
+		return key
+	}
+	if clientID != nil {
+		return clientID.String()
+	}
+	return ""
+}
+
+// HoldOrder parks a cart as an open tab without payments
+func (h *POSHandler) HoldOrder(c *fiber.Ctx) error {
+	var req domain.HeldOrderRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "invalid request body")
+	}
+
+	req.IdempotencyKey = idempotencyKey(c, req.ClientTransactionID)
+	if len(req.IdempotencyKey) > maxIdempotencyKeyLength {
+		return response.BadRequest(c, "idempotency key is too long")
+	}
+
+	tx, err := h.posUsecase.HoldOrder(middleware.GetTenantID(c), middleware.GetUserID(c), req)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Created(c, tx, "order held successfully")
+}
+
+// GetHeldOrders lists the open tabs, optionally for one outlet
+func (h *POSHandler) GetHeldOrders(c *fiber.Ctx) error {
+	var outletID *uuid.UUID
+	if oid := c.Query("outlet_id"); oid != "" {
+		parsed, err := uuid.Parse(oid)
+		if err == nil {
+			outletID = &parsed
+		}
+	}
+
+	orders, err := h.posUsecase.GetHeldOrders(middleware.GetTenantID(c), outletID)
+	if err != nil {
+		return response.InternalError(c, "failed to fetch held orders")
+	}
+	return response.Success(c, orders, "")
+}
+
+// UpdateHeldOrder replaces the items of an open tab
+func (h *POSHandler) UpdateHeldOrder(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid order ID")
+	}
+	var req domain.HeldOrderRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "invalid request body")
+	}
+
+	tx, err := h.posUsecase.UpdateHeldOrder(middleware.GetTenantID(c), id, req)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Success(c, tx, "order updated successfully")
+}
+
+// SettleHeldOrder pays an open tab in full
+func (h *POSHandler) SettleHeldOrder(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid order ID")
+	}
+	var req domain.SettleOrderRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "invalid request body")
+	}
+
+	tx, err := h.posUsecase.SettleHeldOrder(middleware.GetTenantID(c), middleware.GetUserID(c), id, req)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Success(c, tx, "order settled successfully")
+}
+
+// Refund processes a full, line-item or partial-quantity refund
 func (h *POSHandler) Refund(c *fiber.Ctx) error {
 	idStr := c.Params("id")
 	id, err := uuid.Parse(idStr)
@@ -45,15 +154,19 @@ func (h *POSHandler) Refund(c *fiber.Ctx) error {
 		return response.BadRequest(c, "invalid transaction ID")
 	}
 
-	var body struct {
-		Reason string `json:"reason"`
+	// An empty body (or no items) refunds everything not yet refunded
+	var req domain.RefundRequest
+	_ = c.BodyParser(&req)
+
+	req.IdempotencyKey = idempotencyKey(c, req.ClientRefundID)
+	if len(req.IdempotencyKey) > maxIdempotencyKeyLength {
+		return response.BadRequest(c, "idempotency key is too long")
 	}
-	_ = c.BodyParser(&body)
 
 	tenantID := middleware.GetTenantID(c)
 	cashierID := middleware.GetUserID(c)
 
-	refund, err := h.posUsecase.Refund(tenantID, cashierID, id, body.Reason)
+	refund, err := h.posUsecase.Refund(tenantID, cashierID, id, req)
 	if err != nil {
 		return response.BadRequest(c, err.Error())
 	}
@@ -61,6 +174,104 @@ func (h *POSHandler) Refund(c *fiber.Ctx) error {
 	return response.Created(c, refund, "refund processed successfully")
 }
 
+// Void voids a sale from the current shift (cashiers need manager approval)
+func (h *POSHandler) Void(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid transaction ID")
+	}
+
+	var req domain.VoidRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "invalid request body")
+	}
+	req.Device = c.IP()
+
+	tenantID := middleware.GetTenantID(c)
+	userID := middleware.GetUserID(c)
+
+	tx, err := h.posUsecase.Void(tenantID, userID, middleware.GetRole(c), id, req)
+	if errors.Is(err, usecase.ErrPINLocked) {
+		return response.Error(c, fiber.StatusTooManyRequests, err.Error())
+	}
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	return response.Success(c, tx, "transaction voided successfully")
+}
+
+// SplitBill splits an open bill by item, by seat or into equal shares
+func (h *POSHandler) SplitBill(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid transaction ID")
+	}
+
+	var req domain.SplitBillRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "invalid request body")
+	}
+
+	tx, err := h.posUsecase.SplitBill(middleware.GetTenantID(c), id, req)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	return response.Success(c, tx, "bill split successfully")
+}
+
+// PaySplit pays one share of a split bill
+func (h *POSHandler) PaySplit(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid transaction ID")
+	}
+	splitID, err := uuid.Parse(c.Params("split_id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid split ID")
+	}
+
+	var req domain.PaymentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "invalid request body")
+	}
+
+	tenantID := middleware.GetTenantID(c)
+	cashierID := middleware.GetUserID(c)
+
+	tx, err := h.posUsecase.PaySplit(tenantID, cashierID, id, splitID, req)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	return response.Success(c, tx, "split paid successfully")
+}
+
+// GetFailedPostings lists transactions whose automatic journal could not be posted
+func (h *POSHandler) GetFailedPostings(c *fiber.Ctx) error {
+	transactions, err := h.posUsecase.GetFailedPostings(middleware.GetTenantID(c))
+	if err != nil {
+		return response.InternalError(c, "failed to fetch failed postings")
+	}
+	return response.Success(c, transactions, "")
+}
+
+// RepostJournal retries the automatic journal of a transaction
+func (h *POSHandler) RepostJournal(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid transaction ID")
+	}
+
+	tx, err := h.posUsecase.RepostJournal(middleware.GetTenantID(c), id)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	return response.Success(c, tx, "journal posted successfully")
+}
+
 // GetTransactions returns transactions list
 func (h *POSHandler) GetTransactions(c *fiber.Ctx) error {
 	tenantID := middleware.GetTenantID(c)
@@ -114,7 +325,7 @@ func (h *POSHandler) ReprintTransaction(c *fiber.Ctx) error {
 		return response.BadRequest(c, "invalid transaction ID")
 	}
 
-	tx, err := h.posUsecase.IncrementReprint(id)
+	tx, err := h.posUsecase.IncrementReprint(middleware.GetTenantID(c), id)
 	if err != nil {
 		return response.NotFound(c, "transaction not found")
 	}
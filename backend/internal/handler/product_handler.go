@@ -36,7 +36,7 @@ func (h *ProductHandler) CreateProduct(c *fiber.Ctx) error {
 	return response.Created(c, product, "product created successfully")
 }
 
-// GetProducts returns all products for a tenant
+// GetProducts returns all products for a tenant; ?outlet_id adds the outlet's effective prices
 func (h *ProductHandler) GetProducts(c *fiber.Ctx) error {
 	tenantID := middleware.GetTenantID(c)
 	search := c.Query("search")
@@ -49,7 +49,16 @@ func (h *ProductHandler) GetProducts(c *fiber.Ctx) error {
 		}
 	}
 
-	products, err := h.productUsecase.GetProducts(tenantID, search, categoryID)
+	var outletID *uuid.UUID
+	if oid := c.Query("outlet_id"); oid != "" {
+		parsed, err := uuid.Parse(oid)
+		if err != nil {
+			return response.BadRequest(c, "invalid outlet ID")
+		}
+		outletID = &parsed
+	}
+
+	products, err := h.productUsecase.GetProducts(tenantID, search, categoryID, outletID)
 	if err != nil {
 		return response.InternalError(c, "failed to fetch products")
 	}
@@ -170,3 +179,57 @@ func (h *ProductHandler) DeleteCategory(c *fiber.Ctx) error {
 
 	return response.Success(c, nil, "category deleted successfully")
 }
+
+// GetOutletPrices returns the price overrides of an outlet
+func (h *ProductHandler) GetOutletPrices(c *fiber.Ctx) error {
+	outletID, err := uuid.Parse(c.Params("outlet_id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid outlet ID")
+	}
+
+	prices, err := h.productUsecase.GetOutletPrices(middleware.GetTenantID(c), outletID)
+	if err != nil {
+		return response.NotFound(c, err.Error())
+	}
+
+	return response.Success(c, prices, "")
+}
+
+// SetOutletPrices creates or updates price overrides of an outlet in bulk
+func (h *ProductHandler) SetOutletPrices(c *fiber.Ctx) error {
+	outletID, err := uuid.Parse(c.Params("outlet_id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid outlet ID")
+	}
+
+	var req domain.OutletPriceBulkRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "invalid request body")
+	}
+
+	prices, err := h.productUsecase.SetOutletPrices(middleware.GetTenantID(c), outletID, req)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	return response.Success(c, prices, "outlet prices saved successfully")
+}
+
+// DeleteOutletPrices removes price overrides of an outlet in bulk
+func (h *ProductHandler) DeleteOutletPrices(c *fiber.Ctx) error {
+	outletID, err := uuid.Parse(c.Params("outlet_id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid outlet ID")
+	}
+
+	var req domain.OutletPriceBulkRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "invalid request body")
+	}
+
+	if err := h.productUsecase.DeleteOutletPrices(middleware.GetTenantID(c), outletID, req); err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+
+	return response.Success(c, nil, "outlet prices deleted successfully")
+}
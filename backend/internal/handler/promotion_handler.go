@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"github.com/codapos/backend/internal/domain"
+	"github.com/codapos/backend/internal/middleware"
+	"github.com/codapos/backend/internal/usecase"
+	"github.com/codapos/backend/pkg/response"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type PromotionHandler struct {
+	usecase *usecase.PromotionUsecase
+}
+
+func NewPromotionHandler(uc *usecase.PromotionUsecase) *PromotionHandler {
+	return &PromotionHandler{usecase: uc}
+}
+
+// RegisterRoutes registers promotion routes (read: same as products, write: restricted)
+func (h *PromotionHandler) RegisterRoutes(api fiber.Router) {
+	promotions := api.Group("/promotions")
+	promotions.Get("", middleware.PermissionMiddleware(middleware.ActionReadProducts), h.List)
+	promotions.Get("/:id", middleware.PermissionMiddleware(middleware.ActionReadProducts), h.Get)
+	promotions.Post("", middleware.PermissionMiddleware(middleware.ActionManageProducts), h.Create)
+	promotions.Put("/:id", middleware.PermissionMiddleware(middleware.ActionManageProducts), h.Update)
+	promotions.Delete("/:id", middleware.PermissionMiddleware(middleware.ActionManageProducts), h.Delete)
+}
+
+// List returns promotions; ?active=true limits the list to active promotions
+func (h *PromotionHandler) List(c *fiber.Ctx) error {
+	tenantID := middleware.GetTenantID(c)
+	promotions, err := h.usecase.GetPromotions(tenantID, c.Query("active") == "true")
+	if err != nil {
+		return response.InternalError(c, "failed to fetch promotions")
+	}
+	return response.Success(c, promotions, "")
+}
+
+// Get returns a single promotion
+func (h *PromotionHandler) Get(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid promotion ID")
+	}
+	promo, err := h.usecase.GetPromotion(middleware.GetTenantID(c), id)
+	if err != nil {
+		return response.NotFound(c, err.Error())
+	}
+	return response.Success(c, promo, "")
+}
+
+// Create creates a new promotion
+func (h *PromotionHandler) Create(c *fiber.Ctx) error {
+	var promo domain.Promotion
+	if err := c.BodyParser(&promo); err != nil {
+		return response.BadRequest(c, "invalid request body")
+	}
+	if err := h.usecase.CreatePromotion(middleware.GetTenantID(c), &promo); err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Created(c, promo, "promotion created successfully")
+}
+
+// Update updates a promotion
+func (h *PromotionHandler) Update(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid promotion ID")
+	}
+	var req domain.Promotion
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "invalid request body")
+	}
+	promo, err := h.usecase.UpdatePromotion(middleware.GetTenantID(c), id, &req)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Success(c, promo, "promotion updated successfully")
+}
+
+// Delete deletes a promotion
+func (h *PromotionHandler) Delete(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid promotion ID")
+	}
+	if err := h.usecase.DeletePromotion(middleware.GetTenantID(c), id); err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Success(c, nil, "promotion deleted successfully")
+}
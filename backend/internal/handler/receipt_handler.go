@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"errors"
+	"html"
+
+	"github.com/codapos/backend/internal/domain"
+	"github.com/codapos/backend/internal/middleware"
+	"github.com/codapos/backend/internal/receipt"
+	"github.com/codapos/backend/internal/usecase"
+	"github.com/codapos/backend/pkg/response"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type ReceiptHandler struct {
+	usecase *usecase.ReceiptUsecase
+}
+
+func NewReceiptHandler(uc *usecase.ReceiptUsecase) *ReceiptHandler {
+	return &ReceiptHandler{usecase: uc}
+}
+
+// RegisterRoutes registers receipt routes (templates: settings permission, rendering: transactions,
+// sharing links: checkout permission)
+func (h *ReceiptHandler) RegisterRoutes(api fiber.Router) {
+	templates := api.Group("/receipt-templates", middleware.PermissionMiddleware(middleware.ActionManageSettings))
+	templates.Get("", h.ListTemplates)
+	templates.Put("", h.SaveTemplate)
+	templates.Delete("/:id", h.DeleteTemplate)
+
+	api.Get("/pos/transactions/:id/receipt", middleware.PermissionMiddleware(middleware.ActionReadTransactions), h.RenderReceipt)
+	api.Get("/pos/transactions/:id/receipt-links", middleware.PermissionMiddleware(middleware.ActionReadTransactions), h.ListLinks)
+	api.Post("/pos/transactions/:id/receipt-links", middleware.PermissionMiddleware(middleware.ActionPOSCheckout), h.CreateLink)
+	api.Delete("/pos/receipt-links/:id", middleware.PermissionMiddleware(middleware.ActionPOSCheckout), h.RevokeLink)
+}
+
+// RegisterPublicRoutes registers the public digital receipt page opened from a shared link
+func (h *ReceiptHandler) RegisterPublicRoutes(api fiber.Router) {
+	api.Get("/receipts/:token", h.ViewReceipt)
+}
+
+// ListTemplates returns the receipt templates of the tenant
+func (h *ReceiptHandler) ListTemplates(c *fiber.Ctx) error {
+	templates, err := h.usecase.GetTemplates(middleware.GetTenantID(c))
+	if err != nil {
+		return response.InternalError(c, "failed to fetch receipt templates")
+	}
+	return response.Success(c, templates, "")
+}
+
+// SaveTemplate sets the template of an outlet, or the tenant default without outlet_id
+func (h *ReceiptHandler) SaveTemplate(c *fiber.Ctx) error {
+	var req domain.ReceiptTemplateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "invalid request body")
+	}
+	template, err := h.usecase.SaveTemplate(middleware.GetTenantID(c), req)
+	if err != nil {
+		if errors.Is(err, usecase.ErrFeatureNotInPlan) {
+			return response.Forbidden(c, err.Error())
+		}
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Success(c, template, "receipt template saved successfully")
+}
+
+// DeleteTemplate deletes a template
+func (h *ReceiptHandler) DeleteTemplate(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid template ID")
+	}
+	if err := h.usecase.DeleteTemplate(middleware.GetTenantID(c), id); err != nil {
+		return response.NotFound(c, err.Error())
+	}
+	return response.Success(c, nil, "receipt template deleted successfully")
+}
+
+// RenderReceipt returns the receipt of a transaction: ?format=escpos (raw printer
+// bytes), text or html (default), and ?paper=58mm or 80mm to override the template
+func (h *ReceiptHandler) RenderReceipt(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid transaction ID")
+	}
+	format := c.Query("format", domain.ReceiptFormatHTML)
+	body, err := h.usecase.RenderReceipt(middleware.GetTenantID(c), id, format, c.Query("paper"))
+	if err != nil {
+		if errors.Is(err, usecase.ErrFeatureNotInPlan) {
+			return response.Forbidden(c, err.Error())
+		}
+		return response.BadRequest(c, err.Error())
+	}
+	c.Set(fiber.HeaderContentType, receipt.ContentType(format))
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.Send(body)
+}
+
+// CreateLink creates a public link to the receipt, to be sent by WhatsApp or email
+func (h *ReceiptHandler) CreateLink(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid transaction ID")
+	}
+	var req domain.ReceiptLinkRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return response.BadRequest(c, "invalid request body")
+		}
+	}
+	link, err := h.usecase.CreateReceiptLink(middleware.GetTenantID(c), middleware.GetUserID(c), id, req)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Created(c, link, "receipt link created successfully")
+}
+
+// ListLinks returns the links shared for a transaction
+func (h *ReceiptHandler) ListLinks(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid transaction ID")
+	}
+	links, err := h.usecase.GetReceiptLinks(middleware.GetTenantID(c), id)
+	if err != nil {
+		return response.NotFound(c, err.Error())
+	}
+	return response.Success(c, links, "")
+}
+
+// RevokeLink stops a shared link from opening the receipt
+func (h *ReceiptHandler) RevokeLink(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid link ID")
+	}
+	link, err := h.usecase.RevokeReceiptLink(middleware.GetTenantID(c), id)
+	if err != nil {
+		return response.NotFound(c, err.Error())
+	}
+	return response.Success(c, link, "receipt link revoked successfully")
+}
+
+// ViewReceipt shows the digital receipt behind a shared link, without login
+func (h *ReceiptHandler) ViewReceipt(c *fiber.Ctx) error {
+	c.Set("X-Robots-Tag", "noindex, nofollow")
+	c.Set(fiber.HeaderReferrerPolicy, "no-referrer")
+	c.Set(fiber.HeaderCacheControl, "no-store")
+
+	body, err := h.usecase.ViewReceipt(c.Params("token"))
+	switch {
+	case err == nil:
+		c.Set(fiber.HeaderContentType, receipt.ContentType(domain.ReceiptFormatHTML))
+		return c.Send(body)
+	case errors.Is(err, usecase.ErrReceiptLinkExpired):
+		return receiptMessage(c, fiber.StatusGone, "Link struk ini sudah kedaluwarsa.")
+	case errors.Is(err, usecase.ErrReceiptLinkRevoked):
+		return receiptMessage(c, fiber.StatusGone, "Link struk ini sudah tidak berlaku.")
+	case errors.Is(err, usecase.ErrReceiptLinkNotFound):
+		return receiptMessage(c, fiber.StatusNotFound, "Struk tidak ditemukan.")
+	default:
+		return receiptMessage(c, fiber.StatusInternalServerError, "Struk tidak dapat ditampilkan, silakan coba lagi.")
+	}
+}
+
+// receiptMessage answers a customer opening a receipt link with a short page
+func receiptMessage(c *fiber.Ctx, status int, message string) error {
+	c.Set(fiber.HeaderContentType, receipt.ContentType(domain.ReceiptFormatHTML))
+	return c.Status(status).SendString(`<!DOCTYPE html><html><head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1"><title>Struk</title></head>` +
+		`<body style="font-family:sans-serif;text-align:center;padding:48px 16px;color:#333"><p>` + html.EscapeString(message) + `</p></body></html>`)
+}
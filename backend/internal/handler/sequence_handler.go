@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"github.com/codapos/backend/internal/domain"
+	"github.com/codapos/backend/internal/middleware"
+	"github.com/codapos/backend/internal/usecase"
+	"github.com/codapos/backend/pkg/response"
+	"github.com/gofiber/fiber/v2"
+)
+
+type SequenceHandler struct {
+	usecase *usecase.SequenceUsecase
+}
+
+func NewSequenceHandler(uc *usecase.SequenceUsecase) *SequenceHandler {
+	return &SequenceHandler{usecase: uc}
+}
+
+// RegisterRoutes registers document numbering routes (owner settings)
+func (h *SequenceHandler) RegisterRoutes(api fiber.Router) {
+	sequences := api.Group("/sequences", middleware.PermissionMiddleware(middleware.ActionManageSettings))
+	sequences.Get("", h.List)
+	sequences.Put("/:document_type", h.Update)
+}
+
+// List returns the numbering of every document type
+func (h *SequenceHandler) List(c *fiber.Ctx) error {
+	configs, err := h.usecase.GetConfigs(middleware.GetTenantID(c))
+	if err != nil {
+		return response.InternalError(c, "failed to fetch sequences")
+	}
+	return response.Success(c, configs, "")
+}
+
+// Update changes the prefix, reset period or padding of a document type
+func (h *SequenceHandler) Update(c *fiber.Ctx) error {
+	var req domain.SequenceConfigRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "invalid request body")
+	}
+	config, err := h.usecase.UpdateConfig(middleware.GetTenantID(c), c.Params("document_type"), req)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Success(c, config, "sequence updated successfully")
+}
@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/codapos/backend/internal/domain"
+	"github.com/codapos/backend/internal/middleware"
+	"github.com/codapos/backend/internal/usecase"
+	"github.com/codapos/backend/pkg/response"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type ShiftHandler struct {
+	usecase *usecase.ShiftUsecase
+}
+
+func NewShiftHandler(uc *usecase.ShiftUsecase) *ShiftHandler {
+	return &ShiftHandler{usecase: uc}
+}
+
+// RegisterRoutes registers cashier shift routes (drawer: checkout permission, reports: transactions)
+func (h *ShiftHandler) RegisterRoutes(api fiber.Router) {
+	shifts := api.Group("/pos/shifts")
+	shifts.Post("/open", middleware.PermissionMiddleware(middleware.ActionPOSCheckout), h.Open)
+	shifts.Get("/current", middleware.PermissionMiddleware(middleware.ActionPOSCheckout), h.Current)
+	shifts.Post("/:id/cash-movements", middleware.PermissionMiddleware(middleware.ActionPOSCheckout), h.AddCashMovement)
+	shifts.Post("/:id/close", middleware.PermissionMiddleware(middleware.ActionPOSCheckout), h.Close)
+	shifts.Get("", middleware.PermissionMiddleware(middleware.ActionReadTransactions), h.List)
+	shifts.Get("/:id", middleware.PermissionMiddleware(middleware.ActionReadTransactions), h.Get)
+	shifts.Get("/:id/z-report", middleware.PermissionMiddleware(middleware.ActionReadTransactions), h.ZReport)
+}
+
+// Open opens a shift for the current cashier
+func (h *ShiftHandler) Open(c *fiber.Ctx) error {
+	var req domain.OpenShiftRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "invalid request body")
+	}
+	shift, err := h.usecase.OpenShift(middleware.GetTenantID(c), middleware.GetUserID(c), req)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Created(c, shift, "shift opened successfully")
+}
+
+// Current returns the open shift of the current cashier
+func (h *ShiftHandler) Current(c *fiber.Ctx) error {
+	shift, err := h.usecase.CurrentShift(middleware.GetTenantID(c), middleware.GetUserID(c))
+	if err != nil {
+		return response.NotFound(c, err.Error())
+	}
+	return response.Success(c, shift, "")
+}
+
+// AddCashMovement records a cash-in or cash-out on a shift
+func (h *ShiftHandler) AddCashMovement(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid shift ID")
+	}
+	var req domain.CashMovementRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "invalid request body")
+	}
+	movement, err := h.usecase.AddCashMovement(middleware.GetTenantID(c), middleware.GetUserID(c), middleware.GetRole(c), id, req)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Created(c, movement, "cash movement recorded successfully")
+}
+
+// Close closes a shift with the counted cash and returns its Z-report
+func (h *ShiftHandler) Close(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid shift ID")
+	}
+	var req domain.CloseShiftRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "invalid request body")
+	}
+	report, err := h.usecase.CloseShift(middleware.GetTenantID(c), middleware.GetUserID(c), middleware.GetRole(c), id, req)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Success(c, report, "shift closed successfully")
+}
+
+// List returns shifts with pagination, optionally for one outlet
+func (h *ShiftHandler) List(c *fiber.Ctx) error {
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	perPage, _ := strconv.Atoi(c.Query("per_page", "20"))
+
+	var outletID *uuid.UUID
+	if oid := c.Query("outlet_id"); oid != "" {
+		parsed, err := uuid.Parse(oid)
+		if err == nil {
+			outletID = &parsed
+		}
+	}
+
+	shifts, total, err := h.usecase.GetShifts(middleware.GetTenantID(c), outletID, page, perPage)
+	if err != nil {
+		return response.InternalError(c, "failed to fetch shifts")
+	}
+
+	totalPages := (total + int64(perPage) - 1) / int64(perPage)
+	return response.SuccessWithMeta(c, shifts, &response.Meta{
+		Page:       page,
+		PerPage:    perPage,
+		Total:      total,
+		TotalPages: totalPages,
+	})
+}
+
+// Get returns a single shift
+func (h *ShiftHandler) Get(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid shift ID")
+	}
+	shift, err := h.usecase.GetShift(middleware.GetTenantID(c), id)
+	if err != nil {
+		return response.NotFound(c, err.Error())
+	}
+	return response.Success(c, shift, "")
+}
+
+// ZReport returns the Z-report of a closed shift
+func (h *ShiftHandler) ZReport(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid shift ID")
+	}
+	report, err := h.usecase.GetZReport(middleware.GetTenantID(c), id)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Success(c, report, "")
+}
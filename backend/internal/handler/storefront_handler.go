@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"errors"
+	"time"
+
+	"github.com/codapos/backend/internal/domain"
+	"github.com/codapos/backend/internal/repository"
+	"github.com/codapos/backend/internal/usecase"
+	"github.com/codapos/backend/pkg/response"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type StorefrontHandler struct {
+	usecase  *usecase.StorefrontUsecase
+	chatRepo *repository.ChatRepository
+}
+
+func NewStorefrontHandler(uc *usecase.StorefrontUsecase, chatRepo *repository.ChatRepository) *StorefrontHandler {
+	return &StorefrontHandler{usecase: uc, chatRepo: chatRepo}
+}
+
+// RegisterPublicRoutes registers quoting and placing storefront orders
+func (h *StorefrontHandler) RegisterPublicRoutes(api fiber.Router) {
+	api.Post("/store/:slug/quote", h.Quote)
+	api.Post("/store/:slug/checkout", h.Checkout)
+}
+
+// Quote prices the cart of a storefront customer, delivery fee included
+func (h *StorefrontHandler) Quote(c *fiber.Ctx) error {
+	var req domain.StoreCheckoutRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "invalid request body")
+	}
+	quote, err := h.usecase.Quote(c.Params("slug"), req)
+	if err != nil {
+		return storefrontError(c, err)
+	}
+	return response.Success(c, quote, "")
+}
+
+// Checkout places a storefront order and starts its payment. Retries with the same
+// Idempotency-Key header (or client_order_id) return the original order.
+func (h *StorefrontHandler) Checkout(c *fiber.Ctx) error {
+	var req domain.StoreCheckoutRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "invalid request body")
+	}
+	req.IdempotencyKey = c.Get("Idempotency-Key")
+	if req.IdempotencyKey == "" {
+		req.IdempotencyKey = req.ClientOrderID
+	}
+
+	result, err := h.usecase.Checkout(c.Params("slug"), req)
+	if err != nil {
+		return storefrontError(c, err)
+	}
+
+	// Customer and merchant can chat about the order from the start (a retry may already have a room)
+	room, err := h.chatRepo.FindRoomByDeliveryID(result.DeliveryOrderID)
+	if err != nil {
+		now := time.Now()
+		room = &domain.ChatRoom{
+			BaseModel:  domain.BaseModel{ID: uuid.New(), CreatedAt: now, UpdatedAt: now},
+			TenantID:   result.TenantID,
+			DeliveryID: result.DeliveryOrderID,
+			CustomerID: result.CustomerID,
+			Status:     domain.ChatRoomStatusOpen,
+		}
+		if err := h.chatRepo.CreateRoom(room); err != nil {
+			room = nil
+		}
+	}
+	if room != nil {
+		result.ChatRoomID = &room.ID
+	}
+	return response.Created(c, result, result.Message)
+}
+
+func storefrontError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, usecase.ErrStoreNotFound) {
+		return response.NotFound(c, err.Error())
+	}
+	if errors.Is(err, usecase.ErrOutOfStock) {
+		return response.Error(c, fiber.StatusConflict, err.Error())
+	}
+	return chargeError(c, err)
+}
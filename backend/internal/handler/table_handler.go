@@ -0,0 +1,212 @@
+package handler
+
+import (
+	"github.com/codapos/backend/internal/domain"
+	"github.com/codapos/backend/internal/middleware"
+	"github.com/codapos/backend/internal/usecase"
+	"github.com/codapos/backend/pkg/response"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type TableHandler struct {
+	usecase    *usecase.TableUsecase
+	posUsecase *usecase.POSUsecase
+}
+
+func NewTableHandler(uc *usecase.TableUsecase, posUC *usecase.POSUsecase) *TableHandler {
+	return &TableHandler{usecase: uc, posUsecase: posUC}
+}
+
+// RegisterRoutes registers floor plan and table routes (layout: outlet management,
+// seating and table operations: checkout permission)
+func (h *TableHandler) RegisterRoutes(api fiber.Router) {
+	api.Get("/outlets/:id/floor-plan", middleware.PermissionMiddleware(middleware.ActionPOSCheckout), h.FloorPlan)
+
+	floors := api.Group("/floors")
+	floors.Post("", middleware.PermissionMiddleware(middleware.ActionManageOutlets), h.CreateFloor)
+	floors.Put("/:id", middleware.PermissionMiddleware(middleware.ActionManageOutlets), h.UpdateFloor)
+	floors.Delete("/:id", middleware.PermissionMiddleware(middleware.ActionManageOutlets), h.DeleteFloor)
+
+	tables := api.Group("/tables")
+	tables.Get("", middleware.PermissionMiddleware(middleware.ActionPOSCheckout), h.List)
+	tables.Get("/:id", middleware.PermissionMiddleware(middleware.ActionPOSCheckout), h.Get)
+	tables.Post("", middleware.PermissionMiddleware(middleware.ActionManageOutlets), h.Create)
+	tables.Put("/:id", middleware.PermissionMiddleware(middleware.ActionManageOutlets), h.Update)
+	tables.Delete("/:id", middleware.PermissionMiddleware(middleware.ActionManageOutlets), h.Delete)
+	tables.Put("/:id/status", middleware.PermissionMiddleware(middleware.ActionPOSCheckout), h.SetStatus)
+	tables.Post("/:id/move", middleware.PermissionMiddleware(middleware.ActionPOSCheckout), h.Move)
+	tables.Post("/:id/merge", middleware.PermissionMiddleware(middleware.ActionPOSCheckout), h.Merge)
+}
+
+// FloorPlan returns the floors and tables of an outlet
+func (h *TableHandler) FloorPlan(c *fiber.Ctx) error {
+	outletID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid outlet ID")
+	}
+	floors, err := h.usecase.GetFloorPlan(middleware.GetTenantID(c), outletID)
+	if err != nil {
+		return response.NotFound(c, err.Error())
+	}
+	return response.Success(c, floors, "")
+}
+
+// CreateFloor adds a floor to an outlet
+func (h *TableHandler) CreateFloor(c *fiber.Ctx) error {
+	var floor domain.Floor
+	if err := c.BodyParser(&floor); err != nil {
+		return response.BadRequest(c, "invalid request body")
+	}
+	if err := h.usecase.CreateFloor(middleware.GetTenantID(c), &floor); err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Created(c, floor, "floor created successfully")
+}
+
+// UpdateFloor renames or reorders a floor
+func (h *TableHandler) UpdateFloor(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid floor ID")
+	}
+	var req domain.Floor
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "invalid request body")
+	}
+	floor, err := h.usecase.UpdateFloor(middleware.GetTenantID(c), id, &req)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Success(c, floor, "floor updated successfully")
+}
+
+// DeleteFloor deletes an empty floor
+func (h *TableHandler) DeleteFloor(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid floor ID")
+	}
+	if err := h.usecase.DeleteFloor(middleware.GetTenantID(c), id); err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Success(c, nil, "floor deleted successfully")
+}
+
+// List returns the tables of an outlet; ?status= filters by status
+func (h *TableHandler) List(c *fiber.Ctx) error {
+	outletID, err := uuid.Parse(c.Query("outlet_id"))
+	if err != nil {
+		return response.BadRequest(c, "outlet_id is required")
+	}
+	tables, err := h.usecase.GetTables(middleware.GetTenantID(c), outletID, c.Query("status"))
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Success(c, tables, "")
+}
+
+// Get returns a single table with its open order
+func (h *TableHandler) Get(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid table ID")
+	}
+	table, err := h.usecase.GetTable(middleware.GetTenantID(c), id)
+	if err != nil {
+		return response.NotFound(c, err.Error())
+	}
+	return response.Success(c, table, "")
+}
+
+// Create places a new table on a floor
+func (h *TableHandler) Create(c *fiber.Ctx) error {
+	var table domain.DiningTable
+	if err := c.BodyParser(&table); err != nil {
+		return response.BadRequest(c, "invalid request body")
+	}
+	if err := h.usecase.CreateTable(middleware.GetTenantID(c), &table); err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Created(c, table, "table created successfully")
+}
+
+// Update changes a table's layout
+func (h *TableHandler) Update(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid table ID")
+	}
+	var req domain.DiningTable
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "invalid request body")
+	}
+	table, err := h.usecase.UpdateTable(middleware.GetTenantID(c), id, &req)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Success(c, table, "table updated successfully")
+}
+
+// Delete deletes a free table
+func (h *TableHandler) Delete(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid table ID")
+	}
+	if err := h.usecase.DeleteTable(middleware.GetTenantID(c), id); err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Success(c, nil, "table deleted successfully")
+}
+
+// SetStatus changes a table's status by hand
+func (h *TableHandler) SetStatus(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid table ID")
+	}
+	var req domain.TableStatusRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "invalid request body")
+	}
+	table, err := h.usecase.SetTableStatus(middleware.GetTenantID(c), id, req)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Success(c, table, "table status updated successfully")
+}
+
+// Move moves the guests of a table and their order to a free table
+func (h *TableHandler) Move(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid table ID")
+	}
+	var req domain.MoveTableRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "invalid request body")
+	}
+	table, err := h.posUsecase.MoveTable(middleware.GetTenantID(c), id, req)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Success(c, table, "table moved successfully")
+}
+
+// Merge merges other tables into the order of this table
+func (h *TableHandler) Merge(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid table ID")
+	}
+	var req domain.MergeTablesRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "invalid request body")
+	}
+	tx, err := h.posUsecase.MergeTables(middleware.GetTenantID(c), id, req)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Success(c, tx, "tables merged successfully")
+}
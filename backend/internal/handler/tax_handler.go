@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"github.com/codapos/backend/internal/domain"
+	"github.com/codapos/backend/internal/middleware"
+	"github.com/codapos/backend/internal/usecase"
+	"github.com/codapos/backend/pkg/response"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type TaxHandler struct {
+	usecase *usecase.TaxUsecase
+}
+
+func NewTaxHandler(uc *usecase.TaxUsecase) *TaxHandler {
+	return &TaxHandler{usecase: uc}
+}
+
+// RegisterRoutes registers tax rate routes (read: same as products, write: accounting)
+// and the choice of rates per outlet
+func (h *TaxHandler) RegisterRoutes(api fiber.Router) {
+	taxRates := api.Group("/tax-rates")
+	taxRates.Get("", middleware.PermissionMiddleware(middleware.ActionReadProducts), h.List)
+	taxRates.Get("/:id", middleware.PermissionMiddleware(middleware.ActionReadProducts), h.Get)
+	taxRates.Post("", middleware.PermissionMiddleware(middleware.ActionManageAccounting), h.Create)
+	taxRates.Put("/:id", middleware.PermissionMiddleware(middleware.ActionManageAccounting), h.Update)
+	taxRates.Delete("/:id", middleware.PermissionMiddleware(middleware.ActionManageAccounting), h.Delete)
+
+	api.Get("/outlets/:id/tax-rates", middleware.PermissionMiddleware(middleware.ActionManageOutlets), h.GetOutletTaxRates)
+	api.Put("/outlets/:id/tax-rates", middleware.PermissionMiddleware(middleware.ActionManageOutlets), h.SetOutletTaxRates)
+}
+
+// List returns tax rates; ?active=true limits the list to active rates
+func (h *TaxHandler) List(c *fiber.Ctx) error {
+	rates, err := h.usecase.GetTaxRates(middleware.GetTenantID(c), c.Query("active") == "true")
+	if err != nil {
+		return response.InternalError(c, "failed to fetch tax rates")
+	}
+	return response.Success(c, rates, "")
+}
+
+// Get returns a single tax rate
+func (h *TaxHandler) Get(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid tax rate ID")
+	}
+	rate, err := h.usecase.GetTaxRate(middleware.GetTenantID(c), id)
+	if err != nil {
+		return response.NotFound(c, err.Error())
+	}
+	return response.Success(c, rate, "")
+}
+
+// Create creates a new tax rate
+func (h *TaxHandler) Create(c *fiber.Ctx) error {
+	var rate domain.TaxRate
+	if err := c.BodyParser(&rate); err != nil {
+		return response.BadRequest(c, "invalid request body")
+	}
+	if err := h.usecase.CreateTaxRate(middleware.GetTenantID(c), &rate); err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Created(c, rate, "tax rate created successfully")
+}
+
+// Update updates a tax rate
+func (h *TaxHandler) Update(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid tax rate ID")
+	}
+	var req domain.TaxRate
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "invalid request body")
+	}
+	rate, err := h.usecase.UpdateTaxRate(middleware.GetTenantID(c), id, &req)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Success(c, rate, "tax rate updated successfully")
+}
+
+// Delete deletes a tax rate
+func (h *TaxHandler) Delete(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid tax rate ID")
+	}
+	if err := h.usecase.DeleteTaxRate(middleware.GetTenantID(c), id); err != nil {
+		return response.NotFound(c, err.Error())
+	}
+	return response.Success(c, nil, "tax rate deleted successfully")
+}
+
+// GetOutletTaxRates returns the rates charged at an outlet
+func (h *TaxHandler) GetOutletTaxRates(c *fiber.Ctx) error {
+	outletID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid outlet ID")
+	}
+	rates, err := h.usecase.GetOutletTaxRates(middleware.GetTenantID(c), outletID)
+	if err != nil {
+		return response.NotFound(c, err.Error())
+	}
+	return response.Success(c, rates, "")
+}
+
+// SetOutletTaxRates chooses the rates charged at an outlet
+func (h *TaxHandler) SetOutletTaxRates(c *fiber.Ctx) error {
+	outletID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return response.BadRequest(c, "invalid outlet ID")
+	}
+	var req domain.OutletTaxRatesRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "invalid request body")
+	}
+	rates, err := h.usecase.SetOutletTaxRates(middleware.GetTenantID(c), outletID, req)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Success(c, rates, "outlet tax rates updated successfully")
+}
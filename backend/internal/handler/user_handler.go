@@ -24,6 +24,8 @@ func (h *UserHandler) RegisterRoutes(api fiber.Router) {
 	api.Put("/me", h.UpdateProfile)
 	// PUT /me/password — change own password
 	api.Put("/me/password", h.ChangePassword)
+	// PUT /me/pin — set manager override PIN
+	api.Put("/me/pin", h.SetPIN)
 	// PUT /me/merchant — update merchant info
 	api.Put("/me/merchant", h.UpdateMerchant)
 
@@ -74,6 +76,20 @@ func (h *UserHandler) ChangePassword(c *fiber.Ctx) error {
 	return response.Success(c, nil, "password changed successfully")
 }
 
+// SetPIN sets the authenticated user's manager override PIN
+func (h *UserHandler) SetPIN(c *fiber.Ctx) error {
+	var req domain.SetPINRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "invalid request body")
+	}
+
+	userID := middleware.GetUserID(c)
+	if err := h.usecase.SetPIN(userID, req); err != nil {
+		return response.BadRequest(c, err.Error())
+	}
+	return response.Success(c, nil, "PIN updated successfully")
+}
+
 // UpdateMerchant updates the tenant/merchant info
 func (h *UserHandler) UpdateMerchant(c *fiber.Ctx) error {
 	var req domain.UpdateMerchantRequest
@@ -1,7 +1,9 @@
 package middleware
 
 import (
+	"errors"
 	"strings"
+	"time"
 
 	"github.com/codapos/backend/internal/config"
 	"github.com/codapos/backend/pkg/response"
@@ -10,6 +12,12 @@ import (
 	"github.com/google/uuid"
 )
 
+// StreamTokenTTL is how long a stream token can be used to open a stream
+const StreamTokenTTL = time.Minute
+
+// streamScope marks a token that only opens event streams
+const streamScope = "stream"
+
 // AuthMiddleware validates JWT tokens
 func AuthMiddleware(cfg *config.Config) fiber.Handler {
 	return func(c *fiber.Ctx) error {
@@ -23,33 +31,92 @@ func AuthMiddleware(cfg *config.Config) fiber.Handler {
 			return response.Unauthorized(c, "invalid authorization format")
 		}
 
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fiber.NewError(fiber.StatusUnauthorized, "invalid signing method")
-			}
-			return []byte(cfg.JWT.Secret), nil
-		})
-
-		if err != nil || !token.Valid {
+		claims, err := parseToken(cfg, tokenString)
+		if err != nil {
+			return response.Unauthorized(c, err.Error())
+		}
+		// A stream token travels in URLs, so it opens nothing but streams
+		if scope, _ := claims["scope"].(string); scope != "" {
 			return response.Unauthorized(c, "invalid or expired token")
 		}
 
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			return response.Unauthorized(c, "invalid token claims")
+		setClaims(c, claims)
+		return c.Next()
+	}
+}
+
+// StreamAuthMiddleware authenticates an event stream by the stream token in ?token=.
+// Browsers' EventSource cannot send the Authorization header, so the client first
+// fetches a short-lived stream token with its normal token (see StreamToken).
+func StreamAuthMiddleware(cfg *config.Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		tokenString := c.Query("token")
+		if tokenString == "" {
+			return response.Unauthorized(c, "missing stream token")
+		}
+		claims, err := parseToken(cfg, tokenString)
+		if err != nil {
+			return response.Unauthorized(c, err.Error())
+		}
+		if scope, _ := claims["scope"].(string); scope != streamScope {
+			return response.Unauthorized(c, "invalid stream token")
+		}
+
+		setClaims(c, claims)
+		return c.Next()
+	}
+}
+
+// StreamToken issues the authenticated user a stream token for StreamAuthMiddleware,
+// valid for StreamTokenTTL
+func StreamToken(cfg *config.Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		expiresAt := time.Now().Add(StreamTokenTTL)
+		claims := jwt.MapClaims{
+			"user_id":   GetUserID(c).String(),
+			"tenant_id": GetTenantID(c).String(),
+			"email":     c.Locals("email").(string),
+			"role":      GetRole(c),
+			"scope":     streamScope,
+			"exp":       expiresAt.Unix(),
+		}
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(cfg.JWT.Secret))
+		if err != nil {
+			return response.InternalError(c, "failed to issue stream token")
 		}
+		return response.Success(c, fiber.Map{"token": token, "expires_at": expiresAt}, "")
+	}
+}
 
-		// Set user context
-		userID, _ := uuid.Parse(claims["user_id"].(string))
-		tenantID, _ := uuid.Parse(claims["tenant_id"].(string))
+// parseToken verifies a token signed with the JWT secret and returns its claims
+func parseToken(cfg *config.Config, tokenString string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fiber.NewError(fiber.StatusUnauthorized, "invalid signing method")
+		}
+		return []byte(cfg.JWT.Secret), nil
+	})
 
-		c.Locals("user_id", userID)
-		c.Locals("tenant_id", tenantID)
-		c.Locals("role", claims["role"].(string))
-		c.Locals("email", claims["email"].(string))
+	if err != nil || !token.Valid {
+		return nil, errors.New("invalid or expired token")
+	}
 
-		return c.Next()
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errors.New("invalid token claims")
 	}
+	return claims, nil
+}
+
+// setClaims puts the user of a verified token in the request context
+func setClaims(c *fiber.Ctx, claims jwt.MapClaims) {
+	userID, _ := uuid.Parse(claims["user_id"].(string))
+	tenantID, _ := uuid.Parse(claims["tenant_id"].(string))
+
+	c.Locals("user_id", userID)
+	c.Locals("tenant_id", tenantID)
+	c.Locals("role", claims["role"].(string))
+	c.Locals("email", claims["email"].(string))
 }
 
 // RoleMiddleware checks if user has required role
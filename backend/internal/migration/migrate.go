@@ -18,6 +18,7 @@ func AutoMigrate(db *gorm.DB) error {
 		// Core
 		&domain.Tenant{},
 		&domain.User{},
+		&domain.PINAttempt{},
 		&domain.AuditLog{},
 
 		// Phase 1: Core Architecture
@@ -46,6 +47,7 @@ func AutoMigrate(db *gorm.DB) error {
 		// Inventory
 		&domain.Inventory{},
 		&domain.InventoryMovement{},
+		&domain.StockReservation{},
 		&domain.StockTransfer{},
 		&domain.StockTransferItem{},
 
@@ -54,19 +56,46 @@ func AutoMigrate(db *gorm.DB) error {
 		&domain.TransactionItem{},
 		&domain.TransactionPayment{},
 		&domain.SplitBill{},
+		&domain.TransactionTax{},
+		&domain.Shift{},
+		&domain.ShiftCashMovement{},
+		&domain.Floor{},
+		&domain.DiningTable{},
+		&domain.KitchenStation{},
+		&domain.KitchenTicket{},
+		&domain.KitchenTicketItem{},
+		&domain.ReceiptTemplate{},
+		&domain.ReceiptLink{},
 		&domain.TenantBilling{},
 
+		// Loyalty
+		&domain.LoyaltyProgram{},
+		&domain.LoyaltyEarnRule{},
+		&domain.LoyaltyTier{},
+		&domain.LoyaltyAccount{},
+		&domain.LoyaltyEntry{},
+
+		// Gift cards
+		&domain.GiftCard{},
+		&domain.GiftCardMovement{},
+
+		// Document numbering
+		&domain.SequenceConfig{},
+		&domain.SequenceCounter{},
+
 		// Accounting
 		&domain.ChartOfAccount{},
 		&domain.JournalEntry{},
 		&domain.JournalEntryLine{},
 		&domain.FiscalPeriod{},
 		&domain.TaxRate{},
+		&domain.OutletTaxRate{},
 
 		// SaaS
 		&domain.SubscriptionPlan{},
 		&domain.Subscription{},
 		&domain.BillingInvoice{},
+		&domain.PaymentNotification{},
 
 		// Delivery (Phase 7)
 		&domain.DeliveryOrder{},
@@ -90,6 +119,47 @@ func AutoMigrate(db *gorm.DB) error {
 		return fmt.Errorf("migration failed: %w", err)
 	}
 
+	// Percentage promotions used to keep their percent in value, next to rupiah amounts
+	if err := db.Exec("UPDATE promotions SET rate = value, value = 0 WHERE type = ? AND rate = 0 AND value > 0",
+		domain.PromotionTypePercentage).Error; err != nil {
+		return fmt.Errorf("failed to move promotion rates: %w", err)
+	}
+
+	if err := backfillSystemAccounts(db); err != nil {
+		return err
+	}
+
 	log.Println("✅ Database migrations completed successfully!")
 	return nil
 }
+
+// lateSystemAccounts are the system accounts the default chart of accounts gained after
+// tenants had already been set up with it
+var lateSystemAccounts = []domain.ChartOfAccount{
+	{Code: "2300", Name: "Hutang Tip Karyawan", Type: domain.AccountTypeLiability, SubType: domain.AccountSubTypeTips},
+	{Code: "2400", Name: "Hutang Voucher Gift Card", Type: domain.AccountTypeLiability, SubType: domain.AccountSubTypeGiftCard},
+	{Code: "4300", Name: "Pendapatan Service Charge", Type: domain.AccountTypeRevenue, SubType: domain.AccountSubTypeServiceCharge},
+	{Code: "4400", Name: "Pendapatan Voucher Kedaluwarsa", Type: domain.AccountTypeRevenue, SubType: domain.AccountSubTypeBreakage},
+	{Code: "5500", Name: "Beban Program Loyalitas", Type: domain.AccountTypeExpense, SubType: domain.AccountSubTypeLoyalty},
+}
+
+// backfillSystemAccounts adds the late system accounts to the charts of accounts that lack
+// them. A tenant that already has an account of the kind, or uses its code for another
+// account, is left as it is; running it again adds nothing.
+func backfillSystemAccounts(db *gorm.DB) error {
+	for _, acc := range lateSystemAccounts {
+		err := db.Exec(`INSERT INTO chart_of_accounts (tenant_id, code, name, type, sub_type, is_system, is_active, balance, created_at, updated_at)
+			SELECT t.tenant_id, ?, ?, ?, ?, true, true, 0, NOW(), NOW()
+			FROM (SELECT DISTINCT tenant_id FROM chart_of_accounts WHERE deleted_at IS NULL) t
+			WHERE NOT EXISTS (
+				SELECT 1 FROM chart_of_accounts c
+				WHERE c.tenant_id = t.tenant_id AND c.deleted_at IS NULL AND (c.sub_type = ? OR c.code = ?)
+			)
+			ON CONFLICT DO NOTHING`,
+			acc.Code, acc.Name, acc.Type, acc.SubType, acc.SubType, acc.Code).Error
+		if err != nil {
+			return fmt.Errorf("failed to add account %s to existing charts of accounts: %w", acc.Code, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package payment
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/codapos/backend/internal/domain"
+)
+
+// Fake is an in-process gateway for tests and demos. It is deterministic: the token,
+// transaction ID and signature of a payment depend only on its order ID, amount and
+// status. A payment stays pending until Notify moves it, which also gives the signed
+// notification to post to the webhook, as the real gateway would.
+type Fake struct {
+	key    []byte
+	payURL string
+
+	mu       sync.Mutex
+	payments map[string]*domain.GatewayPayment // by order ID
+	tokens   map[string]string                 // order ID by token
+}
+
+// fakeNotification is the notification payload of the fake gateway
+type fakeNotification struct {
+	OrderID              string       `json:"order_id"`
+	GatewayTransactionID string       `json:"gateway_transaction_id"`
+	Status               string       `json:"status"`
+	Amount               domain.Money `json:"amount"`
+	Signature            string       `json:"signature"`
+}
+
+// NewFake creates a fake gateway signing its notifications with secret. payURL is the
+// page a charge redirects the customer to; the token is appended to it.
+func NewFake(secret, payURL string) *Fake {
+	key := sha256.Sum256([]byte("fake-gateway:" + secret))
+	return &Fake{
+		key:      key[:],
+		payURL:   strings.TrimRight(payURL, "/"),
+		payments: make(map[string]*domain.GatewayPayment),
+		tokens:   make(map[string]string),
+	}
+}
+
+func (f *Fake) Provider() string { return domain.PaymentProviderFake }
+
+// CreateCharge records a pending payment. Charging an order again returns the same
+// charge while the amount is unchanged.
+func (f *Fake) CreateCharge(req domain.ChargeRequest) (*domain.Charge, error) {
+	if req.OrderID == "" || req.Amount <= 0 {
+		return nil, errors.New("order ID and a positive amount are required")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if existing, ok := f.payments[req.OrderID]; ok && (existing.Amount != req.Amount || existing.Status != domain.GatewayStatusPending) {
+		return nil, errors.New("order ID has already been charged")
+	}
+	token := f.sign("token", req.OrderID)[:32]
+	f.payments[req.OrderID] = &domain.GatewayPayment{
+		Provider:             domain.PaymentProviderFake,
+		OrderID:              req.OrderID,
+		GatewayTransactionID: "fake-" + f.sign("transaction", req.OrderID)[:24],
+		TransactionStatus:    domain.GatewayStatusPending,
+		PaymentType:          "fake",
+		Status:               domain.GatewayStatusPending,
+		Amount:               req.Amount,
+	}
+	f.tokens[token] = req.OrderID
+	return &domain.Charge{
+		Provider:    domain.PaymentProviderFake,
+		OrderID:     req.OrderID,
+		Token:       token,
+		RedirectURL: f.payURL + "/" + token,
+	}, nil
+}
+
+// GetStatus returns the payment as the fake gateway last left it
+func (f *Fake) GetStatus(orderID string) (*domain.GatewayPayment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p, ok := f.payments[orderID]
+	if !ok {
+		return nil, ErrOrderNotFound
+	}
+	copied := *p
+	return &copied, nil
+}
+
+// Refund refunds a paid payment; less than the paid amount is a partial refund
+func (f *Fake) Refund(orderID string, amount domain.Money, reason string) (*domain.GatewayPayment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p, ok := f.payments[orderID]
+	if !ok {
+		return nil, ErrOrderNotFound
+	}
+	if p.Status != domain.GatewayStatusPaid && p.Status != domain.GatewayStatusPartiallyRefunded {
+		return nil, errors.New("only paid payments can be refunded")
+	}
+	if amount <= 0 || amount > p.Amount {
+		return nil, errors.New("refund amount must be between 0 and the paid amount")
+	}
+	p.Status = domain.GatewayStatusRefunded
+	if amount < p.Amount {
+		p.Status = domain.GatewayStatusPartiallyRefunded
+	}
+	p.TransactionStatus = p.Status
+	copied := *p
+	return &copied, nil
+}
+
+// VerifyNotification checks the HMAC signature of a notification made by Notify
+func (f *Fake) VerifyNotification(payload []byte) (*domain.GatewayPayment, error) {
+	var n fakeNotification
+	if err := json.Unmarshal(payload, &n); err != nil || n.OrderID == "" {
+		return nil, ErrInvalidPayload
+	}
+	p := &domain.GatewayPayment{
+		Provider:             domain.PaymentProviderFake,
+		OrderID:              n.OrderID,
+		GatewayTransactionID: n.GatewayTransactionID,
+		TransactionStatus:    n.Status,
+		PaymentType:          "fake",
+		Status:               n.Status,
+		Amount:               n.Amount,
+	}
+	if !hmac.Equal([]byte(n.Signature), []byte(f.signNotification(n))) {
+		return p, ErrInvalidSignature
+	}
+	return p, nil
+}
+
+// Notify moves a payment to a gateway status, as a customer paying or a payment expiring
+// would, and returns the signed notification the gateway sends for it
+func (f *Fake) Notify(orderID, status string) ([]byte, error) {
+	switch status {
+	case domain.GatewayStatusPaid, domain.GatewayStatusFailed, domain.GatewayStatusExpired, domain.GatewayStatusPending:
+	default:
+		return nil, errors.New("unknown payment status: " + status)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p, ok := f.payments[orderID]
+	if !ok {
+		return nil, ErrOrderNotFound
+	}
+	p.Status = status
+	p.TransactionStatus = status
+	n := fakeNotification{
+		OrderID:              p.OrderID,
+		GatewayTransactionID: p.GatewayTransactionID,
+		Status:               p.Status,
+		Amount:               p.Amount,
+	}
+	n.Signature = f.signNotification(n)
+	return json.Marshal(n)
+}
+
+// NotifyByToken is Notify for the payment a charge token was issued for, as used by the
+// fake payment page
+func (f *Fake) NotifyByToken(token, status string) ([]byte, error) {
+	f.mu.Lock()
+	orderID, ok := f.tokens[token]
+	f.mu.Unlock()
+	if !ok {
+		return nil, ErrOrderNotFound
+	}
+	return f.Notify(orderID, status)
+}
+
+// PaymentByToken returns the payment a charge token was issued for
+func (f *Fake) PaymentByToken(token string) (*domain.GatewayPayment, error) {
+	f.mu.Lock()
+	orderID, ok := f.tokens[token]
+	f.mu.Unlock()
+	if !ok {
+		return nil, ErrOrderNotFound
+	}
+	return f.GetStatus(orderID)
+}
+
+func (f *Fake) signNotification(n fakeNotification) string {
+	return f.sign(n.OrderID, n.GatewayTransactionID, n.Status, n.Amount.String())
+}
+
+func (f *Fake) sign(parts ...string) string {
+	mac := hmac.New(sha256.New, f.key)
+	mac.Write([]byte(strings.Join(parts, "|")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
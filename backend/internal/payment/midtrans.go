@@ -0,0 +1,312 @@
+// Package payment talks to payment gateways: it creates charges, verifies notifications
+// and maps gateway-specific payment states to the domain gateway statuses.
+package payment
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codapos/backend/internal/domain"
+	"github.com/google/uuid"
+)
+
+// Errors returned by gateways
+var (
+	ErrNotConfigured    = errors.New("payment gateway is not configured")
+	ErrInvalidSignature = errors.New("notification signature does not match")
+	ErrInvalidPayload   = errors.New("notification could not be read")
+	ErrOrderNotFound    = errors.New("payment not found at the gateway")
+)
+
+const (
+	midtransSnapSandbox       = "https://app.sandbox.midtrans.com"
+	midtransSnapProduction    = "https://app.midtrans.com"
+	midtransCoreSandbox       = "https://api.sandbox.midtrans.com"
+	midtransCoreProduction    = "https://api.midtrans.com"
+	midtransRequestTimeout    = 15 * time.Second
+	midtransMaxResponseLength = 1 << 20
+)
+
+// midtransPaymentMethods are the payment methods offered on the Snap payment page
+var midtransPaymentMethods = []string{
+	"credit_card", "bca_va", "bni_va", "bri_va", "permata_va",
+	"other_va", "gopay", "shopeepay", "qris",
+}
+
+// Midtrans is the Midtrans gateway: Snap for charges, the Core API for status and refunds.
+// Its keys and mode are read from global config on each call, so changes made by the
+// super admin apply without a restart.
+type Midtrans struct {
+	configRepo domain.GlobalConfigRepository
+	client     *http.Client
+}
+
+func NewMidtrans(configRepo domain.GlobalConfigRepository) *Midtrans {
+	return &Midtrans{
+		configRepo: configRepo,
+		client:     &http.Client{Timeout: midtransRequestTimeout},
+	}
+}
+
+func (m *Midtrans) Provider() string { return domain.PaymentProviderMidtrans }
+
+// midtransConfig is the part of global config a call needs
+type midtransConfig struct {
+	serverKey string
+	snapURL   string
+	coreURL   string
+}
+
+func (m *Midtrans) config() (*midtransConfig, error) {
+	serverKey, err := m.configRepo.FindByKey(domain.ConfigMidtransServerKey)
+	if err != nil || serverKey.Value == "" {
+		return nil, ErrNotConfigured
+	}
+	cfg := &midtransConfig{serverKey: serverKey.Value, snapURL: midtransSnapSandbox, coreURL: midtransCoreSandbox}
+	if mode, err := m.configRepo.FindByKey(domain.ConfigMidtransMode); err == nil && mode.Value == "production" {
+		cfg.snapURL, cfg.coreURL = midtransSnapProduction, midtransCoreProduction
+	}
+	// A base URL in config sends every call elsewhere, e.g. to cmd/midtrans-fake
+	if base, err := m.configRepo.FindByKey(domain.ConfigMidtransAPIBaseURL); err == nil && base.Value != "" {
+		cfg.snapURL = strings.TrimRight(base.Value, "/")
+		cfg.coreURL = cfg.snapURL
+	}
+	return cfg, nil
+}
+
+// CreateCharge creates a Snap transaction; the customer pays on the Snap page
+func (m *Midtrans) CreateCharge(req domain.ChargeRequest) (*domain.Charge, error) {
+	cfg, err := m.config()
+	if err != nil {
+		return nil, err
+	}
+	body := map[string]interface{}{
+		"transaction_details": map[string]interface{}{
+			"order_id":     req.OrderID,
+			"gross_amount": req.Amount.WholeRupiah(),
+		},
+		"customer_details": map[string]interface{}{
+			"first_name": req.CustomerName,
+			"email":      req.Email,
+			"phone":      req.Phone,
+		},
+		"enabled_payments": midtransPaymentMethods,
+		"credit_card": map[string]interface{}{
+			"secure": true,
+		},
+	}
+	if req.ExpiryMinutes > 0 {
+		body["expiry"] = map[string]interface{}{
+			"unit":     "minute",
+			"duration": req.ExpiryMinutes,
+		}
+	}
+	var resp struct {
+		Token       string `json:"token"`
+		RedirectURL string `json:"redirect_url"`
+	}
+	if err := m.call(cfg, http.MethodPost, cfg.snapURL+"/snap/v1/transactions", body, http.StatusCreated, &resp); err != nil {
+		return nil, err
+	}
+	return &domain.Charge{
+		Provider:    domain.PaymentProviderMidtrans,
+		OrderID:     req.OrderID,
+		Token:       resp.Token,
+		RedirectURL: resp.RedirectURL,
+	}, nil
+}
+
+// GetStatus reads the state of a payment from the Core API
+func (m *Midtrans) GetStatus(orderID string) (*domain.GatewayPayment, error) {
+	cfg, err := m.config()
+	if err != nil {
+		return nil, err
+	}
+	var n domain.MidtransNotification
+	if err := m.call(cfg, http.MethodGet, cfg.coreURL+"/v2/"+orderID+"/status", nil, http.StatusOK, &n); err != nil {
+		return nil, err
+	}
+	return midtransPayment(n)
+}
+
+// Refund refunds a settled payment through the Core API
+func (m *Midtrans) Refund(orderID string, amount domain.Money, reason string) (*domain.GatewayPayment, error) {
+	cfg, err := m.config()
+	if err != nil {
+		return nil, err
+	}
+	body := map[string]interface{}{
+		"refund_key": orderID + "-refund-" + uuid.NewString()[:8],
+		"amount":     amount.WholeRupiah(),
+		"reason":     reason,
+	}
+	var n domain.MidtransNotification
+	if err := m.call(cfg, http.MethodPost, cfg.coreURL+"/v2/"+orderID+"/refund", body, http.StatusOK, &n); err != nil {
+		return nil, err
+	}
+	if n.OrderID == "" {
+		n.OrderID = orderID
+	}
+	if n.GrossAmount == "" {
+		n.GrossAmount = strconv.FormatInt(amount.WholeRupiah(), 10)
+	}
+	return midtransPayment(n)
+}
+
+// VerifyNotification checks the SHA-512 signature_key of an HTTP notification
+func (m *Midtrans) VerifyNotification(payload []byte) (*domain.GatewayPayment, error) {
+	var n domain.MidtransNotification
+	if err := json.Unmarshal(payload, &n); err != nil || n.OrderID == "" {
+		return nil, ErrInvalidPayload
+	}
+	p, err := midtransPayment(n)
+	if err != nil {
+		return p, err
+	}
+	cfg, err := m.config()
+	if err != nil {
+		return p, err
+	}
+	if !VerifyMidtransNotification(n, cfg.serverKey) {
+		return p, ErrInvalidSignature
+	}
+	return p, nil
+}
+
+// call sends a request authenticated with the server key and decodes the answer. The Core
+// API answers errors with HTTP 200 and the error in status_code, so that is checked too.
+func (m *Midtrans) call(cfg *midtransConfig, method, url string, body interface{}, wantStatus int, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(cfg.serverKey+":")))
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach midtrans: %w", err)
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, midtransMaxResponseLength))
+	if err != nil {
+		return fmt.Errorf("failed to read midtrans response: %w", err)
+	}
+
+	var result struct {
+		StatusCode        string   `json:"status_code"`
+		StatusMessage     string   `json:"status_message"`
+		ErrorMessages     []string `json:"error_messages"`
+		TransactionStatus string   `json:"transaction_status"`
+	}
+	_ = json.Unmarshal(raw, &result)
+	if result.StatusCode == "404" || resp.StatusCode == http.StatusNotFound {
+		return ErrOrderNotFound
+	}
+	// A transaction comes with its own status code, e.g. 407 for an expired one
+	failed := result.TransactionStatus == "" && result.StatusCode != "" && !strings.HasPrefix(result.StatusCode, "2")
+	if resp.StatusCode != wantStatus || failed {
+		message := result.StatusMessage
+		if len(result.ErrorMessages) > 0 {
+			message = strings.Join(result.ErrorMessages, "; ")
+		}
+		return fmt.Errorf("midtrans error (HTTP %d): %s", resp.StatusCode, message)
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("failed to read midtrans response: %w", err)
+	}
+	return nil
+}
+
+// midtransPayment maps a Midtrans transaction to a gateway payment
+func midtransPayment(n domain.MidtransNotification) (*domain.GatewayPayment, error) {
+	p := &domain.GatewayPayment{
+		Provider:             domain.PaymentProviderMidtrans,
+		OrderID:              n.OrderID,
+		GatewayTransactionID: n.TransactionID,
+		TransactionStatus:    n.TransactionStatus,
+		FraudStatus:          n.FraudStatus,
+		StatusCode:           n.StatusCode,
+		PaymentType:          n.PaymentType,
+		Status:               MidtransStatus(n.TransactionStatus, n.FraudStatus),
+	}
+	amount, ok := ParseAmount(n.GrossAmount)
+	if !ok {
+		return p, ErrInvalidPayload
+	}
+	p.Amount = amount
+	return p, nil
+}
+
+// MidtransSignature is the signature_key Midtrans puts on a notification:
+// SHA-512 of order_id, status_code, gross_amount and the server key
+func MidtransSignature(orderID, statusCode, grossAmount, serverKey string) string {
+	sum := sha512.Sum512([]byte(orderID + statusCode + grossAmount + serverKey))
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyMidtransNotification reports whether a notification was signed with the server key
+func VerifyMidtransNotification(n domain.MidtransNotification, serverKey string) bool {
+	if serverKey == "" || n.SignatureKey == "" {
+		return false
+	}
+	expected := MidtransSignature(n.OrderID, n.StatusCode, n.GrossAmount, serverKey)
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(strings.ToLower(n.SignatureKey))) == 1
+}
+
+// MidtransStatus maps a Midtrans transaction status to a gateway status. A captured card
+// payment only counts once fraud detection accepts it.
+func MidtransStatus(transactionStatus, fraudStatus string) string {
+	switch transactionStatus {
+	case "capture":
+		switch fraudStatus {
+		case "", "accept":
+			return domain.GatewayStatusPaid
+		case "deny":
+			return domain.GatewayStatusFailed
+		default:
+			return domain.GatewayStatusPending
+		}
+	case "settlement":
+		return domain.GatewayStatusPaid
+	case "deny", "cancel", "failure":
+		return domain.GatewayStatusFailed
+	case "expire":
+		return domain.GatewayStatusExpired
+	case "refund":
+		return domain.GatewayStatusRefunded
+	case "partial_refund":
+		return domain.GatewayStatusPartiallyRefunded
+	default:
+		return domain.GatewayStatusPending
+	}
+}
+
+// ParseAmount reads a gateway amount such as "10000.00"
+func ParseAmount(amount string) (domain.Money, bool) {
+	v, err := strconv.ParseFloat(strings.TrimSpace(amount), 64)
+	if err != nil {
+		return 0, false
+	}
+	return domain.NewMoney(v), true
+}
@@ -0,0 +1,78 @@
+package payment
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/codapos/backend/internal/domain"
+)
+
+func TestVerifyMidtransNotification(t *testing.T) {
+	const serverKey = "SB-Mid-server-test"
+	signed := func(n domain.MidtransNotification) domain.MidtransNotification {
+		n.SignatureKey = MidtransSignature(n.OrderID, n.StatusCode, n.GrossAmount, serverKey)
+		return n
+	}
+	valid := signed(domain.MidtransNotification{
+		OrderID:           "ORD-1",
+		StatusCode:        "200",
+		GrossAmount:       "150000.00",
+		TransactionStatus: "settlement",
+	})
+
+	tests := []struct {
+		name      string
+		n         domain.MidtransNotification
+		serverKey string
+		want      bool
+	}{
+		{"signed with the server key", valid, serverKey, true},
+		{"upper case signature", withSignature(valid, strings.ToUpper(valid.SignatureKey)), serverKey, true},
+		{"other server key", valid, "SB-Mid-server-other", false},
+		{"no server key", valid, "", false},
+		{"no signature", withSignature(valid, ""), serverKey, false},
+		{"amount changed", withAmount(valid, "1.00"), serverKey, false},
+		{"amount formatted differently", withAmount(valid, "150000"), serverKey, false},
+	}
+	for _, tt := range tests {
+		if got := VerifyMidtransNotification(tt.n, tt.serverKey); got != tt.want {
+			t.Errorf("%s: VerifyMidtransNotification = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func withSignature(n domain.MidtransNotification, signature string) domain.MidtransNotification {
+	n.SignatureKey = signature
+	return n
+}
+
+func withAmount(n domain.MidtransNotification, amount string) domain.MidtransNotification {
+	n.GrossAmount = amount
+	return n
+}
+
+func TestMidtransStatus(t *testing.T) {
+	tests := []struct {
+		transactionStatus, fraudStatus string
+		want                           string
+	}{
+		{"settlement", "", domain.GatewayStatusPaid},
+		{"capture", "accept", domain.GatewayStatusPaid},
+		{"capture", "", domain.GatewayStatusPaid},
+		{"capture", "challenge", domain.GatewayStatusPending},
+		{"capture", "deny", domain.GatewayStatusFailed},
+		{"pending", "", domain.GatewayStatusPending},
+		{"deny", "", domain.GatewayStatusFailed},
+		{"cancel", "", domain.GatewayStatusFailed},
+		{"failure", "", domain.GatewayStatusFailed},
+		{"expire", "", domain.GatewayStatusExpired},
+		{"refund", "", domain.GatewayStatusRefunded},
+		{"partial_refund", "", domain.GatewayStatusPartiallyRefunded},
+		{"authorize", "", domain.GatewayStatusPending},
+	}
+	for _, tt := range tests {
+		if got := MidtransStatus(tt.transactionStatus, tt.fraudStatus); got != tt.want {
+			t.Errorf("MidtransStatus(%q, %q) = %s, want %s", tt.transactionStatus, tt.fraudStatus, got, tt.want)
+		}
+	}
+}
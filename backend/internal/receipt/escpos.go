@@ -0,0 +1,157 @@
+package receipt
+
+import (
+	"bytes"
+	"image"
+	"strings"
+
+	"github.com/codapos/backend/internal/domain"
+	"github.com/skip2/go-qrcode"
+)
+
+// ESC/POS commands
+var (
+	escInit      = []byte{0x1B, 0x40}
+	escLeft      = []byte{0x1B, 0x61, 0x00}
+	escCenter    = []byte{0x1B, 0x61, 0x01}
+	escBoldOn    = []byte{0x1B, 0x45, 0x01}
+	escBoldOff   = []byte{0x1B, 0x45, 0x00}
+	escDoubleOn  = []byte{0x1D, 0x21, 0x11} // double width and height
+	escDoubleOff = []byte{0x1D, 0x21, 0x00}
+	escFeed      = []byte{0x1B, 0x64, 0x03}
+	escCut       = []byte{0x1D, 0x56, 0x00}
+)
+
+// printDots is the printable width of the paper in dots at 203 dpi
+var printDots = map[string]int{
+	domain.Paper58mm: 384,
+	domain.Paper80mm: 576,
+}
+
+// maxLogoDots caps the height of a printed logo
+const maxLogoDots = 160
+
+// escpos renders the receipt as ESC/POS commands for a thermal printer. Images and
+// QR codes are sent as raster bitmaps, which every ESC/POS printer understands.
+func (r *Receipt) escpos() []byte {
+	width := columns[r.Template.PaperSize]
+	dots := printDots[r.Template.PaperSize]
+	var buf bytes.Buffer
+	buf.Write(escInit)
+
+	for _, blk := range r.layout() {
+		if blk.center {
+			buf.Write(escCenter)
+		} else {
+			buf.Write(escLeft)
+		}
+		switch blk.kind {
+		case kindImage:
+			buf.Write(raster(blk.image, dots, maxLogoDots))
+			buf.WriteByte('\n')
+			continue
+		case kindQR:
+			if qr, err := qrcode.New(blk.text, qrcode.Medium); err == nil {
+				buf.Write(raster(qr.Image(dots/2), dots, dots))
+				buf.WriteByte('\n')
+				continue
+			}
+		}
+
+		blk.text = printable(blk.text)
+		blk.right = printable(blk.right)
+		lineWidth := width
+		if blk.bold {
+			buf.Write(escBoldOn)
+		}
+		if blk.large {
+			buf.Write(escDoubleOn)
+			lineWidth = width / 2
+		}
+		for _, l := range blk.lines(lineWidth) {
+			buf.WriteString(l)
+			buf.WriteByte('\n')
+		}
+		if blk.large {
+			buf.Write(escDoubleOff)
+		}
+		if blk.bold {
+			buf.Write(escBoldOff)
+		}
+	}
+
+	buf.Write(escLeft)
+	buf.Write(escFeed)
+	buf.Write(escCut)
+	return buf.Bytes()
+}
+
+// printable replaces what the printer's default code page cannot print
+func printable(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '\t':
+			return ' '
+		case r < 0x20 || r == 0x7F:
+			return -1
+		case r > 0x7E:
+			return '?'
+		default:
+			return r
+		}
+	}, s)
+}
+
+// raster converts an image to a GS v 0 raster bit image, scaled down to fit within
+// maxWidth by maxHeight dots. Dark pixels print; transparent ones do not.
+func raster(img image.Image, maxWidth, maxHeight int) []byte {
+	bounds := img.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	if srcW == 0 || srcH == 0 {
+		return nil
+	}
+	w, h := srcW, srcH
+	if w > maxWidth {
+		w, h = maxWidth, h*maxWidth/srcW
+	}
+	if h > maxHeight {
+		w, h = w*maxHeight/h, maxHeight
+	}
+	if w < 1 || h < 1 {
+		return nil
+	}
+
+	rowBytes := (w + 7) / 8
+	var out bytes.Buffer
+	// Printers buffer a limited number of raster lines, so tall images go in bands
+	const band = 255
+	for top := 0; top < h; top += band {
+		rows := h - top
+		if rows > band {
+			rows = band
+		}
+		out.Write([]byte{0x1D, 0x76, 0x30, 0x00, byte(rowBytes), byte(rowBytes >> 8), byte(rows), byte(rows >> 8)})
+		for y := top; y < top+rows; y++ {
+			line := make([]byte, rowBytes)
+			sy := bounds.Min.Y + y*srcH/h
+			for x := 0; x < w; x++ {
+				sx := bounds.Min.X + x*srcW/w
+				if dark(img, sx, sy) {
+					line[x/8] |= 0x80 >> (x % 8)
+				}
+			}
+			out.Write(line)
+		}
+	}
+	return out.Bytes()
+}
+
+func dark(img image.Image, x, y int) bool {
+	r, g, b, a := img.At(x, y).RGBA()
+	if a < 0x8000 {
+		return false
+	}
+	// Colours are premultiplied, so compare against half of the alpha
+	lum := (299*r + 587*g + 114*b) / 1000
+	return lum < a/2
+}
@@ -0,0 +1,134 @@
+package receipt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"image"
+	"image/png"
+	"regexp"
+	"strings"
+
+	"github.com/codapos/backend/internal/domain"
+	"github.com/skip2/go-qrcode"
+)
+
+// pageWidth is the printable width of the paper, used as the page width in HTML
+var pageWidth = map[string]string{
+	domain.Paper58mm: "48mm",
+	domain.Paper80mm: "72mm",
+}
+
+const receiptStyle = `@media print {
+    @page { margin: 0; size: %[1]s auto; }
+    body { margin: 0; }
+}
+body { font-family: 'Courier New', monospace; font-size: 12px; width: %[1]s; margin: 0 auto; padding: 4px; color: #000; position: relative; }
+.divider { border-top: 1px dashed #000; margin: 6px 0; }
+.divider-bold { border-top: 2px solid #000; margin: 6px 0; }
+.center { text-align: center; }
+.bold { font-weight: bold; }
+.large { font-size: 16px; }
+.sub { padding-left: 12px; font-size: 10px; color: #444; }
+.row { display: flex; justify-content: space-between; gap: 8px; }
+.row span:last-child { white-space: nowrap; }
+.watermark { border: 1px solid #000; padding: 2px; margin: 4px 0; letter-spacing: 1px; }
+.logo img { max-height: 60px; max-width: 100%%; }
+.qr img { width: 60%%; margin: 6px auto; display: block; }
+`
+
+// watermarkStyle lays the reprint mark diagonally across the whole receipt
+const watermarkStyle = `body::before { content: %q; position: absolute; top: 40%%; left: 0; right: 0; text-align: center; transform: rotate(-30deg); font-size: 24px; font-weight: bold; color: rgba(0, 0, 0, 0.12); pointer-events: none; }
+`
+
+// accentStyle colours the business name and heavy rules with the tenant brand colour
+const accentStyle = `.brand { color: %[1]s; }
+.divider-bold { border-top-color: %[1]s; }
+`
+
+// cssColor matches the hex colours accepted as an accent colour
+var cssColor = regexp.MustCompile(`^#(?:[0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// html renders the receipt as a standalone HTML page for browser printing and sharing.
+// The logo and QR code are embedded, so the page needs nothing else to display.
+func (r *Receipt) html() ([]byte, error) {
+	tx := r.Transaction
+	var b strings.Builder
+	b.WriteString(`<!DOCTYPE html><html><head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1">`)
+	fmt.Fprintf(&b, "<title>%s</title><style>\n", html.EscapeString(tx.TransactionNumber))
+	fmt.Fprintf(&b, receiptStyle, pageWidth[r.Template.PaperSize])
+	if cssColor.MatchString(r.AccentColor) {
+		fmt.Fprintf(&b, accentStyle, r.AccentColor)
+	}
+	if tx.ReprintCount > 0 {
+		fmt.Fprintf(&b, watermarkStyle, fmt.Sprintf("COPY/REPRINT #%d", tx.ReprintCount))
+	}
+	b.WriteString("</style></head><body>\n")
+
+	for _, blk := range r.layout() {
+		switch blk.kind {
+		case kindImage:
+			src, err := dataURI(blk.image)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode logo: %w", err)
+			}
+			fmt.Fprintf(&b, "<div class=\"center logo\"><img src=\"%s\" alt=\"\"/></div>\n", src)
+		case kindQR:
+			png, err := qrcode.Encode(blk.text, qrcode.Medium, 256)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode QR code: %w", err)
+			}
+			fmt.Fprintf(&b, "<div class=\"qr\"><img src=\"data:image/png;base64,%s\" alt=\"%s\"/></div>\n",
+				base64.StdEncoding.EncodeToString(png), html.EscapeString(blk.text))
+		case kindRule:
+			if blk.heavy {
+				b.WriteString("<div class=\"divider-bold\"></div>\n")
+			} else {
+				b.WriteString("<div class=\"divider\"></div>\n")
+			}
+		case kindRow:
+			fmt.Fprintf(&b, "<div class=\"%s\"><span>%s</span><span>%s</span></div>\n",
+				blk.classes("row"), html.EscapeString(blk.text), html.EscapeString(blk.right))
+		default:
+			fmt.Fprintf(&b, "<div class=\"%s\">%s</div>\n", blk.classes(""), html.EscapeString(blk.text))
+		}
+	}
+
+	b.WriteString("</body></html>\n")
+	return []byte(b.String()), nil
+}
+
+func (b block) classes(base string) string {
+	var classes []string
+	if base != "" {
+		classes = append(classes, base)
+	}
+	if b.center {
+		classes = append(classes, "center")
+	}
+	if b.bold {
+		classes = append(classes, "bold")
+	}
+	if b.large {
+		classes = append(classes, "large")
+	}
+	if b.indent {
+		classes = append(classes, "sub")
+	}
+	if b.watermark {
+		classes = append(classes, "watermark")
+	}
+	if b.brand {
+		classes = append(classes, "brand")
+	}
+	return strings.Join(classes, " ")
+}
+
+func dataURI(img image.Image) (string, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return "", err
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
@@ -0,0 +1,400 @@
+// Package receipt renders a transaction as a customer receipt: ESC/POS bytes for
+// thermal printers, plain text and HTML. All formats share one layout, built from
+// the tenant's receipt template.
+package receipt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"strconv"
+	"strings"
+
+	"github.com/codapos/backend/internal/domain"
+)
+
+// Receipt is a transaction ready to be rendered
+type Receipt struct {
+	Transaction  *domain.Transaction // with its items, payments, taxes, cashier and outlet loaded
+	Template     domain.ReceiptTemplate
+	BusinessName string
+	Logo         image.Image // nil prints no logo
+	AccentColor  string      // CSS colour of the business name and heavy rules in HTML, e.g. "#C40000"
+}
+
+// columns is the number of characters of the standard font that fit on a line
+var columns = map[string]int{
+	domain.Paper58mm: 32,
+	domain.Paper80mm: 48,
+}
+
+// ValidPaperSize reports whether receipts can be printed on the given paper
+func ValidPaperSize(paper string) bool {
+	_, ok := columns[paper]
+	return ok
+}
+
+// Render renders the receipt in the given format
+func (r *Receipt) Render(format string) ([]byte, error) {
+	if r.Transaction == nil {
+		return nil, errors.New("receipt has no transaction")
+	}
+	if !ValidPaperSize(r.Template.PaperSize) {
+		return nil, fmt.Errorf("unknown paper size: %s", r.Template.PaperSize)
+	}
+	switch format {
+	case domain.ReceiptFormatESCPOS:
+		return r.escpos(), nil
+	case domain.ReceiptFormatText:
+		return []byte(r.text()), nil
+	case domain.ReceiptFormatHTML:
+		return r.html()
+	default:
+		return nil, fmt.Errorf("unknown receipt format: %s", format)
+	}
+}
+
+// ContentType returns the HTTP content type of a receipt format
+func ContentType(format string) string {
+	switch format {
+	case domain.ReceiptFormatESCPOS:
+		return "application/octet-stream"
+	case domain.ReceiptFormatHTML:
+		return "text/html; charset=utf-8"
+	default:
+		return "text/plain; charset=utf-8"
+	}
+}
+
+type blockKind int
+
+const (
+	kindLine  blockKind = iota // one line of text, wrapped when too long
+	kindRow                    // text on the left and an amount on the right
+	kindRule                   // a dividing line
+	kindImage                  // the logo
+	kindQR                     // a QR code of text
+)
+
+// block is one element of the receipt layout
+type block struct {
+	kind      blockKind
+	text      string
+	right     string
+	center    bool
+	bold      bool
+	large     bool // double width and height
+	indent    bool // detail of the line above, e.g. an item modifier
+	heavy     bool // a heavy rule
+	watermark bool
+	brand     bool // the business name
+	image     image.Image
+}
+
+func line(text string) block       { return block{kind: kindLine, text: text} }
+func centered(text string) block   { return block{kind: kindLine, text: text, center: true} }
+func row(left, right string) block { return block{kind: kindRow, text: left, right: right} }
+func detail(text string) block     { return block{kind: kindLine, text: text, indent: true} }
+
+func rule(heavy bool) block { return block{kind: kindRule, heavy: heavy} }
+
+// layout builds the receipt, top to bottom
+func (r *Receipt) layout() []block {
+	tx := r.Transaction
+	tpl := r.Template
+	var doc []block
+
+	if tpl.ShowLogo && r.Logo != nil {
+		doc = append(doc, block{kind: kindImage, center: true, image: r.Logo})
+	}
+	if tx.ReprintCount > 0 {
+		doc = append(doc, r.watermark())
+	}
+	doc = append(doc, block{kind: kindLine, text: r.BusinessName, center: true, bold: true, large: true, brand: true})
+	for _, text := range r.header() {
+		doc = append(doc, centered(text))
+	}
+	if banner := statusBanner(tx); banner != "" {
+		doc = append(doc, block{kind: kindLine, text: banner, center: true, bold: true})
+	}
+	doc = append(doc, rule(true))
+
+	doc = append(doc, row("No:", tx.TransactionNumber), row("Tgl:", formatDate(tx)))
+	if tpl.ShowCashier && tx.Cashier != nil {
+		doc = append(doc, row("Kasir:", tx.Cashier.FullName))
+	}
+	if tx.Outlet != nil {
+		doc = append(doc, row("Outlet:", tx.Outlet.Name))
+	}
+	if tx.Table != nil {
+		doc = append(doc, row("Meja:", tx.Table.Name))
+	} else if tx.TabName != "" {
+		doc = append(doc, row("Atas Nama:", tx.TabName))
+	}
+	doc = append(doc, rule(false))
+
+	for _, item := range tx.Items {
+		name := item.ProductName
+		if item.VariantName != "" {
+			name += " (" + item.VariantName + ")"
+		}
+		doc = append(doc, line(name))
+		qty := fmt.Sprintf("%s x %s", formatQuantity(item.Quantity), formatRupiah(item.UnitPrice))
+		doc = append(doc, block{kind: kindRow, text: qty, right: formatRupiah(item.Subtotal), indent: true})
+		var modifiers []domain.SelectedModifier
+		_ = json.Unmarshal(item.Modifiers, &modifiers)
+		for _, m := range modifiers {
+			doc = append(doc, detail("+ "+m.Name))
+		}
+		if item.Notes != "" {
+			doc = append(doc, detail("[!] "+item.Notes))
+		}
+		if item.DiscountAmount != 0 {
+			doc = append(doc, block{kind: kindRow, text: "Diskon", right: "-" + formatRupiah(item.DiscountAmount), indent: true})
+		}
+	}
+	doc = append(doc, rule(false))
+
+	doc = append(doc, row("Subtotal", formatRupiah(tx.Subtotal)))
+	if tx.DiscountAmount != 0 {
+		doc = append(doc, row("Diskon", "-"+formatRupiah(tx.DiscountAmount)))
+	}
+	if tx.ServiceChargeAmount != 0 {
+		doc = append(doc, row("Service Charge", formatRupiah(tx.ServiceChargeAmount)))
+	}
+	doc = append(doc, taxRows(tx)...)
+	if tx.TipAmount != 0 {
+		doc = append(doc, row("Tip", formatRupiah(tx.TipAmount)))
+	}
+	if tx.DeliveryFee != 0 {
+		doc = append(doc, row("Ongkir", formatRupiah(tx.DeliveryFee)))
+	}
+	doc = append(doc, rule(true))
+	doc = append(doc, block{kind: kindRow, text: "TOTAL", right: formatRupiah(tx.TotalAmount), bold: true})
+	doc = append(doc, rule(true))
+
+	var paid domain.Money
+	for _, p := range tx.Payments {
+		paid += p.Amount
+		doc = append(doc, row(paymentLabel(p.PaymentMethod), formatRupiah(p.Amount)))
+		if p.ReferenceNumber != "" {
+			doc = append(doc, detail("Ref: "+p.ReferenceNumber))
+		}
+	}
+	if change := paid - tx.TotalAmount; change > 0 && tx.Type == domain.TransactionTypeSale {
+		doc = append(doc, block{kind: kindRow, text: "Kembali", right: formatRupiah(change), bold: true})
+	}
+	if tx.PointsRedeemed != 0 {
+		doc = append(doc, row("Poin Ditukar", fmt.Sprintf("%d", tx.PointsRedeemed)))
+	}
+	if tx.PointsEarned != 0 {
+		doc = append(doc, row("Poin Didapat", fmt.Sprintf("%d", tx.PointsEarned)))
+	}
+	// The receipt is how the buyer gets the codes of the gift cards they bought
+	for _, card := range tx.GiftCards {
+		if card.Status == domain.GiftCardStatusVoided {
+			continue
+		}
+		doc = append(doc, row("Gift Card", formatRupiah(card.InitialAmount)), detail(card.Code))
+		if card.ExpiresAt != nil {
+			doc = append(doc, detail("Berlaku s/d "+card.ExpiresAt.Local().Format("02/01/2006")))
+		}
+	}
+	doc = append(doc, rule(false))
+
+	for _, text := range r.footer() {
+		doc = append(doc, centered(text))
+	}
+	if tx.Notes != "" {
+		doc = append(doc, centered("Catatan: "+tx.Notes))
+	}
+	if content := r.qrContent(); content != "" {
+		doc = append(doc, block{kind: kindQR, text: content, center: true})
+	}
+	if tx.ReprintCount > 0 {
+		doc = append(doc, r.watermark())
+	}
+	doc = append(doc, rule(true))
+	doc = append(doc, block{kind: kindLine, text: "POWERED BY CODAPOS.COM", center: true, bold: true})
+	return doc
+}
+
+// watermark marks every receipt printed after the first one as a copy
+func (r *Receipt) watermark() block {
+	return block{
+		kind:      kindLine,
+		text:      fmt.Sprintf("COPY/REPRINT #%d", r.Transaction.ReprintCount),
+		center:    true,
+		bold:      true,
+		watermark: true,
+	}
+}
+
+// header returns the lines under the business name: the template header, or the
+// address and phone of the outlet
+func (r *Receipt) header() []string {
+	if strings.TrimSpace(r.Template.Header) != "" {
+		return splitLines(r.Template.Header)
+	}
+	var lines []string
+	if outlet := r.Transaction.Outlet; outlet != nil {
+		if outlet.Address != "" {
+			lines = append(lines, outlet.Address)
+		}
+		if outlet.Phone != "" {
+			lines = append(lines, "Tel: "+outlet.Phone)
+		}
+	}
+	return lines
+}
+
+func (r *Receipt) footer() []string {
+	if strings.TrimSpace(r.Template.Footer) != "" {
+		return splitLines(r.Template.Footer)
+	}
+	return []string{"Terima Kasih!", "Selamat Menikmati"}
+}
+
+// qrContent fills in the placeholders of the template QR content
+func (r *Receipt) qrContent() string {
+	content := strings.TrimSpace(r.Template.QRContent)
+	if content == "" {
+		return ""
+	}
+	tx := r.Transaction
+	outlet := ""
+	if tx.Outlet != nil {
+		outlet = tx.Outlet.Name
+	}
+	return strings.NewReplacer(
+		"{id}", tx.ID.String(),
+		"{number}", tx.TransactionNumber,
+		"{total}", strconv.FormatInt(tx.TotalAmount.WholeRupiah(), 10),
+		"{outlet}", outlet,
+	).Replace(content)
+}
+
+// statusBanner flags receipts that are not a plain paid sale
+func statusBanner(tx *domain.Transaction) string {
+	switch {
+	case tx.Type == domain.TransactionTypeRefund:
+		return "*** REFUND ***"
+	case tx.Status == domain.TransactionStatusVoided:
+		return "*** DIBATALKAN ***"
+	case tx.Status == domain.TransactionStatusPending:
+		return "*** BELUM LUNAS ***"
+	default:
+		return ""
+	}
+}
+
+// taxRows lists the taxes of a transaction; inclusive taxes are already part of the
+// item prices and are only shown for information
+func taxRows(tx *domain.Transaction) []block {
+	if len(tx.Taxes) == 0 {
+		if tx.TaxAmount == 0 {
+			return nil
+		}
+		return []block{row("Pajak", formatRupiah(tx.TaxAmount))}
+	}
+	rows := make([]block, 0, len(tx.Taxes))
+	for _, tax := range tx.Taxes {
+		label := fmt.Sprintf("%s %s%%", tax.Name, strconv.FormatFloat(tax.Rate, 'f', -1, 64))
+		if tax.IsInclusive {
+			label += " (termasuk)"
+		}
+		rows = append(rows, row(label, formatRupiah(tax.Amount)))
+	}
+	return rows
+}
+
+// formatRupiah formats an amount the way it is printed on receipts, e.g. "Rp 12.500"
+func formatRupiah(m domain.Money) string {
+	v := m.WholeRupiah()
+	sign := ""
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	digits := strconv.FormatInt(v, 10)
+	var b strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(d)
+	}
+	return sign + "Rp " + b.String()
+}
+
+func formatQuantity(q float64) string {
+	return strconv.FormatFloat(q, 'f', -1, 64)
+}
+
+func formatDate(tx *domain.Transaction) string {
+	return tx.CreatedAt.Local().Format("02/01/2006 15:04")
+}
+
+func paymentLabel(method string) string {
+	switch method {
+	case domain.PaymentCash:
+		return "Tunai"
+	case domain.PaymentQRIS:
+		return "QRIS"
+	case domain.PaymentEWallet:
+		return "E-Wallet"
+	case domain.PaymentBankTransfer:
+		return "Transfer Bank"
+	case domain.PaymentCreditCard:
+		return "Kartu Kredit"
+	case domain.PaymentLoyalty:
+		return "Poin Loyalitas"
+	case domain.PaymentGiftCard:
+		return "Gift Card"
+	case "card":
+		return "Kartu"
+	default:
+		return method
+	}
+}
+
+func splitLines(text string) []string {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimRight(lines[i], " \t")
+	}
+	return lines
+}
+
+// wrap breaks text into lines of at most width characters, between words where it can
+func wrap(text string, width int) []string {
+	if width < 1 {
+		width = 1
+	}
+	var lines []string
+	var current []rune
+	for _, word := range strings.Fields(text) {
+		w := []rune(word)
+		if len(current) > 0 && len(current)+1+len(w) > width {
+			lines = append(lines, string(current))
+			current = nil
+		}
+		for len(w) > width {
+			if len(current) > 0 {
+				lines = append(lines, string(current))
+				current = nil
+			}
+			lines = append(lines, string(w[:width]))
+			w = w[width:]
+		}
+		if len(current) > 0 {
+			current = append(current, ' ')
+		}
+		current = append(current, w...)
+	}
+	if len(current) > 0 || len(lines) == 0 {
+		lines = append(lines, string(current))
+	}
+	return lines
+}
@@ -0,0 +1,62 @@
+package receipt
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// text renders the receipt as fixed-width plain text. Images are left out and a QR
+// code is printed as its content.
+func (r *Receipt) text() string {
+	width := columns[r.Template.PaperSize]
+	var b strings.Builder
+	for _, blk := range r.layout() {
+		for _, l := range blk.lines(width) {
+			if blk.center {
+				l = strings.Repeat(" ", (width-utf8.RuneCountInString(l))/2) + l
+			}
+			b.WriteString(strings.TrimRight(l, " "))
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
+
+// lines lays a text block out on lines of the given width
+func (b block) lines(width int) []string {
+	prefix := ""
+	if b.indent {
+		prefix = "  "
+	}
+	switch b.kind {
+	case kindLine, kindQR:
+		lines := wrap(b.text, width-len(prefix))
+		for i := range lines {
+			lines[i] = prefix + lines[i]
+		}
+		return lines
+	case kindRow:
+		left := prefix + b.text
+		gap := width - utf8.RuneCountInString(left) - utf8.RuneCountInString(b.right)
+		if gap > 0 {
+			return []string{left + strings.Repeat(" ", gap) + b.right}
+		}
+		// Too long for one line: the label wraps and the amount goes right-aligned below it
+		lines := wrap(b.text, width-len(prefix))
+		for i := range lines {
+			lines[i] = prefix + lines[i]
+		}
+		pad := width - utf8.RuneCountInString(b.right)
+		if pad < 0 {
+			pad = 0
+		}
+		return append(lines, strings.Repeat(" ", pad)+b.right)
+	case kindRule:
+		if b.heavy {
+			return []string{strings.Repeat("=", width)}
+		}
+		return []string{strings.Repeat("-", width)}
+	default:
+		return nil
+	}
+}
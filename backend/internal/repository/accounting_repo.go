@@ -40,7 +40,7 @@ func (r *accountingRepo) UpdateAccount(account *domain.ChartOfAccount) error {
 	return r.db.Save(account).Error
 }
 
-func (r *accountingRepo) UpdateAccountBalance(accountID uuid.UUID, amount float64) error {
+func (r *accountingRepo) UpdateAccountBalance(accountID uuid.UUID, amount domain.Money) error {
 	return r.db.Model(&domain.ChartOfAccount{}).
 		Where("id = ?", accountID).
 		UpdateColumn("balance", gorm.Expr("balance + ?", amount)).Error
@@ -60,6 +60,15 @@ func (r *accountingRepo) FindJournalByID(id uuid.UUID) (*domain.JournalEntry, er
 	return &entry, nil
 }
 
+func (r *accountingRepo) FindJournalsByReference(referenceType string, referenceID uuid.UUID) ([]domain.JournalEntry, error) {
+	var entries []domain.JournalEntry
+	err := r.db.Preload("Lines.Account").
+		Where("reference_type = ? AND reference_id = ?", referenceType, referenceID).
+		Order("created_at ASC").
+		Find(&entries).Error
+	return entries, err
+}
+
 func (r *accountingRepo) FindJournalsByTenantID(tenantID uuid.UUID, startDate, endDate *time.Time, limit, offset int) ([]domain.JournalEntry, int64, error) {
 	var entries []domain.JournalEntry
 	var total int64
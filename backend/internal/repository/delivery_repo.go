@@ -4,6 +4,7 @@ import (
 	"github.com/codapos/backend/internal/domain"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type DeliveryRepository struct {
@@ -26,6 +27,34 @@ func (r *DeliveryRepository) FindOrderByID(id uuid.UUID) (*domain.DeliveryOrder,
 	return &order, err
 }
 
+// LockOrderByID loads an order and locks its row until the surrounding transaction ends
+func (r *DeliveryRepository) LockOrderByID(id uuid.UUID) (*domain.DeliveryOrder, error) {
+	var order domain.DeliveryOrder
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&order).Error
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (r *DeliveryRepository) FindOrderByIdempotencyKey(tenantID uuid.UUID, key string) (*domain.DeliveryOrder, error) {
+	var order domain.DeliveryOrder
+	err := r.db.Where("tenant_id = ? AND idempotency_key = ?", tenantID, key).First(&order).Error
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (r *DeliveryRepository) FindOrderByMidtransOrderID(midtransOrderID string) (*domain.DeliveryOrder, error) {
+	var order domain.DeliveryOrder
+	err := r.db.Where("midtrans_order_id = ?", midtransOrderID).First(&order).Error
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
 func (r *DeliveryRepository) FindOrdersByTenantID(tenantID uuid.UUID, status string, limit, offset int) ([]domain.DeliveryOrder, int64, error) {
 	var orders []domain.DeliveryOrder
 	var total int64
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/codapos/backend/internal/domain"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type giftCardRepo struct {
+	db *gorm.DB
+}
+
+func NewGiftCardRepository(db *gorm.DB) domain.GiftCardRepository {
+	return &giftCardRepo{db: db}
+}
+
+func (r *giftCardRepo) Create(card *domain.GiftCard) error {
+	return r.db.Create(card).Error
+}
+
+func (r *giftCardRepo) Update(card *domain.GiftCard) error {
+	return r.db.Omit("Movements").Save(card).Error
+}
+
+func (r *giftCardRepo) FindByID(id uuid.UUID) (*domain.GiftCard, error) {
+	var card domain.GiftCard
+	err := r.db.Preload("Movements", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC") }).
+		Where("id = ?", id).First(&card).Error
+	if err != nil {
+		return nil, err
+	}
+	return &card, nil
+}
+
+func (r *giftCardRepo) FindByCode(tenantID uuid.UUID, code string) (*domain.GiftCard, error) {
+	var card domain.GiftCard
+	err := r.db.Preload("Movements", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC") }).
+		Where("tenant_id = ? AND code = ?", tenantID, code).First(&card).Error
+	if err != nil {
+		return nil, err
+	}
+	return &card, nil
+}
+
+func (r *giftCardRepo) FindByTenantID(tenantID uuid.UUID, status string, limit, offset int) ([]domain.GiftCard, int64, error) {
+	var cards []domain.GiftCard
+	var total int64
+	query := r.db.Model(&domain.GiftCard{}).Where("tenant_id = ?", tenantID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	query.Count(&total)
+	err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&cards).Error
+	return cards, total, err
+}
+
+func (r *giftCardRepo) FindByTransactionItem(itemID uuid.UUID) ([]domain.GiftCard, error) {
+	var cards []domain.GiftCard
+	err := r.db.Where("transaction_item_id = ?", itemID).Order("created_at ASC").Find(&cards).Error
+	return cards, err
+}
+
+func (r *giftCardRepo) FindExpiring(tenantID *uuid.UUID, now time.Time) ([]domain.GiftCard, error) {
+	var cards []domain.GiftCard
+	query := r.db.Where("status IN ? AND expires_at <= ?",
+		[]string{domain.GiftCardStatusActive, domain.GiftCardStatusSpent}, now)
+	if tenantID != nil {
+		query = query.Where("tenant_id = ?", *tenantID)
+	}
+	err := query.Order("expires_at ASC").Find(&cards).Error
+	return cards, err
+}
+
+func (r *giftCardRepo) Lock(id uuid.UUID) (*domain.GiftCard, error) {
+	var card domain.GiftCard
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&card).Error
+	if err != nil {
+		return nil, err
+	}
+	return &card, nil
+}
+
+func (r *giftCardRepo) CreateMovement(movement *domain.GiftCardMovement) error {
+	return r.db.Create(movement).Error
+}
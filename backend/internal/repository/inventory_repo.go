@@ -1,9 +1,12 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/codapos/backend/internal/domain"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type inventoryRepo struct {
@@ -97,3 +100,41 @@ func (r *inventoryRepo) FindMovements(outletID uuid.UUID, productID *uuid.UUID,
 	err := q.Order("created_at DESC").Limit(limit).Find(&movements).Error
 	return movements, err
 }
+
+func (r *inventoryRepo) LockStock(outletID, productID uuid.UUID, variantID *uuid.UUID) (float64, error) {
+	var rows []domain.Inventory
+	query := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("outlet_id = ? AND product_id = ?", outletID, productID)
+	if variantID != nil {
+		query = query.Where("variant_id = ?", *variantID)
+	} else {
+		query = query.Where("variant_id IS NULL")
+	}
+	if err := query.Limit(1).Find(&rows).Error; err != nil || len(rows) == 0 {
+		return 0, err
+	}
+	return rows[0].Quantity, nil
+}
+
+func (r *inventoryRepo) CreateReservation(reservation *domain.StockReservation) error {
+	return r.db.Create(reservation).Error
+}
+
+func (r *inventoryRepo) FindActiveReservations(outletID uuid.UUID, now time.Time) ([]domain.StockReservation, error) {
+	var reservations []domain.StockReservation
+	err := r.db.Where("outlet_id = ? AND status = ? AND expires_at > ?", outletID, domain.ReservationStatusActive, now).
+		Find(&reservations).Error
+	return reservations, err
+}
+
+func (r *inventoryRepo) SetReservationStatus(referenceType string, referenceID uuid.UUID, status string) error {
+	return r.db.Model(&domain.StockReservation{}).
+		Where("reference_type = ? AND reference_id = ? AND status = ?", referenceType, referenceID, domain.ReservationStatusActive).
+		Update("status", status).Error
+}
+
+func (r *inventoryRepo) ExpireReservations(now time.Time) error {
+	return r.db.Model(&domain.StockReservation{}).
+		Where("status = ? AND expires_at <= ?", domain.ReservationStatusActive, now).
+		Update("status", domain.ReservationStatusExpired).Error
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/codapos/backend/internal/domain"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type kitchenRepo struct {
+	db *gorm.DB
+}
+
+func NewKitchenRepository(db *gorm.DB) domain.KitchenRepository {
+	return &kitchenRepo{db: db}
+}
+
+func (r *kitchenRepo) CreateStation(station *domain.KitchenStation) error {
+	return r.db.Create(station).Error
+}
+
+func (r *kitchenRepo) FindStationByID(id uuid.UUID) (*domain.KitchenStation, error) {
+	var station domain.KitchenStation
+	if err := r.db.Preload("Categories").Where("id = ?", id).First(&station).Error; err != nil {
+		return nil, err
+	}
+	return &station, nil
+}
+
+func (r *kitchenRepo) FindStationsByOutlet(outletID uuid.UUID) ([]domain.KitchenStation, error) {
+	var stations []domain.KitchenStation
+	err := r.db.Preload("Categories").Where("outlet_id = ?", outletID).Order("name ASC").Find(&stations).Error
+	return stations, err
+}
+
+// UpdateStation saves a station and replaces the categories it serves
+func (r *kitchenRepo) UpdateStation(station *domain.KitchenStation) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Categories").Save(station).Error; err != nil {
+			return err
+		}
+		return tx.Model(station).Association("Categories").Replace(station.Categories)
+	})
+}
+
+func (r *kitchenRepo) DeleteStation(id uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		station := &domain.KitchenStation{BaseModel: domain.BaseModel{ID: id}}
+		if err := tx.Model(station).Association("Categories").Clear(); err != nil {
+			return err
+		}
+		return tx.Delete(station).Error
+	})
+}
+
+func (r *kitchenRepo) CreateTicket(ticket *domain.KitchenTicket) error {
+	return r.db.Create(ticket).Error
+}
+
+func (r *kitchenRepo) FindTicketByID(id uuid.UUID) (*domain.KitchenTicket, error) {
+	var ticket domain.KitchenTicket
+	err := r.db.
+		Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC, seat_number ASC") }).
+		Preload("Station").
+		Where("id = ?", id).
+		First(&ticket).Error
+	if err != nil {
+		return nil, err
+	}
+	return &ticket, nil
+}
+
+func (r *kitchenRepo) FindTickets(outletID uuid.UUID, stationID *uuid.UUID, status string) ([]domain.KitchenTicket, error) {
+	var tickets []domain.KitchenTicket
+	query := r.db.Where("outlet_id = ?", outletID)
+	if stationID != nil {
+		query = query.Where("station_id = ?", *stationID)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.
+		Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC, seat_number ASC") }).
+		Preload("Station").
+		Order("created_at ASC").
+		Find(&tickets).Error
+	return tickets, err
+}
+
+func (r *kitchenRepo) FindTicketsByTransaction(transactionID uuid.UUID) ([]domain.KitchenTicket, error) {
+	var tickets []domain.KitchenTicket
+	err := r.db.
+		Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC, seat_number ASC") }).
+		Preload("Station").
+		Where("transaction_id = ?", transactionID).
+		Order("created_at ASC").
+		Find(&tickets).Error
+	return tickets, err
+}
+
+func (r *kitchenRepo) UpdateTicket(ticket *domain.KitchenTicket) error {
+	return r.db.Omit("Station", "Items").Save(ticket).Error
+}
+
+func (r *kitchenRepo) UpdateItem(item *domain.KitchenTicketItem) error {
+	return r.db.Save(item).Error
+}
+
+func (r *kitchenRepo) MoveTickets(fromTransactionID, toTransactionID uuid.UUID) error {
+	return r.db.Model(&domain.KitchenTicket{}).
+		Where("transaction_id = ?", fromTransactionID).
+		Update("transaction_id", toTransactionID).Error
+}
+
+func (r *kitchenRepo) PrepMetrics(tenantID uuid.UUID, outletID *uuid.UUID, from, to time.Time) ([]domain.KitchenPrepMetric, error) {
+	var metrics []domain.KitchenPrepMetric
+	query := r.db.Table("kitchen_ticket_items i").
+		Select(`t.station_id, s.name AS station_name, i.product_id, MAX(i.product_name) AS product_name,
+			COUNT(*) AS items, SUM(i.quantity) AS quantity,
+			AVG(i.wait_seconds) AS avg_wait_seconds, AVG(i.prep_seconds) AS avg_prep_seconds,
+			MAX(i.prep_seconds) AS max_prep_seconds,
+			COALESCE(AVG(i.serve_seconds) FILTER (WHERE i.served_at IS NOT NULL), 0) AS avg_serve_seconds`).
+		Joins("JOIN kitchen_tickets t ON t.id = i.ticket_id").
+		Joins("JOIN kitchen_stations s ON s.id = t.station_id").
+		Where("t.tenant_id = ? AND i.ready_at >= ? AND i.ready_at < ?", tenantID, from, to)
+	if outletID != nil {
+		query = query.Where("t.outlet_id = ?", *outletID)
+	}
+	err := query.
+		Group("t.station_id, s.name, i.product_id").
+		Order("avg_prep_seconds DESC").
+		Scan(&metrics).Error
+	return metrics, err
+}
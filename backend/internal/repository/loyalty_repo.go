@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"github.com/codapos/backend/internal/domain"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type loyaltyRepo struct {
+	db *gorm.DB
+}
+
+func NewLoyaltyRepository(db *gorm.DB) domain.LoyaltyRepository {
+	return &loyaltyRepo{db: db}
+}
+
+func (r *loyaltyRepo) FindProgram(tenantID uuid.UUID) (*domain.LoyaltyProgram, error) {
+	var program domain.LoyaltyProgram
+	err := r.db.Where("tenant_id = ?", tenantID).First(&program).Error
+	if err != nil {
+		return nil, err
+	}
+	return &program, nil
+}
+
+func (r *loyaltyRepo) SaveProgram(program *domain.LoyaltyProgram) error {
+	return r.db.Save(program).Error
+}
+
+func (r *loyaltyRepo) FindRules(tenantID uuid.UUID) ([]domain.LoyaltyEarnRule, error) {
+	var rules []domain.LoyaltyEarnRule
+	err := r.db.Preload("Category").Where("tenant_id = ?", tenantID).Order("name ASC").Find(&rules).Error
+	return rules, err
+}
+
+func (r *loyaltyRepo) FindRuleByID(id uuid.UUID) (*domain.LoyaltyEarnRule, error) {
+	var rule domain.LoyaltyEarnRule
+	err := r.db.Preload("Category").Where("id = ?", id).First(&rule).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *loyaltyRepo) SaveRule(rule *domain.LoyaltyEarnRule) error {
+	return r.db.Omit("Category").Save(rule).Error
+}
+
+func (r *loyaltyRepo) DeleteRule(id uuid.UUID) error {
+	return r.db.Delete(&domain.LoyaltyEarnRule{}, "id = ?", id).Error
+}
+
+func (r *loyaltyRepo) FindTiers(tenantID uuid.UUID) ([]domain.LoyaltyTier, error) {
+	var tiers []domain.LoyaltyTier
+	err := r.db.Where("tenant_id = ?", tenantID).Order("min_points ASC").Find(&tiers).Error
+	return tiers, err
+}
+
+func (r *loyaltyRepo) FindTierByID(id uuid.UUID) (*domain.LoyaltyTier, error) {
+	var tier domain.LoyaltyTier
+	err := r.db.Where("id = ?", id).First(&tier).Error
+	if err != nil {
+		return nil, err
+	}
+	return &tier, nil
+}
+
+func (r *loyaltyRepo) SaveTier(tier *domain.LoyaltyTier) error {
+	return r.db.Save(tier).Error
+}
+
+func (r *loyaltyRepo) DeleteTier(id uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Members of the tier drop to whatever their points reach next time they earn
+		if err := tx.Model(&domain.LoyaltyAccount{}).Where("tier_id = ?", id).Update("tier_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&domain.LoyaltyTier{}, "id = ?", id).Error
+	})
+}
+
+func (r *loyaltyRepo) FindAccount(customerID uuid.UUID) (*domain.LoyaltyAccount, error) {
+	var account domain.LoyaltyAccount
+	err := r.db.Preload("Tier").Where("customer_id = ?", customerID).First(&account).Error
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (r *loyaltyRepo) LockAccount(tenantID, customerID uuid.UUID) (*domain.LoyaltyAccount, error) {
+	account := domain.LoyaltyAccount{TenantID: tenantID, CustomerID: customerID}
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("customer_id = ?", customerID).
+		FirstOrCreate(&account).Error
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (r *loyaltyRepo) UpdateAccount(account *domain.LoyaltyAccount) error {
+	return r.db.Omit("Tier", "Customer").Save(account).Error
+}
+
+func (r *loyaltyRepo) CreateEntry(entry *domain.LoyaltyEntry) error {
+	return r.db.Create(entry).Error
+}
+
+func (r *loyaltyRepo) UpdateEntry(entry *domain.LoyaltyEntry) error {
+	return r.db.Save(entry).Error
+}
+
+func (r *loyaltyRepo) FindEntries(customerID uuid.UUID, limit, offset int) ([]domain.LoyaltyEntry, int64, error) {
+	var entries []domain.LoyaltyEntry
+	var total int64
+	r.db.Model(&domain.LoyaltyEntry{}).Where("customer_id = ?", customerID).Count(&total)
+	err := r.db.Where("customer_id = ?", customerID).
+		Order("created_at DESC").Limit(limit).Offset(offset).Find(&entries).Error
+	return entries, total, err
+}
+
+func (r *loyaltyRepo) FindEntriesByTransaction(transactionID uuid.UUID) ([]domain.LoyaltyEntry, error) {
+	var entries []domain.LoyaltyEntry
+	err := r.db.Where("transaction_id = ?", transactionID).Order("created_at ASC").Find(&entries).Error
+	return entries, err
+}
+
+func (r *loyaltyRepo) FindOpenLots(customerID uuid.UUID) ([]domain.LoyaltyEntry, error) {
+	var lots []domain.LoyaltyEntry
+	err := r.db.Where("customer_id = ? AND remaining > 0", customerID).
+		Order("expires_at ASC NULLS LAST, created_at ASC").Find(&lots).Error
+	return lots, err
+}
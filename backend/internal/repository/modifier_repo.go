@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"github.com/codapos/backend/internal/domain"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type modifierGroupRepo struct {
+	db *gorm.DB
+}
+
+func NewModifierGroupRepository(db *gorm.DB) domain.ModifierGroupRepository {
+	return &modifierGroupRepo{db: db}
+}
+
+func orderedModifiers(db *gorm.DB) *gorm.DB {
+	return db.Order("sort_order ASC, name ASC")
+}
+
+func (r *modifierGroupRepo) Create(group *domain.ModifierGroup) error {
+	return r.db.Create(group).Error
+}
+
+func (r *modifierGroupRepo) FindByID(id uuid.UUID) (*domain.ModifierGroup, error) {
+	var group domain.ModifierGroup
+	err := r.db.Preload("Modifiers", orderedModifiers).Where("id = ?", id).First(&group).Error
+	if err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+func (r *modifierGroupRepo) FindByTenantID(tenantID uuid.UUID) ([]domain.ModifierGroup, error) {
+	var groups []domain.ModifierGroup
+	err := r.db.
+		Preload("Modifiers", orderedModifiers).
+		Where("tenant_id = ?", tenantID).
+		Order("sort_order ASC, name ASC").
+		Find(&groups).Error
+	return groups, err
+}
+
+func (r *modifierGroupRepo) Update(group *domain.ModifierGroup) error {
+	return r.db.Omit("Modifiers").Save(group).Error
+}
+
+func (r *modifierGroupRepo) Delete(id uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM product_modifier_groups WHERE modifier_group_id = ?", id).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("group_id = ?", id).Delete(&domain.Modifier{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&domain.ModifierGroup{}, "id = ?", id).Error
+	})
+}
+
+func (r *modifierGroupRepo) CreateModifier(modifier *domain.Modifier) error {
+	return r.db.Create(modifier).Error
+}
+
+func (r *modifierGroupRepo) FindModifierByID(id uuid.UUID) (*domain.Modifier, error) {
+	var modifier domain.Modifier
+	err := r.db.Where("id = ?", id).First(&modifier).Error
+	if err != nil {
+		return nil, err
+	}
+	return &modifier, nil
+}
+
+func (r *modifierGroupRepo) UpdateModifier(modifier *domain.Modifier) error {
+	return r.db.Save(modifier).Error
+}
+
+func (r *modifierGroupRepo) DeleteModifier(id uuid.UUID) error {
+	return r.db.Delete(&domain.Modifier{}, "id = ?", id).Error
+}
+
+func (r *modifierGroupRepo) SetProductGroups(product *domain.Product, groups []domain.ModifierGroup) error {
+	return r.db.Model(product).Association("ModifierGroups").Replace(groups)
+}
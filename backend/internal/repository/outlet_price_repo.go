@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/codapos/backend/internal/domain"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type outletPriceRepo struct {
+	db *gorm.DB
+}
+
+func NewOutletPriceRepository(db *gorm.DB) domain.OutletPriceRepository {
+	return &outletPriceRepo{db: db}
+}
+
+func (r *outletPriceRepo) FindByOutletID(outletID uuid.UUID) ([]domain.OutletPrice, error) {
+	var prices []domain.OutletPrice
+	err := r.db.Where("outlet_id = ?", outletID).Find(&prices).Error
+	return prices, err
+}
+
+// Upsert creates or updates overrides. Product-level rows have a NULL variant_id,
+// which the unique index does not catch, so rows are matched explicitly.
+func (r *outletPriceRepo) Upsert(prices []domain.OutletPrice) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for i := range prices {
+			price := &prices[i]
+			var existing domain.OutletPrice
+			err := variantScope(tx.Where("outlet_id = ? AND product_id = ?", price.OutletID, price.ProductID), price.VariantID).
+				First(&existing).Error
+			switch {
+			case err == nil:
+				existing.Price = price.Price
+				if err := tx.Save(&existing).Error; err != nil {
+					return err
+				}
+				*price = existing
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				if err := tx.Create(price).Error; err != nil {
+					return err
+				}
+			default:
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *outletPriceRepo) Delete(outletID, productID uuid.UUID, variantID *uuid.UUID) error {
+	return variantScope(r.db.Where("outlet_id = ? AND product_id = ?", outletID, productID), variantID).
+		Delete(&domain.OutletPrice{}).Error
+}
+
+func variantScope(db *gorm.DB, variantID *uuid.UUID) *gorm.DB {
+	if variantID == nil {
+		return db.Where("variant_id IS NULL")
+	}
+	return db.Where("variant_id = ?", *variantID)
+}
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"github.com/codapos/backend/internal/domain"
+	"gorm.io/gorm"
+)
+
+type paymentNotificationRepo struct {
+	db *gorm.DB
+}
+
+func NewPaymentNotificationRepository(db *gorm.DB) domain.PaymentNotificationRepository {
+	return &paymentNotificationRepo{db: db}
+}
+
+func (r *paymentNotificationRepo) Create(notification *domain.PaymentNotification) error {
+	return r.db.Create(notification).Error
+}
+
+func (r *paymentNotificationRepo) FindByOrderID(orderID string) ([]domain.PaymentNotification, error) {
+	var notifications []domain.PaymentNotification
+	err := r.db.Where("order_id = ?", orderID).Order("created_at ASC").Find(&notifications).Error
+	return notifications, err
+}
+
+func (r *paymentNotificationRepo) FindAll(result string, limit, offset int) ([]domain.PaymentNotification, int64, error) {
+	var notifications []domain.PaymentNotification
+	var total int64
+	query := r.db.Model(&domain.PaymentNotification{})
+	if result != "" {
+		query = query.Where("result = ?", result)
+	}
+	query.Count(&total)
+	err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&notifications).Error
+	return notifications, total, err
+}
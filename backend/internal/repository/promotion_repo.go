@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"github.com/codapos/backend/internal/domain"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type promotionRepo struct {
+	db *gorm.DB
+}
+
+func NewPromotionRepository(db *gorm.DB) domain.PromotionRepository {
+	return &promotionRepo{db: db}
+}
+
+func (r *promotionRepo) Create(promotion *domain.Promotion) error {
+	return r.db.Create(promotion).Error
+}
+
+func (r *promotionRepo) FindByID(id uuid.UUID) (*domain.Promotion, error) {
+	var promotion domain.Promotion
+	err := r.db.Where("id = ?", id).First(&promotion).Error
+	if err != nil {
+		return nil, err
+	}
+	return &promotion, nil
+}
+
+func (r *promotionRepo) FindByTenantID(tenantID uuid.UUID, activeOnly bool) ([]domain.Promotion, error) {
+	var promotions []domain.Promotion
+	query := r.db.Where("tenant_id = ?", tenantID)
+	if activeOnly {
+		query = query.Where("is_active = ?", true)
+	}
+	err := query.Order("created_at DESC").Find(&promotions).Error
+	return promotions, err
+}
+
+func (r *promotionRepo) Update(promotion *domain.Promotion) error {
+	return r.db.Save(promotion).Error
+}
+
+func (r *promotionRepo) Delete(id uuid.UUID) error {
+	return r.db.Delete(&domain.Promotion{}, "id = ?", id).Error
+}
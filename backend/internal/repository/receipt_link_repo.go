@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/codapos/backend/internal/domain"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type receiptLinkRepo struct {
+	db *gorm.DB
+}
+
+func NewReceiptLinkRepository(db *gorm.DB) domain.ReceiptLinkRepository {
+	return &receiptLinkRepo{db: db}
+}
+
+func (r *receiptLinkRepo) Create(link *domain.ReceiptLink) error {
+	return r.db.Create(link).Error
+}
+
+func (r *receiptLinkRepo) FindByID(id uuid.UUID) (*domain.ReceiptLink, error) {
+	var link domain.ReceiptLink
+	if err := r.db.Where("id = ?", id).First(&link).Error; err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+func (r *receiptLinkRepo) FindByToken(token string) (*domain.ReceiptLink, error) {
+	var link domain.ReceiptLink
+	if err := r.db.Where("token = ?", token).First(&link).Error; err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+func (r *receiptLinkRepo) FindByTransaction(transactionID uuid.UUID) ([]domain.ReceiptLink, error) {
+	var links []domain.ReceiptLink
+	err := r.db.Where("transaction_id = ?", transactionID).Order("created_at DESC").Find(&links).Error
+	return links, err
+}
+
+func (r *receiptLinkRepo) Update(link *domain.ReceiptLink) error {
+	return r.db.Save(link).Error
+}
+
+func (r *receiptLinkRepo) RecordView(id uuid.UUID, at time.Time) error {
+	return r.db.Model(&domain.ReceiptLink{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"view_count":     gorm.Expr("view_count + 1"),
+			"last_viewed_at": at,
+		}).Error
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"github.com/codapos/backend/internal/domain"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type receiptTemplateRepo struct {
+	db *gorm.DB
+}
+
+func NewReceiptTemplateRepository(db *gorm.DB) domain.ReceiptTemplateRepository {
+	return &receiptTemplateRepo{db: db}
+}
+
+func (r *receiptTemplateRepo) Create(template *domain.ReceiptTemplate) error {
+	return r.db.Create(template).Error
+}
+
+func (r *receiptTemplateRepo) FindByID(id uuid.UUID) (*domain.ReceiptTemplate, error) {
+	var template domain.ReceiptTemplate
+	if err := r.db.Where("id = ?", id).First(&template).Error; err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (r *receiptTemplateRepo) FindByOutlet(tenantID uuid.UUID, outletID *uuid.UUID) (*domain.ReceiptTemplate, error) {
+	var template domain.ReceiptTemplate
+	query := r.db.Where("tenant_id = ?", tenantID)
+	if outletID != nil {
+		query = query.Where("outlet_id = ?", *outletID)
+	} else {
+		query = query.Where("outlet_id IS NULL")
+	}
+	if err := query.Order("created_at ASC").First(&template).Error; err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (r *receiptTemplateRepo) FindByTenant(tenantID uuid.UUID) ([]domain.ReceiptTemplate, error) {
+	var templates []domain.ReceiptTemplate
+	err := r.db.Where("tenant_id = ?", tenantID).Order("outlet_id NULLS FIRST, created_at ASC").Find(&templates).Error
+	return templates, err
+}
+
+func (r *receiptTemplateRepo) Update(template *domain.ReceiptTemplate) error {
+	return r.db.Save(template).Error
+}
+
+func (r *receiptTemplateRepo) Delete(id uuid.UUID) error {
+	return r.db.Delete(&domain.ReceiptTemplate{}, "id = ?", id).Error
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"github.com/codapos/backend/internal/domain"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type sequenceRepo struct {
+	db *gorm.DB
+}
+
+func NewSequenceRepository(db *gorm.DB) domain.SequenceRepository {
+	return &sequenceRepo{db: db}
+}
+
+// Next issues the next value with a single upsert, so the first number of a period
+// and concurrent increments are both race free.
+func (r *sequenceRepo) Next(tenantID, outletID uuid.UUID, documentType, period string) (int64, error) {
+	var value int64
+	err := r.db.Raw(`
+		INSERT INTO sequence_counters (tenant_id, outlet_id, document_type, period, last_value, created_at, updated_at)
+		VALUES (?, ?, ?, ?, 1, NOW(), NOW())
+		ON CONFLICT (tenant_id, outlet_id, document_type, period)
+		DO UPDATE SET last_value = sequence_counters.last_value + 1, updated_at = NOW()
+		RETURNING last_value`,
+		tenantID, outletID, documentType, period,
+	).Scan(&value).Error
+	return value, err
+}
+
+func (r *sequenceRepo) FindConfigs(tenantID uuid.UUID) ([]domain.SequenceConfig, error) {
+	var configs []domain.SequenceConfig
+	err := r.db.Where("tenant_id = ?", tenantID).Order("document_type").Find(&configs).Error
+	return configs, err
+}
+
+func (r *sequenceRepo) FindConfig(tenantID uuid.UUID, documentType string) (*domain.SequenceConfig, error) {
+	var config domain.SequenceConfig
+	err := r.db.Where("tenant_id = ? AND document_type = ?", tenantID, documentType).First(&config).Error
+	if err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func (r *sequenceRepo) SaveConfig(config *domain.SequenceConfig) error {
+	return r.db.Save(config).Error
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"github.com/codapos/backend/internal/domain"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type shiftRepo struct {
+	db *gorm.DB
+}
+
+func NewShiftRepository(db *gorm.DB) domain.ShiftRepository {
+	return &shiftRepo{db: db}
+}
+
+func (r *shiftRepo) Create(shift *domain.Shift) error {
+	return r.db.Create(shift).Error
+}
+
+func (r *shiftRepo) FindByID(id uuid.UUID) (*domain.Shift, error) {
+	var shift domain.Shift
+	err := r.db.
+		Preload("Outlet").
+		Preload("Cashier").
+		Preload("CashMovements", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC") }).
+		Where("id = ?", id).
+		First(&shift).Error
+	if err != nil {
+		return nil, err
+	}
+	return &shift, nil
+}
+
+func (r *shiftRepo) LockByID(id uuid.UUID) (*domain.Shift, error) {
+	return r.lock(id, "UPDATE")
+}
+
+func (r *shiftRepo) LockSharedByID(id uuid.UUID) (*domain.Shift, error) {
+	return r.lock(id, "SHARE")
+}
+
+func (r *shiftRepo) lock(id uuid.UUID, strength string) (*domain.Shift, error) {
+	var shift domain.Shift
+	err := r.db.Clauses(clause.Locking{Strength: strength}).
+		Preload("Outlet").
+		Preload("Cashier").
+		Preload("CashMovements", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC") }).
+		Where("id = ?", id).
+		First(&shift).Error
+	if err != nil {
+		return nil, err
+	}
+	return &shift, nil
+}
+
+func (r *shiftRepo) FindOpenByCashier(cashierID uuid.UUID) (*domain.Shift, error) {
+	var shift domain.Shift
+	err := r.db.
+		Where("cashier_id = ? AND status = ?", cashierID, domain.ShiftStatusOpen).
+		First(&shift).Error
+	if err != nil {
+		return nil, err
+	}
+	return &shift, nil
+}
+
+func (r *shiftRepo) FindByTenantID(tenantID uuid.UUID, outletID *uuid.UUID, limit, offset int) ([]domain.Shift, int64, error) {
+	var shifts []domain.Shift
+	var total int64
+
+	query := r.db.Model(&domain.Shift{}).Where("tenant_id = ?", tenantID)
+	if outletID != nil {
+		query = query.Where("outlet_id = ?", *outletID)
+	}
+
+	query.Count(&total)
+
+	err := query.
+		Preload("Outlet").
+		Preload("Cashier").
+		Order("opened_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&shifts).Error
+
+	return shifts, total, err
+}
+
+func (r *shiftRepo) Update(shift *domain.Shift) error {
+	return r.db.Omit("Outlet", "Cashier", "CashMovements").Save(shift).Error
+}
+
+func (r *shiftRepo) CreateCashMovement(movement *domain.ShiftCashMovement) error {
+	return r.db.Create(movement).Error
+}
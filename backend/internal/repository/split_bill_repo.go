@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"github.com/codapos/backend/internal/domain"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type splitBillRepo struct {
+	db *gorm.DB
+}
+
+func NewSplitBillRepository(db *gorm.DB) domain.SplitBillRepository {
+	return &splitBillRepo{db: db}
+}
+
+func (r *splitBillRepo) CreateBatch(splits []domain.SplitBill) error {
+	return r.db.Create(&splits).Error
+}
+
+func (r *splitBillRepo) FindByID(id uuid.UUID) (*domain.SplitBill, error) {
+	var split domain.SplitBill
+	err := r.db.Where("id = ?", id).First(&split).Error
+	if err != nil {
+		return nil, err
+	}
+	return &split, nil
+}
+
+func (r *splitBillRepo) FindByTransactionID(transactionID uuid.UUID) ([]domain.SplitBill, error) {
+	var splits []domain.SplitBill
+	err := r.db.Where("transaction_id = ?", transactionID).Order("split_number ASC").Find(&splits).Error
+	return splits, err
+}
+
+func (r *splitBillRepo) Update(split *domain.SplitBill) error {
+	return r.db.Save(split).Error
+}
+
+func (r *splitBillRepo) DeleteByTransactionID(transactionID uuid.UUID) error {
+	return r.db.Where("transaction_id = ?", transactionID).Delete(&domain.SplitBill{}).Error
+}
@@ -49,3 +49,16 @@ func (r *subscriptionRepo) FindByTenantID(tenantID uuid.UUID) (*domain.Subscript
 func (r *subscriptionRepo) UpdateSubscription(sub *domain.Subscription) error {
 	return r.db.Save(sub).Error
 }
+
+func (r *subscriptionRepo) FindInvoiceByNumber(invoiceNumber string) (*domain.BillingInvoice, error) {
+	var invoice domain.BillingInvoice
+	err := r.db.Where("invoice_number = ?", invoiceNumber).First(&invoice).Error
+	if err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+func (r *subscriptionRepo) UpdateInvoice(invoice *domain.BillingInvoice) error {
+	return r.db.Save(invoice).Error
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/codapos/backend/internal/domain"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type tableRepo struct {
+	db *gorm.DB
+}
+
+func NewTableRepository(db *gorm.DB) domain.TableRepository {
+	return &tableRepo{db: db}
+}
+
+// openOrder matches tables whose order is still open
+const openOrder = "EXISTS (SELECT 1 FROM transactions t WHERE t.id = dining_tables.transaction_id AND t.status = ?)"
+
+func (r *tableRepo) CreateFloor(floor *domain.Floor) error {
+	return r.db.Create(floor).Error
+}
+
+func (r *tableRepo) FindFloorByID(id uuid.UUID) (*domain.Floor, error) {
+	var floor domain.Floor
+	if err := r.db.Where("id = ?", id).First(&floor).Error; err != nil {
+		return nil, err
+	}
+	return &floor, nil
+}
+
+func (r *tableRepo) FindFloorsByOutlet(outletID uuid.UUID) ([]domain.Floor, error) {
+	var floors []domain.Floor
+	err := r.db.
+		Preload("Tables", func(db *gorm.DB) *gorm.DB { return db.Order("name ASC") }).
+		Preload("Tables.Transaction").
+		Where("outlet_id = ?", outletID).
+		Order("sort_order ASC, name ASC").
+		Find(&floors).Error
+	return floors, err
+}
+
+func (r *tableRepo) UpdateFloor(floor *domain.Floor) error {
+	return r.db.Omit("Tables").Save(floor).Error
+}
+
+func (r *tableRepo) DeleteFloor(id uuid.UUID) error {
+	return r.db.Delete(&domain.Floor{}, "id = ?", id).Error
+}
+
+func (r *tableRepo) CountByFloor(floorID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.Model(&domain.DiningTable{}).Where("floor_id = ?", floorID).Count(&count).Error
+	return count, err
+}
+
+func (r *tableRepo) Create(table *domain.DiningTable) error {
+	return r.db.Create(table).Error
+}
+
+func (r *tableRepo) FindByID(id uuid.UUID) (*domain.DiningTable, error) {
+	var table domain.DiningTable
+	if err := r.db.Preload("Transaction").Where("id = ?", id).First(&table).Error; err != nil {
+		return nil, err
+	}
+	return &table, nil
+}
+
+func (r *tableRepo) FindByOutlet(outletID uuid.UUID, status string) ([]domain.DiningTable, error) {
+	var tables []domain.DiningTable
+	query := r.db.Where("outlet_id = ?", outletID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Preload("Transaction").Order("name ASC").Find(&tables).Error
+	return tables, err
+}
+
+func (r *tableRepo) FindByTransactionID(transactionID uuid.UUID) ([]domain.DiningTable, error) {
+	var tables []domain.DiningTable
+	err := r.db.Where("transaction_id = ?", transactionID).Order("name ASC").Find(&tables).Error
+	return tables, err
+}
+
+func (r *tableRepo) Update(table *domain.DiningTable) error {
+	return r.db.Omit("Transaction").Save(table).Error
+}
+
+func (r *tableRepo) Delete(id uuid.UUID) error {
+	return r.db.Delete(&domain.DiningTable{}, "id = ?", id).Error
+}
+
+// Seat is a conditional update so two cashiers cannot seat guests at the same table
+func (r *tableRepo) Seat(id uuid.UUID, status string, transactionID *uuid.UUID, since time.Time) error {
+	result := r.db.Model(&domain.DiningTable{}).
+		Where("id = ?", id).
+		Where("status = ? OR (transaction_id IS NOT NULL AND NOT "+openOrder+")", domain.TableStatusFree, domain.TransactionStatusPending).
+		Updates(map[string]interface{}{"status": status, "transaction_id": transactionID, "occupied_at": since})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("table is already occupied")
+	}
+	return nil
+}
+
+func (r *tableRepo) SetStatusByTransaction(transactionID uuid.UUID, status string) error {
+	return r.db.Model(&domain.DiningTable{}).
+		Where("transaction_id = ?", transactionID).
+		Update("status", status).Error
+}
+
+func (r *tableRepo) ReleaseByTransaction(transactionID uuid.UUID) error {
+	return r.db.Model(&domain.DiningTable{}).
+		Where("transaction_id = ?", transactionID).
+		Updates(map[string]interface{}{"status": domain.TableStatusFree, "transaction_id": nil, "occupied_at": nil}).Error
+}
+
+func (r *tableRepo) ReleaseClosed(tenantID uuid.UUID) error {
+	return r.db.Model(&domain.DiningTable{}).
+		Where("tenant_id = ? AND transaction_id IS NOT NULL AND NOT "+openOrder, tenantID, domain.TransactionStatusPending).
+		Updates(map[string]interface{}{"status": domain.TableStatusFree, "transaction_id": nil, "occupied_at": nil}).Error
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"github.com/codapos/backend/internal/domain"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type taxRateRepo struct {
+	db *gorm.DB
+}
+
+func NewTaxRateRepository(db *gorm.DB) domain.TaxRateRepository {
+	return &taxRateRepo{db: db}
+}
+
+func (r *taxRateRepo) Create(rate *domain.TaxRate) error {
+	return r.db.Create(rate).Error
+}
+
+func (r *taxRateRepo) FindByID(id uuid.UUID) (*domain.TaxRate, error) {
+	var rate domain.TaxRate
+	err := r.db.Preload("Account").Where("id = ?", id).First(&rate).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rate, nil
+}
+
+func (r *taxRateRepo) FindByTenantID(tenantID uuid.UUID, activeOnly bool) ([]domain.TaxRate, error) {
+	var rates []domain.TaxRate
+	query := r.db.Preload("Account").Where("tenant_id = ?", tenantID)
+	if activeOnly {
+		query = query.Where("is_active = ?", true)
+	}
+	err := query.Order("sort_order ASC, name ASC").Find(&rates).Error
+	return rates, err
+}
+
+// FindByOutletID returns the rates assigned to an outlet, in the order they are charged
+func (r *taxRateRepo) FindByOutletID(outletID uuid.UUID) ([]domain.TaxRate, error) {
+	var rates []domain.TaxRate
+	err := r.db.
+		Joins("JOIN outlet_tax_rates ON outlet_tax_rates.tax_rate_id = tax_rates.id").
+		Where("outlet_tax_rates.outlet_id = ?", outletID).
+		Order("tax_rates.sort_order ASC, tax_rates.name ASC").
+		Find(&rates).Error
+	return rates, err
+}
+
+func (r *taxRateRepo) Update(rate *domain.TaxRate) error {
+	return r.db.Omit("Account").Save(rate).Error
+}
+
+func (r *taxRateRepo) Delete(id uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("tax_rate_id = ?", id).Delete(&domain.OutletTaxRate{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&domain.TaxRate{}, "id = ?", id).Error
+	})
+}
+
+// SetOutletTaxRates replaces the rates assigned to an outlet
+func (r *taxRateRepo) SetOutletTaxRates(outletID uuid.UUID, rateIDs []uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("outlet_id = ?", outletID).Delete(&domain.OutletTaxRate{}).Error; err != nil {
+			return err
+		}
+		for _, id := range rateIDs {
+			if err := tx.Create(&domain.OutletTaxRate{OutletID: outletID, TaxRateID: id}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
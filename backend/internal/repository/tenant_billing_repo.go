@@ -18,6 +18,15 @@ func (r *tenantBillingRepository) Create(billing *domain.TenantBilling) error {
 	return r.db.Create(billing).Error
 }
 
+func (r *tenantBillingRepository) FindByID(id uuid.UUID) (*domain.TenantBilling, error) {
+	var billing domain.TenantBilling
+	err := r.db.Where("id = ?", id).First(&billing).Error
+	if err != nil {
+		return nil, err
+	}
+	return &billing, nil
+}
+
 func (r *tenantBillingRepository) GetByTenantAndMonth(tenantID uuid.UUID, month string) (*domain.TenantBilling, error) {
 	var billing domain.TenantBilling
 	err := r.db.Where("tenant_id = ? AND billing_month = ?", tenantID, month).First(&billing).Error
@@ -1,9 +1,12 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/codapos/backend/internal/domain"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type transactionRepo struct {
@@ -23,7 +26,12 @@ func (r *transactionRepo) FindByID(id uuid.UUID) (*domain.Transaction, error) {
 	err := r.db.
 		Preload("Items").
 		Preload("Payments").
+		Preload("Taxes").
+		Preload("GiftCards", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC") }).
+		Preload("Splits", func(db *gorm.DB) *gorm.DB { return db.Order("split_number ASC") }).
 		Preload("Cashier").
+		Preload("TipStaff").
+		Preload("Table").
 		Preload("Outlet").
 		Where("id = ?", id).
 		First(&tx).Error
@@ -33,6 +41,36 @@ func (r *transactionRepo) FindByID(id uuid.UUID) (*domain.Transaction, error) {
 	return &tx, nil
 }
 
+func (r *transactionRepo) LockByID(id uuid.UUID) (*domain.Transaction, error) {
+	var tx domain.Transaction
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Preload("Items").
+		Preload("Payments").
+		Preload("Taxes").
+		Preload("Splits", func(db *gorm.DB) *gorm.DB { return db.Order("split_number ASC") }).
+		Where("id = ?", id).
+		First(&tx).Error
+	if err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
+
+func (r *transactionRepo) FindByIdempotencyKey(tenantID uuid.UUID, key string) (*domain.Transaction, error) {
+	var tx domain.Transaction
+	err := r.db.
+		Preload("Items").
+		Preload("Payments").
+		Preload("Taxes").
+		Preload("Splits", func(db *gorm.DB) *gorm.DB { return db.Order("split_number ASC") }).
+		Where("tenant_id = ? AND idempotency_key = ?", tenantID, key).
+		First(&tx).Error
+	if err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
+
 func (r *transactionRepo) FindByTenantID(tenantID uuid.UUID, outletID *uuid.UUID, limit, offset int) ([]domain.Transaction, int64, error) {
 	var transactions []domain.Transaction
 	var total int64
@@ -57,11 +95,122 @@ func (r *transactionRepo) FindByTenantID(tenantID uuid.UUID, outletID *uuid.UUID
 	return transactions, total, err
 }
 
+func (r *transactionRepo) FindRefunds(originalID uuid.UUID) ([]domain.Transaction, error) {
+	var refunds []domain.Transaction
+	err := r.db.
+		Preload("Items").
+		Preload("Payments").
+		Where("original_transaction_id = ? AND type = ?", originalID, domain.TransactionTypeRefund).
+		Order("created_at ASC").
+		Find(&refunds).Error
+	return refunds, err
+}
+
+func (r *transactionRepo) FindByJournalStatus(tenantID uuid.UUID, status string) ([]domain.Transaction, error) {
+	var transactions []domain.Transaction
+	err := r.db.
+		Where("tenant_id = ? AND journal_status = ?", tenantID, status).
+		Order("created_at ASC").
+		Find(&transactions).Error
+	return transactions, err
+}
+
+func (r *transactionRepo) FindByShiftID(shiftID uuid.UUID) ([]domain.Transaction, error) {
+	var transactions []domain.Transaction
+	err := r.db.
+		Preload("Payments").
+		Preload("Splits").
+		Where("shift_id = ? OR id IN (?)", shiftID,
+			r.db.Model(&domain.TransactionPayment{}).Select("transaction_id").Where("shift_id = ?", shiftID)).
+		Order("created_at ASC").
+		Find(&transactions).Error
+	return transactions, err
+}
+
+// FindHeld returns the unpaid held orders and open bills, oldest first
+func (r *transactionRepo) FindHeld(tenantID uuid.UUID, outletID *uuid.UUID) ([]domain.Transaction, error) {
+	var transactions []domain.Transaction
+	query := r.db.Where("tenant_id = ? AND type = ? AND status = ?", tenantID, domain.TransactionTypeSale, domain.TransactionStatusPending)
+	if outletID != nil {
+		query = query.Where("outlet_id = ?", *outletID)
+	}
+	err := query.
+		Preload("Items").
+		Preload("Splits", func(db *gorm.DB) *gorm.DB { return db.Order("split_number ASC") }).
+		Preload("Cashier").
+		Preload("Table").
+		Order("created_at ASC").
+		Find(&transactions).Error
+	return transactions, err
+}
+
+// ExpireHeld marks the held orders whose expiry has passed as expired. An order being
+// settled holds its row lock and is skipped; settling checks the expiry itself.
+func (r *transactionRepo) ExpireHeld(tenantID *uuid.UUID, now time.Time) ([]domain.Transaction, error) {
+	var expired []domain.Transaction
+	query := r.db.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("type = ? AND status = ? AND expires_at < ?", domain.TransactionTypeSale, domain.TransactionStatusPending, now)
+	if tenantID != nil {
+		query = query.Where("tenant_id = ?", *tenantID)
+	}
+	if err := query.Preload("Items").Find(&expired).Error; err != nil {
+		return nil, err
+	}
+	if len(expired) == 0 {
+		return nil, nil
+	}
+	ids := make([]uuid.UUID, len(expired))
+	for i := range expired {
+		ids[i] = expired[i].ID
+		expired[i].Status = domain.TransactionStatusExpired
+	}
+	err := r.db.Model(&domain.Transaction{}).
+		Where("id IN ?", ids).
+		Update("status", domain.TransactionStatusExpired).Error
+	if err != nil {
+		return nil, err
+	}
+	return expired, nil
+}
+
+// ReplaceItems stores a re-priced held order: its items and taxes are replaced
+func (r *transactionRepo) ReplaceItems(transaction *domain.Transaction) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("transaction_id = ?", transaction.ID).Delete(&domain.TransactionItem{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("transaction_id = ?", transaction.ID).Delete(&domain.TransactionTax{}).Error; err != nil {
+			return err
+		}
+		for i := range transaction.Items {
+			transaction.Items[i].ID = uuid.Nil
+			transaction.Items[i].TransactionID = transaction.ID
+		}
+		for i := range transaction.Taxes {
+			transaction.Taxes[i].ID = uuid.Nil
+			transaction.Taxes[i].TransactionID = transaction.ID
+		}
+		if len(transaction.Items) > 0 {
+			if err := tx.Create(&transaction.Items).Error; err != nil {
+				return err
+			}
+		}
+		if len(transaction.Taxes) > 0 {
+			if err := tx.Create(&transaction.Taxes).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Omit(clause.Associations).Save(transaction).Error
+	})
+}
+
 func (r *transactionRepo) FindByNumber(number string) (*domain.Transaction, error) {
 	var tx domain.Transaction
 	err := r.db.
 		Preload("Items").
 		Preload("Payments").
+		Preload("Taxes").
+		Preload("GiftCards", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC") }).
 		Where("transaction_number = ?", number).
 		First(&tx).Error
 	if err != nil {
@@ -74,6 +223,37 @@ func (r *transactionRepo) Update(transaction *domain.Transaction) error {
 	return r.db.Save(transaction).Error
 }
 
+func (r *transactionRepo) UpdateItem(item *domain.TransactionItem) error {
+	return r.db.Save(item).Error
+}
+
+func (r *transactionRepo) UpdateJournalStatus(id uuid.UUID, status, message string) error {
+	return r.db.Model(&domain.Transaction{}).Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{"journal_status": status, "journal_error": message}).Error
+}
+
+func (r *transactionRepo) UpdatePoints(id uuid.UUID, earned, redeemed int64) error {
+	return r.db.Model(&domain.Transaction{}).Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{"points_earned": earned, "points_redeemed": redeemed}).Error
+}
+
+func (r *transactionRepo) CreatePayment(payment *domain.TransactionPayment) error {
+	return r.db.Create(payment).Error
+}
+
+func (r *transactionRepo) FindPaymentByReference(reference string) (*domain.TransactionPayment, error) {
+	var payment domain.TransactionPayment
+	err := r.db.Where("reference_number = ?", reference).Order("created_at ASC").First(&payment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+func (r *transactionRepo) UpdatePayment(payment *domain.TransactionPayment) error {
+	return r.db.Save(payment).Error
+}
+
 func (r *transactionRepo) Delete(id uuid.UUID) error {
 	return r.db.Delete(&domain.Transaction{}, "id = ?", id).Error
 }
@@ -81,21 +261,22 @@ func (r *transactionRepo) Delete(id uuid.UUID) error {
 func (r *transactionRepo) GetMDRMonthlyAggregation(month, year int) ([]struct {
 	TenantID    uuid.UUID
 	TotalTrx    int
-	TotalMDRVal float64
+	TotalMDRVal domain.Money
 }, error) {
 	var results []struct {
 		TenantID    uuid.UUID
 		TotalTrx    int
-		TotalMDRVal float64
+		TotalMDRVal domain.Money
 	}
 
 	// Calculate totals for a given month and year
 	// NOTE: We rely on total_mdr_merchant which includes fee_midtrans + fee_codapos
 	// We only bill on Cashless / Non-Cash payments, where total_mdr_merchant > 0
+	// Voided sales never settle, so they are left out of the MDR bill
 	err := r.db.Model(&domain.Transaction{}).
 		Select("tenant_id, COUNT(id) as total_trx, SUM(total_mdr_merchant) as total_mdr_val").
 		Where("EXTRACT(MONTH FROM created_at) = ? AND EXTRACT(YEAR FROM created_at) = ?", month, year).
-		Where("status = ?", domain.TransactionStatusCompleted).
+		Where("status IN ?", []string{domain.TransactionStatusCompleted, domain.TransactionStatusPartiallyRefunded}).
 		Where("total_mdr_merchant > 0").
 		Group("tenant_id").
 		Scan(&results).Error
@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"github.com/codapos/backend/internal/domain"
+	"gorm.io/gorm"
+)
+
+type unitOfWork struct {
+	db *gorm.DB
+}
+
+func NewUnitOfWork(db *gorm.DB) domain.UnitOfWork {
+	return &unitOfWork{db: db}
+}
+
+func (u *unitOfWork) Do(fn func(repos domain.Repositories) error) error {
+	return u.db.Transaction(func(tx *gorm.DB) error {
+		return fn(domain.Repositories{
+			Transactions: NewTransactionRepository(tx),
+			SplitBills:   NewSplitBillRepository(tx),
+			Inventory:    NewInventoryRepository(tx),
+			Accounting:   NewAccountingRepository(tx),
+			Sequences:    NewSequenceRepository(tx),
+			Tables:       NewTableRepository(tx),
+			Kitchen:      NewKitchenRepository(tx),
+			Deliveries:   NewDeliveryRepository(tx),
+			Loyalty:      NewLoyaltyRepository(tx),
+			GiftCards:    NewGiftCardRepository(tx),
+			Shifts:       NewShiftRepository(tx),
+		})
+	})
+}
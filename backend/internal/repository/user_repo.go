@@ -1,9 +1,12 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/codapos/backend/internal/domain"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type userRepo struct {
@@ -59,3 +62,37 @@ func (r *userRepo) Update(user *domain.User) error {
 func (r *userRepo) Delete(id uuid.UUID) error {
 	return r.db.Delete(&domain.User{}, id).Error
 }
+
+func (r *userRepo) FindPINAttempt(subject string) (*domain.PINAttempt, error) {
+	var attempt domain.PINAttempt
+	err := r.db.Where("subject = ?", subject).First(&attempt).Error
+	if err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+func (r *userRepo) RecordPINFailure(subject string) (int, error) {
+	// Counted in one statement so concurrent attempts cannot lose an increment
+	attempt := domain.PINAttempt{Subject: subject, Failures: 1}
+	err := r.db.Clauses(
+		clause.OnConflict{
+			Columns: []clause.Column{{Name: "subject"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"failures":   gorm.Expr("pin_attempts.failures + 1"),
+				"updated_at": time.Now(),
+			}),
+		},
+		clause.Returning{Columns: []clause.Column{{Name: "failures"}}},
+	).Create(&attempt).Error
+	return attempt.Failures, err
+}
+
+func (r *userRepo) LockPIN(subject string, until time.Time) error {
+	return r.db.Model(&domain.PINAttempt{}).Where("subject = ?", subject).
+		Updates(map[string]interface{}{"failures": 0, "locked_until": until}).Error
+}
+
+func (r *userRepo) ClearPINAttempts(subjects ...string) error {
+	return r.db.Where("subject IN ?", subjects).Delete(&domain.PINAttempt{}).Error
+}
@@ -30,6 +30,8 @@ func (u *AccountingUsecase) InitializeDefaultCOA(tenantID uuid.UUID) error {
 		{TenantID: tenantID, Code: "2000", Name: "Kewajiban", Type: domain.AccountTypeLiability, IsSystem: true},
 		{TenantID: tenantID, Code: "2100", Name: "Hutang Usaha", Type: domain.AccountTypeLiability, SubType: domain.AccountSubTypePayable, IsSystem: true},
 		{TenantID: tenantID, Code: "2200", Name: "Hutang Pajak", Type: domain.AccountTypeLiability, SubType: domain.AccountSubTypeTax, IsSystem: true},
+		{TenantID: tenantID, Code: "2300", Name: "Hutang Tip Karyawan", Type: domain.AccountTypeLiability, SubType: domain.AccountSubTypeTips, IsSystem: true},
+		{TenantID: tenantID, Code: "2400", Name: "Hutang Voucher Gift Card", Type: domain.AccountTypeLiability, SubType: domain.AccountSubTypeGiftCard, IsSystem: true},
 
 		// Equity
 		{TenantID: tenantID, Code: "3000", Name: "Modal", Type: domain.AccountTypeEquity, IsSystem: true},
@@ -40,6 +42,8 @@ func (u *AccountingUsecase) InitializeDefaultCOA(tenantID uuid.UUID) error {
 		{TenantID: tenantID, Code: "4000", Name: "Pendapatan", Type: domain.AccountTypeRevenue, IsSystem: true},
 		{TenantID: tenantID, Code: "4100", Name: "Penjualan", Type: domain.AccountTypeRevenue, SubType: domain.AccountSubTypeSales, IsSystem: true},
 		{TenantID: tenantID, Code: "4200", Name: "Pendapatan Lain-lain", Type: domain.AccountTypeRevenue, IsSystem: true},
+		{TenantID: tenantID, Code: "4300", Name: "Pendapatan Service Charge", Type: domain.AccountTypeRevenue, SubType: domain.AccountSubTypeServiceCharge, IsSystem: true},
+		{TenantID: tenantID, Code: "4400", Name: "Pendapatan Voucher Kedaluwarsa", Type: domain.AccountTypeRevenue, SubType: domain.AccountSubTypeBreakage, IsSystem: true},
 
 		// Expenses
 		{TenantID: tenantID, Code: "5000", Name: "Beban", Type: domain.AccountTypeExpense, IsSystem: true},
@@ -47,6 +51,7 @@ func (u *AccountingUsecase) InitializeDefaultCOA(tenantID uuid.UUID) error {
 		{TenantID: tenantID, Code: "5200", Name: "Beban Gaji", Type: domain.AccountTypeExpense, IsSystem: true},
 		{TenantID: tenantID, Code: "5300", Name: "Beban Sewa", Type: domain.AccountTypeExpense, IsSystem: true},
 		{TenantID: tenantID, Code: "5400", Name: "Beban Operasional", Type: domain.AccountTypeExpense, IsSystem: true},
+		{TenantID: tenantID, Code: "5500", Name: "Beban Program Loyalitas", Type: domain.AccountTypeExpense, SubType: domain.AccountSubTypeLoyalty, IsSystem: true},
 	}
 
 	for _, acc := range defaultAccounts {
@@ -12,10 +12,13 @@ import (
 
 type DeliveryUsecase struct {
 	deliveryRepo domain.DeliveryRepository
+	uow          domain.UnitOfWork
+	sequences    *SequenceUsecase
+	pos          *POSUsecase
 }
 
-func NewDeliveryUsecase(dr domain.DeliveryRepository) *DeliveryUsecase {
-	return &DeliveryUsecase{deliveryRepo: dr}
+func NewDeliveryUsecase(dr domain.DeliveryRepository, uow domain.UnitOfWork, sequences *SequenceUsecase, pos *POSUsecase) *DeliveryUsecase {
+	return &DeliveryUsecase{deliveryRepo: dr, uow: uow, sequences: sequences, pos: pos}
 }
 
 // CreateOrder creates a new delivery order
@@ -24,15 +27,23 @@ func (u *DeliveryUsecase) CreateOrder(tenantID uuid.UUID, req domain.CreateDeliv
 		return nil, errors.New("pickup and dropoff addresses are required")
 	}
 
-	// Calculate distance and fee
-	distanceKm := u.calculateDistance(req.PickupLat, req.PickupLng, req.DropoffLat, req.DropoffLng)
-	deliveryFee := u.calculateFee(distanceKm)
-	estimatedTime := u.estimateTime(distanceKm)
+	// A retried request with the same key returns the order created the first time
+	if req.IdempotencyKey != "" {
+		if existing, err := u.deliveryRepo.FindOrderByIdempotencyKey(tenantID, req.IdempotencyKey); err == nil {
+			return existing, nil
+		}
+	}
+
+	quote := u.Quote(req.PickupLat, req.PickupLng, req.DropoffLat, req.DropoffLng)
 
+	status := domain.DeliveryStatusPending
+	if req.AwaitPayment {
+		status = domain.DeliveryStatusWaitingPayment
+	}
 	order := &domain.DeliveryOrder{
 		TenantID:       tenantID,
-		OrderNumber:    fmt.Sprintf("DLV-%s-%d", tenantID.String()[:6], time.Now().UnixMilli()%100000),
-		Status:         domain.DeliveryStatusPending,
+		OutletID:       req.OutletID,
+		Status:         status,
 		PickupAddress:  req.PickupAddress,
 		PickupLat:      req.PickupLat,
 		PickupLng:      req.PickupLng,
@@ -45,9 +56,12 @@ func (u *DeliveryUsecase) CreateOrder(tenantID uuid.UUID, req domain.CreateDeliv
 		DropoffPhone:   req.DropoffPhone,
 		PackageDesc:    req.PackageDesc,
 		Notes:          req.Notes,
-		DistanceKm:     distanceKm,
-		DeliveryFee:    deliveryFee,
-		EstimatedTime:  estimatedTime,
+		DistanceKm:     quote.DistanceKm,
+		DeliveryFee:    quote.DeliveryFee,
+		EstimatedTime:  quote.EstimatedTime,
+	}
+	if req.IdempotencyKey != "" {
+		order.IdempotencyKey = &req.IdempotencyKey
 	}
 
 	if req.TransactionID != "" {
@@ -63,12 +77,36 @@ func (u *DeliveryUsecase) CreateOrder(tenantID uuid.UUID, req domain.CreateDeliv
 		}
 	}
 
-	if err := u.deliveryRepo.CreateOrder(order); err != nil {
+	// The number is issued inside the unit of work so a failed insert leaves no gap
+	err := u.uow.Do(func(repos domain.Repositories) error {
+		number, err := u.sequences.NextWith(repos.Sequences, tenantID, nil, domain.SequenceDelivery)
+		if err != nil {
+			return err
+		}
+		order.OrderNumber = number
+		return repos.Deliveries.CreateOrder(order)
+	})
+	if err != nil {
+		if req.IdempotencyKey != "" {
+			if existing, findErr := u.deliveryRepo.FindOrderByIdempotencyKey(tenantID, req.IdempotencyKey); findErr == nil {
+				return existing, nil
+			}
+		}
 		return nil, errors.New("failed to create delivery order")
 	}
 	return order, nil
 }
 
+// Quote works out the distance, fee and travel time of a delivery
+func (u *DeliveryUsecase) Quote(pickupLat, pickupLng, dropoffLat, dropoffLng float64) domain.DeliveryQuote {
+	distanceKm := u.calculateDistance(pickupLat, pickupLng, dropoffLat, dropoffLng)
+	return domain.DeliveryQuote{
+		DistanceKm:    distanceKm,
+		DeliveryFee:   u.calculateFee(distanceKm),
+		EstimatedTime: u.estimateTime(distanceKm),
+	}
+}
+
 // GetOrders returns delivery orders for a tenant
 func (u *DeliveryUsecase) GetOrders(tenantID uuid.UUID, status string, limit, offset int) ([]domain.DeliveryOrder, int64, error) {
 	if limit <= 0 {
@@ -82,8 +120,9 @@ func (u *DeliveryUsecase) GetOrderByID(id uuid.UUID) (*domain.DeliveryOrder, err
 	return u.deliveryRepo.FindOrderByID(id)
 }
 
-// UpdateOrderStatus updates delivery status (GoSend-style flow)
-func (u *DeliveryUsecase) UpdateOrderStatus(orderID uuid.UUID, req domain.UpdateDeliveryStatusRequest) (*domain.DeliveryOrder, error) {
+// UpdateOrderStatus updates delivery status (GoSend-style flow). Cancelling a storefront
+// order voids the sale booked for it.
+func (u *DeliveryUsecase) UpdateOrderStatus(orderID, userID uuid.UUID, req domain.UpdateDeliveryStatusRequest) (*domain.DeliveryOrder, error) {
 	order, err := u.deliveryRepo.FindOrderByID(orderID)
 	if err != nil {
 		return nil, errors.New("order not found")
@@ -95,6 +134,7 @@ func (u *DeliveryUsecase) UpdateOrderStatus(orderID uuid.UUID, req domain.Update
 	}
 
 	now := time.Now()
+	from := order.Status
 	order.Status = req.Status
 
 	switch req.Status {
@@ -115,8 +155,25 @@ func (u *DeliveryUsecase) UpdateOrderStatus(orderID uuid.UUID, req domain.Update
 		order.DriverLng = req.DriverLng
 	}
 
-	if err := u.deliveryRepo.UpdateOrder(order); err != nil {
-		return nil, errors.New("failed to update order")
+	if req.Status == domain.DeliveryStatusCancelled {
+		if err := u.pos.CancelOnlineOrder(order, from, &userID); err != nil {
+			return nil, err
+		}
+		return order, nil
+	}
+	// A gateway notification may cancel the order meanwhile; the row is locked so the
+	// status is not written back over it
+	err = u.uow.Do(func(repos domain.Repositories) error {
+		if err := lockOnlineOrder(repos, order, from); err != nil {
+			return err
+		}
+		if err := repos.Deliveries.UpdateOrder(order); err != nil {
+			return errors.New("failed to update order")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	return order, nil
 }
@@ -231,10 +288,11 @@ func (u *DeliveryUsecase) UpdateDriverLocation(userID uuid.UUID, lat, lng float6
 	return u.deliveryRepo.UpdateDriver(driver)
 }
 
-// isValidTransition checks if a status transition is valid (GoSend-style flow)
+// isValidTransition checks if a status transition is valid (GoSend-style flow).
+// An order waiting for payment only becomes pending when the gateway confirms it.
 func (u *DeliveryUsecase) isValidTransition(current, next string) bool {
 	validTransitions := map[string][]string{
-		domain.DeliveryStatusWaitingPayment: {domain.DeliveryStatusPending, domain.DeliveryStatusCancelled},
+		domain.DeliveryStatusWaitingPayment: {domain.DeliveryStatusCancelled},
 		domain.DeliveryStatusPending:        {domain.DeliveryStatusPreparing, domain.DeliveryStatusCancelled},
 		domain.DeliveryStatusPreparing:      {domain.DeliveryStatusOnDelivery, domain.DeliveryStatusCancelled},
 		domain.DeliveryStatusOnDelivery:     {domain.DeliveryStatusDelivered},
@@ -268,11 +326,11 @@ func (u *DeliveryUsecase) calculateDistance(lat1, lng1, lat2, lng2 float64) floa
 }
 
 // calculateFee calculates delivery fee
-func (u *DeliveryUsecase) calculateFee(distanceKm float64) float64 {
+func (u *DeliveryUsecase) calculateFee(distanceKm float64) domain.Money {
 	baseFee := 5000.0  // Rp 5,000 base
 	perKmFee := 3000.0 // Rp 3,000/km
 	fee := baseFee + (distanceKm * perKmFee)
-	return math.Round(fee/500) * 500 // round to nearest 500
+	return domain.Rupiah(int64(math.Round(fee/500) * 500)) // round to nearest 500
 }
 
 // estimateTime estimates delivery time in minutes
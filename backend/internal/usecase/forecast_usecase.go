@@ -75,14 +75,14 @@ func (u *ForecastUsecase) aggregateDailySales(txns []domain.Transaction, days in
 	dailyMap := make(map[string]*domain.DailySummary)
 
 	for _, tx := range txns {
-		if tx.CreatedAt.Before(cutoff) || tx.Status != domain.TransactionStatusCompleted {
+		if tx.CreatedAt.Before(cutoff) || !domain.IsReportableStatus(tx.Status) {
 			continue
 		}
 		dateKey := tx.CreatedAt.Format("2006-01-02")
 		if _, ok := dailyMap[dateKey]; !ok {
 			dailyMap[dateKey] = &domain.DailySummary{Date: dateKey}
 		}
-		dailyMap[dateKey].Revenue += tx.TotalAmount
+		dailyMap[dateKey].Revenue += tx.TotalAmount.Float64()
 		dailyMap[dateKey].OrderCount++
 	}
 
@@ -256,7 +256,7 @@ func (u *ForecastUsecase) forecastProducts(txns []domain.Transaction, forecastDa
 	products := make(map[string]*productAgg)
 
 	for _, tx := range txns {
-		if tx.Status != domain.TransactionStatusCompleted {
+		if !domain.IsReportableStatus(tx.Status) {
 			continue
 		}
 		for _, item := range tx.Items {
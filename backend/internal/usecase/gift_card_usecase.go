@@ -0,0 +1,542 @@
+package usecase
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codapos/backend/internal/domain"
+	"github.com/google/uuid"
+)
+
+// ErrInsufficientGiftCardBalance is returned when a gift card pays more than it holds
+var ErrInsufficientGiftCardBalance = errors.New("gift card balance is not enough")
+
+// ErrGiftCardUsed is returned when a sold gift card is refunded after it was spent from
+var ErrGiftCardUsed = errors.New("gift card has been used and cannot be taken back")
+
+// giftCardAlphabet leaves out characters that are easily misread (0/O, 1/I/L)
+const giftCardAlphabet = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+
+type GiftCardUsecase struct {
+	giftCardRepo domain.GiftCardRepository
+	productRepo  domain.ProductRepository
+	uow          domain.UnitOfWork
+	sequences    *SequenceUsecase
+}
+
+func NewGiftCardUsecase(gr domain.GiftCardRepository, pr domain.ProductRepository, uow domain.UnitOfWork, sequences *SequenceUsecase) *GiftCardUsecase {
+	return &GiftCardUsecase{
+		giftCardRepo: gr,
+		productRepo:  pr,
+		uow:          uow,
+		sequences:    sequences,
+	}
+}
+
+// List returns the gift cards of a tenant, newest first, after expiring the lapsed ones
+func (u *GiftCardUsecase) List(tenantID uuid.UUID, status string, page, perPage int) ([]domain.GiftCard, int64, error) {
+	if err := u.expire(&tenantID); err != nil {
+		return nil, 0, err
+	}
+	return u.giftCardRepo.FindByTenantID(tenantID, status, perPage, (page-1)*perPage)
+}
+
+// Lookup finds a gift card by its code, with its balance movements
+func (u *GiftCardUsecase) Lookup(tenantID uuid.UUID, code string) (*domain.GiftCard, error) {
+	if err := u.expire(&tenantID); err != nil {
+		return nil, err
+	}
+	card, err := u.giftCardRepo.FindByCode(tenantID, normalizeGiftCardCode(code))
+	if err != nil {
+		return nil, errors.New("gift card not found")
+	}
+	return card, nil
+}
+
+// attach checks the gift card payments of a sale before it is booked and links them to
+// their cards. Gift cards pay at most due, as they give no change, and cannot buy
+// other gift cards.
+func (u *GiftCardUsecase) attach(tenantID uuid.UUID, tx *domain.Transaction, due domain.Money, requests []domain.PaymentRequest, payments []domain.TransactionPayment) error {
+	now := time.Now()
+	charged := make(map[uuid.UUID]domain.Money)
+	var paid domain.Money
+	for i, p := range requests {
+		if p.PaymentMethod != domain.PaymentGiftCard {
+			continue
+		}
+		if p.Amount <= 0 {
+			return errors.New("gift card payment must be greater than zero")
+		}
+		if strings.TrimSpace(p.GiftCardCode) == "" {
+			return errors.New("gift card code is required")
+		}
+		for _, item := range tx.Items {
+			if item.IsGiftCard {
+				return errors.New("gift cards cannot be bought with a gift card")
+			}
+		}
+		card, err := u.giftCardRepo.FindByCode(tenantID, normalizeGiftCardCode(p.GiftCardCode))
+		if err != nil {
+			return errors.New("gift card not found")
+		}
+		charged[card.ID] += p.Amount
+		if err := checkGiftCard(card, charged[card.ID], now); err != nil {
+			return err
+		}
+		paid += p.Amount
+		payments[i].GiftCardID = &card.ID
+		payments[i].ReferenceNumber = maskGiftCardCode(card.Code)
+	}
+	if paid > due {
+		return errors.New("gift cards cannot pay more than the total")
+	}
+	return nil
+}
+
+// book moves the balances of the gift cards a transaction's payments were taken from,
+// or refunded to
+func (u *GiftCardUsecase) book(repos domain.Repositories, tx *domain.Transaction, payments []domain.TransactionPayment) error {
+	now := time.Now()
+	for _, p := range payments {
+		if p.PaymentMethod != domain.PaymentGiftCard || p.GiftCardID == nil {
+			continue
+		}
+		if p.Amount < 0 {
+			if err := reloadGiftCard(repos, *p.GiftCardID, -p.Amount, tx.ID, &tx.CashierID, now); err != nil {
+				return err
+			}
+			continue
+		}
+
+		card, err := repos.GiftCards.Lock(*p.GiftCardID)
+		if err != nil {
+			return errors.New("gift card not found")
+		}
+		if err := checkGiftCard(card, p.Amount, now); err != nil {
+			return err
+		}
+		card.Balance -= p.Amount
+		if card.Balance == 0 {
+			card.Status = domain.GiftCardStatusSpent
+		}
+		if err := repos.GiftCards.Update(card); err != nil {
+			return fmt.Errorf("failed to update gift card: %w", err)
+		}
+		if err := repos.GiftCards.CreateMovement(&domain.GiftCardMovement{
+			GiftCardID:    card.ID,
+			TransactionID: &tx.ID,
+			Type:          domain.GiftCardMovementRedeem,
+			Amount:        -p.Amount,
+			BalanceAfter:  card.Balance,
+			CreatedBy:     &tx.CashierID,
+		}); err != nil {
+			return fmt.Errorf("failed to record gift card movement: %w", err)
+		}
+	}
+	return nil
+}
+
+// issue creates a gift card worth the unit price for every card a completed sale bought.
+// The first card sold opens the liability and breakage accounts if the chart of
+// accounts predates gift cards.
+func (u *GiftCardUsecase) issue(repos domain.Repositories, tx *domain.Transaction) error {
+	now := time.Now()
+	opened := false
+	for _, item := range tx.Items {
+		if !item.IsGiftCard || item.Quantity <= 0 {
+			continue
+		}
+		if !opened {
+			if _, _, err := openGiftCardAccounts(repos, tx.TenantID); err != nil {
+				return err
+			}
+			opened = true
+		}
+
+		var expiresAt *time.Time
+		if product, err := u.productRepo.FindByID(item.ProductID); err == nil && product.GiftCardValidDays > 0 {
+			t := now.AddDate(0, 0, product.GiftCardValidDays)
+			expiresAt = &t
+		}
+		productID, itemID := item.ProductID, item.ID
+		for n := 0; n < int(item.Quantity); n++ {
+			code, err := newGiftCardCode()
+			if err != nil {
+				return err
+			}
+			card := &domain.GiftCard{
+				TenantID:          tx.TenantID,
+				Code:              code,
+				InitialAmount:     item.UnitPrice,
+				Balance:           item.UnitPrice,
+				Status:            domain.GiftCardStatusActive,
+				ExpiresAt:         expiresAt,
+				ProductID:         &productID,
+				OutletID:          &tx.OutletID,
+				CustomerID:        tx.CustomerID,
+				TransactionID:     &tx.ID,
+				TransactionItemID: &itemID,
+			}
+			if err := repos.GiftCards.Create(card); err != nil {
+				return fmt.Errorf("failed to issue gift card: %w", err)
+			}
+			if err := repos.GiftCards.CreateMovement(&domain.GiftCardMovement{
+				GiftCardID:    card.ID,
+				TransactionID: &tx.ID,
+				Type:          domain.GiftCardMovementIssue,
+				Amount:        card.Balance,
+				BalanceAfter:  card.Balance,
+				CreatedBy:     &tx.CashierID,
+			}); err != nil {
+				return fmt.Errorf("failed to record gift card movement: %w", err)
+			}
+			tx.GiftCards = append(tx.GiftCards, *card)
+		}
+	}
+	return nil
+}
+
+// voidIssued voids count unused cards issued on a sale line, for a refund or void of
+// that line. A card that has been spent from can no longer be taken back.
+func (u *GiftCardUsecase) voidIssued(repos domain.Repositories, itemID uuid.UUID, count int, transactionID, createdBy *uuid.UUID) error {
+	cards, err := repos.GiftCards.FindByTransactionItem(itemID)
+	if err != nil {
+		return fmt.Errorf("failed to load gift cards: %w", err)
+	}
+	for i := range cards {
+		if count == 0 {
+			break
+		}
+		card, err := repos.GiftCards.Lock(cards[i].ID)
+		if err != nil {
+			return fmt.Errorf("failed to lock gift card: %w", err)
+		}
+		if card.Status != domain.GiftCardStatusActive || card.Balance != card.InitialAmount {
+			continue
+		}
+		voided := card.Balance
+		card.Balance = 0
+		card.Status = domain.GiftCardStatusVoided
+		if err := repos.GiftCards.Update(card); err != nil {
+			return fmt.Errorf("failed to void gift card: %w", err)
+		}
+		if err := repos.GiftCards.CreateMovement(&domain.GiftCardMovement{
+			GiftCardID:    card.ID,
+			TransactionID: transactionID,
+			Type:          domain.GiftCardMovementVoid,
+			Amount:        -voided,
+			CreatedBy:     createdBy,
+		}); err != nil {
+			return fmt.Errorf("failed to record gift card movement: %w", err)
+		}
+		count--
+	}
+	if count > 0 {
+		return ErrGiftCardUsed
+	}
+	return nil
+}
+
+// reverse undoes the gift cards of a voided sale: the cards it sold are voided and the
+// cards it was paid with get their balance back
+func (u *GiftCardUsecase) reverse(repos domain.Repositories, tx *domain.Transaction, createdBy *uuid.UUID) error {
+	for _, item := range tx.Items {
+		if item.IsGiftCard {
+			if err := u.voidIssued(repos, item.ID, int(item.Quantity), &tx.ID, createdBy); err != nil {
+				return err
+			}
+		}
+	}
+	now := time.Now()
+	for _, p := range tx.Payments {
+		if p.PaymentMethod == domain.PaymentGiftCard && p.GiftCardID != nil && p.Amount > 0 {
+			if err := reloadGiftCard(repos, *p.GiftCardID, p.Amount, tx.ID, createdBy, now); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// refundable tells whether a gift card can still take back the value spent from it
+func (u *GiftCardUsecase) refundable(id uuid.UUID) bool {
+	card, err := u.giftCardRepo.FindByID(id)
+	if err != nil {
+		return false
+	}
+	return checkGiftCard(card, 0, time.Now()) == nil
+}
+
+// ExpireDue expires the lapsed gift cards of every tenant. It runs on a schedule so
+// breakage is booked when cards lapse rather than when they are next looked at.
+func (u *GiftCardUsecase) ExpireDue() error {
+	return u.expire(nil)
+}
+
+// expire closes the gift cards (of one tenant, or of all when tenantID is nil) whose
+// expiry has passed. What is left on them is no longer owed and is recognised as
+// breakage revenue. Each card is expired on its own, so one failure does not hold
+// back the rest.
+func (u *GiftCardUsecase) expire(tenantID *uuid.UUID) error {
+	now := time.Now()
+	cards, err := u.giftCardRepo.FindExpiring(tenantID, now)
+	if err != nil {
+		return fmt.Errorf("failed to load expired gift cards: %w", err)
+	}
+	var errs []error
+	for _, c := range cards {
+		if err := u.expireCard(c.ID, now); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (u *GiftCardUsecase) expireCard(id uuid.UUID, now time.Time) error {
+	return u.uow.Do(func(repos domain.Repositories) error {
+		card, err := repos.GiftCards.Lock(id)
+		if err != nil {
+			return fmt.Errorf("failed to lock gift card: %w", err)
+		}
+		// Someone else may have expired or voided it meanwhile
+		if card.ExpiresAt == nil || card.ExpiresAt.After(now) ||
+			(card.Status != domain.GiftCardStatusActive && card.Status != domain.GiftCardStatusSpent) {
+			return nil
+		}
+		breakage := card.Balance
+		card.Balance = 0
+		card.Status = domain.GiftCardStatusExpired
+		card.ExpiredAt = &now
+		if err := repos.GiftCards.Update(card); err != nil {
+			return fmt.Errorf("failed to expire gift card: %w", err)
+		}
+		if breakage == 0 {
+			return nil
+		}
+		if err := repos.GiftCards.CreateMovement(&domain.GiftCardMovement{
+			GiftCardID: card.ID,
+			Type:       domain.GiftCardMovementExpire,
+			Amount:     -breakage,
+		}); err != nil {
+			return fmt.Errorf("failed to record gift card movement: %w", err)
+		}
+		return u.postBreakage(repos, card, breakage)
+	})
+}
+
+// postBreakage moves the balance left on an expired card from the gift card liability
+// to breakage revenue, dated when the card expired
+func (u *GiftCardUsecase) postBreakage(repos domain.Repositories, card *domain.GiftCard, amount domain.Money) error {
+	liability, breakage, err := openGiftCardAccounts(repos, card.TenantID)
+	if err != nil {
+		return err
+	}
+	if liability == uuid.Nil || breakage == uuid.Nil {
+		// No chart of accounts yet, so the card's sale was never booked either
+		return nil
+	}
+
+	journal := &domain.JournalEntry{
+		TenantID:      card.TenantID,
+		OutletID:      card.OutletID,
+		Date:          *card.ExpiresAt,
+		Description:   fmt.Sprintf("Auto journal for expired gift card %s", maskGiftCardCode(card.Code)),
+		Source:        domain.JournalSourceGiftCard,
+		ReferenceType: "gift_card",
+		ReferenceID:   &card.ID,
+		Status:        "posted",
+		TotalDebit:    amount,
+		TotalCredit:   amount,
+		Lines: []domain.JournalEntryLine{
+			{AccountID: liability, Debit: amount, Description: "Gift card balance expired"},
+			{AccountID: breakage, Credit: amount, Description: "Gift card breakage"},
+		},
+	}
+	journal.EntryNumber, err = u.sequences.NextWith(repos.Sequences, card.TenantID, card.OutletID, domain.SequenceJournal)
+	if err != nil {
+		return err
+	}
+	if err := repos.Accounting.CreateJournal(journal); err != nil {
+		return fmt.Errorf("failed to post breakage journal: %w", err)
+	}
+	// Both accounts are credit-natural: the liability shrinks and the revenue grows
+	if err := repos.Accounting.UpdateAccountBalance(liability, -amount); err != nil {
+		return fmt.Errorf("failed to update account balance: %w", err)
+	}
+	if err := repos.Accounting.UpdateAccountBalance(breakage, amount); err != nil {
+		return fmt.Errorf("failed to update account balance: %w", err)
+	}
+	return nil
+}
+
+// reloadGiftCard puts value back on a card, for a refund or a void of a sale it paid
+func reloadGiftCard(repos domain.Repositories, id uuid.UUID, amount domain.Money, transactionID uuid.UUID, createdBy *uuid.UUID, now time.Time) error {
+	card, err := repos.GiftCards.Lock(id)
+	if err != nil {
+		return errors.New("gift card not found")
+	}
+	if err := checkGiftCard(card, 0, now); err != nil {
+		return fmt.Errorf("cannot refund to gift card %s: %w", maskGiftCardCode(card.Code), err)
+	}
+	card.Balance += amount
+	card.Status = domain.GiftCardStatusActive
+	if err := repos.GiftCards.Update(card); err != nil {
+		return fmt.Errorf("failed to update gift card: %w", err)
+	}
+	if err := repos.GiftCards.CreateMovement(&domain.GiftCardMovement{
+		GiftCardID:    card.ID,
+		TransactionID: &transactionID,
+		Type:          domain.GiftCardMovementRefund,
+		Amount:        amount,
+		BalanceAfter:  card.Balance,
+		CreatedBy:     createdBy,
+	}); err != nil {
+		return fmt.Errorf("failed to record gift card movement: %w", err)
+	}
+	return nil
+}
+
+// checkGiftCard tells whether a card can pay amount now
+func checkGiftCard(card *domain.GiftCard, amount domain.Money, now time.Time) error {
+	switch {
+	case card.Status == domain.GiftCardStatusVoided:
+		return errors.New("gift card has been voided")
+	case card.Status == domain.GiftCardStatusExpired || (card.ExpiresAt != nil && !now.Before(*card.ExpiresAt)):
+		return errors.New("gift card has expired")
+	case card.Balance < amount:
+		return ErrInsufficientGiftCardBalance
+	}
+	return nil
+}
+
+// openGiftCardAccounts returns the gift card liability and breakage revenue accounts,
+// adding them to a chart of accounts set up before gift cards existed (2400 and 4400,
+// or the next free codes after them)
+func openGiftCardAccounts(repos domain.Repositories, tenantID uuid.UUID) (liability, breakage uuid.UUID, err error) {
+	accounts, err := repos.Accounting.FindAccountsByTenantID(tenantID)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("failed to load chart of accounts: %w", err)
+	}
+	if len(accounts) == 0 {
+		// The default chart of accounts, which has both, is not initialized yet
+		return uuid.Nil, uuid.Nil, nil
+	}
+	used := make(map[string]bool, len(accounts))
+	for _, a := range accounts {
+		switch a.SubType {
+		case domain.AccountSubTypeGiftCard:
+			liability = a.ID
+		case domain.AccountSubTypeBreakage:
+			breakage = a.ID
+		}
+		used[a.Code] = true
+	}
+	if liability == uuid.Nil {
+		liability, err = openGiftCardAccount(repos, tenantID, 2400, "Hutang Voucher Gift Card", domain.AccountTypeLiability, domain.AccountSubTypeGiftCard, used)
+		if err != nil {
+			return uuid.Nil, uuid.Nil, err
+		}
+	}
+	if breakage == uuid.Nil {
+		breakage, err = openGiftCardAccount(repos, tenantID, 4400, "Pendapatan Voucher Kedaluwarsa", domain.AccountTypeRevenue, domain.AccountSubTypeBreakage, used)
+		if err != nil {
+			return uuid.Nil, uuid.Nil, err
+		}
+	}
+	return liability, breakage, nil
+}
+
+func openGiftCardAccount(repos domain.Repositories, tenantID uuid.UUID, from int, name, accountType, subType string, used map[string]bool) (uuid.UUID, error) {
+	for next := from; next < from+100; next += 10 {
+		code := strconv.Itoa(next)
+		if used[code] {
+			continue
+		}
+		account := &domain.ChartOfAccount{
+			TenantID: tenantID,
+			Code:     code,
+			Name:     name,
+			Type:     accountType,
+			SubType:  subType,
+			IsActive: true,
+			IsSystem: true,
+		}
+		if err := repos.Accounting.CreateAccount(account); err != nil {
+			return uuid.Nil, fmt.Errorf("failed to create gift card account: %w", err)
+		}
+		used[code] = true
+		return account.ID, nil
+	}
+	return uuid.Nil, nil
+}
+
+// giftCardPaid is the part of a transaction paid with (or refunded to) gift cards
+func giftCardPaid(payments []domain.TransactionPayment) domain.Money {
+	var paid domain.Money
+	for _, p := range payments {
+		if p.PaymentMethod == domain.PaymentGiftCard {
+			paid += p.Amount
+		}
+	}
+	return paid
+}
+
+// giftCardsSold is the value of the gift cards a transaction sold (or took back)
+func giftCardsSold(tx *domain.Transaction) domain.Money {
+	var sold domain.Money
+	for _, item := range tx.Items {
+		if item.IsGiftCard {
+			sold += item.Subtotal
+		}
+	}
+	return sold
+}
+
+// newGiftCardCode generates a random code of 16 characters in groups of four
+func newGiftCardCode() (string, error) {
+	var b strings.Builder
+	size := big.NewInt(int64(len(giftCardAlphabet)))
+	for i := 0; i < 16; i++ {
+		if i > 0 && i%4 == 0 {
+			b.WriteByte('-')
+		}
+		n, err := rand.Int(rand.Reader, size)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate gift card code: %w", err)
+		}
+		b.WriteByte(giftCardAlphabet[n.Int64()])
+	}
+	return b.String(), nil
+}
+
+// normalizeGiftCardCode formats a code as typed at the till (any case, with or
+// without dashes or spaces) the way it is stored
+func normalizeGiftCardCode(code string) string {
+	var raw []rune
+	for _, r := range strings.ToUpper(code) {
+		if r != '-' && r != ' ' {
+			raw = append(raw, r)
+		}
+	}
+	var b strings.Builder
+	for i, r := range raw {
+		if i > 0 && i%4 == 0 {
+			b.WriteByte('-')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// maskGiftCardCode shows only the last group of a code, for receipts and journals
+func maskGiftCardCode(code string) string {
+	if len(code) <= 4 {
+		return code
+	}
+	return "****-" + code[len(code)-4:]
+}
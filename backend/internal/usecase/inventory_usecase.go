@@ -1,6 +1,7 @@
 package usecase
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/codapos/backend/internal/domain"
@@ -15,9 +16,23 @@ func NewInventoryUsecase(ir domain.InventoryRepository) *InventoryUsecase {
 	return &InventoryUsecase{inventoryRepo: ir}
 }
 
-// GetStockByOutlet returns all inventory records for an outlet
+// GetStockByOutlet returns all inventory records for an outlet, with the stock reserved
+// for unpaid online orders and what is available to sell
 func (u *InventoryUsecase) GetStockByOutlet(outletID uuid.UUID) ([]domain.Inventory, error) {
-	return u.inventoryRepo.FindByOutlet(outletID)
+	inventory, err := u.inventoryRepo.FindByOutlet(outletID)
+	if err != nil {
+		return nil, err
+	}
+	reserved, err := reservedStock(u.inventoryRepo, outletID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	for i := range inventory {
+		inv := &inventory[i]
+		inv.Reserved = reserved[stockKey(inv.ProductID, inv.VariantID)]
+		inv.Available = inv.Quantity - inv.Reserved
+	}
+	return inventory, nil
 }
 
 // GetStockByProduct returns the stock for a specific product at an outlet
@@ -81,3 +96,28 @@ func (u *InventoryUsecase) SetStock(outletID, productID uuid.UUID, variantID *uu
 func (u *InventoryUsecase) GetMovements(outletID uuid.UUID, productID *uuid.UUID, limit int) ([]domain.InventoryMovement, error) {
 	return u.inventoryRepo.FindMovements(outletID, productID, limit)
 }
+
+// stockKey identifies the stock of a product, or of one of its variants, at an outlet
+func stockKey(productID uuid.UUID, variantID *uuid.UUID) string {
+	if variantID == nil {
+		return productID.String()
+	}
+	return productID.String() + "/" + variantID.String()
+}
+
+// reservedStock sums the live reservations at an outlet by stockKey. Lapsed reservations
+// are marked expired on the way, so they stop counting the moment they expire.
+func reservedStock(repo domain.InventoryRepository, outletID uuid.UUID, now time.Time) (map[string]float64, error) {
+	if err := repo.ExpireReservations(now); err != nil {
+		return nil, fmt.Errorf("failed to expire stock reservations: %w", err)
+	}
+	reservations, err := repo.FindActiveReservations(outletID, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load stock reservations: %w", err)
+	}
+	reserved := make(map[string]float64, len(reservations))
+	for _, r := range reservations {
+		reserved[stockKey(r.ProductID, r.VariantID)] += r.Quantity
+	}
+	return reserved, nil
+}
@@ -0,0 +1,553 @@
+package usecase
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/codapos/backend/internal/domain"
+	"github.com/google/uuid"
+)
+
+type KitchenUsecase struct {
+	kitchenRepo  domain.KitchenRepository
+	productRepo  domain.ProductRepository
+	categoryRepo domain.CategoryRepository
+	outletRepo   domain.OutletRepository
+	hub          *kitchenHub
+}
+
+func NewKitchenUsecase(kr domain.KitchenRepository, pr domain.ProductRepository, cr domain.CategoryRepository, or domain.OutletRepository) *KitchenUsecase {
+	return &KitchenUsecase{
+		kitchenRepo:  kr,
+		productRepo:  pr,
+		categoryRepo: cr,
+		outletRepo:   or,
+		hub:          &kitchenHub{subscribers: make(map[*kitchenSubscriber]struct{})},
+	}
+}
+
+// GetStations returns the stations of an outlet
+func (u *KitchenUsecase) GetStations(tenantID, outletID uuid.UUID) ([]domain.KitchenStation, error) {
+	if err := u.checkOutlet(tenantID, outletID); err != nil {
+		return nil, err
+	}
+	return u.kitchenRepo.FindStationsByOutlet(outletID)
+}
+
+// CreateStation adds a station to an outlet
+func (u *KitchenUsecase) CreateStation(tenantID uuid.UUID, req domain.KitchenStationRequest) (*domain.KitchenStation, error) {
+	if err := u.checkOutlet(tenantID, req.OutletID); err != nil {
+		return nil, err
+	}
+	station := &domain.KitchenStation{TenantID: tenantID, OutletID: req.OutletID, IsActive: true}
+	if err := u.applyStation(tenantID, station, req); err != nil {
+		return nil, err
+	}
+	if err := u.kitchenRepo.CreateStation(station); err != nil {
+		return nil, fmt.Errorf("failed to create station: %w", err)
+	}
+	if err := u.keepOneDefault(station); err != nil {
+		return nil, err
+	}
+	return station, nil
+}
+
+// UpdateStation renames a station or changes the categories it prepares
+func (u *KitchenUsecase) UpdateStation(tenantID, id uuid.UUID, req domain.KitchenStationRequest) (*domain.KitchenStation, error) {
+	station, err := u.station(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.applyStation(tenantID, station, req); err != nil {
+		return nil, err
+	}
+	if err := u.kitchenRepo.UpdateStation(station); err != nil {
+		return nil, fmt.Errorf("failed to update station: %w", err)
+	}
+	if err := u.keepOneDefault(station); err != nil {
+		return nil, err
+	}
+	return station, nil
+}
+
+// DeleteStation removes a station that has no open tickets
+func (u *KitchenUsecase) DeleteStation(tenantID, id uuid.UUID) error {
+	station, err := u.station(tenantID, id)
+	if err != nil {
+		return err
+	}
+	open, err := u.kitchenRepo.FindTickets(station.OutletID, &station.ID, domain.KitchenTicketOpen)
+	if err != nil {
+		return fmt.Errorf("failed to load tickets: %w", err)
+	}
+	if len(open) > 0 {
+		return errors.New("station still has open tickets")
+	}
+	return u.kitchenRepo.DeleteStation(station.ID)
+}
+
+// GetTickets returns the tickets of an outlet, optionally for one station and status
+func (u *KitchenUsecase) GetTickets(tenantID, outletID uuid.UUID, stationID *uuid.UUID, status string) ([]domain.KitchenTicket, error) {
+	if err := u.checkOutlet(tenantID, outletID); err != nil {
+		return nil, err
+	}
+	return u.kitchenRepo.FindTickets(outletID, stationID, status)
+}
+
+// SetItemStatus moves one ticket item on to a later status
+func (u *KitchenUsecase) SetItemStatus(tenantID, ticketID, itemID uuid.UUID, req domain.KitchenStatusRequest) (*domain.KitchenTicket, error) {
+	ticket, err := u.ticket(tenantID, ticketID)
+	if err != nil {
+		return nil, err
+	}
+	target, ok := kitchenStages[req.Status]
+	if !ok || req.Status == domain.KitchenItemQueued {
+		return nil, fmt.Errorf("unknown kitchen status: %s", req.Status)
+	}
+
+	var item *domain.KitchenTicketItem
+	for i := range ticket.Items {
+		if ticket.Items[i].ID == itemID {
+			item = &ticket.Items[i]
+		}
+	}
+	if item == nil {
+		return nil, errors.New("ticket item not found")
+	}
+	if item.Status == domain.KitchenItemCancelled {
+		return nil, errors.New("item was cancelled")
+	}
+	if kitchenStages[item.Status] >= target {
+		return nil, fmt.Errorf("item is already %s", item.Status)
+	}
+
+	advanceKitchenItem(item, target, time.Now())
+	if err := u.kitchenRepo.UpdateItem(item); err != nil {
+		return nil, fmt.Errorf("failed to update item: %w", err)
+	}
+	return u.saveTicket(ticket)
+}
+
+// SetTicketStatus moves every item of a ticket that is behind on to the given status
+func (u *KitchenUsecase) SetTicketStatus(tenantID, ticketID uuid.UUID, req domain.KitchenStatusRequest) (*domain.KitchenTicket, error) {
+	ticket, err := u.ticket(tenantID, ticketID)
+	if err != nil {
+		return nil, err
+	}
+	target, ok := kitchenStages[req.Status]
+	if !ok || req.Status == domain.KitchenItemQueued {
+		return nil, fmt.Errorf("unknown kitchen status: %s", req.Status)
+	}
+
+	now := time.Now()
+	for i := range ticket.Items {
+		item := &ticket.Items[i]
+		if item.Status == domain.KitchenItemCancelled || kitchenStages[item.Status] >= target {
+			continue
+		}
+		advanceKitchenItem(item, target, now)
+		if err := u.kitchenRepo.UpdateItem(item); err != nil {
+			return nil, fmt.Errorf("failed to update item: %w", err)
+		}
+	}
+	return u.saveTicket(ticket)
+}
+
+// PrepMetrics returns prep times per product and station for items made ready in [from, to)
+func (u *KitchenUsecase) PrepMetrics(tenantID uuid.UUID, outletID *uuid.UUID, from, to time.Time) ([]domain.KitchenPrepMetric, error) {
+	if outletID != nil {
+		if err := u.checkOutlet(tenantID, *outletID); err != nil {
+			return nil, err
+		}
+	}
+	return u.kitchenRepo.PrepMetrics(tenantID, outletID, from, to)
+}
+
+// Subscribe connects a kitchen display to the events of an outlet, optionally of one
+// station. It returns the open tickets to draw first; cancel must be called when the
+// display disconnects. The events channel is closed when the display falls behind.
+func (u *KitchenUsecase) Subscribe(tenantID, outletID uuid.UUID, stationID *uuid.UUID) ([]domain.KitchenTicket, <-chan domain.KitchenEvent, func(), error) {
+	if err := u.checkOutlet(tenantID, outletID); err != nil {
+		return nil, nil, nil, err
+	}
+	// Subscribe before reading so no ticket falls between the snapshot and the stream
+	events, cancel := u.hub.subscribe(outletID, stationID)
+	open, err := u.kitchenRepo.FindTickets(outletID, stationID, domain.KitchenTicketOpen)
+	if err != nil {
+		cancel()
+		return nil, nil, nil, fmt.Errorf("failed to load tickets: %w", err)
+	}
+	return open, events, cancel, nil
+}
+
+// fire brings the kitchen in line with an order: what was added since the last round
+// goes out on new tickets, routed to stations by category, and items taken off the
+// order are cancelled while still queued. A voided order cancels everything queued.
+// It runs in the unit of work of the order; the events are published after it commits.
+func (u *KitchenUsecase) fire(repos domain.Repositories, tx *domain.Transaction) ([]domain.KitchenEvent, error) {
+	stations, err := repos.Kitchen.FindStationsByOutlet(tx.OutletID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kitchen stations: %w", err)
+	}
+	router := newStationRouter(stations)
+	if router.empty() {
+		return nil, nil
+	}
+	tickets, err := repos.Kitchen.FindTicketsByTransaction(tx.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kitchen tickets: %w", err)
+	}
+
+	// Quantity ordered versus quantity already sent, per dish
+	delta := make(map[string]float64)
+	lines := make(map[string]domain.TransactionItem)
+	var keys []string
+	if tx.Status != domain.TransactionStatusVoided && tx.Status != domain.TransactionStatusExpired {
+		for _, item := range tx.Items {
+			if item.IsGiftCard {
+				continue // nothing to cook
+			}
+			key := kitchenKey(item.ProductID, item.VariantID, item.Modifiers, item.Notes, item.SeatNumber)
+			if _, ok := lines[key]; !ok {
+				lines[key] = item
+				keys = append(keys, key)
+			}
+			delta[key] += item.Quantity
+		}
+	}
+	for _, ticket := range tickets {
+		for _, item := range ticket.Items {
+			if item.Status != domain.KitchenItemCancelled {
+				delta[kitchenKey(item.ProductID, item.VariantID, item.Modifiers, item.Notes, item.SeatNumber)] -= item.Quantity
+			}
+		}
+	}
+
+	var events []domain.KitchenEvent
+	now := time.Now()
+
+	// Take removed dishes off the queue, latest first; what is already cooking stays
+	for t := len(tickets) - 1; t >= 0; t-- {
+		ticket := &tickets[t]
+		changed := false
+		for i := len(ticket.Items) - 1; i >= 0; i-- {
+			item := &ticket.Items[i]
+			key := kitchenKey(item.ProductID, item.VariantID, item.Modifiers, item.Notes, item.SeatNumber)
+			if item.Status != domain.KitchenItemQueued || delta[key] >= 0 {
+				continue
+			}
+			if excess := -delta[key]; excess < item.Quantity {
+				item.Quantity -= excess
+				delta[key] = 0
+			} else {
+				delta[key] += item.Quantity
+				item.Status = domain.KitchenItemCancelled
+			}
+			item.UpdatedAt = now
+			if err := repos.Kitchen.UpdateItem(item); err != nil {
+				return nil, fmt.Errorf("failed to cancel kitchen item: %w", err)
+			}
+			changed = true
+		}
+		if changed {
+			ticket.Status = kitchenTicketStatus(ticket.Items)
+			if err := repos.Kitchen.UpdateTicket(ticket); err != nil {
+				return nil, fmt.Errorf("failed to update kitchen ticket: %w", err)
+			}
+			events = append(events, domain.KitchenEvent{Type: domain.KitchenEventTicketUpdated, Ticket: ticket})
+		}
+	}
+
+	// Send what was added, one ticket per station
+	byStation := make(map[uuid.UUID]*domain.KitchenTicket)
+	var created []*domain.KitchenTicket
+	for _, key := range keys {
+		if delta[key] <= 0 {
+			continue
+		}
+		line := lines[key]
+		station, err := u.route(router, line.ProductID)
+		if err != nil {
+			return nil, err
+		}
+		if station == nil {
+			continue
+		}
+		ticket, ok := byStation[station.ID]
+		if !ok {
+			ticket = &domain.KitchenTicket{
+				TenantID:          tx.TenantID,
+				OutletID:          tx.OutletID,
+				StationID:         station.ID,
+				TransactionID:     tx.ID,
+				TransactionNumber: tx.TransactionNumber,
+				OrderType:         tx.OrderType,
+				TabName:           tx.TabName,
+				Status:            domain.KitchenTicketOpen,
+				Station:           station,
+			}
+			byStation[station.ID] = ticket
+			created = append(created, ticket)
+		}
+		ticket.Items = append(ticket.Items, domain.KitchenTicketItem{
+			ProductID:   line.ProductID,
+			VariantID:   line.VariantID,
+			ProductName: line.ProductName,
+			VariantName: line.VariantName,
+			Quantity:    delta[key],
+			Modifiers:   line.Modifiers,
+			Notes:       line.Notes,
+			SeatNumber:  line.SeatNumber,
+			Status:      domain.KitchenItemQueued,
+		})
+	}
+	for _, ticket := range created {
+		station := ticket.Station
+		ticket.Station = nil
+		if err := repos.Kitchen.CreateTicket(ticket); err != nil {
+			return nil, fmt.Errorf("failed to create kitchen ticket: %w", err)
+		}
+		ticket.Station = station
+		events = append(events, domain.KitchenEvent{Type: domain.KitchenEventTicketCreated, Ticket: ticket})
+	}
+	return events, nil
+}
+
+// publish pushes committed kitchen changes to the displays
+func (u *KitchenUsecase) publish(events []domain.KitchenEvent) {
+	for _, ev := range events {
+		u.hub.publish(ev)
+	}
+}
+
+// route picks the station for a product: the station serving its category or the
+// closest parent category, otherwise the outlet's default station
+func (u *KitchenUsecase) route(router *stationRouter, productID uuid.UUID) (*domain.KitchenStation, error) {
+	product, err := u.productRepo.FindByID(productID)
+	if err != nil {
+		return nil, fmt.Errorf("product not found: %s", productID)
+	}
+	categoryID := product.CategoryID
+	for depth := 0; categoryID != nil && depth < 10; depth++ {
+		if station, ok := router.byCategory[*categoryID]; ok {
+			return station, nil
+		}
+		category, err := u.categoryRepo.FindByID(*categoryID)
+		if err != nil {
+			break
+		}
+		categoryID = category.ParentID
+	}
+	return router.fallback, nil
+}
+
+func (u *KitchenUsecase) applyStation(tenantID uuid.UUID, station *domain.KitchenStation, req domain.KitchenStationRequest) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return errors.New("station name is required")
+	}
+	categories := make([]domain.Category, 0, len(req.CategoryIDs))
+	for _, id := range req.CategoryIDs {
+		category, err := u.categoryRepo.FindByID(id)
+		if err != nil || category.TenantID != tenantID {
+			return errors.New("category not found")
+		}
+		categories = append(categories, *category)
+	}
+	station.Name = name
+	station.IsDefault = req.IsDefault
+	if req.IsActive != nil {
+		station.IsActive = *req.IsActive
+	}
+	station.Categories = categories
+	return nil
+}
+
+// keepOneDefault clears the default flag of the outlet's other stations
+func (u *KitchenUsecase) keepOneDefault(station *domain.KitchenStation) error {
+	if !station.IsDefault {
+		return nil
+	}
+	stations, err := u.kitchenRepo.FindStationsByOutlet(station.OutletID)
+	if err != nil {
+		return fmt.Errorf("failed to load stations: %w", err)
+	}
+	for i := range stations {
+		if other := &stations[i]; other.ID != station.ID && other.IsDefault {
+			other.IsDefault = false
+			if err := u.kitchenRepo.UpdateStation(other); err != nil {
+				return fmt.Errorf("failed to update station: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+func (u *KitchenUsecase) saveTicket(ticket *domain.KitchenTicket) (*domain.KitchenTicket, error) {
+	ticket.Status = kitchenTicketStatus(ticket.Items)
+	if err := u.kitchenRepo.UpdateTicket(ticket); err != nil {
+		return nil, fmt.Errorf("failed to update ticket: %w", err)
+	}
+	u.hub.publish(domain.KitchenEvent{Type: domain.KitchenEventTicketUpdated, Ticket: ticket})
+	return ticket, nil
+}
+
+func (u *KitchenUsecase) checkOutlet(tenantID, outletID uuid.UUID) error {
+	outlet, err := u.outletRepo.FindByID(outletID)
+	if err != nil || outlet.TenantID != tenantID {
+		return errors.New("outlet not found")
+	}
+	return nil
+}
+
+func (u *KitchenUsecase) station(tenantID, id uuid.UUID) (*domain.KitchenStation, error) {
+	station, err := u.kitchenRepo.FindStationByID(id)
+	if err != nil || station.TenantID != tenantID {
+		return nil, errors.New("station not found")
+	}
+	return station, nil
+}
+
+func (u *KitchenUsecase) ticket(tenantID, id uuid.UUID) (*domain.KitchenTicket, error) {
+	ticket, err := u.kitchenRepo.FindTicketByID(id)
+	if err != nil || ticket.TenantID != tenantID {
+		return nil, errors.New("ticket not found")
+	}
+	return ticket, nil
+}
+
+// kitchenStages ranks the item statuses an item moves through
+var kitchenStages = map[string]int{
+	domain.KitchenItemQueued:  0,
+	domain.KitchenItemCooking: 1,
+	domain.KitchenItemReady:   2,
+	domain.KitchenItemServed:  3,
+}
+
+// advanceKitchenItem moves an item through every stage up to target, stamping each one
+// so skipped stages still give consistent prep-time metrics
+func advanceKitchenItem(item *domain.KitchenTicketItem, target int, now time.Time) {
+	for stage := kitchenStages[item.Status] + 1; stage <= target; stage++ {
+		switch stage {
+		case kitchenStages[domain.KitchenItemCooking]:
+			item.StartedAt = &now
+			item.WaitSeconds = int(now.Sub(item.CreatedAt).Seconds())
+			item.Status = domain.KitchenItemCooking
+		case kitchenStages[domain.KitchenItemReady]:
+			item.ReadyAt = &now
+			item.PrepSeconds = int(now.Sub(*item.StartedAt).Seconds())
+			item.Status = domain.KitchenItemReady
+		case kitchenStages[domain.KitchenItemServed]:
+			item.ServedAt = &now
+			item.ServeSeconds = int(now.Sub(*item.ReadyAt).Seconds())
+			item.Status = domain.KitchenItemServed
+		}
+	}
+}
+
+func kitchenTicketStatus(items []domain.KitchenTicketItem) string {
+	for _, item := range items {
+		if item.Status != domain.KitchenItemServed && item.Status != domain.KitchenItemCancelled {
+			return domain.KitchenTicketOpen
+		}
+	}
+	return domain.KitchenTicketDone
+}
+
+// kitchenKey identifies a dish regardless of its price: the same product, variant,
+// modifiers, notes and seat are the same dish to the kitchen
+func kitchenKey(productID uuid.UUID, variantID *uuid.UUID, modifiers domain.JSON, notes string, seat int) string {
+	var selected []domain.SelectedModifier
+	_ = json.Unmarshal(modifiers, &selected)
+	ids := make([]string, 0, len(selected))
+	for _, m := range selected {
+		ids = append(ids, m.ModifierID.String())
+	}
+	sort.Strings(ids)
+	variant := ""
+	if variantID != nil {
+		variant = variantID.String()
+	}
+	return fmt.Sprintf("%s|%s|%s|%s|%d", productID, variant, strings.Join(ids, ","), strings.TrimSpace(notes), seat)
+}
+
+// stationRouter maps categories to the active stations of an outlet
+type stationRouter struct {
+	byCategory map[uuid.UUID]*domain.KitchenStation
+	fallback   *domain.KitchenStation
+}
+
+func newStationRouter(stations []domain.KitchenStation) *stationRouter {
+	router := &stationRouter{byCategory: make(map[uuid.UUID]*domain.KitchenStation)}
+	for i := range stations {
+		station := &stations[i]
+		if !station.IsActive {
+			continue
+		}
+		for _, category := range station.Categories {
+			router.byCategory[category.ID] = station
+		}
+		if station.IsDefault {
+			router.fallback = station
+		}
+	}
+	return router
+}
+
+func (r *stationRouter) empty() bool {
+	return len(r.byCategory) == 0 && r.fallback == nil
+}
+
+// kitchenHub fans kitchen events out to the displays connected to this server.
+// Subscribers are kept in memory, so the displays of an outlet must be served by the
+// same instance as the POS that places its orders.
+type kitchenHub struct {
+	mu          sync.Mutex
+	subscribers map[*kitchenSubscriber]struct{}
+}
+
+type kitchenSubscriber struct {
+	outletID  uuid.UUID
+	stationID *uuid.UUID
+	events    chan domain.KitchenEvent
+}
+
+func (h *kitchenHub) subscribe(outletID uuid.UUID, stationID *uuid.UUID) (<-chan domain.KitchenEvent, func()) {
+	sub := &kitchenSubscriber{outletID: outletID, stationID: stationID, events: make(chan domain.KitchenEvent, 64)}
+	h.mu.Lock()
+	h.subscribers[sub] = struct{}{}
+	h.mu.Unlock()
+	return sub.events, func() { h.drop(sub) }
+}
+
+func (h *kitchenHub) drop(sub *kitchenSubscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subscribers[sub]; ok {
+		delete(h.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+// publish never blocks: a display that falls behind is disconnected and draws the
+// open tickets again when it reconnects
+func (h *kitchenHub) publish(ev domain.KitchenEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subscribers {
+		if sub.outletID != ev.Ticket.OutletID || (sub.stationID != nil && *sub.stationID != ev.Ticket.StationID) {
+			continue
+		}
+		select {
+		case sub.events <- ev:
+		default:
+			delete(h.subscribers, sub)
+			close(sub.events)
+		}
+	}
+}
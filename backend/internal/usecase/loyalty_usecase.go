@@ -0,0 +1,877 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codapos/backend/internal/domain"
+	"github.com/google/uuid"
+)
+
+// ErrInsufficientPoints is returned when a customer redeems more points than they have
+var ErrInsufficientPoints = errors.New("not enough loyalty points")
+
+// loyaltyExpiryWindow is how far ahead the balance warns about lapsing points
+const loyaltyExpiryWindow = 30 * 24 * time.Hour
+
+type LoyaltyUsecase struct {
+	loyaltyRepo    domain.LoyaltyRepository
+	customerRepo   domain.CustomerRepository
+	productRepo    domain.ProductRepository
+	categoryRepo   domain.CategoryRepository
+	accountingRepo domain.AccountingRepository
+	uow            domain.UnitOfWork
+}
+
+func NewLoyaltyUsecase(
+	lr domain.LoyaltyRepository,
+	cr domain.CustomerRepository,
+	pr domain.ProductRepository,
+	catr domain.CategoryRepository,
+	ar domain.AccountingRepository,
+	uow domain.UnitOfWork,
+) *LoyaltyUsecase {
+	return &LoyaltyUsecase{
+		loyaltyRepo:    lr,
+		customerRepo:   cr,
+		productRepo:    pr,
+		categoryRepo:   catr,
+		accountingRepo: ar,
+		uow:            uow,
+	}
+}
+
+// GetProgram returns the points program of a tenant; tenants that never set one up
+// get an inactive program
+func (u *LoyaltyUsecase) GetProgram(tenantID uuid.UUID) (*domain.LoyaltyProgram, error) {
+	program, err := u.loyaltyRepo.FindProgram(tenantID)
+	if err != nil {
+		return &domain.LoyaltyProgram{TenantID: tenantID}, nil
+	}
+	return program, nil
+}
+
+// SaveProgram stores the points program of a tenant. Activating it opens the expense
+// account that sales paid with points are booked to.
+func (u *LoyaltyUsecase) SaveProgram(tenantID uuid.UUID, req *domain.LoyaltyProgram) (*domain.LoyaltyProgram, error) {
+	program, err := u.GetProgram(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	program.IsActive = req.IsActive
+	program.SpendPerPoint = req.SpendPerPoint
+	program.PointValue = req.PointValue
+	program.MinRedeemPoints = req.MinRedeemPoints
+	program.ExpiryDays = req.ExpiryDays
+
+	switch {
+	case program.SpendPerPoint < 0 || program.PointValue < 0:
+		return nil, errors.New("spend per point and point value cannot be negative")
+	case program.IsActive && program.SpendPerPoint == 0:
+		return nil, errors.New("spend per point is required")
+	case program.IsActive && program.PointValue == 0:
+		return nil, errors.New("point value is required")
+	case program.MinRedeemPoints < 0:
+		return nil, errors.New("minimum points to redeem cannot be negative")
+	case program.ExpiryDays < 0:
+		return nil, errors.New("expiry days cannot be negative")
+	}
+
+	if program.IsActive {
+		if err := u.openLoyaltyAccount(tenantID); err != nil {
+			return nil, err
+		}
+	}
+	if err := u.loyaltyRepo.SaveProgram(program); err != nil {
+		return nil, fmt.Errorf("failed to save loyalty program: %w", err)
+	}
+	return program, nil
+}
+
+// openLoyaltyAccount adds the expense account for sales paid with points to a chart of
+// accounts set up before the program existed (5500, or the next free code after it)
+func (u *LoyaltyUsecase) openLoyaltyAccount(tenantID uuid.UUID) error {
+	accounts, err := u.accountingRepo.FindAccountsByTenantID(tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to load chart of accounts: %w", err)
+	}
+	if len(accounts) == 0 {
+		// The default chart of accounts, which has one, is not initialized yet
+		return nil
+	}
+	used := make(map[string]bool, len(accounts))
+	for _, a := range accounts {
+		if a.SubType == domain.AccountSubTypeLoyalty {
+			return nil
+		}
+		used[a.Code] = true
+	}
+	for next := 5500; next < 5600; next += 10 {
+		code := strconv.Itoa(next)
+		if used[code] {
+			continue
+		}
+		account := &domain.ChartOfAccount{
+			TenantID: tenantID,
+			Code:     code,
+			Name:     "Beban Program Loyalitas",
+			Type:     domain.AccountTypeExpense,
+			SubType:  domain.AccountSubTypeLoyalty,
+			IsActive: true,
+			IsSystem: true,
+		}
+		if err := u.accountingRepo.CreateAccount(account); err != nil {
+			return fmt.Errorf("failed to create loyalty account: %w", err)
+		}
+		return nil
+	}
+	return nil
+}
+
+// GetRules returns the earn rules of a tenant
+func (u *LoyaltyUsecase) GetRules(tenantID uuid.UUID) ([]domain.LoyaltyEarnRule, error) {
+	return u.loyaltyRepo.FindRules(tenantID)
+}
+
+// CreateRule validates and stores an earn rule
+func (u *LoyaltyUsecase) CreateRule(tenantID uuid.UUID, rule *domain.LoyaltyEarnRule) error {
+	rule.ID = uuid.Nil
+	rule.TenantID = tenantID
+	if err := u.validateRule(rule); err != nil {
+		return err
+	}
+	if err := u.loyaltyRepo.SaveRule(rule); err != nil {
+		return fmt.Errorf("failed to create earn rule: %w", err)
+	}
+	return nil
+}
+
+// UpdateRule replaces the editable fields of an earn rule
+func (u *LoyaltyUsecase) UpdateRule(tenantID, id uuid.UUID, req *domain.LoyaltyEarnRule) (*domain.LoyaltyEarnRule, error) {
+	rule, err := u.loyaltyRepo.FindRuleByID(id)
+	if err != nil || rule.TenantID != tenantID {
+		return nil, errors.New("earn rule not found")
+	}
+	rule.Name = req.Name
+	rule.CategoryID = req.CategoryID
+	rule.Weekday = req.Weekday
+	rule.Multiplier = req.Multiplier
+	rule.IsActive = req.IsActive
+	if err := u.validateRule(rule); err != nil {
+		return nil, err
+	}
+	if err := u.loyaltyRepo.SaveRule(rule); err != nil {
+		return nil, fmt.Errorf("failed to update earn rule: %w", err)
+	}
+	return u.loyaltyRepo.FindRuleByID(rule.ID)
+}
+
+// DeleteRule removes an earn rule
+func (u *LoyaltyUsecase) DeleteRule(tenantID, id uuid.UUID) error {
+	rule, err := u.loyaltyRepo.FindRuleByID(id)
+	if err != nil || rule.TenantID != tenantID {
+		return errors.New("earn rule not found")
+	}
+	return u.loyaltyRepo.DeleteRule(rule.ID)
+}
+
+func (u *LoyaltyUsecase) validateRule(rule *domain.LoyaltyEarnRule) error {
+	rule.Name = strings.TrimSpace(rule.Name)
+	switch {
+	case rule.Name == "":
+		return errors.New("rule name is required")
+	case rule.Multiplier <= 0:
+		return errors.New("multiplier must be greater than zero")
+	case rule.CategoryID == nil && rule.Weekday == nil:
+		return errors.New("a rule needs a category, a day of the week or both")
+	case rule.Weekday != nil && (*rule.Weekday < 0 || *rule.Weekday > 6):
+		return errors.New("day of the week must be between 0 (Sunday) and 6 (Saturday)")
+	}
+	if rule.CategoryID != nil {
+		category, err := u.categoryRepo.FindByID(*rule.CategoryID)
+		if err != nil || category.TenantID != rule.TenantID {
+			return errors.New("category not found")
+		}
+	}
+	return nil
+}
+
+// GetTiers returns the membership tiers of a tenant, lowest first
+func (u *LoyaltyUsecase) GetTiers(tenantID uuid.UUID) ([]domain.LoyaltyTier, error) {
+	return u.loyaltyRepo.FindTiers(tenantID)
+}
+
+// CreateTier validates and stores a membership tier. Members reach it the next time
+// they earn points.
+func (u *LoyaltyUsecase) CreateTier(tenantID uuid.UUID, tier *domain.LoyaltyTier) error {
+	tier.ID = uuid.Nil
+	tier.TenantID = tenantID
+	if err := validateTier(tier); err != nil {
+		return err
+	}
+	if err := u.loyaltyRepo.SaveTier(tier); err != nil {
+		return fmt.Errorf("failed to create tier: %w", err)
+	}
+	return nil
+}
+
+// UpdateTier replaces the editable fields of a membership tier
+func (u *LoyaltyUsecase) UpdateTier(tenantID, id uuid.UUID, req *domain.LoyaltyTier) (*domain.LoyaltyTier, error) {
+	tier, err := u.loyaltyRepo.FindTierByID(id)
+	if err != nil || tier.TenantID != tenantID {
+		return nil, errors.New("tier not found")
+	}
+	tier.Name = req.Name
+	tier.MinPoints = req.MinPoints
+	tier.EarnMultiplier = req.EarnMultiplier
+	tier.DiscountPercent = req.DiscountPercent
+	if err := validateTier(tier); err != nil {
+		return nil, err
+	}
+	if err := u.loyaltyRepo.SaveTier(tier); err != nil {
+		return nil, fmt.Errorf("failed to update tier: %w", err)
+	}
+	return tier, nil
+}
+
+// DeleteTier removes a membership tier; its members keep their points
+func (u *LoyaltyUsecase) DeleteTier(tenantID, id uuid.UUID) error {
+	tier, err := u.loyaltyRepo.FindTierByID(id)
+	if err != nil || tier.TenantID != tenantID {
+		return errors.New("tier not found")
+	}
+	return u.loyaltyRepo.DeleteTier(tier.ID)
+}
+
+func validateTier(tier *domain.LoyaltyTier) error {
+	tier.Name = strings.TrimSpace(tier.Name)
+	if tier.EarnMultiplier == 0 {
+		tier.EarnMultiplier = 1
+	}
+	switch {
+	case tier.Name == "":
+		return errors.New("tier name is required")
+	case tier.MinPoints < 0:
+		return errors.New("minimum points cannot be negative")
+	case tier.EarnMultiplier < 0:
+		return errors.New("earn multiplier must be greater than zero")
+	case tier.DiscountPercent < 0 || tier.DiscountPercent > 100:
+		return errors.New("discount must be between 0 and 100 percent")
+	}
+	return nil
+}
+
+// Balance looks a customer up by phone number and returns their points
+func (u *LoyaltyUsecase) Balance(tenantID uuid.UUID, phone string) (*domain.LoyaltyBalance, error) {
+	phone = strings.TrimSpace(phone)
+	if phone == "" {
+		return nil, errors.New("phone number is required")
+	}
+	customer, err := u.customerRepo.FindByPhone(tenantID, phone)
+	if err != nil {
+		return nil, errors.New("customer not found")
+	}
+	return u.balanceOf(tenantID, customer)
+}
+
+// CustomerBalance returns the points of a customer
+func (u *LoyaltyUsecase) CustomerBalance(tenantID, customerID uuid.UUID) (*domain.LoyaltyBalance, error) {
+	customer, err := u.customerRepo.FindByID(customerID)
+	if err != nil || customer.TenantID != tenantID {
+		return nil, errors.New("customer not found")
+	}
+	return u.balanceOf(tenantID, customer)
+}
+
+// balanceOf lapses the customer's expired points and reports what is left
+func (u *LoyaltyUsecase) balanceOf(tenantID uuid.UUID, customer *domain.Customer) (*domain.LoyaltyBalance, error) {
+	now := time.Now()
+	var account *domain.LoyaltyAccount
+	var lots []domain.LoyaltyEntry
+	err := u.uow.Do(func(repos domain.Repositories) error {
+		var err error
+		if account, err = repos.Loyalty.LockAccount(tenantID, customer.ID); err != nil {
+			return fmt.Errorf("failed to open loyalty account: %w", err)
+		}
+		if err := expirePoints(repos, account, now); err != nil {
+			return err
+		}
+		lots, err = repos.Loyalty.FindOpenLots(customer.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	program, _ := u.GetProgram(tenantID)
+	balance := &domain.LoyaltyBalance{
+		Customer:       customer,
+		Balance:        account.Balance,
+		LifetimePoints: account.LifetimePoints,
+	}
+	if account.Balance > 0 {
+		balance.Value = program.PointValue * domain.Money(account.Balance)
+	}
+	for _, lot := range lots {
+		if lot.ExpiresAt == nil || lot.ExpiresAt.Sub(now) > loyaltyExpiryWindow {
+			continue
+		}
+		balance.ExpiringPoints += lot.Remaining
+		if balance.ExpiringAt == nil {
+			balance.ExpiringAt = lot.ExpiresAt
+		}
+	}
+
+	tiers, err := u.loyaltyRepo.FindTiers(tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tiers: %w", err)
+	}
+	for i := range tiers {
+		if account.TierID != nil && tiers[i].ID == *account.TierID {
+			balance.Tier = &tiers[i]
+		}
+		if tiers[i].MinPoints > account.LifetimePoints && balance.NextTier == nil {
+			balance.NextTier = &tiers[i]
+		}
+	}
+	return balance, nil
+}
+
+// Ledger returns the point movements of a customer, newest first
+func (u *LoyaltyUsecase) Ledger(tenantID, customerID uuid.UUID, page, perPage int) ([]domain.LoyaltyEntry, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if perPage <= 0 {
+		perPage = 20
+	}
+	customer, err := u.customerRepo.FindByID(customerID)
+	if err != nil || customer.TenantID != tenantID {
+		return nil, 0, errors.New("customer not found")
+	}
+	return u.loyaltyRepo.FindEntries(customer.ID, perPage, (page-1)*perPage)
+}
+
+// Adjust adds or removes points by hand, e.g. as a goodwill gesture. Adjustments do
+// not count towards the customer's tier.
+func (u *LoyaltyUsecase) Adjust(tenantID, userID, customerID uuid.UUID, req domain.LoyaltyAdjustRequest) (*domain.LoyaltyBalance, error) {
+	if req.Points == 0 {
+		return nil, errors.New("points are required")
+	}
+	if strings.TrimSpace(req.Notes) == "" {
+		return nil, errors.New("a note explaining the adjustment is required")
+	}
+	customer, err := u.customerRepo.FindByID(customerID)
+	if err != nil || customer.TenantID != tenantID {
+		return nil, errors.New("customer not found")
+	}
+	program, _ := u.GetProgram(tenantID)
+
+	now := time.Now()
+	err = u.uow.Do(func(repos domain.Repositories) error {
+		account, err := repos.Loyalty.LockAccount(tenantID, customer.ID)
+		if err != nil {
+			return fmt.Errorf("failed to open loyalty account: %w", err)
+		}
+		if err := expirePoints(repos, account, now); err != nil {
+			return err
+		}
+		entry := &domain.LoyaltyEntry{
+			TenantID:   tenantID,
+			CustomerID: customer.ID,
+			Type:       domain.LoyaltyEntryAdjust,
+			Points:     req.Points,
+			Notes:      req.Notes,
+			CreatedBy:  &userID,
+		}
+		if req.Points > 0 {
+			entry.Remaining = req.Points
+			entry.ExpiresAt = pointsExpiry(program, now)
+		} else {
+			if account.Balance < -req.Points {
+				return ErrInsufficientPoints
+			}
+			if err := spendPoints(repos, customer.ID, -req.Points, nil); err != nil {
+				return err
+			}
+		}
+		if err := repos.Loyalty.CreateEntry(entry); err != nil {
+			return fmt.Errorf("failed to record adjustment: %w", err)
+		}
+		account.Balance += req.Points
+		return repos.Loyalty.UpdateAccount(account)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return u.balanceOf(tenantID, customer)
+}
+
+// loyaltyMember is a customer of the program at checkout, with the points they redeem
+// as a discount
+type loyaltyMember struct {
+	program *domain.LoyaltyProgram
+	tier    *domain.LoyaltyTier
+	balance int64
+	redeem  int64
+}
+
+// member looks up the customer of a sale in the program. Customers of tenants without
+// an active program, and sales without a customer, have no member.
+func (u *LoyaltyUsecase) member(tenantID uuid.UUID, customerID *uuid.UUID, redeem int64) (*loyaltyMember, error) {
+	if redeem < 0 {
+		return nil, errors.New("points to redeem cannot be negative")
+	}
+	if customerID == nil {
+		if redeem > 0 {
+			return nil, errors.New("select a customer to redeem loyalty points")
+		}
+		return nil, nil
+	}
+	customer, err := u.customerRepo.FindByID(*customerID)
+	if err != nil || customer.TenantID != tenantID {
+		return nil, errors.New("customer not found")
+	}
+	program, err := u.loyaltyRepo.FindProgram(tenantID)
+	if err != nil || !program.IsActive {
+		if redeem > 0 {
+			return nil, errors.New("loyalty program is not active")
+		}
+		return nil, nil
+	}
+
+	m := &loyaltyMember{program: program, redeem: redeem}
+	if account, err := u.loyaltyRepo.FindAccount(customer.ID); err == nil {
+		m.tier = account.Tier
+		m.balance = account.Balance
+		// Lapsed points are written off when the sale is settled; don't offer them
+		lots, err := u.loyaltyRepo.FindOpenLots(customer.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load loyalty points: %w", err)
+		}
+		now := time.Now()
+		for _, lot := range lots {
+			if lot.ExpiresAt != nil && !lot.ExpiresAt.After(now) {
+				m.balance -= lot.Remaining
+			}
+		}
+	}
+	if redeem > m.balance {
+		return nil, ErrInsufficientPoints
+	}
+	return m, nil
+}
+
+// discount takes the tier discount and the points redeemed off the items, after the
+// promotion and before tax, and returns the amount taken off
+func (m *loyaltyMember) discount(items []domain.TransactionItem) (domain.Money, error) {
+	var net domain.Money
+	for _, item := range items {
+		net += item.Subtotal - item.DiscountAmount
+	}
+	var total domain.Money
+	if m.tier != nil && m.tier.DiscountPercent > 0 {
+		total += net.Percent(m.tier.DiscountPercent)
+	}
+	if m.redeem > 0 {
+		value := m.program.PointValue * domain.Money(m.redeem)
+		if value > net-total {
+			return 0, fmt.Errorf("at most %d points can be redeemed on this sale", int64((net-total)/m.program.PointValue))
+		}
+		total += value
+	}
+	if total <= 0 {
+		return 0, nil
+	}
+
+	// Spread over the lines by what is left of them, capped at that, with the
+	// rounding remainder on the largest line
+	var assigned, largestLeft domain.Money
+	largest := 0
+	for i := range items {
+		left := items[i].Subtotal - items[i].DiscountAmount
+		share := min(total.MulRatio(left, net), left)
+		items[i].DiscountAmount += share
+		assigned += share
+		if left > largestLeft {
+			largest, largestLeft = i, left
+		}
+	}
+	items[largest].DiscountAmount += total - assigned
+	return total, nil
+}
+
+// pointsPaid is the points a sale's loyalty payments spend, which must be whole points
+func (m *loyaltyMember) pointsPaid(tx *domain.Transaction, payments []domain.PaymentRequest) (int64, error) {
+	var paid domain.Money
+	for _, p := range payments {
+		if p.PaymentMethod != domain.PaymentLoyalty {
+			continue
+		}
+		if m == nil {
+			return 0, errors.New("select a customer of the loyalty program to pay with points")
+		}
+		if p.Amount <= 0 || p.Amount%m.program.PointValue != 0 {
+			return 0, fmt.Errorf("points are paid in whole points of Rp%s", m.program.PointValue)
+		}
+		paid += p.Amount
+	}
+	if paid > tx.TotalAmount {
+		return 0, errors.New("loyalty points cannot pay more than the total")
+	}
+	if paid == 0 {
+		return 0, nil
+	}
+	return int64(paid / m.program.PointValue), nil
+}
+
+// redeemPoints sets the points a sale spends, as a discount and as payments
+func (m *loyaltyMember) redeemPoints(tx *domain.Transaction, payments []domain.PaymentRequest) error {
+	paid, err := m.pointsPaid(tx, payments)
+	if err != nil {
+		return err
+	}
+	if m == nil {
+		return nil
+	}
+	points := m.redeem + paid
+	if points > m.balance {
+		return ErrInsufficientPoints
+	}
+	if points > 0 && points < m.program.MinRedeemPoints {
+		return fmt.Errorf("at least %d points must be redeemed at once", m.program.MinRedeemPoints)
+	}
+	tx.PointsRedeemed = points
+	return nil
+}
+
+// settle books the points of a completed sale: the points redeemed on it are spent and
+// the points it earns are added as a new lot. The customer's tier follows their
+// lifetime points.
+func (u *LoyaltyUsecase) settle(repos domain.Repositories, tx *domain.Transaction) error {
+	if tx.CustomerID == nil {
+		return nil
+	}
+	program, err := repos.Loyalty.FindProgram(tx.TenantID)
+	if err != nil || !program.IsActive {
+		if tx.PointsRedeemed > 0 {
+			return errors.New("loyalty program is not active")
+		}
+		return nil
+	}
+	account, err := repos.Loyalty.LockAccount(tx.TenantID, *tx.CustomerID)
+	if err != nil {
+		return fmt.Errorf("failed to open loyalty account: %w", err)
+	}
+	now := time.Now()
+	if err := expirePoints(repos, account, now); err != nil {
+		return err
+	}
+
+	if tx.PointsRedeemed > 0 {
+		if account.Balance < tx.PointsRedeemed {
+			return ErrInsufficientPoints
+		}
+		if err := spendPoints(repos, account.CustomerID, tx.PointsRedeemed, nil); err != nil {
+			return err
+		}
+		if err := repos.Loyalty.CreateEntry(&domain.LoyaltyEntry{
+			TenantID:      tx.TenantID,
+			CustomerID:    account.CustomerID,
+			TransactionID: &tx.ID,
+			Type:          domain.LoyaltyEntryRedeem,
+			Points:        -tx.PointsRedeemed,
+			Notes:         "Redeemed on " + tx.TransactionNumber,
+			CreatedBy:     &tx.CashierID,
+		}); err != nil {
+			return fmt.Errorf("failed to record redeemed points: %w", err)
+		}
+		account.Balance -= tx.PointsRedeemed
+	}
+
+	var tier *domain.LoyaltyTier
+	if account.TierID != nil {
+		tier, _ = repos.Loyalty.FindTierByID(*account.TierID)
+	}
+	earned, err := u.earnedPoints(repos, tx, program, tier)
+	if err != nil {
+		return err
+	}
+	if earned > 0 {
+		if err := repos.Loyalty.CreateEntry(&domain.LoyaltyEntry{
+			TenantID:      tx.TenantID,
+			CustomerID:    account.CustomerID,
+			TransactionID: &tx.ID,
+			Type:          domain.LoyaltyEntryEarn,
+			Points:        earned,
+			Remaining:     earned,
+			ExpiresAt:     pointsExpiry(program, now),
+			Notes:         "Earned on " + tx.TransactionNumber,
+			CreatedBy:     &tx.CashierID,
+		}); err != nil {
+			return fmt.Errorf("failed to record earned points: %w", err)
+		}
+		account.Balance += earned
+		account.LifetimePoints += earned
+		if err := updateTier(repos, account); err != nil {
+			return err
+		}
+	}
+	if err := repos.Loyalty.UpdateAccount(account); err != nil {
+		return fmt.Errorf("failed to update loyalty account: %w", err)
+	}
+
+	tx.PointsEarned = earned
+	if err := repos.Transactions.UpdatePoints(tx.ID, tx.PointsEarned, tx.PointsRedeemed); err != nil {
+		return fmt.Errorf("failed to update transaction points: %w", err)
+	}
+	return nil
+}
+
+// earnedPoints works out the points a sale earns: a point for every SpendPerPoint of
+// net sales, multiplied per item by the best matching earn rule and for the whole sale
+// by the customer's tier. The part of the bill paid with points earns nothing, and
+// gift cards earn when they are spent rather than when they are bought.
+func (u *LoyaltyUsecase) earnedPoints(repos domain.Repositories, tx *domain.Transaction, program *domain.LoyaltyProgram, tier *domain.LoyaltyTier) (int64, error) {
+	if program.SpendPerPoint <= 0 || tx.TotalAmount <= 0 {
+		return 0, nil
+	}
+	rules, err := repos.Loyalty.FindRules(tx.TenantID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load earn rules: %w", err)
+	}
+	day := int(time.Now().Weekday())
+	if !tx.CreatedAt.IsZero() {
+		day = int(tx.CreatedAt.Weekday())
+	}
+
+	categories := make(map[uuid.UUID]*uuid.UUID)
+	var spend float64
+	for _, item := range tx.Items {
+		net := item.Subtotal - item.DiscountAmount - item.TaxIncluded
+		if net <= 0 || item.IsGiftCard {
+			continue
+		}
+		categoryID, ok := categories[item.ProductID]
+		if !ok {
+			if product, err := u.productRepo.FindByID(item.ProductID); err == nil {
+				categoryID = product.CategoryID
+			}
+			categories[item.ProductID] = categoryID
+		}
+		multiplier := 1.0
+		for _, rule := range rules {
+			if !rule.IsActive ||
+				(rule.Weekday != nil && *rule.Weekday != day) ||
+				(rule.CategoryID != nil && (categoryID == nil || *rule.CategoryID != *categoryID)) {
+				continue
+			}
+			multiplier = math.Max(multiplier, rule.Multiplier)
+		}
+		spend += net.Float64() * multiplier
+	}
+
+	if paid := loyaltyPaid(tx.Payments); paid > 0 {
+		spend *= float64(max(tx.TotalAmount-paid, 0)) / float64(tx.TotalAmount)
+	}
+	if tier != nil && tier.EarnMultiplier > 0 {
+		spend *= tier.EarnMultiplier
+	}
+	return int64(math.Floor(spend/program.SpendPerPoint.Float64() + 1e-9)), nil
+}
+
+// reverse takes back the points of a sale in proportion to the amount returned: earned
+// points are clawed back and redeemed points are given back. final reverses whatever
+// is left, for the refund that completes the sale or for a void.
+func (u *LoyaltyUsecase) reverse(repos domain.Repositories, sale *domain.Transaction, returned domain.Money, final bool, createdBy *uuid.UUID, note string) error {
+	if sale.CustomerID == nil || (sale.PointsEarned == 0 && sale.PointsRedeemed == 0) {
+		return nil
+	}
+	entries, err := repos.Loyalty.FindEntriesByTransaction(sale.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load loyalty entries: %w", err)
+	}
+	var clawedBack, givenBack int64
+	var earnLot *domain.LoyaltyEntry
+	for i := range entries {
+		switch entries[i].Type {
+		case domain.LoyaltyEntryClawback:
+			clawedBack -= entries[i].Points
+		case domain.LoyaltyEntryReturn:
+			givenBack += entries[i].Points
+		case domain.LoyaltyEntryEarn:
+			earnLot = &entries[i]
+		}
+	}
+
+	claw := sale.PointsEarned - clawedBack
+	giveBack := sale.PointsRedeemed - givenBack
+	if !final {
+		ratio := returned.Float64() / sale.TotalAmount.Float64()
+		claw = min(claw, int64(math.Round(float64(sale.PointsEarned)*ratio)))
+		giveBack = min(giveBack, int64(math.Round(float64(sale.PointsRedeemed)*ratio)))
+	}
+	if claw <= 0 && giveBack <= 0 {
+		return nil
+	}
+
+	account, err := repos.Loyalty.LockAccount(sale.TenantID, *sale.CustomerID)
+	if err != nil {
+		return fmt.Errorf("failed to open loyalty account: %w", err)
+	}
+	now := time.Now()
+	if err := expirePoints(repos, account, now); err != nil {
+		return err
+	}
+
+	if claw > 0 {
+		// The points still unspent on the sale's own lot go first; points already spent
+		// elsewhere leave the balance short
+		if err := spendPoints(repos, account.CustomerID, min(claw, account.Balance), earnLot); err != nil {
+			return err
+		}
+		if err := repos.Loyalty.CreateEntry(&domain.LoyaltyEntry{
+			TenantID:      sale.TenantID,
+			CustomerID:    account.CustomerID,
+			TransactionID: &sale.ID,
+			Type:          domain.LoyaltyEntryClawback,
+			Points:        -claw,
+			Notes:         note,
+			CreatedBy:     createdBy,
+		}); err != nil {
+			return fmt.Errorf("failed to record clawed back points: %w", err)
+		}
+		account.Balance -= claw
+		account.LifetimePoints -= claw
+		if err := updateTier(repos, account); err != nil {
+			return err
+		}
+	}
+	if giveBack > 0 {
+		program, _ := repos.Loyalty.FindProgram(sale.TenantID)
+		if err := repos.Loyalty.CreateEntry(&domain.LoyaltyEntry{
+			TenantID:      sale.TenantID,
+			CustomerID:    account.CustomerID,
+			TransactionID: &sale.ID,
+			Type:          domain.LoyaltyEntryReturn,
+			Points:        giveBack,
+			Remaining:     giveBack,
+			ExpiresAt:     pointsExpiry(program, now),
+			Notes:         note,
+			CreatedBy:     createdBy,
+		}); err != nil {
+			return fmt.Errorf("failed to record returned points: %w", err)
+		}
+		account.Balance += giveBack
+	}
+	if err := repos.Loyalty.UpdateAccount(account); err != nil {
+		return fmt.Errorf("failed to update loyalty account: %w", err)
+	}
+	return nil
+}
+
+// expirePoints writes off the points of a customer's lots that have lapsed
+func expirePoints(repos domain.Repositories, account *domain.LoyaltyAccount, now time.Time) error {
+	lots, err := repos.Loyalty.FindOpenLots(account.CustomerID)
+	if err != nil {
+		return fmt.Errorf("failed to load loyalty points: %w", err)
+	}
+	var expired int64
+	for i := range lots {
+		lot := &lots[i]
+		if lot.ExpiresAt == nil || lot.ExpiresAt.After(now) {
+			continue
+		}
+		expired += lot.Remaining
+		lot.Remaining = 0
+		if err := repos.Loyalty.UpdateEntry(lot); err != nil {
+			return fmt.Errorf("failed to expire loyalty points: %w", err)
+		}
+	}
+	if expired == 0 {
+		return nil
+	}
+	if err := repos.Loyalty.CreateEntry(&domain.LoyaltyEntry{
+		TenantID:   account.TenantID,
+		CustomerID: account.CustomerID,
+		Type:       domain.LoyaltyEntryExpire,
+		Points:     -expired,
+		Notes:      "Points expired",
+	}); err != nil {
+		return fmt.Errorf("failed to record expired points: %w", err)
+	}
+	account.Balance -= expired
+	return repos.Loyalty.UpdateAccount(account)
+}
+
+// spendPoints uses up points from the customer's lots, the given lot first and then
+// the lots that lapse first
+func spendPoints(repos domain.Repositories, customerID uuid.UUID, points int64, first *domain.LoyaltyEntry) error {
+	if points <= 0 {
+		return nil
+	}
+	lots, err := repos.Loyalty.FindOpenLots(customerID)
+	if err != nil {
+		return fmt.Errorf("failed to load loyalty points: %w", err)
+	}
+	if first != nil {
+		for i := range lots {
+			if lots[i].ID == first.ID {
+				lots[0], lots[i] = lots[i], lots[0]
+				break
+			}
+		}
+	}
+	for i := range lots {
+		if points == 0 {
+			break
+		}
+		take := min(points, lots[i].Remaining)
+		lots[i].Remaining -= take
+		points -= take
+		if err := repos.Loyalty.UpdateEntry(&lots[i]); err != nil {
+			return fmt.Errorf("failed to spend loyalty points: %w", err)
+		}
+	}
+	return nil
+}
+
+// updateTier moves the customer to the highest tier their lifetime points reach
+func updateTier(repos domain.Repositories, account *domain.LoyaltyAccount) error {
+	tiers, err := repos.Loyalty.FindTiers(account.TenantID)
+	if err != nil {
+		return fmt.Errorf("failed to load tiers: %w", err)
+	}
+	account.TierID = nil
+	for i := range tiers {
+		if tiers[i].MinPoints <= account.LifetimePoints {
+			account.TierID = &tiers[i].ID
+		}
+	}
+	account.Tier = nil
+	return nil
+}
+
+// pointsExpiry is when points added now lapse under the program
+func pointsExpiry(program *domain.LoyaltyProgram, now time.Time) *time.Time {
+	if program == nil || program.ExpiryDays <= 0 {
+		return nil
+	}
+	expiresAt := now.AddDate(0, 0, program.ExpiryDays)
+	return &expiresAt
+}
+
+// loyaltyPaid is the part of a transaction paid (or refunded) in points
+func loyaltyPaid(payments []domain.TransactionPayment) domain.Money {
+	var paid domain.Money
+	for _, p := range payments {
+		if p.PaymentMethod == domain.PaymentLoyalty {
+			paid += p.Amount
+		}
+	}
+	return paid
+}
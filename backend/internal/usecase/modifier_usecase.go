@@ -0,0 +1,231 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/codapos/backend/internal/domain"
+	"github.com/google/uuid"
+)
+
+type ModifierUsecase struct {
+	modifierRepo domain.ModifierGroupRepository
+	productRepo  domain.ProductRepository
+}
+
+func NewModifierUsecase(mr domain.ModifierGroupRepository, pr domain.ProductRepository) *ModifierUsecase {
+	return &ModifierUsecase{modifierRepo: mr, productRepo: pr}
+}
+
+// CreateGroup creates a modifier group, optionally with its modifiers
+func (u *ModifierUsecase) CreateGroup(tenantID uuid.UUID, group *domain.ModifierGroup) error {
+	group.TenantID = tenantID
+	if err := validateModifierGroup(group); err != nil {
+		return err
+	}
+	for i := range group.Modifiers {
+		if err := validateModifier(&group.Modifiers[i]); err != nil {
+			return err
+		}
+	}
+	return u.modifierRepo.Create(group)
+}
+
+// GetGroups returns the modifier groups of a tenant with their modifiers
+func (u *ModifierUsecase) GetGroups(tenantID uuid.UUID) ([]domain.ModifierGroup, error) {
+	return u.modifierRepo.FindByTenantID(tenantID)
+}
+
+// GetGroup returns a single modifier group owned by the tenant
+func (u *ModifierUsecase) GetGroup(tenantID, id uuid.UUID) (*domain.ModifierGroup, error) {
+	group, err := u.modifierRepo.FindByID(id)
+	if err != nil || group.TenantID != tenantID {
+		return nil, errors.New("modifier group not found")
+	}
+	return group, nil
+}
+
+// UpdateGroup replaces the editable fields of a modifier group (modifiers are managed separately)
+func (u *ModifierUsecase) UpdateGroup(tenantID, id uuid.UUID, req *domain.ModifierGroup) (*domain.ModifierGroup, error) {
+	group, err := u.GetGroup(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	group.Name = req.Name
+	group.IsRequired = req.IsRequired
+	group.MinSelect = req.MinSelect
+	group.MaxSelect = req.MaxSelect
+	group.SortOrder = req.SortOrder
+
+	if err := validateModifierGroup(group); err != nil {
+		return nil, err
+	}
+	if err := u.modifierRepo.Update(group); err != nil {
+		return nil, fmt.Errorf("failed to update modifier group: %w", err)
+	}
+	return group, nil
+}
+
+// DeleteGroup removes a modifier group, its modifiers and its product links
+func (u *ModifierUsecase) DeleteGroup(tenantID, id uuid.UUID) error {
+	if _, err := u.GetGroup(tenantID, id); err != nil {
+		return err
+	}
+	return u.modifierRepo.Delete(id)
+}
+
+// AddModifier adds a modifier option to a group
+func (u *ModifierUsecase) AddModifier(tenantID, groupID uuid.UUID, modifier *domain.Modifier) error {
+	if _, err := u.GetGroup(tenantID, groupID); err != nil {
+		return err
+	}
+	modifier.GroupID = groupID
+	if err := validateModifier(modifier); err != nil {
+		return err
+	}
+	return u.modifierRepo.CreateModifier(modifier)
+}
+
+// UpdateModifier replaces the editable fields of a modifier option
+func (u *ModifierUsecase) UpdateModifier(tenantID, groupID, id uuid.UUID, req *domain.Modifier) (*domain.Modifier, error) {
+	modifier, err := u.getModifier(tenantID, groupID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	modifier.Name = req.Name
+	modifier.Price = req.Price
+	modifier.IsActive = req.IsActive
+	modifier.SortOrder = req.SortOrder
+
+	if err := validateModifier(modifier); err != nil {
+		return nil, err
+	}
+	if err := u.modifierRepo.UpdateModifier(modifier); err != nil {
+		return nil, fmt.Errorf("failed to update modifier: %w", err)
+	}
+	return modifier, nil
+}
+
+// DeleteModifier removes a modifier option from a group
+func (u *ModifierUsecase) DeleteModifier(tenantID, groupID, id uuid.UUID) error {
+	if _, err := u.getModifier(tenantID, groupID, id); err != nil {
+		return err
+	}
+	return u.modifierRepo.DeleteModifier(id)
+}
+
+// AttachToProduct sets the modifier groups offered on a product (an empty list detaches all)
+func (u *ModifierUsecase) AttachToProduct(tenantID, productID uuid.UUID, req domain.AttachModifierGroupsRequest) (*domain.Product, error) {
+	product, err := u.productRepo.FindByID(productID)
+	if err != nil || product.TenantID != tenantID {
+		return nil, errors.New("product not found")
+	}
+
+	groups := make([]domain.ModifierGroup, 0, len(req.GroupIDs))
+	for _, id := range req.GroupIDs {
+		group, err := u.GetGroup(tenantID, id)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, *group)
+	}
+
+	if err := u.modifierRepo.SetProductGroups(product, groups); err != nil {
+		return nil, fmt.Errorf("failed to attach modifier groups: %w", err)
+	}
+	return u.productRepo.FindByID(productID)
+}
+
+func (u *ModifierUsecase) getModifier(tenantID, groupID, id uuid.UUID) (*domain.Modifier, error) {
+	if _, err := u.GetGroup(tenantID, groupID); err != nil {
+		return nil, err
+	}
+	modifier, err := u.modifierRepo.FindModifierByID(id)
+	if err != nil || modifier.GroupID != groupID {
+		return nil, errors.New("modifier not found")
+	}
+	return modifier, nil
+}
+
+func validateModifierGroup(group *domain.ModifierGroup) error {
+	if group.Name == "" {
+		return errors.New("modifier group name is required")
+	}
+	if group.MinSelect < 0 || group.MaxSelect < 0 {
+		return errors.New("min_select and max_select cannot be negative")
+	}
+	if group.MaxSelect > 0 && group.MinSelect > group.MaxSelect {
+		return errors.New("min_select cannot be greater than max_select")
+	}
+	return nil
+}
+
+func validateModifier(modifier *domain.Modifier) error {
+	if modifier.Name == "" {
+		return errors.New("modifier name is required")
+	}
+	if modifier.Price < 0 {
+		return errors.New("modifier price cannot be negative")
+	}
+	return nil
+}
+
+// resolveModifiers prices the selected modifiers of a cart line from the product's
+// modifier groups and enforces each group's IsRequired, MinSelect and MaxSelect.
+func resolveModifiers(product *domain.Product, reqs []domain.ModifierRequest) ([]domain.SelectedModifier, domain.Money, error) {
+	type option struct {
+		group    *domain.ModifierGroup
+		modifier *domain.Modifier
+	}
+	options := make(map[uuid.UUID]option)
+	for gi := range product.ModifierGroups {
+		group := &product.ModifierGroups[gi]
+		for mi := range group.Modifiers {
+			if group.Modifiers[mi].IsActive {
+				options[group.Modifiers[mi].ID] = option{group: group, modifier: &group.Modifiers[mi]}
+			}
+		}
+	}
+
+	selected := make([]domain.SelectedModifier, 0, len(reqs))
+	counts := make(map[uuid.UUID]int)
+	seen := make(map[uuid.UUID]bool)
+	var total domain.Money
+	for _, req := range reqs {
+		opt, ok := options[req.ModifierID]
+		if !ok {
+			return nil, 0, fmt.Errorf("modifier %s is not available for %s", req.ModifierID, product.Name)
+		}
+		if seen[req.ModifierID] {
+			return nil, 0, fmt.Errorf("modifier %s selected more than once", opt.modifier.Name)
+		}
+		seen[req.ModifierID] = true
+		counts[opt.group.ID]++
+		total += opt.modifier.Price
+		selected = append(selected, domain.SelectedModifier{
+			ModifierID: opt.modifier.ID,
+			GroupID:    opt.group.ID,
+			GroupName:  opt.group.Name,
+			Name:       opt.modifier.Name,
+			Price:      opt.modifier.Price,
+		})
+	}
+
+	for _, group := range product.ModifierGroups {
+		min := group.MinSelect
+		if group.IsRequired && min < 1 {
+			min = 1
+		}
+		count := counts[group.ID]
+		if count < min {
+			return nil, 0, fmt.Errorf("%s: choose at least %d from %s", product.Name, min, group.Name)
+		}
+		if group.MaxSelect > 0 && count > group.MaxSelect {
+			return nil, 0, fmt.Errorf("%s: choose at most %d from %s", product.Name, group.MaxSelect, group.Name)
+		}
+	}
+
+	return selected, total, nil
+}
@@ -0,0 +1,466 @@
+package usecase
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/codapos/backend/internal/domain"
+	"github.com/codapos/backend/internal/payment"
+	"github.com/google/uuid"
+)
+
+// Errors of handling a gateway notification; the gateway retries notifications answered with an error
+var (
+	ErrInvalidNotification = errors.New("invalid payment notification")
+	ErrPaymentOrderUnknown = errors.New("payment order not found")
+)
+
+// Errors of starting a payment at a gateway
+var (
+	ErrGatewayUnavailable = errors.New("payment gateway is not available")
+	ErrChargeFailed       = errors.New("payment gateway refused the payment")
+)
+
+// cancelReasonRefunded is the cancel reason of a delivery order whose payment was refunded
+const cancelReasonRefunded = "payment refunded"
+
+type PaymentUsecase struct {
+	configRepo        domain.GlobalConfigRepository
+	notificationRepo  domain.PaymentNotificationRepository
+	tenantRepo        domain.TenantRepository
+	deliveryRepo      domain.DeliveryRepository
+	transactionRepo   domain.TransactionRepository
+	subscriptionRepo  domain.SubscriptionRepository
+	tenantBillingRepo domain.TenantBillingRepository
+	pos               *POSUsecase
+	gateways          map[string]domain.PaymentGateway // by provider
+}
+
+func NewPaymentUsecase(
+	gcr domain.GlobalConfigRepository,
+	pnr domain.PaymentNotificationRepository,
+	tnr domain.TenantRepository,
+	dr domain.DeliveryRepository,
+	tr domain.TransactionRepository,
+	sr domain.SubscriptionRepository,
+	tbr domain.TenantBillingRepository,
+	pos *POSUsecase,
+	gateways ...domain.PaymentGateway,
+) *PaymentUsecase {
+	byProvider := make(map[string]domain.PaymentGateway, len(gateways))
+	for _, g := range gateways {
+		byProvider[g.Provider()] = g
+	}
+	return &PaymentUsecase{
+		configRepo:        gcr,
+		notificationRepo:  pnr,
+		tenantRepo:        tnr,
+		deliveryRepo:      dr,
+		transactionRepo:   tr,
+		subscriptionRepo:  sr,
+		tenantBillingRepo: tbr,
+		pos:               pos,
+		gateways:          byProvider,
+	}
+}
+
+// Gateway returns the gateway of a provider
+func (u *PaymentUsecase) Gateway(provider string) (domain.PaymentGateway, error) {
+	g, ok := u.gateways[provider]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrGatewayUnavailable, provider)
+	}
+	return g, nil
+}
+
+// GatewayFor returns the gateway customers of a tenant pay through: the tenant's own
+// choice, else the platform default, else Midtrans. Without a tenant, e.g. for platform
+// invoices, it is the platform default.
+func (u *PaymentUsecase) GatewayFor(tenantID *uuid.UUID) (domain.PaymentGateway, error) {
+	if tenantID != nil {
+		tenant, err := u.tenantRepo.FindByID(*tenantID)
+		if err != nil {
+			return nil, errors.New("tenant not found")
+		}
+		if tenant.PaymentGateway != "" {
+			return u.Gateway(tenant.PaymentGateway)
+		}
+	}
+	if provider, err := u.configRepo.FindByKey(domain.ConfigPaymentGateway); err == nil && provider.Value != "" {
+		return u.Gateway(provider.Value)
+	}
+	return u.Gateway(domain.PaymentProviderMidtrans)
+}
+
+// SetTenantGateway sets the gateway a tenant's customers pay through; an empty provider
+// returns the tenant to the platform default
+func (u *PaymentUsecase) SetTenantGateway(tenantID uuid.UUID, provider string) (*domain.Tenant, error) {
+	provider = strings.TrimSpace(provider)
+	if provider != "" {
+		if _, err := u.Gateway(provider); err != nil {
+			return nil, err
+		}
+	}
+	tenant, err := u.tenantRepo.FindByID(tenantID)
+	if err != nil {
+		return nil, errors.New("tenant not found")
+	}
+	tenant.PaymentGateway = provider
+	if err := u.tenantRepo.Update(tenant); err != nil {
+		return nil, fmt.Errorf("failed to update tenant: %w", err)
+	}
+	return tenant, nil
+}
+
+// CreateCharge starts a payment through the gateway of the tenant
+func (u *PaymentUsecase) CreateCharge(tenantID *uuid.UUID, req domain.ChargeRequest) (*domain.Charge, error) {
+	req.OrderID = strings.TrimSpace(req.OrderID)
+	if req.OrderID == "" || len(req.OrderID) > 50 {
+		return nil, errors.New("order ID is required and at most 50 characters")
+	}
+	if req.Amount < domain.Rupiah(1) {
+		return nil, errors.New("amount must be at least Rp 1")
+	}
+	gateway, err := u.GatewayFor(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	charge, err := gateway.CreateCharge(req)
+	if errors.Is(err, payment.ErrNotConfigured) {
+		return nil, fmt.Errorf("%w: %s is not configured", ErrGatewayUnavailable, gateway.Provider())
+	}
+	if err != nil {
+		log.Printf("⚠️ %s charge for %s failed: %v", gateway.Provider(), req.OrderID, err)
+		return nil, fmt.Errorf("%w: %v", ErrChargeFailed, err)
+	}
+	return charge, nil
+}
+
+// HandleNotification verifies a payment notification posted by a gateway and applies it
+// to the order it pays for: a storefront delivery order, a POS transaction, a subscription
+// invoice or an MDR invoice. Notifications may arrive more than once and out of order,
+// so a target only ever moves forward; repeats and stale states leave it unchanged.
+// Every notification is logged with its outcome.
+func (u *PaymentUsecase) HandleNotification(provider string, payload []byte) (*domain.PaymentNotification, error) {
+	record := &domain.PaymentNotification{
+		Provider: provider,
+		Payload:  domain.JSON("{}"),
+	}
+	if json.Valid(payload) {
+		record.Payload = domain.JSON(payload)
+	}
+	gateway, err := u.Gateway(provider)
+	if err != nil {
+		record.Result = domain.NotificationRejected
+		record.Message = err.Error()
+		return record, u.logNotification(record, ErrInvalidNotification)
+	}
+
+	p, err := gateway.VerifyNotification(payload)
+	if p != nil {
+		fillNotification(record, p)
+	}
+	switch {
+	case err == nil:
+	case errors.Is(err, payment.ErrInvalidSignature), errors.Is(err, payment.ErrInvalidPayload):
+		record.Result = domain.NotificationRejected
+		record.Message = err.Error()
+		return record, u.logNotification(record, ErrInvalidNotification)
+	default:
+		record.Result = domain.NotificationFailed
+		record.Message = err.Error()
+		return record, u.logNotification(record, err)
+	}
+	record.SignatureValid = true
+	return record, u.apply(record)
+}
+
+// SyncPayment asks the gateway for the state of a payment and applies it like a
+// notification, for when a notification never arrived
+func (u *PaymentUsecase) SyncPayment(provider, orderID string) (*domain.PaymentNotification, error) {
+	gateway, err := u.Gateway(provider)
+	if err != nil {
+		return nil, err
+	}
+	p, err := gateway.GetStatus(orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch payment status: %w", err)
+	}
+	record := &domain.PaymentNotification{Provider: provider, SignatureValid: true}
+	fillNotification(record, p)
+	record.Payload, _ = json.Marshal(p)
+	return record, u.apply(record)
+}
+
+// GetNotifications returns the logged gateway notifications, newest first
+func (u *PaymentUsecase) GetNotifications(orderID, result string, page, perPage int) ([]domain.PaymentNotification, int64, error) {
+	if orderID != "" {
+		notifications, err := u.notificationRepo.FindByOrderID(orderID)
+		return notifications, int64(len(notifications)), err
+	}
+	return u.notificationRepo.FindAll(result, perPage, (page-1)*perPage)
+}
+
+// apply applies a verified payment state to its target and logs the outcome
+func (u *PaymentUsecase) apply(record *domain.PaymentNotification) error {
+	if err := u.applyNotification(record); err != nil {
+		if errors.Is(err, ErrPaymentOrderUnknown) {
+			record.Result = domain.NotificationUnmatched
+		} else {
+			record.Result = domain.NotificationFailed
+		}
+		record.Message = err.Error()
+		return u.logNotification(record, err)
+	}
+	return u.logNotification(record, nil)
+}
+
+func fillNotification(record *domain.PaymentNotification, p *domain.GatewayPayment) {
+	record.OrderID = p.OrderID
+	record.GatewayTransactionID = p.GatewayTransactionID
+	record.TransactionStatus = p.TransactionStatus
+	record.FraudStatus = p.FraudStatus
+	record.StatusCode = p.StatusCode
+	record.PaymentType = p.PaymentType
+	record.Status = p.Status
+	record.GrossAmount = p.Amount
+}
+
+// logNotification stores a notification and passes on the error of handling it. A
+// notification that could not be stored is answered with an error so it is sent again.
+func (u *PaymentUsecase) logNotification(record *domain.PaymentNotification, handleErr error) error {
+	if err := u.notificationRepo.Create(record); err != nil {
+		log.Printf("⚠️ Failed to log %s notification for %s: %v", record.Provider, record.OrderID, err)
+		if handleErr == nil {
+			return fmt.Errorf("failed to log payment notification: %w", err)
+		}
+	}
+	return handleErr
+}
+
+// applyNotification finds what the order pays for and moves it to the notified status
+func (u *PaymentUsecase) applyNotification(record *domain.PaymentNotification) error {
+	if order, err := u.deliveryRepo.FindOrderByMidtransOrderID(record.OrderID); err == nil {
+		record.TenantID, record.TargetType, record.TargetID = &order.TenantID, domain.PaymentTargetDeliveryOrder, &order.ID
+		err := u.applyToDeliveryOrder(order, record)
+		if errors.Is(err, ErrOrderChanged) {
+			// The merchant or another notification moved the order on meanwhile; decide
+			// again from where it is now
+			if order, err = u.deliveryRepo.FindOrderByID(order.ID); err != nil {
+				return fmt.Errorf("failed to reload delivery order: %w", err)
+			}
+			return u.applyToDeliveryOrder(order, record)
+		}
+		return err
+	}
+	if p, err := u.transactionRepo.FindPaymentByReference(record.OrderID); err == nil {
+		tx, err := u.transactionRepo.FindByID(p.TransactionID)
+		if err != nil {
+			return fmt.Errorf("failed to load transaction: %w", err)
+		}
+		record.TenantID, record.TargetType, record.TargetID = &tx.TenantID, domain.PaymentTargetTransaction, &tx.ID
+		return u.applyToTransactionPayment(tx, p, record)
+	}
+	if invoice, err := u.subscriptionRepo.FindInvoiceByNumber(record.OrderID); err == nil {
+		record.TenantID, record.TargetType, record.TargetID = &invoice.TenantID, domain.PaymentTargetBillingInvoice, &invoice.ID
+		return u.applyToInvoice(invoice, record)
+	}
+	if id, ok := domain.ParseTenantBillingOrderID(record.OrderID); ok {
+		if bill, err := u.tenantBillingRepo.FindByID(id); err == nil {
+			record.TenantID, record.TargetType, record.TargetID = &bill.TenantID, domain.PaymentTargetTenantBilling, &bill.ID
+			return u.applyToTenantBilling(bill, record)
+		}
+	}
+	return ErrPaymentOrderUnknown
+}
+
+// applyToDeliveryOrder releases a paid storefront order to the merchant and books its
+// sale, or cancels it when the payment fails, expires or is refunded before the order
+// is on its way; a refund voids the sale booked for it. A payment for an order that was
+// cancelled meanwhile is flagged for a refund. The order is locked and its status checked
+// again when it is changed, and ErrOrderChanged is returned when it moved on.
+func (u *PaymentUsecase) applyToDeliveryOrder(order *domain.DeliveryOrder, record *domain.PaymentNotification) error {
+	now := time.Now()
+	from := order.Status
+	switch record.Status {
+	case domain.GatewayStatusPaid:
+		if order.Status == domain.DeliveryStatusCancelled && order.CancelReason != cancelReasonRefunded {
+			return refundRequired(record, "payment received for an order that is already cancelled")
+		}
+		if order.Status != domain.DeliveryStatusWaitingPayment {
+			return unchanged(record, "order is already "+order.Status)
+		}
+		if record.GrossAmount != order.TotalAmount {
+			return amountMismatch(record, order.TotalAmount)
+		}
+		order.Status = domain.DeliveryStatusPending
+	case domain.GatewayStatusFailed, domain.GatewayStatusExpired:
+		if order.Status != domain.DeliveryStatusWaitingPayment {
+			return unchanged(record, "order is already "+order.Status)
+		}
+		order.Status = domain.DeliveryStatusCancelled
+		order.CancelledAt = &now
+		order.CancelReason = "payment " + record.Status
+	case domain.GatewayStatusRefunded:
+		switch order.Status {
+		case domain.DeliveryStatusWaitingPayment, domain.DeliveryStatusPending, domain.DeliveryStatusPreparing:
+		default:
+			return unchanged(record, "refund recorded; order is already "+order.Status)
+		}
+		order.Status = domain.DeliveryStatusCancelled
+		order.CancelledAt = &now
+		order.CancelReason = cancelReasonRefunded
+	default:
+		return unchanged(record, "")
+	}
+	if order.Status == domain.DeliveryStatusPending {
+		err := u.pos.BookOnlineOrder(order, record.PaymentType)
+		if errors.Is(err, ErrOutOfStock) {
+			// The reservation lapsed before the payment came in and the stock was sold since
+			order.Status = domain.DeliveryStatusCancelled
+			order.CancelledAt = &now
+			order.CancelReason = err.Error()
+			if err := u.pos.CancelOnlineOrder(order, from, nil); err != nil {
+				return fmt.Errorf("failed to cancel delivery order: %w", err)
+			}
+			return refundRequired(record, "paid after its stock reservation lapsed, "+err.Error()+"; order cancelled")
+		}
+		if err != nil {
+			return fmt.Errorf("failed to book sale of delivery order: %w", err)
+		}
+	} else if err := u.pos.CancelOnlineOrder(order, from, nil); err != nil {
+		return fmt.Errorf("failed to cancel delivery order: %w", err)
+	}
+	return applied(record, "order is now "+order.Status)
+}
+
+// applyToTransactionPayment keeps the status of a POS gateway payment in step with the
+// gateway. The sale itself is not changed: a sale whose payment failed or was refunded
+// is flagged in the log for the cashier to void or refund.
+func (u *PaymentUsecase) applyToTransactionPayment(tx *domain.Transaction, p *domain.TransactionPayment, record *domain.PaymentNotification) error {
+	var status string
+	switch record.Status {
+	case domain.GatewayStatusPaid:
+		if p.Status == domain.PaymentStatusRefunded {
+			return unchanged(record, "payment was already refunded")
+		}
+		if record.GrossAmount != p.Amount {
+			return amountMismatch(record, p.Amount)
+		}
+		status = domain.PaymentStatusCompleted
+	case domain.GatewayStatusFailed, domain.GatewayStatusExpired:
+		if p.Status == domain.PaymentStatusRefunded {
+			return unchanged(record, "payment was already refunded")
+		}
+		status = domain.PaymentStatusFailed
+	case domain.GatewayStatusRefunded:
+		status = domain.PaymentStatusRefunded
+	default:
+		return unchanged(record, "")
+	}
+	if p.Status == status {
+		return unchanged(record, "payment is already "+status)
+	}
+	p.Status = status
+	if err := u.transactionRepo.UpdatePayment(p); err != nil {
+		return fmt.Errorf("failed to update payment: %w", err)
+	}
+	message := "payment is now " + status
+	if status != domain.PaymentStatusCompleted && tx.Status == domain.TransactionStatusCompleted {
+		message += "; sale " + tx.TransactionNumber + " should be voided or refunded"
+	}
+	return applied(record, message)
+}
+
+// applyToInvoice settles a subscription invoice
+func (u *PaymentUsecase) applyToInvoice(invoice *domain.BillingInvoice, record *domain.PaymentNotification) error {
+	switch record.Status {
+	case domain.GatewayStatusPaid:
+		if invoice.Status == domain.InvoiceStatusPaid || invoice.Status == domain.InvoiceStatusRefunded {
+			return unchanged(record, "invoice is already "+invoice.Status)
+		}
+		if record.GrossAmount != invoice.Amount {
+			return amountMismatch(record, invoice.Amount)
+		}
+		now := time.Now()
+		invoice.Status = domain.InvoiceStatusPaid
+		invoice.PaidAt = &now
+	case domain.GatewayStatusFailed, domain.GatewayStatusExpired:
+		if invoice.Status != domain.InvoiceStatusPending {
+			return unchanged(record, "invoice is already "+invoice.Status)
+		}
+		invoice.Status = domain.InvoiceStatusFailed
+		if record.Status == domain.GatewayStatusExpired {
+			invoice.Status = domain.InvoiceStatusExpired
+		}
+	case domain.GatewayStatusRefunded:
+		if invoice.Status != domain.InvoiceStatusPaid {
+			return unchanged(record, "invoice is "+invoice.Status)
+		}
+		invoice.Status = domain.InvoiceStatusRefunded
+	default:
+		return unchanged(record, "")
+	}
+	if err := u.subscriptionRepo.UpdateInvoice(invoice); err != nil {
+		return fmt.Errorf("failed to update invoice: %w", err)
+	}
+	return applied(record, "invoice is now "+invoice.Status)
+}
+
+// applyToTenantBilling settles a monthly MDR invoice; a refund reopens it
+func (u *PaymentUsecase) applyToTenantBilling(bill *domain.TenantBilling, record *domain.PaymentNotification) error {
+	switch record.Status {
+	case domain.GatewayStatusPaid:
+		if bill.Status == domain.BillingStatusPaid {
+			return unchanged(record, "billing is already paid")
+		}
+		if due := bill.TotalMDR + bill.PenaltyFee; record.GrossAmount != due {
+			return amountMismatch(record, due)
+		}
+		bill.Status = domain.BillingStatusPaid
+	case domain.GatewayStatusRefunded:
+		if bill.Status != domain.BillingStatusPaid {
+			return unchanged(record, "billing is "+bill.Status)
+		}
+		bill.Status = domain.BillingStatusUnpaid
+	default:
+		// A failed or expired payment leaves the billing due
+		return unchanged(record, "")
+	}
+	if err := u.tenantBillingRepo.Update(bill); err != nil {
+		return fmt.Errorf("failed to update billing: %w", err)
+	}
+	return applied(record, "billing is now "+bill.Status)
+}
+
+func applied(record *domain.PaymentNotification, message string) error {
+	record.Result = domain.NotificationApplied
+	record.Message = message
+	return nil
+}
+
+func unchanged(record *domain.PaymentNotification, message string) error {
+	record.Result = domain.NotificationUnchanged
+	record.Message = message
+	return nil
+}
+
+// refundRequired leaves the target as it is; the payment has to be refunded to the customer
+func refundRequired(record *domain.PaymentNotification, message string) error {
+	record.Result = domain.NotificationRefundRequired
+	record.Message = message
+	log.Printf("⚠️ Payment %s: %s, refund it", record.OrderID, record.Message)
+	return nil
+}
+
+// amountMismatch leaves the target as it is for someone to review
+func amountMismatch(record *domain.PaymentNotification, due domain.Money) error {
+	record.Result = domain.NotificationAmountMismatch
+	record.Message = fmt.Sprintf("paid %s but %s is due", record.GrossAmount, due)
+	log.Printf("⚠️ Payment %s: %s", record.OrderID, record.Message)
+	return nil
+}
@@ -0,0 +1,209 @@
+package usecase
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/codapos/backend/internal/domain"
+	"github.com/codapos/backend/internal/payment"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// The repositories below keep what the notification flow touches in memory. The
+// embedded interfaces are nil, so any other method panics if a test reaches it.
+
+type memoryConfigRepo struct {
+	domain.GlobalConfigRepository
+	values map[string]string
+}
+
+func (r *memoryConfigRepo) FindByKey(key string) (*domain.GlobalConfig, error) {
+	v, ok := r.values[key]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &domain.GlobalConfig{Key: key, Value: v}, nil
+}
+
+type memoryNotificationRepo struct {
+	domain.PaymentNotificationRepository
+	logged []domain.PaymentNotification
+}
+
+func (r *memoryNotificationRepo) Create(n *domain.PaymentNotification) error {
+	r.logged = append(r.logged, *n)
+	return nil
+}
+
+type noDeliveryRepo struct{ domain.DeliveryRepository }
+
+func (noDeliveryRepo) FindOrderByMidtransOrderID(string) (*domain.DeliveryOrder, error) {
+	return nil, gorm.ErrRecordNotFound
+}
+
+type noTransactionRepo struct{ domain.TransactionRepository }
+
+func (noTransactionRepo) FindPaymentByReference(string) (*domain.TransactionPayment, error) {
+	return nil, gorm.ErrRecordNotFound
+}
+
+type memoryInvoiceRepo struct {
+	domain.SubscriptionRepository
+	invoices map[string]*domain.BillingInvoice
+	updates  int
+}
+
+func (r *memoryInvoiceRepo) FindInvoiceByNumber(number string) (*domain.BillingInvoice, error) {
+	invoice, ok := r.invoices[number]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *invoice
+	return &copied, nil
+}
+
+func (r *memoryInvoiceRepo) UpdateInvoice(invoice *domain.BillingInvoice) error {
+	r.updates++
+	copied := *invoice
+	r.invoices[invoice.InvoiceNumber] = &copied
+	return nil
+}
+
+// paymentFixture is a payment usecase on the fake gateway with one pending invoice
+type paymentFixture struct {
+	usecase       *PaymentUsecase
+	gateway       *payment.Fake
+	invoices      *memoryInvoiceRepo
+	notifications *memoryNotificationRepo
+	invoice       *domain.BillingInvoice
+}
+
+func newPaymentFixture(t *testing.T, due domain.Money) *paymentFixture {
+	t.Helper()
+	invoice := &domain.BillingInvoice{
+		ID:            uuid.New(),
+		TenantID:      uuid.New(),
+		InvoiceNumber: "INV-TEST-0001",
+		Amount:        due,
+		Status:        domain.InvoiceStatusPending,
+	}
+	f := &paymentFixture{
+		gateway:       payment.NewFake("test-secret", "http://fake.test/pay"),
+		invoices:      &memoryInvoiceRepo{invoices: map[string]*domain.BillingInvoice{invoice.InvoiceNumber: invoice}},
+		notifications: &memoryNotificationRepo{},
+		invoice:       invoice,
+	}
+	configs := &memoryConfigRepo{values: map[string]string{domain.ConfigPaymentGateway: domain.PaymentProviderFake}}
+	f.usecase = NewPaymentUsecase(configs, f.notifications, nil, noDeliveryRepo{}, noTransactionRepo{}, f.invoices, nil, nil, f.gateway)
+	return f
+}
+
+// charge starts the payment of the invoice through the usecase and returns the signed
+// notification of it being paid
+func (f *paymentFixture) charge(t *testing.T, amount domain.Money) []byte {
+	t.Helper()
+	charge, err := f.usecase.CreateCharge(nil, domain.ChargeRequest{OrderID: f.invoice.InvoiceNumber, Amount: amount})
+	if err != nil {
+		t.Fatalf("CreateCharge: %v", err)
+	}
+	if charge.Provider != domain.PaymentProviderFake || charge.Token == "" {
+		t.Fatalf("CreateCharge returned %+v", charge)
+	}
+	payload, err := f.gateway.Notify(f.invoice.InvoiceNumber, domain.GatewayStatusPaid)
+	if err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	return payload
+}
+
+func (f *paymentFixture) stored() *domain.BillingInvoice {
+	return f.invoices.invoices[f.invoice.InvoiceNumber]
+}
+
+func TestHandleNotificationPaysInvoice(t *testing.T) {
+	f := newPaymentFixture(t, domain.Rupiah(150000))
+	payload := f.charge(t, domain.Rupiah(150000))
+
+	record, err := f.usecase.HandleNotification(domain.PaymentProviderFake, payload)
+	if err != nil {
+		t.Fatalf("HandleNotification: %v", err)
+	}
+	if record.Result != domain.NotificationApplied || !record.SignatureValid {
+		t.Errorf("result = %s (signature valid %v), want applied", record.Result, record.SignatureValid)
+	}
+	if record.TargetType != domain.PaymentTargetBillingInvoice || *record.TargetID != f.invoice.ID {
+		t.Errorf("target = %s %v, want the invoice", record.TargetType, record.TargetID)
+	}
+	if got := f.stored(); got.Status != domain.InvoiceStatusPaid || got.PaidAt == nil {
+		t.Errorf("invoice status = %s, paid at %v, want paid", got.Status, got.PaidAt)
+	}
+	if len(f.notifications.logged) != 1 {
+		t.Errorf("logged %d notifications, want 1", len(f.notifications.logged))
+	}
+}
+
+func TestHandleNotificationRejectsBadSignature(t *testing.T) {
+	f := newPaymentFixture(t, domain.Rupiah(150000))
+	payload := f.charge(t, domain.Rupiah(150000))
+	// The amount is changed after signing
+	tampered := bytes.Replace(payload, []byte(`"amount":150000`), []byte(`"amount":1`), 1)
+	if bytes.Equal(tampered, payload) {
+		t.Fatalf("payload %s has no amount to tamper with", payload)
+	}
+
+	record, err := f.usecase.HandleNotification(domain.PaymentProviderFake, tampered)
+	if !errors.Is(err, ErrInvalidNotification) {
+		t.Fatalf("HandleNotification error = %v, want ErrInvalidNotification", err)
+	}
+	if record.Result != domain.NotificationRejected || record.SignatureValid {
+		t.Errorf("result = %s (signature valid %v), want rejected", record.Result, record.SignatureValid)
+	}
+	if got := f.stored(); got.Status != domain.InvoiceStatusPending || f.invoices.updates != 0 {
+		t.Errorf("invoice status = %s after %d updates, want it left pending", got.Status, f.invoices.updates)
+	}
+	if len(f.notifications.logged) != 1 || f.notifications.logged[0].Result != domain.NotificationRejected {
+		t.Errorf("logged %+v, want the rejected notification", f.notifications.logged)
+	}
+}
+
+func TestHandleNotificationAmountMismatch(t *testing.T) {
+	f := newPaymentFixture(t, domain.Rupiah(150000))
+	payload := f.charge(t, domain.Rupiah(100000))
+
+	record, err := f.usecase.HandleNotification(domain.PaymentProviderFake, payload)
+	if err != nil {
+		t.Fatalf("HandleNotification: %v", err)
+	}
+	if record.Result != domain.NotificationAmountMismatch {
+		t.Errorf("result = %s, want amount_mismatch", record.Result)
+	}
+	if got := f.stored(); got.Status != domain.InvoiceStatusPending || f.invoices.updates != 0 {
+		t.Errorf("invoice status = %s after %d updates, want it left pending", got.Status, f.invoices.updates)
+	}
+}
+
+func TestHandleNotificationReplayIsIdempotent(t *testing.T) {
+	f := newPaymentFixture(t, domain.Rupiah(150000))
+	payload := f.charge(t, domain.Rupiah(150000))
+
+	if _, err := f.usecase.HandleNotification(domain.PaymentProviderFake, payload); err != nil {
+		t.Fatalf("first HandleNotification: %v", err)
+	}
+	paidAt := *f.stored().PaidAt
+
+	record, err := f.usecase.HandleNotification(domain.PaymentProviderFake, payload)
+	if err != nil {
+		t.Fatalf("replayed HandleNotification: %v", err)
+	}
+	if record.Result != domain.NotificationUnchanged {
+		t.Errorf("replay result = %s, want unchanged", record.Result)
+	}
+	if f.invoices.updates != 1 || !f.stored().PaidAt.Equal(paidAt) {
+		t.Errorf("invoice updated %d times, paid at %v then %v; want one update", f.invoices.updates, paidAt, f.stored().PaidAt)
+	}
+	if len(f.notifications.logged) != 2 {
+		t.Errorf("logged %d notifications, want both deliveries", len(f.notifications.logged))
+	}
+}
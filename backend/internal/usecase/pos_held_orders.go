@@ -0,0 +1,313 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/codapos/backend/internal/domain"
+	"github.com/google/uuid"
+)
+
+// heldOrderExpiry returns when an unpaid order at the outlet lapses, nil when it never does
+func heldOrderExpiry(outlet *domain.Outlet, from time.Time) *time.Time {
+	if outlet.HeldOrderExpiryMinutes <= 0 {
+		return nil
+	}
+	expiresAt := from.Add(time.Duration(outlet.HeldOrderExpiryMinutes) * time.Minute)
+	return &expiresAt
+}
+
+// HoldOrder parks a cart as an open tab (per table or customer name) without payments.
+// Stock is only deducted when the tab is settled.
+func (u *POSUsecase) HoldOrder(tenantID, cashierID uuid.UUID, req domain.HeldOrderRequest) (*domain.Transaction, error) {
+	if existing, err := u.findIdempotent(tenantID, req.IdempotencyKey, domain.TransactionTypeSale, nil); existing != nil || err != nil {
+		return existing, err
+	}
+	outlet, err := u.outletRepo.FindByID(req.OutletID)
+	if err != nil || outlet.TenantID != tenantID {
+		return nil, errors.New("outlet not found")
+	}
+
+	tx, err := u.priceOrder(tenantID, outlet, req.Items, req.PromotionID, nil)
+	if err != nil {
+		return nil, err
+	}
+	table, err := u.seatOrder(tx, req.OrderType, req.TableID, req.GuestCount, true)
+	if err != nil {
+		return nil, err
+	}
+	tabName := strings.TrimSpace(req.TabName)
+	if tabName == "" && table != nil {
+		tabName = table.Name
+	}
+	if tabName == "" {
+		return nil, errors.New("tab name is required")
+	}
+	tx.CashierID = cashierID
+	tx.Status = domain.TransactionStatusPending
+	tx.TabName = tabName
+	tx.Notes = req.Notes
+	tx.IdempotencyKey = optionalKey(req.IdempotencyKey)
+	tx.ExpiresAt = heldOrderExpiry(outlet, time.Now())
+
+	var kitchenEvents []domain.KitchenEvent
+	err = u.uow.Do(func(repos domain.Repositories) error {
+		number, err := u.sequences.NextWith(repos.Sequences, tenantID, &tx.OutletID, domain.SequenceTransaction)
+		if err != nil {
+			return err
+		}
+		tx.TransactionNumber = number
+		if err := repos.Transactions.Create(tx); err != nil {
+			return fmt.Errorf("failed to hold order: %w", err)
+		}
+		if table != nil {
+			if err := repos.Tables.Seat(table.ID, domain.TableStatusOccupied, &tx.ID, time.Now()); err != nil {
+				return err
+			}
+		}
+		kitchenEvents, err = u.kitchen.fire(repos, tx)
+		return err
+	})
+	if err != nil {
+		if existing, _ := u.findIdempotent(tenantID, req.IdempotencyKey, domain.TransactionTypeSale, nil); existing != nil {
+			return existing, nil
+		}
+		return nil, err
+	}
+	u.kitchen.publish(kitchenEvents)
+	return tx, nil
+}
+
+// UpdateHeldOrder replaces the items of an open tab and prices it again at the current
+// prices. Splits that were prepared for the old contents are dropped.
+func (u *POSUsecase) UpdateHeldOrder(tenantID, id uuid.UUID, req domain.HeldOrderRequest) (*domain.Transaction, error) {
+	tx, err := u.heldOrder(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if hasPaidSplits(tx) {
+		return nil, errors.New("bill already has paid splits and cannot be changed")
+	}
+	if req.GuestCount < 0 {
+		return nil, errors.New("guest count cannot be negative")
+	}
+	outlet, err := u.outletRepo.FindByID(tx.OutletID)
+	if err != nil {
+		return nil, errors.New("outlet not found")
+	}
+
+	priced, err := u.priceOrder(tenantID, outlet, req.Items, req.PromotionID, nil)
+	if err != nil {
+		return nil, err
+	}
+	reprice(tx, priced)
+	if req.GuestCount > 0 {
+		tx.GuestCount = req.GuestCount
+	}
+	if tabName := strings.TrimSpace(req.TabName); tabName != "" {
+		tx.TabName = tabName
+	}
+	tx.Notes = req.Notes
+	tx.ExpiresAt = heldOrderExpiry(outlet, time.Now())
+
+	var kitchenEvents []domain.KitchenEvent
+	err = u.uow.Do(func(repos domain.Repositories) error {
+		if _, err := lockHeldOrder(repos, tx.ID); err != nil {
+			return err
+		}
+		if err := repos.SplitBills.DeleteByTransactionID(tx.ID); err != nil {
+			return fmt.Errorf("failed to clear splits: %w", err)
+		}
+		if err := repos.Transactions.ReplaceItems(tx); err != nil {
+			return fmt.Errorf("failed to update order: %w", err)
+		}
+		// Added dishes go to the kitchen as a new round, removed ones are taken off the queue
+		kitchenEvents, err = u.kitchen.fire(repos, tx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	u.kitchen.publish(kitchenEvents)
+	return u.transactionRepo.FindByID(tx.ID)
+}
+
+// GetHeldOrders returns the open tabs of a tenant, optionally for one outlet.
+// Tabs past their expiry are expired first.
+func (u *POSUsecase) GetHeldOrders(tenantID uuid.UUID, outletID *uuid.UUID) ([]domain.Transaction, error) {
+	if err := expireHeldOrders(u.uow, u.kitchen, &tenantID); err != nil {
+		return nil, err
+	}
+	return u.transactionRepo.FindHeld(tenantID, outletID)
+}
+
+// SettleHeldOrder pays an open tab in full, possibly on another device than the one
+// that opened it. Stock is deducted and the journal posted as for a checkout.
+func (u *POSUsecase) SettleHeldOrder(tenantID, cashierID, id uuid.UUID, req domain.SettleOrderRequest) (*domain.Transaction, error) {
+	if err := u.checkBillingStatus(tenantID); err != nil {
+		return nil, err
+	}
+	tx, err := u.heldOrder(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if hasPaidSplits(tx) {
+		return nil, errors.New("bill is being paid in splits, pay the remaining splits")
+	}
+	shiftID, err := u.openShiftAt(cashierID, tx.OutletID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.applyTip(tenantID, cashierID, tx, req.TipAmount, req.TipStaffID); err != nil {
+		return nil, err
+	}
+	tx.ShiftID = shiftID
+	payments, err := applyPayments(tx, req.Payments)
+	if err != nil {
+		return nil, err
+	}
+	member, err := u.loyalty.member(tenantID, tx.CustomerID, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := member.redeemPoints(tx, req.Payments); err != nil {
+		return nil, err
+	}
+	if err := u.giftCards.attach(tenantID, tx, tx.TotalAmount, req.Payments, payments); err != nil {
+		return nil, err
+	}
+	tx.Status = domain.TransactionStatusCompleted
+	tx.ExpiresAt = nil
+
+	err = u.uow.Do(func(repos domain.Repositories) error {
+		if err := holdShift(repos, shiftID); err != nil {
+			return err
+		}
+		locked, err := lockHeldOrder(repos, tx.ID)
+		if err != nil {
+			return err
+		}
+		// The tab was priced from what was read above; an edit in between would be lost
+		if !sameItems(locked.Items, tx.Items) {
+			return errors.New("order was changed on another device, reload it and try again")
+		}
+		if err := repos.SplitBills.DeleteByTransactionID(tx.ID); err != nil {
+			return fmt.Errorf("failed to clear splits: %w", err)
+		}
+		tx.Splits = nil
+		if err := repos.Transactions.Update(tx); err != nil {
+			return fmt.Errorf("failed to settle order: %w", err)
+		}
+		for i := range payments {
+			if err := repos.Transactions.CreatePayment(&payments[i]); err != nil {
+				return fmt.Errorf("failed to record payment: %w", err)
+			}
+		}
+		tx.Payments = payments
+		if err := u.giftCards.book(repos, tx, payments); err != nil {
+			return err
+		}
+		if err := repos.Tables.ReleaseByTransaction(tx.ID); err != nil {
+			return fmt.Errorf("failed to release table: %w", err)
+		}
+		return u.settleSale(repos, tenantID, cashierID, tx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return u.transactionRepo.FindByID(tx.ID)
+}
+
+// heldOrder loads an open tab of the tenant, expiring it when its time has passed
+func (u *POSUsecase) heldOrder(tenantID, id uuid.UUID) (*domain.Transaction, error) {
+	tx, err := u.transactionRepo.FindByID(id)
+	if err != nil || tx.TenantID != tenantID || tx.Type != domain.TransactionTypeSale {
+		return nil, errors.New("order not found")
+	}
+	if tx.Status != domain.TransactionStatusPending {
+		return nil, errors.New("order is not open")
+	}
+	if tx.ExpiresAt != nil && time.Now().After(*tx.ExpiresAt) {
+		if err := expireHeldOrders(u.uow, u.kitchen, &tenantID); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("order has expired")
+	}
+	return tx, nil
+}
+
+// ExpireHeldOrders expires the lapsed held orders and open bills of every tenant. It runs
+// periodically; reading held orders or tables also expires a tenant's lapsed orders.
+func (u *POSUsecase) ExpireHeldOrders() error {
+	return expireHeldOrders(u.uow, u.kitchen, nil)
+}
+
+// expireHeldOrders expires the held orders past their expiry, of one tenant or of all
+// with a nil tenant. Their tables are freed and the dishes still queued for them are
+// taken off the kitchen display in the same unit of work.
+func expireHeldOrders(uow domain.UnitOfWork, kitchen *KitchenUsecase, tenantID *uuid.UUID) error {
+	var kitchenEvents []domain.KitchenEvent
+	err := uow.Do(func(repos domain.Repositories) error {
+		expired, err := repos.Transactions.ExpireHeld(tenantID, time.Now())
+		if err != nil {
+			return fmt.Errorf("failed to expire held orders: %w", err)
+		}
+		for i := range expired {
+			if err := repos.Tables.ReleaseByTransaction(expired[i].ID); err != nil {
+				return fmt.Errorf("failed to release table: %w", err)
+			}
+			events, err := kitchen.fire(repos, &expired[i])
+			if err != nil {
+				return err
+			}
+			kitchenEvents = append(kitchenEvents, events...)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	kitchen.publish(kitchenEvents)
+	return nil
+}
+
+// lockHeldOrder locks an open tab for the rest of the unit of work and checks again that
+// it is still open, unexpired and not partly paid, since another device may have
+// settled or split it since it was read
+func lockHeldOrder(repos domain.Repositories, id uuid.UUID) (*domain.Transaction, error) {
+	tx, err := repos.Transactions.LockByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock order: %w", err)
+	}
+	if tx.Status != domain.TransactionStatusPending {
+		return nil, errors.New("order is not open")
+	}
+	if tx.ExpiresAt != nil && time.Now().After(*tx.ExpiresAt) {
+		return nil, errors.New("order has expired")
+	}
+	if hasPaidSplits(tx) {
+		return nil, errors.New("bill already has paid splits and cannot be changed")
+	}
+	return tx, nil
+}
+
+// sameItems reports whether two reads of a tab hold the same lines. Editing a tab
+// replaces all its lines, so the IDs change with every edit.
+func sameItems(a, b []domain.TransactionItem) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	ids := make(map[uuid.UUID]bool, len(a))
+	for _, item := range a {
+		ids[item.ID] = true
+	}
+	for _, item := range b {
+		if !ids[item.ID] {
+			return false
+		}
+	}
+	return true
+}
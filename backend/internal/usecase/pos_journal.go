@@ -0,0 +1,433 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/codapos/backend/internal/domain"
+	"github.com/google/uuid"
+)
+
+// posAccounts are the system accounts used by the automatic POS journals
+type posAccounts struct {
+	cash, sales, tax, serviceCharge, tips uuid.UUID
+	loyalty                               uuid.UUID // expense of sales paid with points
+	giftCards                             uuid.UUID // gift card balances owed to holders
+	// every account of the tenant, to check the accounts chosen on tax rates
+	known map[uuid.UUID]bool
+}
+
+// loadPOSAccounts finds the system accounts of a tenant. A missing account is not a
+// database error: the caller records the posting as failed so it can be reposted later.
+func loadPOSAccounts(repos domain.Repositories, tenantID uuid.UUID) (posAccounts, error) {
+	acc := posAccounts{known: make(map[uuid.UUID]bool)}
+	accounts, err := repos.Accounting.FindAccountsByTenantID(tenantID)
+	if err != nil {
+		return acc, fmt.Errorf("failed to load chart of accounts: %w", err)
+	}
+	for _, a := range accounts {
+		acc.known[a.ID] = true
+		switch a.SubType {
+		case domain.AccountSubTypeCash:
+			acc.cash = a.ID
+		case domain.AccountSubTypeSales:
+			acc.sales = a.ID
+		case domain.AccountSubTypeTax:
+			// Per-tax accounts are children of the tax payable account
+			if acc.tax == uuid.Nil || a.ParentID == nil {
+				acc.tax = a.ID
+			}
+		case domain.AccountSubTypeServiceCharge:
+			acc.serviceCharge = a.ID
+		case domain.AccountSubTypeTips:
+			acc.tips = a.ID
+		case domain.AccountSubTypeLoyalty:
+			acc.loyalty = a.ID
+		case domain.AccountSubTypeGiftCard:
+			acc.giftCards = a.ID
+		}
+	}
+	return acc, nil
+}
+
+// missing describes the system accounts the journal of tx needs but the tenant does not have
+func (a posAccounts) missing(tx *domain.Transaction) string {
+	switch {
+	case a.cash == uuid.Nil:
+		return "chart of accounts has no cash account"
+	case a.sales == uuid.Nil:
+		return "chart of accounts has no sales account"
+	case tx.ServiceChargeAmount != 0 && a.serviceCharge == uuid.Nil:
+		return "chart of accounts has no service charge account"
+	case tx.TipAmount != 0 && a.tips == uuid.Nil:
+		return "chart of accounts has no tips payable account"
+	case loyaltyPaid(tx.Payments) != 0 && a.loyalty == uuid.Nil:
+		return "chart of accounts has no loyalty program account"
+	case (giftCardPaid(tx.Payments) != 0 || giftCardsSold(tx) != 0) && a.giftCards == uuid.Nil:
+		return "chart of accounts has no gift card liability account"
+	}
+	return ""
+}
+
+// taxLine is the tax owed to one liability account
+type taxLine struct {
+	accountID uuid.UUID
+	name      string
+	amount    domain.Money
+}
+
+// taxLines splits the tax of a transaction over the liability accounts of its taxes,
+// in the order they were charged. Taxes without an account of their own, and
+// transactions from before the tax engine, go to the tax payable account.
+func (a posAccounts) taxLines(tx *domain.Transaction) ([]taxLine, string) {
+	taxes := tx.Taxes
+	if len(taxes) == 0 && tx.TaxAmount != 0 {
+		taxes = []domain.TransactionTax{{Name: "Tax", Amount: tx.TaxAmount}}
+	}
+
+	var lines []taxLine
+	for _, t := range taxes {
+		if t.Amount == 0 {
+			continue
+		}
+		accountID := a.tax
+		if t.AccountID != nil {
+			accountID = *t.AccountID
+		}
+		if accountID == uuid.Nil {
+			return nil, "chart of accounts has no tax payable account"
+		}
+		if !a.known[accountID] {
+			return nil, fmt.Sprintf("tax account of %s not found", t.Name)
+		}
+		lines = append(lines, taxLine{accountID: accountID, name: t.Name, amount: t.Amount})
+	}
+	return lines, ""
+}
+
+// postSaleJournal posts the automatic journal entry of a sale (POS → Journal)
+func (u *POSUsecase) postSaleJournal(repos domain.Repositories, tenantID uuid.UUID, tx *domain.Transaction) error {
+	accounts, err := loadPOSAccounts(repos, tenantID)
+	if err != nil {
+		return err
+	}
+	if reason := accounts.missing(tx); reason != "" {
+		return markJournal(repos, tx, domain.JournalStatusFailed, reason)
+	}
+	taxes, reason := accounts.taxLines(tx)
+	if reason != "" {
+		return markJournal(repos, tx, domain.JournalStatusFailed, reason)
+	}
+
+	// Revenue is recognised net of promotion discounts and of every tax, inclusive or not.
+	// Service charge is revenue of its own; tips are owed to staff. The part paid with
+	// loyalty points is a cost of the program rather than cash. Gift cards sold are owed
+	// to their holders until spent, so paying with one settles that debt instead of cash.
+	netSales := tx.TotalAmount - tx.TaxAmount - tx.ServiceChargeAmount - tx.TipAmount - tx.DeliveryFee
+	pointsPaid := loyaltyPaid(tx.Payments)
+	cardsPaid := giftCardPaid(tx.Payments)
+	cardsSold := giftCardsSold(tx)
+
+	journal := &domain.JournalEntry{
+		TenantID:      tenantID,
+		OutletID:      &tx.OutletID,
+		Date:          tx.CreatedAt,
+		Description:   fmt.Sprintf("Auto journal for sale %s", tx.TransactionNumber),
+		Source:        domain.JournalSourcePOSSale,
+		ReferenceType: "transaction",
+		ReferenceID:   &tx.ID,
+		Status:        "posted",
+		TotalDebit:    tx.TotalAmount,
+		TotalCredit:   tx.TotalAmount,
+		Lines: []domain.JournalEntryLine{
+			{AccountID: accounts.cash, Debit: tx.TotalAmount - pointsPaid - cardsPaid, Description: "Cash received"},
+			{AccountID: accounts.sales, Credit: netSales - cardsSold, Description: "Sales revenue"},
+		},
+	}
+	if pointsPaid != 0 {
+		journal.Lines = append(journal.Lines, domain.JournalEntryLine{
+			AccountID:   accounts.loyalty,
+			Debit:       pointsPaid,
+			Description: "Paid with loyalty points",
+		})
+	}
+	if cardsPaid != 0 {
+		journal.Lines = append(journal.Lines, domain.JournalEntryLine{
+			AccountID:   accounts.giftCards,
+			Debit:       cardsPaid,
+			Description: "Paid with gift card",
+		})
+	}
+	if cardsSold != 0 {
+		journal.Lines = append(journal.Lines, domain.JournalEntryLine{
+			AccountID:   accounts.giftCards,
+			Credit:      cardsSold,
+			Description: "Gift cards sold",
+		})
+	}
+	for _, t := range taxes {
+		journal.Lines = append(journal.Lines, domain.JournalEntryLine{
+			AccountID:   t.accountID,
+			Credit:      t.amount,
+			Description: "Tax payable - " + t.name,
+		})
+	}
+	if tx.ServiceChargeAmount != 0 {
+		journal.Lines = append(journal.Lines, domain.JournalEntryLine{
+			AccountID:   accounts.serviceCharge,
+			Credit:      tx.ServiceChargeAmount,
+			Description: "Service charge",
+		})
+	}
+	if tx.TipAmount != 0 {
+		journal.Lines = append(journal.Lines, domain.JournalEntryLine{
+			AccountID:   accounts.tips,
+			Credit:      tx.TipAmount,
+			Description: "Tips payable to staff",
+		})
+	}
+	if tx.DeliveryFee != 0 {
+		journal.Lines = append(journal.Lines, domain.JournalEntryLine{
+			AccountID:   accounts.sales,
+			Credit:      tx.DeliveryFee,
+			Description: "Delivery fee",
+		})
+	}
+
+	return u.postJournal(repos, tx, journal, accounts)
+}
+
+// postRefundJournal posts the automatic journal entry reversing the refunded part of a sale
+func (u *POSUsecase) postRefundJournal(repos domain.Repositories, tenantID uuid.UUID, refund *domain.Transaction) error {
+	amount := -refund.TotalAmount
+	netSales := -(refund.TotalAmount - refund.TaxAmount - refund.ServiceChargeAmount - refund.TipAmount - refund.DeliveryFee)
+	pointsReturned := -loyaltyPaid(refund.Payments)
+	cardsRefunded := -giftCardPaid(refund.Payments)
+	cardsReturned := -giftCardsSold(refund)
+
+	accounts, err := loadPOSAccounts(repos, tenantID)
+	if err != nil {
+		return err
+	}
+	if reason := accounts.missing(refund); reason != "" {
+		return markJournal(repos, refund, domain.JournalStatusFailed, reason)
+	}
+	taxes, reason := accounts.taxLines(refund)
+	if reason != "" {
+		return markJournal(repos, refund, domain.JournalStatusFailed, reason)
+	}
+
+	journal := &domain.JournalEntry{
+		TenantID:      tenantID,
+		OutletID:      &refund.OutletID,
+		Date:          refund.CreatedAt,
+		Description:   fmt.Sprintf("Auto journal for refund %s", refund.TransactionNumber),
+		Source:        domain.JournalSourcePOSRefund,
+		ReferenceType: "transaction",
+		ReferenceID:   &refund.ID,
+		Status:        "posted",
+		TotalDebit:    amount,
+		TotalCredit:   amount,
+		Lines: []domain.JournalEntryLine{
+			{AccountID: accounts.sales, Debit: netSales - cardsReturned, Description: "Sales returned"},
+			{AccountID: accounts.cash, Credit: amount - pointsReturned - cardsRefunded, Description: "Cash refunded"},
+		},
+	}
+	if cardsReturned != 0 {
+		journal.Lines = append(journal.Lines, domain.JournalEntryLine{
+			AccountID:   accounts.giftCards,
+			Debit:       cardsReturned,
+			Description: "Gift cards taken back",
+		})
+	}
+	if pointsReturned != 0 {
+		journal.Lines = append(journal.Lines, domain.JournalEntryLine{
+			AccountID:   accounts.loyalty,
+			Credit:      pointsReturned,
+			Description: "Loyalty points returned",
+		})
+	}
+	if cardsRefunded != 0 {
+		journal.Lines = append(journal.Lines, domain.JournalEntryLine{
+			AccountID:   accounts.giftCards,
+			Credit:      cardsRefunded,
+			Description: "Refunded to gift card",
+		})
+	}
+	for _, t := range taxes {
+		journal.Lines = append(journal.Lines, domain.JournalEntryLine{
+			AccountID:   t.accountID,
+			Debit:       -t.amount,
+			Description: "Tax payable reversed - " + t.name,
+		})
+	}
+	if refund.ServiceChargeAmount != 0 {
+		journal.Lines = append(journal.Lines, domain.JournalEntryLine{
+			AccountID:   accounts.serviceCharge,
+			Debit:       -refund.ServiceChargeAmount,
+			Description: "Service charge returned",
+		})
+	}
+	if refund.TipAmount != 0 {
+		journal.Lines = append(journal.Lines, domain.JournalEntryLine{
+			AccountID:   accounts.tips,
+			Debit:       -refund.TipAmount,
+			Description: "Tips returned",
+		})
+	}
+	if refund.DeliveryFee != 0 {
+		journal.Lines = append(journal.Lines, domain.JournalEntryLine{
+			AccountID:   accounts.sales,
+			Debit:       -refund.DeliveryFee,
+			Description: "Delivery fee returned",
+		})
+	}
+
+	return u.postJournal(repos, refund, journal, accounts)
+}
+
+// reverseSaleJournal posts a mirror image of the sale's journal entries and rolls back
+// the account balances they moved
+func (u *POSUsecase) reverseSaleJournal(repos domain.Repositories, tenantID uuid.UUID, tx *domain.Transaction) error {
+	journals, err := repos.Accounting.FindJournalsByReference("transaction", tx.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load sale journal: %w", err)
+	}
+
+	for _, original := range journals {
+		if original.Source != domain.JournalSourcePOSSale {
+			continue
+		}
+		journal := &domain.JournalEntry{
+			TenantID:      tenantID,
+			OutletID:      original.OutletID,
+			Date:          *tx.VoidedAt,
+			Description:   fmt.Sprintf("Auto journal for void of %s: %s", tx.TransactionNumber, tx.VoidReason),
+			Source:        domain.JournalSourcePOSVoid,
+			ReferenceType: "transaction",
+			ReferenceID:   &tx.ID,
+			Status:        "posted",
+			TotalDebit:    original.TotalCredit,
+			TotalCredit:   original.TotalDebit,
+			CreatedBy:     tx.VoidedBy,
+			ApprovedBy:    tx.VoidApprovedBy,
+		}
+		journal.EntryNumber, err = u.sequences.NextWith(repos.Sequences, tenantID, original.OutletID, domain.SequenceJournal)
+		if err != nil {
+			return err
+		}
+		for _, line := range original.Lines {
+			journal.Lines = append(journal.Lines, domain.JournalEntryLine{
+				AccountID:   line.AccountID,
+				Debit:       line.Credit,
+				Credit:      line.Debit,
+				Description: "Void: " + line.Description,
+			})
+		}
+		if err := repos.Accounting.CreateJournal(journal); err != nil {
+			return fmt.Errorf("failed to post void journal: %w", err)
+		}
+
+		// Balances grow on the account's natural side, so undo the original movement
+		for _, line := range original.Lines {
+			delta := line.Credit - line.Debit
+			if line.Account != nil && (line.Account.Type == domain.AccountTypeAsset || line.Account.Type == domain.AccountTypeExpense) {
+				delta = line.Debit - line.Credit
+			}
+			if err := repos.Accounting.UpdateAccountBalance(line.AccountID, -delta); err != nil {
+				return fmt.Errorf("failed to update account balance: %w", err)
+			}
+		}
+	}
+
+	// A sale whose journal never posted has nothing left to repost once voided
+	if tx.JournalStatus == domain.JournalStatusFailed {
+		return markJournal(repos, tx, "", "")
+	}
+	return nil
+}
+
+// postJournal stores a journal entry, moves the balances of its accounts and marks
+// the transaction as posted. POS journals only touch the cash and loyalty expense
+// accounts (debit-natural) and sales, service charge, tax, tips and gift card accounts
+// (credit-natural).
+func (u *POSUsecase) postJournal(repos domain.Repositories, tx *domain.Transaction, journal *domain.JournalEntry, accounts posAccounts) error {
+	number, err := u.sequences.NextWith(repos.Sequences, journal.TenantID, journal.OutletID, domain.SequenceJournal)
+	if err != nil {
+		return err
+	}
+	journal.EntryNumber = number
+	if err := repos.Accounting.CreateJournal(journal); err != nil {
+		return fmt.Errorf("failed to post journal: %w", err)
+	}
+	for _, line := range journal.Lines {
+		delta := line.Credit - line.Debit
+		if line.AccountID == accounts.cash || line.AccountID == accounts.loyalty {
+			delta = line.Debit - line.Credit
+		}
+		if err := repos.Accounting.UpdateAccountBalance(line.AccountID, delta); err != nil {
+			return fmt.Errorf("failed to update account balance: %w", err)
+		}
+	}
+	return markJournal(repos, tx, domain.JournalStatusPosted, "")
+}
+
+func markJournal(repos domain.Repositories, tx *domain.Transaction, status, message string) error {
+	tx.JournalStatus = status
+	tx.JournalError = message
+	if err := repos.Transactions.UpdateJournalStatus(tx.ID, status, message); err != nil {
+		return fmt.Errorf("failed to update journal status: %w", err)
+	}
+	return nil
+}
+
+// GetFailedPostings returns the transactions whose automatic journal could not be posted
+func (u *POSUsecase) GetFailedPostings(tenantID uuid.UUID) ([]domain.Transaction, error) {
+	return u.transactionRepo.FindByJournalStatus(tenantID, domain.JournalStatusFailed)
+}
+
+// RepostJournal retries the automatic journal of a transaction whose posting failed,
+// e.g. after the missing accounts were added to the chart of accounts
+func (u *POSUsecase) RepostJournal(tenantID, transactionID uuid.UUID) (*domain.Transaction, error) {
+	tx, err := u.transactionRepo.FindByID(transactionID)
+	if err != nil || tx.TenantID != tenantID {
+		return nil, errors.New("transaction not found")
+	}
+	if tx.JournalStatus != domain.JournalStatusFailed {
+		return nil, errors.New("transaction has no failed journal posting")
+	}
+
+	err = u.uow.Do(func(repos domain.Repositories) error {
+		// A concurrent retry may have posted it since; the lock makes the second one wait and see that
+		locked, err := repos.Transactions.LockByID(tx.ID)
+		if err != nil {
+			return fmt.Errorf("failed to lock transaction: %w", err)
+		}
+		if locked.JournalStatus != domain.JournalStatusFailed {
+			return errors.New("transaction has no failed journal posting")
+		}
+		switch tx.Type {
+		case domain.TransactionTypeSale:
+			return u.postSaleJournal(repos, tenantID, tx)
+		case domain.TransactionTypeRefund:
+			if tx.OriginalTransactionID != nil {
+				original, err := repos.Transactions.FindByID(*tx.OriginalTransactionID)
+				if err != nil {
+					return fmt.Errorf("failed to load original sale: %w", err)
+				}
+				if original.JournalStatus == domain.JournalStatusFailed {
+					return errors.New("repost the journal of the original sale first")
+				}
+			}
+			return u.postRefundJournal(repos, tenantID, tx)
+		}
+		return fmt.Errorf("cannot repost a %s transaction", tx.Type)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if tx.JournalStatus == domain.JournalStatusFailed {
+		return nil, fmt.Errorf("journal still cannot be posted: %s", tx.JournalError)
+	}
+	return tx, nil
+}
@@ -0,0 +1,402 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/codapos/backend/internal/domain"
+	"github.com/google/uuid"
+)
+
+// ErrOutOfStock is returned when an online order asks for more than the outlet has left to sell
+var ErrOutOfStock = errors.New("out of stock")
+
+// ErrOrderChanged is returned when a delivery order moved on between being read and being
+// locked to change it; the change has to be decided again from its current state
+var ErrOrderChanged = errors.New("delivery order changed")
+
+// reservationDeliveryOrder is the reference type of stock reserved for a storefront order
+const reservationDeliveryOrder = "delivery_order"
+
+// stockLine is the quantity of one product (or variant) an order takes from an outlet
+type stockLine struct {
+	key       string
+	name      string
+	productID uuid.UUID
+	variantID *uuid.UUID
+	quantity  float64
+}
+
+// ReserveOnlineOrder holds the stock of an unpaid storefront order at its outlet until
+// expiresAt, so it cannot be sold twice while the customer pays. It fails with
+// ErrOutOfStock when the outlet has less left to sell than the order asks for.
+func (u *POSUsecase) ReserveOnlineOrder(order *domain.DeliveryOrder, expiresAt time.Time) error {
+	sale, err := orderSale(order)
+	if err != nil || sale == nil || order.OutletID == nil {
+		return err
+	}
+	lines, err := u.trackedStock(sale.Items)
+	if err != nil || len(lines) == 0 {
+		return err
+	}
+	outletID := *order.OutletID
+	return u.uow.Do(func(repos domain.Repositories) error {
+		if err := lockAvailableStock(repos, outletID, lines); err != nil {
+			return err
+		}
+		for _, line := range lines {
+			reservation := &domain.StockReservation{
+				TenantID:      order.TenantID,
+				OutletID:      outletID,
+				ProductID:     line.productID,
+				VariantID:     line.variantID,
+				Quantity:      line.quantity,
+				ReferenceType: reservationDeliveryOrder,
+				ReferenceID:   order.ID,
+				ExpiresAt:     expiresAt,
+			}
+			if err := repos.Inventory.CreateReservation(reservation); err != nil {
+				return fmt.Errorf("failed to reserve stock for %s: %w", line.name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// lockAvailableStock locks the stock rows of an order's lines, so concurrent checkouts
+// of the same products queue up, and fails with ErrOutOfStock when the outlet has less
+// left to sell than a line takes: what is on hand less what live reservations hold
+func lockAvailableStock(repos domain.Repositories, outletID uuid.UUID, lines []stockLine) error {
+	onHand := make(map[string]float64, len(lines))
+	for _, line := range lines {
+		quantity, err := repos.Inventory.LockStock(outletID, line.productID, line.variantID)
+		if err != nil {
+			return fmt.Errorf("failed to lock stock for %s: %w", line.name, err)
+		}
+		onHand[line.key] = quantity
+	}
+	reserved, err := reservedStock(repos.Inventory, outletID, time.Now())
+	if err != nil {
+		return err
+	}
+	for _, line := range lines {
+		if onHand[line.key]-reserved[line.key] < line.quantity {
+			return fmt.Errorf("%w: %s", ErrOutOfStock, line.name)
+		}
+	}
+	return nil
+}
+
+// CheckOnlineStock tells whether an outlet has enough left to sell for the items of an
+// online order. Nothing is held; ReserveOnlineOrder makes the final check.
+func (u *POSUsecase) CheckOnlineStock(outletID uuid.UUID, items []domain.TransactionItem) error {
+	lines, err := u.trackedStock(items)
+	if err != nil || len(lines) == 0 {
+		return err
+	}
+	inventory, err := u.inventoryRepo.FindByOutlet(outletID)
+	if err != nil {
+		return fmt.Errorf("failed to load stock: %w", err)
+	}
+	onHand := make(map[string]float64, len(inventory))
+	for _, inv := range inventory {
+		onHand[stockKey(inv.ProductID, inv.VariantID)] = inv.Quantity
+	}
+	reserved, err := reservedStock(u.inventoryRepo, outletID, time.Now())
+	if err != nil {
+		return err
+	}
+	for _, line := range lines {
+		if onHand[line.key]-reserved[line.key] < line.quantity {
+			return fmt.Errorf("%w: %s", ErrOutOfStock, line.name)
+		}
+	}
+	return nil
+}
+
+// trackedStock totals the items of an order by product and variant, leaving out products
+// that do not track stock. Lines are sorted so stock rows are always locked in the same order.
+func (u *POSUsecase) trackedStock(items []domain.TransactionItem) ([]stockLine, error) {
+	tracked := make(map[uuid.UUID]bool)
+	index := make(map[string]int)
+	var lines []stockLine
+	for _, item := range items {
+		track, ok := tracked[item.ProductID]
+		if !ok {
+			product, err := u.productRepo.FindByID(item.ProductID)
+			if err != nil {
+				return nil, fmt.Errorf("product not found: %s", item.ProductID)
+			}
+			track = product.TrackStock
+			tracked[item.ProductID] = track
+		}
+		if !track {
+			continue
+		}
+		key := stockKey(item.ProductID, item.VariantID)
+		if i, ok := index[key]; ok {
+			lines[i].quantity += item.Quantity
+			continue
+		}
+		name := item.ProductName
+		if item.VariantName != "" {
+			name += " (" + item.VariantName + ")"
+		}
+		index[key] = len(lines)
+		lines = append(lines, stockLine{key: key, name: name, productID: item.ProductID, variantID: item.VariantID, quantity: item.Quantity})
+	}
+	sort.Slice(lines, func(i, j int) bool { return lines[i].key < lines[j].key })
+	return lines, nil
+}
+
+// BookOnlineOrder books a paid storefront order as a sale of the outlet fulfilling it:
+// the sale priced at checkout is recorded on the online channel with the gateway
+// payment, stock is deducted and the journal posted, and the order is released to the
+// merchant, all in one unit of work. The stock reserved for the order becomes the sale's
+// stock movement; when that reservation lapsed before the payment came in, the stock is
+// checked again and ErrOutOfStock returned if it has been sold meanwhile. Orders placed
+// before storefront sales were recorded are only released. The order must still be
+// waiting for payment when it is locked, or ErrOrderChanged is returned.
+func (u *POSUsecase) BookOnlineOrder(order *domain.DeliveryOrder, paymentType string) error {
+	sale, err := orderSale(order)
+	if err != nil {
+		return err
+	}
+	if sale == nil || order.OutletID == nil || order.TransactionID != nil {
+		return u.uow.Do(func(repos domain.Repositories) error {
+			if err := lockOnlineOrder(repos, order, domain.DeliveryStatusWaitingPayment); err != nil {
+				return err
+			}
+			return repos.Deliveries.UpdateOrder(order)
+		})
+	}
+
+	cashierID, err := u.onlineCashier(order.TenantID)
+	if err != nil {
+		return err
+	}
+	tx := &domain.Transaction{
+		TenantID:            order.TenantID,
+		OutletID:            *order.OutletID,
+		CashierID:           cashierID,
+		CustomerID:          order.CustomerID,
+		IdempotencyKey:      optionalKey("delivery:" + order.ID.String()),
+		Type:                domain.TransactionTypeSale,
+		Status:              domain.TransactionStatusCompleted,
+		OrderType:           domain.OrderTypeDelivery,
+		Channel:             domain.ChannelOnline,
+		Subtotal:            sale.Subtotal,
+		DiscountAmount:      sale.DiscountAmount,
+		TaxAmount:           sale.TaxAmount,
+		ServiceChargeRate:   sale.ServiceChargeRate,
+		ServiceChargeAmount: sale.ServiceChargeAmount,
+		ServiceChargeTax:    sale.ServiceChargeTax,
+		ServiceChargeTaxes:  sale.ServiceChargeTaxes,
+		DeliveryFee:         sale.DeliveryFee,
+		TotalAmount:         sale.TotalAmount,
+		Notes:               order.Notes,
+		TabName:             order.DropoffContact,
+		Items:               sale.Items,
+		Taxes:               sale.Taxes,
+	}
+	for i := range tx.Items {
+		tx.Items[i].ID = uuid.Nil
+		tx.Items[i].TransactionID = uuid.Nil
+	}
+	for i := range tx.Taxes {
+		tx.Taxes[i].ID = uuid.Nil
+		tx.Taxes[i].TransactionID = uuid.Nil
+	}
+	if tx.TotalAmount != order.TotalAmount {
+		return fmt.Errorf("sale of delivery order %s does not match its total", order.OrderNumber)
+	}
+	payments, err := applyPayments(tx, []domain.PaymentRequest{{
+		PaymentMethod:   onlinePaymentMethod(paymentType),
+		Amount:          order.TotalAmount,
+		ReferenceNumber: order.MidtransOrderID,
+	}})
+	if err != nil {
+		return err
+	}
+	tx.Payments = payments
+
+	var kitchenEvents []domain.KitchenEvent
+	err = u.uow.Do(func(repos domain.Repositories) error {
+		if err := lockOnlineOrder(repos, order, domain.DeliveryStatusWaitingPayment); err != nil {
+			return err
+		}
+		number, err := u.sequences.NextWith(repos.Sequences, tx.TenantID, &tx.OutletID, domain.SequenceTransaction)
+		if err != nil {
+			return err
+		}
+		tx.TransactionNumber = number
+		if err := repos.Transactions.Create(tx); err != nil {
+			return fmt.Errorf("failed to create transaction: %w", err)
+		}
+		order.TransactionID = &tx.ID
+		if err := repos.Deliveries.UpdateOrder(order); err != nil {
+			return fmt.Errorf("failed to update delivery order: %w", err)
+		}
+		if err := repos.Inventory.SetReservationStatus(reservationDeliveryOrder, order.ID, domain.ReservationStatusConverted); err != nil {
+			return fmt.Errorf("failed to convert stock reservation: %w", err)
+		}
+		if kitchenEvents, err = u.kitchen.fire(repos, tx); err != nil {
+			return err
+		}
+		return u.settleSale(repos, tx.TenantID, cashierID, tx)
+	})
+	if err != nil {
+		order.TransactionID = nil
+		return err
+	}
+	u.kitchen.publish(kitchenEvents)
+	return nil
+}
+
+// CancelOnlineOrder cancels a storefront order and releases the stock reserved for it.
+// When its sale was already booked, the sale is voided: stock goes back to the outlet, the journal is reversed and queued
+// dishes are taken off the kitchen display. userID is nil when the gateway cancelled it.
+// from is the status the cancellation was decided from; ErrOrderChanged is returned when
+// the order has moved on by the time it is locked.
+func (u *POSUsecase) CancelOnlineOrder(order *domain.DeliveryOrder, from string, userID *uuid.UUID) error {
+	var tx *domain.Transaction
+	if order.TransactionID != nil {
+		found, err := u.transactionRepo.FindByID(*order.TransactionID)
+		if err != nil {
+			return fmt.Errorf("failed to load sale of delivery order %s: %w", order.OrderNumber, err)
+		}
+		switch found.Status {
+		case domain.TransactionStatusCompleted:
+			tx = found
+		case domain.TransactionStatusVoided:
+		default:
+			return fmt.Errorf("sale of delivery order %s is %s, refund it at the cashier instead", order.OrderNumber, found.Status)
+		}
+	}
+
+	var kitchenEvents []domain.KitchenEvent
+	err := u.uow.Do(func(repos domain.Repositories) error {
+		if err := lockOnlineOrder(repos, order, from); err != nil {
+			return err
+		}
+		if err := repos.Deliveries.UpdateOrder(order); err != nil {
+			return fmt.Errorf("failed to update delivery order: %w", err)
+		}
+		if err := repos.Inventory.SetReservationStatus(reservationDeliveryOrder, order.ID, domain.ReservationStatusReleased); err != nil {
+			return fmt.Errorf("failed to release stock reservation: %w", err)
+		}
+		if tx == nil {
+			return nil
+		}
+		locked, err := repos.Transactions.LockByID(tx.ID)
+		if err != nil {
+			return fmt.Errorf("failed to lock sale of delivery order %s: %w", order.OrderNumber, err)
+		}
+		if locked.Status != domain.TransactionStatusCompleted {
+			return fmt.Errorf("%w: sale of delivery order %s is now %s", ErrOrderChanged, order.OrderNumber, locked.Status)
+		}
+
+		now := time.Now()
+		if order.CancelledAt != nil {
+			now = *order.CancelledAt
+		}
+		reason := order.CancelReason
+		if reason == "" {
+			reason = "delivery order " + order.OrderNumber + " cancelled"
+		}
+		tx.Status = domain.TransactionStatusVoided
+		tx.VoidReason = reason
+		tx.VoidedBy = userID
+		tx.VoidApprovedBy = userID
+		tx.VoidedAt = &now
+
+		if err := restoreSaleStock(repos, tx, reason, userID); err != nil {
+			return err
+		}
+		if err := repos.Transactions.Update(tx); err != nil {
+			return fmt.Errorf("failed to void transaction: %w", err)
+		}
+		if kitchenEvents, err = u.kitchen.fire(repos, tx); err != nil {
+			return err
+		}
+		if err := u.loyalty.reverse(repos, tx, tx.TotalAmount, true, userID, "Void of "+tx.TransactionNumber); err != nil {
+			return err
+		}
+		if err := u.giftCards.reverse(repos, tx, userID); err != nil {
+			return err
+		}
+		return u.reverseSaleJournal(repos, tx.TenantID, tx)
+	})
+	if err != nil {
+		return err
+	}
+	u.kitchen.publish(kitchenEvents)
+	return nil
+}
+
+// lockOnlineOrder locks a delivery order for a change decided from the copy passed in,
+// and checks the locked row is still in the status and with the sale that copy had
+func lockOnlineOrder(repos domain.Repositories, order *domain.DeliveryOrder, from string) error {
+	locked, err := repos.Deliveries.LockOrderByID(order.ID)
+	if err != nil {
+		return fmt.Errorf("failed to lock delivery order: %w", err)
+	}
+	sameSale := (locked.TransactionID == nil) == (order.TransactionID == nil) &&
+		(locked.TransactionID == nil || *locked.TransactionID == *order.TransactionID)
+	if locked.Status != from || !sameSale {
+		return fmt.Errorf("%w: order %s is now %s", ErrOrderChanged, order.OrderNumber, locked.Status)
+	}
+	return nil
+}
+
+// restoreSaleStock puts the stock of a voided sale back
+func restoreSaleStock(repos domain.Repositories, tx *domain.Transaction, reason string, userID *uuid.UUID) error {
+	for _, item := range tx.Items {
+		if err := repos.Inventory.UpdateStock(tx.OutletID, item.ProductID, item.VariantID, item.Quantity); err != nil {
+			return fmt.Errorf("failed to restore stock for %s: %w", item.ProductName, err)
+		}
+		movement := &domain.InventoryMovement{
+			OutletID:      tx.OutletID,
+			ProductID:     item.ProductID,
+			VariantID:     item.VariantID,
+			Type:          domain.MovementVoid,
+			Quantity:      item.Quantity,
+			ReferenceType: "transaction",
+			ReferenceID:   &tx.ID,
+			Notes:         reason,
+			CreatedBy:     userID,
+		}
+		if err := repos.Inventory.CreateMovement(movement); err != nil {
+			return fmt.Errorf("failed to record stock movement: %w", err)
+		}
+	}
+	return nil
+}
+
+// onlineCashier is the user storefront sales are booked under: the tenant owner
+func (u *POSUsecase) onlineCashier(tenantID uuid.UUID) (uuid.UUID, error) {
+	users, err := u.userRepo.FindByTenantID(tenantID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to load users: %w", err)
+	}
+	for _, user := range users {
+		if user.Role == domain.RoleOwner {
+			return user.ID, nil
+		}
+	}
+	return uuid.Nil, errors.New("tenant has no owner to book online sales under")
+}
+
+// onlinePaymentMethod maps the payment type reported by the gateway to a payment method,
+// so the MDR of the sale is charged like a cashier's gateway payment
+func onlinePaymentMethod(paymentType string) string {
+	switch paymentType {
+	case "":
+		return "online"
+	case "echannel", "permata":
+		return domain.PaymentBankTransfer
+	}
+	return paymentType
+}
@@ -0,0 +1,320 @@
+package usecase
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/codapos/backend/internal/domain"
+	"github.com/google/uuid"
+)
+
+// sweepTables expires lapsed held orders and frees the tables whose order has been
+// paid, merged or has expired. The periodic sweep may not have run yet, so this runs
+// before tables are read.
+func sweepTables(uow domain.UnitOfWork, kitchen *KitchenUsecase, tables domain.TableRepository, tenantID uuid.UUID) error {
+	if err := expireHeldOrders(uow, kitchen, &tenantID); err != nil {
+		return err
+	}
+	if err := tables.ReleaseClosed(tenantID); err != nil {
+		return fmt.Errorf("failed to release tables: %w", err)
+	}
+	return nil
+}
+
+// seatOrder sets the order type, table and guest count of a new order. An unpaid order
+// takes its table until it is paid; that table is returned so it can be taken in the
+// unit of work that stores the order. A sale paid on the spot only records its table.
+func (u *POSUsecase) seatOrder(tx *domain.Transaction, orderType string, tableID *uuid.UUID, guests int, open bool) (*domain.DiningTable, error) {
+	if orderType == "" {
+		orderType = domain.OrderTypeTakeaway
+		if tableID != nil {
+			orderType = domain.OrderTypeDineIn
+		}
+	}
+	switch orderType {
+	case domain.OrderTypeDineIn, domain.OrderTypeTakeaway, domain.OrderTypeDelivery:
+	default:
+		return nil, fmt.Errorf("unknown order type: %s", orderType)
+	}
+	if guests < 0 {
+		return nil, errors.New("guest count cannot be negative")
+	}
+	tx.OrderType = orderType
+	tx.GuestCount = guests
+	if tableID == nil {
+		return nil, nil
+	}
+
+	if orderType != domain.OrderTypeDineIn {
+		return nil, errors.New("only dine-in orders can be seated at a table")
+	}
+	table, err := u.tableRepo.FindByID(*tableID)
+	if err != nil || table.TenantID != tx.TenantID || table.OutletID != tx.OutletID {
+		return nil, errors.New("table not found")
+	}
+	if !table.IsActive {
+		return nil, errors.New("table is not in use")
+	}
+	if guests == 0 {
+		return nil, errors.New("guest count is required for dine-in orders at a table")
+	}
+	tx.TableID = &table.ID
+	if !open {
+		return nil, nil
+	}
+	if err := sweepTables(u.uow, u.kitchen, u.tableRepo, tx.TenantID); err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+// MoveTable moves seated guests, and their open order, to a free table
+func (u *POSUsecase) MoveTable(tenantID, fromID uuid.UUID, req domain.MoveTableRequest) (*domain.DiningTable, error) {
+	if err := sweepTables(u.uow, u.kitchen, u.tableRepo, tenantID); err != nil {
+		return nil, err
+	}
+	from, err := u.tableRepo.FindByID(fromID)
+	if err != nil || from.TenantID != tenantID {
+		return nil, errors.New("table not found")
+	}
+	to, err := u.tableRepo.FindByID(req.ToTableID)
+	if err != nil || to.TenantID != tenantID || to.OutletID != from.OutletID {
+		return nil, errors.New("destination table not found")
+	}
+	if to.ID == from.ID {
+		return nil, errors.New("guests are already at this table")
+	}
+	if !to.IsActive {
+		return nil, errors.New("destination table is not in use")
+	}
+	if from.Status == domain.TableStatusFree {
+		return nil, errors.New("table has no guests to move")
+	}
+
+	var order *domain.Transaction
+	if from.TransactionID != nil {
+		if order, err = u.heldOrder(tenantID, *from.TransactionID); err != nil {
+			return nil, err
+		}
+	}
+	since := time.Now()
+	if from.OccupiedAt != nil {
+		since = *from.OccupiedAt
+	}
+
+	err = u.uow.Do(func(repos domain.Repositories) error {
+		if err := repos.Tables.Seat(to.ID, from.Status, from.TransactionID, since); err != nil {
+			return err
+		}
+		from.Status = domain.TableStatusFree
+		from.TransactionID = nil
+		from.OccupiedAt = nil
+		if err := repos.Tables.Update(from); err != nil {
+			return fmt.Errorf("failed to release table: %w", err)
+		}
+		if order == nil || order.TableID == nil || *order.TableID != from.ID {
+			return nil
+		}
+		order.TableID = &to.ID
+		order.Table = nil
+		if order.TabName == from.Name {
+			order.TabName = to.Name
+		}
+		if err := repos.Transactions.Update(order); err != nil {
+			return fmt.Errorf("failed to move order: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return u.tableRepo.FindByID(to.ID)
+}
+
+// MergeTables joins tables to the open order of the target table. Orders open on the
+// merged tables are moved into it and kept on record as merged; the combined order
+// is priced again at the current prices and its splits are dropped.
+func (u *POSUsecase) MergeTables(tenantID, targetID uuid.UUID, req domain.MergeTablesRequest) (*domain.Transaction, error) {
+	if len(req.TableIDs) == 0 {
+		return nil, errors.New("select the tables to merge")
+	}
+	if err := sweepTables(u.uow, u.kitchen, u.tableRepo, tenantID); err != nil {
+		return nil, err
+	}
+	target, err := u.tableRepo.FindByID(targetID)
+	if err != nil || target.TenantID != tenantID {
+		return nil, errors.New("table not found")
+	}
+	if target.TransactionID == nil {
+		return nil, errors.New("table has no open order to merge into")
+	}
+	order, err := u.heldOrder(tenantID, *target.TransactionID)
+	if err != nil {
+		return nil, err
+	}
+	if hasPaidSplits(order) {
+		return nil, errors.New("bill already has paid splits and cannot be changed")
+	}
+	outlet, err := u.outletRepo.FindByID(order.OutletID)
+	if err != nil {
+		return nil, errors.New("outlet not found")
+	}
+
+	items := orderItemRequests(order.Items)
+	var tables []domain.DiningTable
+	var merged []*domain.Transaction
+	seen := map[uuid.UUID]bool{target.ID: true}
+	mergedOrders := map[uuid.UUID]bool{order.ID: true}
+	for _, id := range req.TableIDs {
+		if seen[id] {
+			continue
+		}
+		table, err := u.tableRepo.FindByID(id)
+		if err != nil || table.TenantID != tenantID || table.OutletID != target.OutletID {
+			return nil, errors.New("table not found")
+		}
+		if !table.IsActive {
+			return nil, fmt.Errorf("table %s is not in use", table.Name)
+		}
+		seen[id] = true
+		tables = append(tables, *table)
+		if table.TransactionID == nil || mergedOrders[*table.TransactionID] {
+			continue
+		}
+
+		source, err := u.heldOrder(tenantID, *table.TransactionID)
+		if err != nil {
+			return nil, fmt.Errorf("table %s: %w", table.Name, err)
+		}
+		if hasPaidSplits(source) {
+			return nil, fmt.Errorf("table %s is being paid in splits and cannot be merged", table.Name)
+		}
+		mergedOrders[source.ID] = true
+		merged = append(merged, source)
+		items = append(items, orderItemRequests(source.Items)...)
+		order.GuestCount += source.GuestCount
+
+		// Every table seated on the merged order follows it
+		linked, err := u.tableRepo.FindByTransactionID(source.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load tables: %w", err)
+		}
+		for _, t := range linked {
+			if !seen[t.ID] {
+				seen[t.ID] = true
+				tables = append(tables, t)
+			}
+		}
+	}
+
+	priced, err := u.priceOrder(tenantID, outlet, items, order.PromotionID, nil)
+	if err != nil {
+		return nil, err
+	}
+	reprice(order, priced)
+	order.ExpiresAt = heldOrderExpiry(outlet, time.Now())
+
+	var kitchenEvents []domain.KitchenEvent
+	err = u.uow.Do(func(repos domain.Repositories) error {
+		if err := repos.SplitBills.DeleteByTransactionID(order.ID); err != nil {
+			return fmt.Errorf("failed to clear splits: %w", err)
+		}
+		if err := repos.Transactions.ReplaceItems(order); err != nil {
+			return fmt.Errorf("failed to merge orders: %w", err)
+		}
+		for _, source := range merged {
+			if err := repos.SplitBills.DeleteByTransactionID(source.ID); err != nil {
+				return fmt.Errorf("failed to clear splits: %w", err)
+			}
+			source.Status = domain.TransactionStatusMerged
+			source.MergedIntoID = &order.ID
+			source.ExpiresAt = nil
+			source.Splits = nil
+			if err := repos.Transactions.Update(source); err != nil {
+				return fmt.Errorf("failed to merge order %s: %w", source.TransactionNumber, err)
+			}
+			// Dishes already sent stay on their tickets and now belong to the combined order
+			if err := repos.Kitchen.MoveTickets(source.ID, order.ID); err != nil {
+				return fmt.Errorf("failed to move kitchen tickets: %w", err)
+			}
+		}
+		if kitchenEvents, err = u.kitchen.fire(repos, order); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for i := range tables {
+			table := &tables[i]
+			if table.Status == domain.TableStatusFree {
+				if err := repos.Tables.Seat(table.ID, domain.TableStatusOccupied, &order.ID, now); err != nil {
+					return fmt.Errorf("table %s: %w", table.Name, err)
+				}
+				continue
+			}
+			table.TransactionID = &order.ID
+			if table.OccupiedAt == nil {
+				table.OccupiedAt = &now
+			}
+			if err := repos.Tables.Update(table); err != nil {
+				return fmt.Errorf("failed to merge table %s: %w", table.Name, err)
+			}
+		}
+		// The combined bill has not been presented yet
+		return repos.Tables.SetStatusByTransaction(order.ID, domain.TableStatusOccupied)
+	})
+	if err != nil {
+		return nil, err
+	}
+	u.kitchen.publish(kitchenEvents)
+	return u.transactionRepo.FindByID(order.ID)
+}
+
+// reprice replaces the priced contents of an open order; its tip is kept
+func reprice(tx, priced *domain.Transaction) {
+	tx.Subtotal = priced.Subtotal
+	tx.DiscountAmount = priced.DiscountAmount
+	tx.TaxAmount = priced.TaxAmount
+	tx.ServiceChargeRate = priced.ServiceChargeRate
+	tx.ServiceChargeAmount = priced.ServiceChargeAmount
+	tx.ServiceChargeTax = priced.ServiceChargeTax
+	tx.ServiceChargeTaxes = priced.ServiceChargeTaxes
+	tx.TotalAmount = priced.TotalAmount + tx.TipAmount
+	tx.PromotionID = priced.PromotionID
+	// Points redeemed as a discount are not carried over to the new price
+	tx.PointsRedeemed = priced.PointsRedeemed
+	tx.Items = priced.Items
+	tx.Taxes = priced.Taxes
+}
+
+// orderItemRequests turns the items of an order back into a cart so it can be priced again
+func orderItemRequests(items []domain.TransactionItem) []domain.CheckoutItemRequest {
+	requests := make([]domain.CheckoutItemRequest, 0, len(items))
+	for _, item := range items {
+		var selected []domain.SelectedModifier
+		_ = json.Unmarshal(item.Modifiers, &selected)
+		modifiers := make([]domain.ModifierRequest, 0, len(selected))
+		for _, m := range selected {
+			modifiers = append(modifiers, domain.ModifierRequest{ModifierID: m.ModifierID})
+		}
+		requests = append(requests, domain.CheckoutItemRequest{
+			ProductID:  item.ProductID,
+			VariantID:  item.VariantID,
+			Quantity:   item.Quantity,
+			Modifiers:  modifiers,
+			Notes:      item.Notes,
+			SeatNumber: item.SeatNumber,
+		})
+	}
+	return requests
+}
+
+func hasPaidSplits(tx *domain.Transaction) bool {
+	for _, split := range tx.Splits {
+		if split.Status == domain.SplitStatusPaid {
+			return true
+		}
+	}
+	return false
+}
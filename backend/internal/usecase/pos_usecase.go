@@ -4,11 +4,24 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/codapos/backend/internal/domain"
 	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// ErrPINLocked is returned while PIN approval is locked after too many wrong PINs
+var ErrPINLocked = errors.New("too many wrong PINs, try again later")
+
+// Wrong override PINs allowed in a row, and how long PIN approval is locked after that
+const (
+	maxPINFailures = 5
+	pinLockout     = 15 * time.Minute
 )
 
 type POSUsecase struct {
@@ -17,6 +30,19 @@ type POSUsecase struct {
 	inventoryRepo     domain.InventoryRepository
 	accountingRepo    domain.AccountingRepository
 	tenantBillingRepo domain.TenantBillingRepository
+	promotionRepo     domain.PromotionRepository
+	userRepo          domain.UserRepository
+	splitBillRepo     domain.SplitBillRepository
+	outletPriceRepo   domain.OutletPriceRepository
+	outletRepo        domain.OutletRepository
+	shiftRepo         domain.ShiftRepository
+	tableRepo         domain.TableRepository
+	uow               domain.UnitOfWork
+	sequences         *SequenceUsecase
+	taxes             *TaxUsecase
+	kitchen           *KitchenUsecase
+	loyalty           *LoyaltyUsecase
+	giftCards         *GiftCardUsecase
 }
 
 func NewPOSUsecase(
@@ -25,6 +51,19 @@ func NewPOSUsecase(
 	ir domain.InventoryRepository,
 	ar domain.AccountingRepository,
 	tbr domain.TenantBillingRepository,
+	pmr domain.PromotionRepository,
+	ur domain.UserRepository,
+	sbr domain.SplitBillRepository,
+	opr domain.OutletPriceRepository,
+	or domain.OutletRepository,
+	shr domain.ShiftRepository,
+	tblr domain.TableRepository,
+	uow domain.UnitOfWork,
+	sequences *SequenceUsecase,
+	taxes *TaxUsecase,
+	kitchen *KitchenUsecase,
+	loyalty *LoyaltyUsecase,
+	giftCards *GiftCardUsecase,
 ) *POSUsecase {
 	return &POSUsecase{
 		transactionRepo:   tr,
@@ -32,62 +71,225 @@ func NewPOSUsecase(
 		inventoryRepo:     ir,
 		accountingRepo:    ar,
 		tenantBillingRepo: tbr,
+		promotionRepo:     pmr,
+		userRepo:          ur,
+		splitBillRepo:     sbr,
+		outletPriceRepo:   opr,
+		outletRepo:        or,
+		shiftRepo:         shr,
+		tableRepo:         tblr,
+		uow:               uow,
+		sequences:         sequences,
+		taxes:             taxes,
+		kitchen:           kitchen,
+		loyalty:           loyalty,
+		giftCards:         giftCards,
 	}
 }
 
-// Checkout processes a POS transaction
+// Checkout processes a POS transaction.
+// A retried checkout with the same idempotency key returns the original transaction.
 func (u *POSUsecase) Checkout(tenantID, cashierID uuid.UUID, req domain.CheckoutRequest) (*domain.Transaction, error) {
-	// 1. Verify Tenant Billing Status (The 7th Rule Enforcement)
+	if existing, err := u.findIdempotent(tenantID, req.IdempotencyKey, domain.TransactionTypeSale, nil); existing != nil || err != nil {
+		return existing, err
+	}
+	if err := u.checkBillingStatus(tenantID); err != nil {
+		return nil, err
+	}
+
+	outlet, err := u.outletRepo.FindByID(req.OutletID)
+	if err != nil || outlet.TenantID != tenantID {
+		return nil, errors.New("outlet not found")
+	}
+	shiftID, err := u.openShiftAt(cashierID, outlet.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	member, err := u.loyalty.member(tenantID, req.CustomerID, req.RedeemPoints)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := u.priceOrder(tenantID, outlet, req.Items, req.PromotionID, member)
+	if err != nil {
+		return nil, err
+	}
+	table, err := u.seatOrder(tx, req.OrderType, req.TableID, req.GuestCount, req.OpenBill)
+	if err != nil {
+		return nil, err
+	}
+	tx.CashierID = cashierID
+	tx.CustomerID = req.CustomerID
+	tx.ShiftID = shiftID
+	tx.IdempotencyKey = optionalKey(req.IdempotencyKey)
+	tx.Notes = req.Notes
+	if table != nil {
+		tx.TabName = table.Name
+	}
+	if err := u.applyTip(tenantID, cashierID, tx, req.TipAmount, req.TipStaffID); err != nil {
+		return nil, err
+	}
+
+	if req.OpenBill {
+		// Open bills are paid later (e.g. through split bills) and expire like held orders
+		tx.Status = domain.TransactionStatusPending
+		tx.ExpiresAt = heldOrderExpiry(outlet, time.Now())
+		if err := member.redeemPoints(tx, nil); err != nil {
+			return nil, err
+		}
+	} else {
+		payments, err := applyPayments(tx, req.Payments)
+		if err != nil {
+			return nil, err
+		}
+		if err := member.redeemPoints(tx, req.Payments); err != nil {
+			return nil, err
+		}
+		if err := u.giftCards.attach(tenantID, tx, tx.TotalAmount, req.Payments, payments); err != nil {
+			return nil, err
+		}
+		tx.Status = domain.TransactionStatusCompleted
+		tx.Payments = payments
+	}
+
+	// The sale, its stock movements, its journal and its kitchen tickets commit together
+	var kitchenEvents []domain.KitchenEvent
+	err = u.uow.Do(func(repos domain.Repositories) error {
+		if err := holdShift(repos, shiftID); err != nil {
+			return err
+		}
+		// Numbers are issued inside the unit of work so a failed sale leaves no gap
+		number, err := u.sequences.NextWith(repos.Sequences, tenantID, &tx.OutletID, domain.SequenceTransaction)
+		if err != nil {
+			return err
+		}
+		tx.TransactionNumber = number
+		if err := repos.Transactions.Create(tx); err != nil {
+			return fmt.Errorf("failed to create transaction: %w", err)
+		}
+		if table != nil {
+			if err := repos.Tables.Seat(table.ID, domain.TableStatusOccupied, &tx.ID, time.Now()); err != nil {
+				return err
+			}
+		}
+		if kitchenEvents, err = u.kitchen.fire(repos, tx); err != nil {
+			return err
+		}
+		if tx.Status == domain.TransactionStatusCompleted {
+			if err := u.giftCards.book(repos, tx, tx.Payments); err != nil {
+				return err
+			}
+			return u.settleSale(repos, tenantID, cashierID, tx)
+		}
+		return nil
+	})
+	if err != nil {
+		// A concurrent retry may have won the race for the same key
+		if existing, _ := u.findIdempotent(tenantID, req.IdempotencyKey, domain.TransactionTypeSale, nil); existing != nil {
+			return existing, nil
+		}
+		return nil, err
+	}
+	u.kitchen.publish(kitchenEvents)
+
+	return tx, nil
+}
+
+// Quote prices a cart as Checkout would, without storing anything, so the client can
+// charge the customer the amount the checkout will ask for
+func (u *POSUsecase) Quote(tenantID, cashierID uuid.UUID, req domain.QuoteRequest) (*domain.Transaction, error) {
+	outlet, err := u.outletRepo.FindByID(req.OutletID)
+	if err != nil || outlet.TenantID != tenantID {
+		return nil, errors.New("outlet not found")
+	}
+	member, err := u.loyalty.member(tenantID, req.CustomerID, req.RedeemPoints)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := u.priceOrder(tenantID, outlet, req.Items, req.PromotionID, member)
+	if err != nil {
+		return nil, err
+	}
+	tx.CustomerID = req.CustomerID
+	if err := u.applyTip(tenantID, cashierID, tx, req.TipAmount, req.TipStaffID); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// checkBillingStatus enforces the 7th rule: the POS is frozen while MDR bills are overdue
+func (u *POSUsecase) checkBillingStatus(tenantID uuid.UUID) error {
 	// Get all unpaid or past_due bills
 	unpaidBills, _, _ := u.tenantBillingRepo.FindByTenantID(tenantID, 10, 0)
 	now := time.Now()
 	for _, b := range unpaidBills {
 		// If status is suspended, immediately block
 		if b.Status == "suspended" {
-			return nil, errors.New("akun anda ditangguhkan karena menunggak tagihan MDR lebih dari 1 bulan. Harap segera melunasi tagihan")
+			return errors.New("akun anda ditangguhkan karena menunggak tagihan MDR lebih dari 1 bulan. Harap segera melunasi tagihan")
 		}
 		// If status is past_due OR (status is unpaid AND we are past the 7th of the month)
 		if b.Status == "past_due" || (b.Status == "unpaid" && now.Day() > 7) {
-			return nil, errors.New("akses Kasir (POS) dibekukan sementara karena ada Tagihan MDR yang melewati jatuh tempo (Tanggal 7). Harap bayar tagihan di menu Tagihan MDR")
+			return errors.New("akses Kasir (POS) dibekukan sementara karena ada Tagihan MDR yang melewati jatuh tempo (Tanggal 7). Harap bayar tagihan di menu Tagihan MDR")
 		}
 	}
+	return nil
+}
+
+// priceOrder prices a cart server-side: outlet prices, modifiers, promotion, member
+// discounts, taxes and service charge. The returned sale has no payments, tip or MDR yet.
+func (u *POSUsecase) priceOrder(tenantID uuid.UUID, outlet *domain.Outlet, reqItems []domain.CheckoutItemRequest, promotionID *uuid.UUID, member *loyaltyMember) (*domain.Transaction, error) {
+	if len(reqItems) == 0 {
+		return nil, errors.New("order has no items")
+	}
+
+	// Outlet price overrides (e.g. franchise outlets in tourist areas)
+	outletPrices, err := u.outletPriceRepo.FindByOutletID(outlet.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load outlet prices: %w", err)
+	}
+	priceBook := newOutletPriceBook(outletPrices)
 
 	// Build transaction items
 	var items []domain.TransactionItem
-	var subtotal float64
-	var totalTax float64
+	var taxRates []float64
+	var subtotal domain.Money
 
-	for _, itemReq := range req.Items {
+	for _, itemReq := range reqItems {
+		if itemReq.Quantity <= 0 {
+			return nil, errors.New("quantity must be greater than zero")
+		}
 		product, err := u.productRepo.FindByID(itemReq.ProductID)
-		if err != nil {
+		if err != nil || product.TenantID != tenantID {
 			return nil, fmt.Errorf("product not found: %s", itemReq.ProductID)
 		}
+		if product.IsGiftCard && itemReq.Quantity != math.Trunc(itemReq.Quantity) {
+			return nil, fmt.Errorf("%s is sold in whole cards", product.Name)
+		}
 
-		unitPrice := product.BasePrice
+		unitPrice := priceBook.unitPrice(product, nil)
 		variantName := ""
 
 		// Check variant
 		if itemReq.VariantID != nil {
-			for _, v := range product.Variants {
-				if v.ID == *itemReq.VariantID {
-					unitPrice += v.AdditionalPrice
+			for i := range product.Variants {
+				if v := &product.Variants[i]; v.ID == *itemReq.VariantID {
+					unitPrice = priceBook.unitPrice(product, v)
 					variantName = v.Name
 					break
 				}
 			}
 		}
 
-		// Add modifier prices
-		modifierTotal := 0.0
-		for _, mod := range itemReq.Modifiers {
-			modifierTotal += mod.Price
+		// Add modifier prices (priced from the product's modifier groups)
+		modifiers, modifierTotal, err := resolveModifiers(product, itemReq.Modifiers)
+		if err != nil {
+			return nil, err
 		}
 		unitPrice += modifierTotal
 
-		itemSubtotal := unitPrice * itemReq.Quantity
-		taxAmount := itemSubtotal * (product.TaxRate / 100)
+		itemSubtotal := unitPrice.Mul(itemReq.Quantity)
 
-		modJSON, _ := json.Marshal(itemReq.Modifiers)
+		modJSON, _ := json.Marshal(modifiers)
 
 		items = append(items, domain.TransactionItem{
 			ProductID:   itemReq.ProductID,
@@ -96,87 +298,183 @@ func (u *POSUsecase) Checkout(tenantID, cashierID uuid.UUID, req domain.Checkout
 			VariantName: variantName,
 			Quantity:    itemReq.Quantity,
 			UnitPrice:   unitPrice,
-			TaxAmount:   taxAmount,
 			Subtotal:    itemSubtotal,
 			Modifiers:   domain.JSON(modJSON),
 			Notes:       itemReq.Notes,
+			SeatNumber:  itemReq.SeatNumber,
+			IsGiftCard:  product.IsGiftCard,
 		})
+		taxRates = append(taxRates, product.TaxRate)
 
 		subtotal += itemSubtotal
-		totalTax += taxAmount
 	}
 
-	totalAmount := subtotal + totalTax
+	// Gift cards are sold at face value, so discounts go on the other lines only
+	var discountable []domain.TransactionItem
+	var discountableLines []int
+	for i, item := range items {
+		if !item.IsGiftCard {
+			discountable = append(discountable, item)
+			discountableLines = append(discountableLines, i)
+		}
+	}
+
+	// Apply promotion server-side; the discount is spread over the items
+	var discountAmount domain.Money
+	if promotionID != nil {
+		promo, err := u.promotionRepo.FindByID(*promotionID)
+		if err != nil || promo.TenantID != tenantID {
+			return nil, errors.New("promotion not found")
+		}
+		discountAmount, err = applyPromotion(promo, outlet.ID, discountable, time.Now())
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	// Validate payment total
-	var paymentTotal float64
-	for _, p := range req.Payments {
+	// Loyalty members get their tier discount and the points they redeem on top
+	if member != nil {
+		memberDiscount, err := member.discount(discountable)
+		if err != nil {
+			return nil, err
+		}
+		discountAmount += memberDiscount
+	}
+	for j, i := range discountableLines {
+		items[i].DiscountAmount = discountable[j].DiscountAmount
+	}
+
+	// Tax is charged on the discounted line amount; inclusive taxes are already in the price
+	outletTaxes, err := u.taxes.RatesForOutlet(tenantID, outlet.ID)
+	if err != nil {
+		return nil, err
+	}
+	applyTaxes(items, outletTaxes, taxRates)
+	var totalTax, taxIncluded, giftCards domain.Money
+	for _, item := range items {
+		totalTax += item.TaxAmount
+		taxIncluded += item.TaxIncluded
+		if item.IsGiftCard {
+			giftCards += item.Subtotal
+		}
+	}
+
+	// Service charge is a percentage of net sales (after discounts, before tax, without
+	// gift cards); where it is taxable the outlet's rates are charged on it as well
+	var serviceCharge, serviceChargeTax domain.Money
+	var scTaxes []domain.ItemTax
+	if outlet.ServiceChargeRate > 0 {
+		serviceCharge = (subtotal - discountAmount - taxIncluded - giftCards).Percent(outlet.ServiceChargeRate)
+		if outlet.ServiceChargeTaxable {
+			scTaxes = serviceChargeTaxes(serviceCharge, outletTaxes)
+			for _, t := range scTaxes {
+				serviceChargeTax += t.Amount
+			}
+		}
+	}
+	totalTax += serviceChargeTax
+	scTaxesJSON, _ := json.Marshal(scTaxes)
+
+	return &domain.Transaction{
+		TenantID:            tenantID,
+		OutletID:            outlet.ID,
+		Type:                domain.TransactionTypeSale,
+		Subtotal:            subtotal,
+		DiscountAmount:      discountAmount,
+		TaxAmount:           totalTax,
+		ServiceChargeRate:   outlet.ServiceChargeRate,
+		ServiceChargeAmount: serviceCharge,
+		ServiceChargeTax:    serviceChargeTax,
+		ServiceChargeTaxes:  domain.JSON(scTaxesJSON),
+		TotalAmount:         subtotal - discountAmount + totalTax - taxIncluded + serviceCharge,
+		PromotionID:         promotionID,
+		Items:               items,
+		Taxes:               summarizeTaxes(items, scTaxes),
+	}, nil
+}
+
+// applyTip adds a tip on top of the bill, owed to the chosen staff member or to the cashier
+func (u *POSUsecase) applyTip(tenantID, cashierID uuid.UUID, tx *domain.Transaction, amount domain.Money, staffID *uuid.UUID) error {
+	if amount < 0 {
+		return errors.New("tip amount cannot be negative")
+	}
+	tx.TotalAmount += amount - tx.TipAmount
+	tx.TipAmount = amount
+	tx.TipStaffID = nil
+	if amount == 0 {
+		return nil
+	}
+	id := cashierID
+	if staffID != nil {
+		staff, err := u.userRepo.FindByID(*staffID)
+		if err != nil || staff.TenantID != tenantID {
+			return errors.New("tip staff not found")
+		}
+		id = staff.ID
+	}
+	tx.TipStaffID = &id
+	return nil
+}
+
+// applyPayments checks that the payments cover the bill and records the MDR of the
+// primary payment method. Tips are handed to staff in full, so they are left out of
+// the MDR base and of the merchant's net profit.
+func applyPayments(tx *domain.Transaction, requests []domain.PaymentRequest) ([]domain.TransactionPayment, error) {
+	var paymentTotal domain.Money
+	for _, p := range requests {
 		paymentTotal += p.Amount
 	}
-	if paymentTotal < totalAmount {
+	if paymentTotal < tx.TotalAmount {
 		return nil, errors.New("payment amount is less than total")
 	}
 
-	// Generate transaction number
-	txNumber := fmt.Sprintf("TXN-%s-%d", time.Now().Format("20060102"), time.Now().UnixNano()%100000)
-
 	// Determine primary payment method (use logic to calculate MDR)
-	var primaryPaymentMethod string
-	if len(req.Payments) > 0 {
-		primaryPaymentMethod = req.Payments[0].PaymentMethod
+	if len(requests) > 0 {
+		tx.PaymentMethod = requests[0].PaymentMethod
 	}
 
 	// Calculate MDR Margins
-	feeMidtrans, feeCodapos, mdrPercent, mdrFlat := calculateMDR(primaryPaymentMethod, totalAmount)
-	totalMDRMerchant := feeMidtrans + feeCodapos
-	netProfit := totalAmount - totalMDRMerchant
-
-	// Create transaction
-	tx := &domain.Transaction{
-		TenantID:          tenantID,
-		OutletID:          req.OutletID,
-		CashierID:         cashierID,
-		TransactionNumber: txNumber,
-		Type:              domain.TransactionTypeSale,
-		Status:            domain.TransactionStatusCompleted,
-		Subtotal:          subtotal,
-		TaxAmount:         totalTax,
-		TotalAmount:       totalAmount,
-		PromotionID:       req.PromotionID,
-		Notes:             req.Notes,
-		Items:             items,
-		PaymentMethod:     primaryPaymentMethod,
-		MDRRatePercentage: mdrPercent,
-		MDRRateFlat:       mdrFlat,
-		FeeMidtrans:       feeMidtrans,
-		FeeCodapos:        feeCodapos,
-		TotalMDRMerchant:  totalMDRMerchant,
-		NetProfit:         netProfit,
-	}
-
-	// Create payments
-	for _, p := range req.Payments {
-		tx.Payments = append(tx.Payments, domain.TransactionPayment{
+	feeMidtrans, feeCodapos, mdrPercent, mdrFlat := calculateMDR(tx.PaymentMethod, tx.TotalAmount-tx.TipAmount)
+	tx.MDRRatePercentage = mdrPercent
+	tx.MDRRateFlat = mdrFlat
+	tx.FeeMidtrans = feeMidtrans
+	tx.FeeCodapos = feeCodapos
+	tx.TotalMDRMerchant = feeMidtrans + feeCodapos
+	tx.NetProfit = tx.TotalAmount - tx.TipAmount - tx.TotalMDRMerchant
+
+	payments := make([]domain.TransactionPayment, 0, len(requests))
+	for _, p := range requests {
+		payments = append(payments, domain.TransactionPayment{
+			TransactionID:   tx.ID,
 			PaymentMethod:   p.PaymentMethod,
 			Amount:          p.Amount,
 			ReferenceNumber: p.ReferenceNumber,
+			ShiftID:         tx.ShiftID,
 			Status:          "completed",
 		})
 	}
+	return payments, nil
+}
 
-	if err := u.transactionRepo.Create(tx); err != nil {
-		return nil, fmt.Errorf("failed to create transaction: %w", err)
+// settleSale deducts stock for a completed sale and posts its journal
+func (u *POSUsecase) settleSale(repos domain.Repositories, tenantID, cashierID uuid.UUID, tx *domain.Transaction) error {
+	// Tracked stock cannot be sold from under the unpaid online orders holding it
+	lines, err := u.trackedStock(tx.Items)
+	if err != nil {
+		return err
+	}
+	if err := lockAvailableStock(repos, tx.OutletID, lines); err != nil {
+		return err
 	}
 
 	// Auto-deduct inventory
-	for _, item := range items {
-		if err := u.inventoryRepo.UpdateStock(req.OutletID, item.ProductID, item.VariantID, -item.Quantity); err != nil {
-			// Log but don't fail the transaction
-			fmt.Printf("Warning: failed to deduct stock for product %s: %v\n", item.ProductID, err)
+	for _, item := range tx.Items {
+		if err := repos.Inventory.UpdateStock(tx.OutletID, item.ProductID, item.VariantID, -item.Quantity); err != nil {
+			return fmt.Errorf("failed to deduct stock for %s: %w", item.ProductName, err)
 		}
 		// Record movement
 		movement := &domain.InventoryMovement{
-			OutletID:      req.OutletID,
+			OutletID:      tx.OutletID,
 			ProductID:     item.ProductID,
 			VariantID:     item.VariantID,
 			Type:          domain.MovementSale,
@@ -185,13 +483,50 @@ func (u *POSUsecase) Checkout(tenantID, cashierID uuid.UUID, req domain.Checkout
 			ReferenceID:   &tx.ID,
 			CreatedBy:     &cashierID,
 		}
-		_ = u.inventoryRepo.CreateMovement(movement)
+		if err := repos.Inventory.CreateMovement(movement); err != nil {
+			return fmt.Errorf("failed to record stock movement: %w", err)
+		}
+	}
+
+	// Spend the points redeemed on the sale and add the points it earns
+	if err := u.loyalty.settle(repos, tx); err != nil {
+		return err
+	}
+	// Issue the gift cards the sale bought
+	if err := u.giftCards.issue(repos, tx); err != nil {
+		return err
 	}
 
 	// Auto-create accounting journal entry (POS → Journal)
-	go u.createSaleJournal(tenantID, tx)
+	return u.postSaleJournal(repos, tenantID, tx)
+}
 
-	return tx, nil
+// findIdempotent returns the transaction already booked under an idempotency key, if any.
+// Reusing a key for a different kind of request (or another sale's refund) is an error.
+func (u *POSUsecase) findIdempotent(tenantID uuid.UUID, key, txType string, originalID *uuid.UUID) (*domain.Transaction, error) {
+	if key == "" {
+		return nil, nil
+	}
+	existing, err := u.transactionRepo.FindByIdempotencyKey(tenantID, key)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up idempotency key: %w", err)
+	}
+	if existing.Type != txType ||
+		(originalID != nil && (existing.OriginalTransactionID == nil || *existing.OriginalTransactionID != *originalID)) {
+		return nil, errors.New("idempotency key was already used for a different request")
+	}
+	return existing, nil
+}
+
+// optionalKey stores empty idempotency keys as NULL so they never collide
+func optionalKey(key string) *string {
+	if key == "" {
+		return nil
+	}
+	return &key
 }
 
 // calculateMDR computes Midtrans fee, CODAPOS margin (0.5%), and the split based on payment method.
@@ -200,28 +535,28 @@ func (u *POSUsecase) Checkout(tenantID, cashierID uuid.UUID, req domain.Checkout
 // "gopay", "shopeepay", "ewallet" -> 2.0% (Midtrans), 0.5% (Codapos) -> Merchant: 2.5%
 // "bank_transfer", "virtual_account" -> 4000 Flat (Midtrans), 1000 Flat (Codapos) -> Merchant: 5000
 // "cash" -> 0
-func calculateMDR(paymentMethod string, totalAmount float64) (feeMidtrans, feeCodapos, percentage, flat float64) {
+func calculateMDR(paymentMethod string, totalAmount domain.Money) (feeMidtrans, feeCodapos domain.Money, percentage float64, flat domain.Money) {
 	method := strings.ToLower(paymentMethod)
 
 	// In CODAPOS context, these are mapping to Payment constants
 	switch method {
 	case domain.PaymentCreditCard:
 		percentage = 3.4
-		flat = 2000.0
-		feeMidtrans = (totalAmount * 0.029) + 2000.0
-		feeCodapos = (totalAmount * 0.005)
+		flat = domain.Rupiah(2000)
+		feeMidtrans = totalAmount.Percent(2.9) + domain.Rupiah(2000)
+		feeCodapos = totalAmount.Percent(0.5)
 	case domain.PaymentQRIS:
 		percentage = 1.2
-		feeMidtrans = totalAmount * 0.007
-		feeCodapos = totalAmount * 0.005
+		feeMidtrans = totalAmount.Percent(0.7)
+		feeCodapos = totalAmount.Percent(0.5)
 	case domain.PaymentEWallet, "gopay", "shopeepay", "dana", "ovo", "linkaja":
 		percentage = 2.5
-		feeMidtrans = totalAmount * 0.020
-		feeCodapos = totalAmount * 0.005
+		feeMidtrans = totalAmount.Percent(2.0)
+		feeCodapos = totalAmount.Percent(0.5)
 	case domain.PaymentBankTransfer, "virtual_account", "bca_va", "bni_va", "bri_va", "mandiri_va":
-		flat = 5000.0
-		feeMidtrans = 4000.0
-		feeCodapos = 1000.0
+		flat = domain.Rupiah(5000)
+		feeMidtrans = domain.Rupiah(4000)
+		feeCodapos = domain.Rupiah(1000)
 	default:
 		// "cash", "whatsapp", etc. NO MDR.
 		return 0, 0, 0, 0
@@ -230,102 +565,901 @@ func calculateMDR(paymentMethod string, totalAmount float64) (feeMidtrans, feeCo
 	return feeMidtrans, feeCodapos, percentage, flat
 }
 
-// createSaleJournal creates an automatic journal entry for a sale
-func (u *POSUsecase) createSaleJournal(tenantID uuid.UUID, tx *domain.Transaction) {
-	entryNumber := fmt.Sprintf("JRN-SALE-%s", tx.TransactionNumber)
-	journal := &domain.JournalEntry{
-		TenantID:      tenantID,
-		OutletID:      &tx.OutletID,
-		EntryNumber:   entryNumber,
-		Date:          tx.CreatedAt,
-		Description:   fmt.Sprintf("Auto journal for sale %s", tx.TransactionNumber),
-		Source:        domain.JournalSourcePOSSale,
-		ReferenceType: "transaction",
-		ReferenceID:   &tx.ID,
-		Status:        "posted",
-		TotalDebit:    tx.TotalAmount,
-		TotalCredit:   tx.TotalAmount,
-	}
-
-	// Get system accounts
-	accounts, _ := u.accountingRepo.FindAccountsByTenantID(tenantID)
-	var cashAccountID, salesAccountID, taxAccountID uuid.UUID
-
-	for _, acc := range accounts {
-		switch acc.SubType {
-		case domain.AccountSubTypeCash:
-			cashAccountID = acc.ID
-		case domain.AccountSubTypeSales:
-			salesAccountID = acc.ID
-		case domain.AccountSubTypeTax:
-			taxAccountID = acc.ID
-		}
-	}
-
-	if cashAccountID != uuid.Nil && salesAccountID != uuid.Nil {
-		journal.Lines = []domain.JournalEntryLine{
-			{AccountID: cashAccountID, Debit: tx.TotalAmount, Description: "Cash received"},
-			{AccountID: salesAccountID, Credit: tx.Subtotal, Description: "Sales revenue"},
-		}
-		if tx.TaxAmount > 0 && taxAccountID != uuid.Nil {
-			journal.Lines = append(journal.Lines, domain.JournalEntryLine{
-				AccountID:   taxAccountID,
-				Credit:      tx.TaxAmount,
-				Description: "Tax payable",
-			})
-		}
-		_ = u.accountingRepo.CreateJournal(journal)
-
-		// Update account balances
-		_ = u.accountingRepo.UpdateAccountBalance(cashAccountID, tx.TotalAmount)
-		_ = u.accountingRepo.UpdateAccountBalance(salesAccountID, tx.Subtotal)
-		if tx.TaxAmount > 0 && taxAccountID != uuid.Nil {
-			_ = u.accountingRepo.UpdateAccountBalance(taxAccountID, tx.TaxAmount)
+// Refund refunds selected items (or everything not yet refunded) of a sale.
+// Several partial refunds may be made against one sale until it is fully refunded.
+func (u *POSUsecase) Refund(tenantID, cashierID uuid.UUID, transactionID uuid.UUID, req domain.RefundRequest) (*domain.Transaction, error) {
+	if existing, err := u.findIdempotent(tenantID, req.IdempotencyKey, domain.TransactionTypeRefund, &transactionID); existing != nil || err != nil {
+		return existing, err
+	}
+
+	sale, err := u.transactionRepo.FindByID(transactionID)
+	if err != nil || sale.TenantID != tenantID {
+		return nil, errors.New("original transaction not found")
+	}
+
+	if sale.Type != domain.TransactionTypeSale {
+		return nil, errors.New("only sales can be refunded")
+	}
+	// Refunds are paid out of the refunding cashier's drawer
+	shiftID, err := u.openShiftAt(cashierID, sale.OutletID)
+	if err != nil {
+		return nil, err
+	}
+
+	var refund *domain.Transaction
+	err = u.uow.Do(func(repos domain.Repositories) error {
+		if err := holdShift(repos, shiftID); err != nil {
+			return err
+		}
+		// The sale is locked and re-read so concurrent refunds see each other's quantities
+		original, err := repos.Transactions.LockByID(transactionID)
+		if err != nil {
+			return fmt.Errorf("failed to lock transaction: %w", err)
+		}
+		if refund, err = u.buildRefund(repos, original, req, cashierID, shiftID); err != nil {
+			return err
+		}
+		totalAmount := -refund.TotalAmount
+		fullyRefunded := original.Status == domain.TransactionStatusRefunded
+
+		number, err := u.sequences.NextWith(repos.Sequences, tenantID, &original.OutletID, domain.SequenceRefund)
+		if err != nil {
+			return err
+		}
+		refund.TransactionNumber = number
+		if err := repos.Transactions.Create(refund); err != nil {
+			return fmt.Errorf("failed to create refund: %w", err)
+		}
+
+		// Update original items and status
+		for i := range original.Items {
+			if err := repos.Transactions.UpdateItem(&original.Items[i]); err != nil {
+				return fmt.Errorf("failed to update refunded quantity: %w", err)
+			}
+		}
+		if err := repos.Transactions.Update(original); err != nil {
+			return fmt.Errorf("failed to update original transaction: %w", err)
+		}
+
+		// Restore inventory
+		for _, item := range refund.Items {
+			if err := repos.Inventory.UpdateStock(original.OutletID, item.ProductID, item.VariantID, -item.Quantity); err != nil {
+				return fmt.Errorf("failed to restore stock for %s: %w", item.ProductName, err)
+			}
+			movement := &domain.InventoryMovement{
+				OutletID:      original.OutletID,
+				ProductID:     item.ProductID,
+				VariantID:     item.VariantID,
+				Type:          domain.MovementRefund,
+				Quantity:      -item.Quantity,
+				ReferenceType: "transaction",
+				ReferenceID:   &refund.ID,
+				CreatedBy:     &cashierID,
+			}
+			if err := repos.Inventory.CreateMovement(movement); err != nil {
+				return fmt.Errorf("failed to record stock movement: %w", err)
+			}
+		}
+
+		// Gift cards sold are taken back, and the share paid with gift cards goes back on them
+		for _, item := range refund.Items {
+			if item.IsGiftCard {
+				if err := u.giftCards.voidIssued(repos, *item.OriginalItemID, int(-item.Quantity), &refund.ID, &cashierID); err != nil {
+					return err
+				}
+			}
+		}
+		if err := u.giftCards.book(repos, refund, refund.Payments); err != nil {
+			return err
+		}
+
+		// Earned points go back with the goods; redeemed points come back to the customer
+		if err := u.loyalty.reverse(repos, original, totalAmount, fullyRefunded, &cashierID, "Refund "+refund.TransactionNumber); err != nil {
+			return err
+		}
+
+		// Reverse the revenue in the ledger (Refund → Journal)
+		if original.JournalStatus == domain.JournalStatusFailed {
+			return markJournal(repos, refund, domain.JournalStatusFailed, "journal of the original sale is not posted")
+		}
+		return u.postRefundJournal(repos, tenantID, refund)
+	})
+	if err != nil {
+		if existing, _ := u.findIdempotent(tenantID, req.IdempotencyKey, domain.TransactionTypeRefund, &transactionID); existing != nil {
+			return existing, nil
 		}
+		return nil, err
 	}
+
+	return refund, nil
 }
 
-// Refund processes a refund
-func (u *POSUsecase) Refund(tenantID, cashierID uuid.UUID, transactionID uuid.UUID, reason string) (*domain.Transaction, error) {
-	original, err := u.transactionRepo.FindByID(transactionID)
+// buildRefund works out the refund of a sale locked by the caller: the quantities and
+// amounts still left to refund per line, the service charge, tip and delivery fee that go
+// with them and how it is paid back. The sale's items and status are updated in place.
+func (u *POSUsecase) buildRefund(repos domain.Repositories, original *domain.Transaction, req domain.RefundRequest, cashierID uuid.UUID, shiftID *uuid.UUID) (*domain.Transaction, error) {
+	switch original.Status {
+	case domain.TransactionStatusCompleted, domain.TransactionStatusPartiallyRefunded:
+	case domain.TransactionStatusRefunded:
+		return nil, errors.New("transaction already refunded")
+	default:
+		return nil, fmt.Errorf("cannot refund a %s transaction", original.Status)
+	}
+
+	// Resolve which quantities to refund per original item
+	quantities := make(map[uuid.UUID]float64)
+	if len(req.Items) == 0 {
+		for _, item := range original.Items {
+			if remaining := item.Quantity - item.RefundedQuantity; remaining > 0 {
+				quantities[item.ID] = remaining
+			}
+		}
+	} else {
+		for _, r := range req.Items {
+			if r.Quantity <= 0 {
+				return nil, errors.New("refund quantity must be greater than zero")
+			}
+			quantities[r.ItemID] += r.Quantity
+		}
+	}
+	if len(quantities) == 0 {
+		return nil, errors.New("nothing left to refund on this transaction")
+	}
+
+	// Amounts already refunded per item, so the final refund of a line returns the exact remainder
+	previous, err := repos.Transactions.FindRefunds(original.ID)
 	if err != nil {
-		return nil, errors.New("original transaction not found")
+		return nil, fmt.Errorf("failed to load previous refunds: %w", err)
+	}
+	refundedSoFar := make(map[uuid.UUID]domain.TransactionItem)
+	var prevServiceCharge, prevServiceChargeTax, prevTip, prevDeliveryFee domain.Money
+	for _, p := range previous {
+		prevServiceCharge -= p.ServiceChargeAmount
+		prevServiceChargeTax -= p.ServiceChargeTax
+		prevTip -= p.TipAmount
+		prevDeliveryFee -= p.DeliveryFee
+		for _, item := range p.Items {
+			if item.OriginalItemID == nil {
+				continue
+			}
+			acc := refundedSoFar[*item.OriginalItemID]
+			acc.Subtotal -= item.Subtotal
+			acc.DiscountAmount -= item.DiscountAmount
+			acc.TaxAmount -= item.TaxAmount
+			refundedSoFar[*item.OriginalItemID] = acc
+		}
 	}
 
-	if original.Status == domain.TransactionStatusRefunded {
-		return nil, errors.New("transaction already refunded")
+	var refundItems []domain.TransactionItem
+	var subtotal, discount, tax, taxIncluded, giftCards domain.Money
+	for i := range original.Items {
+		item := &original.Items[i]
+		qty, ok := quantities[item.ID]
+		if !ok {
+			continue
+		}
+		delete(quantities, item.ID)
+
+		remaining := item.Quantity - item.RefundedQuantity
+		if qty > remaining {
+			return nil, fmt.Errorf("cannot refund %.2f of %s, only %.2f left", qty, item.ProductName, remaining)
+		}
+		if item.IsGiftCard && qty != math.Trunc(qty) {
+			return nil, fmt.Errorf("%s is refunded in whole cards", item.ProductName)
+		}
+
+		var lineSubtotal, lineDiscount, lineTax domain.Money
+		if qty == remaining {
+			prev := refundedSoFar[item.ID]
+			lineSubtotal = item.Subtotal - prev.Subtotal
+			lineDiscount = item.DiscountAmount - prev.DiscountAmount
+			lineTax = item.TaxAmount - prev.TaxAmount
+		} else {
+			ratio := qty / item.Quantity
+			lineSubtotal = item.Subtotal.Mul(ratio)
+			lineDiscount = item.DiscountAmount.Mul(ratio)
+			lineTax = item.TaxAmount.Mul(ratio)
+		}
+
+		itemID := item.ID
+		refundItem := domain.TransactionItem{
+			ProductID:      item.ProductID,
+			VariantID:      item.VariantID,
+			ProductName:    item.ProductName,
+			VariantName:    item.VariantName,
+			Quantity:       -qty,
+			UnitPrice:      item.UnitPrice,
+			DiscountAmount: -lineDiscount,
+			TaxAmount:      -lineTax,
+			Subtotal:       -lineSubtotal,
+			Modifiers:      item.Modifiers,
+			Notes:          item.Notes,
+			IsGiftCard:     item.IsGiftCard,
+			OriginalItemID: &itemID,
+		}
+		if lineTaxes := refundTaxes(item.Taxes, item.Subtotal-item.DiscountAmount, lineSubtotal-lineDiscount, item.TaxAmount, lineTax); lineTaxes != nil {
+			setItemTaxes(&refundItem, lineTaxes)
+		}
+		refundItems = append(refundItems, refundItem)
+		item.RefundedQuantity += qty
+
+		subtotal += lineSubtotal
+		discount += lineDiscount
+		tax += lineTax
+		taxIncluded -= refundItem.TaxIncluded
+		if item.IsGiftCard {
+			giftCards += lineSubtotal - lineDiscount
+		}
+	}
+	if len(quantities) > 0 {
+		return nil, errors.New("refund contains items that do not belong to this transaction")
+	}
+
+	fullyRefunded := true
+	var saleIncluded, saleGiftCards domain.Money
+	for _, item := range original.Items {
+		if item.RefundedQuantity < item.Quantity {
+			fullyRefunded = false
+		}
+		saleIncluded += item.TaxIncluded
+		if item.IsGiftCard {
+			saleGiftCards += item.Subtotal - item.DiscountAmount
+		}
+	}
+
+	// The service charge follows the refunded net sales; the tip and delivery fee are
+	// only returned with the refund that completes the sale
+	var serviceCharge, serviceChargeTax, tip, deliveryFee domain.Money
+	var scTaxes []domain.ItemTax
+	if original.ServiceChargeAmount != 0 {
+		if fullyRefunded {
+			serviceCharge = original.ServiceChargeAmount - prevServiceCharge
+			serviceChargeTax = original.ServiceChargeTax - prevServiceChargeTax
+		} else {
+			saleNet := original.Subtotal - original.DiscountAmount - saleIncluded - saleGiftCards
+			serviceCharge = original.ServiceChargeAmount.MulRatio(subtotal-discount-taxIncluded-giftCards, saleNet)
+			serviceChargeTax = original.ServiceChargeTax.MulRatio(serviceCharge, original.ServiceChargeAmount)
+		}
+		scTaxes = refundTaxes(original.ServiceChargeTaxes, original.ServiceChargeAmount, serviceCharge, original.ServiceChargeTax, serviceChargeTax)
+	}
+	var tipStaffID *uuid.UUID
+	if fullyRefunded && original.TipAmount != prevTip {
+		tip = original.TipAmount - prevTip
+		tipStaffID = original.TipStaffID
+	}
+	if fullyRefunded {
+		deliveryFee = original.DeliveryFee - prevDeliveryFee
+	}
+	tax += serviceChargeTax
+	scTaxesJSON, _ := json.Marshal(scTaxes)
+
+	totalAmount := subtotal - discount + tax - taxIncluded + serviceCharge + tip + deliveryFee
+
+	payments := u.refundPayments(original, previous, totalAmount, fullyRefunded)
+	for i := range payments {
+		payments[i].ShiftID = shiftID
 	}
 
 	// Create refund transaction
-	txNumber := fmt.Sprintf("REF-%s-%d", time.Now().Format("20060102"), time.Now().UnixNano()%100000)
 	refund := &domain.Transaction{
-		TenantID:              tenantID,
+		TenantID:              original.TenantID,
 		OutletID:              original.OutletID,
 		CashierID:             cashierID,
-		TransactionNumber:     txNumber,
+		CustomerID:            original.CustomerID,
+		ShiftID:               shiftID,
 		Type:                  domain.TransactionTypeRefund,
 		Status:                domain.TransactionStatusCompleted,
-		Subtotal:              -original.Subtotal,
-		TaxAmount:             -original.TaxAmount,
-		TotalAmount:           -original.TotalAmount,
-		RefundReason:          reason,
-		OriginalTransactionID: &transactionID,
+		OrderType:             original.OrderType,
+		Channel:               original.Channel,
+		TableID:               original.TableID,
+		Subtotal:              -subtotal,
+		DiscountAmount:        -discount,
+		TaxAmount:             -tax,
+		ServiceChargeRate:     original.ServiceChargeRate,
+		ServiceChargeAmount:   -serviceCharge,
+		ServiceChargeTax:      -serviceChargeTax,
+		ServiceChargeTaxes:    domain.JSON(scTaxesJSON),
+		TipAmount:             -tip,
+		TipStaffID:            tipStaffID,
+		DeliveryFee:           -deliveryFee,
+		TotalAmount:           -totalAmount,
+		RefundReason:          req.Reason,
+		IdempotencyKey:        optionalKey(req.IdempotencyKey),
+		OriginalTransactionID: &original.ID,
+		PaymentMethod:         original.PaymentMethod,
+		Items:                 refundItems,
+		Taxes:                 summarizeTaxes(refundItems, scTaxes),
+		Payments:              payments,
 	}
 
-	if err := u.transactionRepo.Create(refund); err != nil {
-		return nil, fmt.Errorf("failed to create refund: %w", err)
+	if fullyRefunded {
+		original.Status = domain.TransactionStatusRefunded
+	} else {
+		original.Status = domain.TransactionStatusPartiallyRefunded
 	}
+	return refund, nil
+}
 
-	// Update original
-	original.Status = domain.TransactionStatusRefunded
-	_ = u.transactionRepo.Update(original)
+// refundPayments pays a refund back the way the sale was paid. The shares paid with
+// points and gift cards go back to them in proportion to the refund, the rest with the
+// sale's payment method; a gift card that expired or was voided since is paid out instead.
+func (u *POSUsecase) refundPayments(original *domain.Transaction, previous []domain.Transaction, amount domain.Money, final bool) []domain.TransactionPayment {
+	type source struct {
+		payment        domain.TransactionPayment
+		paid, returned domain.Money
+	}
+	var sources []*source
+	find := func(p domain.TransactionPayment) *source {
+		for _, s := range sources {
+			if s.payment.PaymentMethod == p.PaymentMethod &&
+				(s.payment.GiftCardID == nil || (p.GiftCardID != nil && *p.GiftCardID == *s.payment.GiftCardID)) {
+				return s
+			}
+		}
+		return nil
+	}
+	for _, p := range original.Payments {
+		if !isStoredValue(p.PaymentMethod) {
+			continue
+		}
+		s := find(p)
+		if s == nil {
+			s = &source{payment: p}
+			sources = append(sources, s)
+		}
+		s.paid += p.Amount
+	}
+	for _, prev := range previous {
+		for _, p := range prev.Payments {
+			if s := find(p); s != nil {
+				s.returned -= p.Amount
+			}
+		}
+	}
 
-	// Restore inventory
-	for _, item := range original.Items {
-		_ = u.inventoryRepo.UpdateStock(original.OutletID, item.ProductID, item.VariantID, item.Quantity)
+	var payments []domain.TransactionPayment
+	var storedValue domain.Money
+	for _, s := range sources {
+		share := s.paid.MulRatio(amount, original.TotalAmount)
+		if final {
+			share = s.paid - s.returned
+		}
+		share = min(share, s.paid-s.returned, amount-storedValue)
+		if share <= 0 || (s.payment.GiftCardID != nil && !u.giftCards.refundable(*s.payment.GiftCardID)) {
+			continue
+		}
+		storedValue += share
+		payments = append(payments, domain.TransactionPayment{
+			PaymentMethod:   s.payment.PaymentMethod,
+			Amount:          -share,
+			ReferenceNumber: s.payment.ReferenceNumber,
+			GiftCardID:      s.payment.GiftCardID,
+			Status:          "completed",
+		})
 	}
 
-	return refund, nil
+	method := original.PaymentMethod
+	if isStoredValue(method) {
+		method = domain.PaymentCash
+		for _, p := range original.Payments {
+			if !isStoredValue(p.PaymentMethod) {
+				method = p.PaymentMethod
+				break
+			}
+		}
+	}
+	if cash := amount - storedValue; cash != 0 || len(payments) == 0 {
+		payments = append(payments, domain.TransactionPayment{
+			PaymentMethod: method,
+			Amount:        -cash,
+			Status:        "completed",
+		})
+	}
+	return payments
+}
+
+// isStoredValue tells the payment methods that spend value the merchant holds for the
+// customer (points and gift cards) rather than bring money in
+func isStoredValue(method string) bool {
+	return method == domain.PaymentLoyalty || method == domain.PaymentGiftCard
+}
+
+// Void cancels a completed sale from the current shift. Stock is put back and the
+// sale journal is reversed; the sale stays on record with status voided.
+// Cashiers need an outlet manager or owner to approve the void.
+func (u *POSUsecase) Void(tenantID, userID uuid.UUID, role string, transactionID uuid.UUID, req domain.VoidRequest) (*domain.Transaction, error) {
+	tx, err := u.transactionRepo.FindByID(transactionID)
+	if err != nil || tx.TenantID != tenantID {
+		return nil, errors.New("transaction not found")
+	}
+
+	if tx.Type != domain.TransactionTypeSale {
+		return nil, errors.New("only sales can be voided")
+	}
+	switch tx.Status {
+	case domain.TransactionStatusCompleted:
+	case domain.TransactionStatusVoided:
+		return nil, errors.New("transaction already voided")
+	case domain.TransactionStatusPartiallyRefunded, domain.TransactionStatusRefunded:
+		return nil, errors.New("refunded transactions cannot be voided, use refund instead")
+	default:
+		return nil, fmt.Errorf("cannot void a %s transaction", tx.Status)
+	}
+	if strings.TrimSpace(req.Reason) == "" {
+		return nil, errors.New("void reason is required")
+	}
+
+	// Voids are limited to the shift the sale was taken in, while it is still open.
+	// Sales taken without a shift have no drawer to keep in balance.
+	if tx.ShiftID != nil {
+		shift, err := u.shiftRepo.FindByID(*tx.ShiftID)
+		if err != nil || shift.Status != domain.ShiftStatusOpen {
+			return nil, errors.New("only transactions from the current shift can be voided, use refund instead")
+		}
+	}
+	now := time.Now()
+
+	approverID := userID
+	if role == domain.RoleCashier {
+		approver, err := u.resolveVoidApprover(tenantID, tx.OutletID, req)
+		if err != nil {
+			return nil, err
+		}
+		approverID = approver.ID
+	}
+
+	var kitchenEvents []domain.KitchenEvent
+	err = u.uow.Do(func(repos domain.Repositories) error {
+		if err := holdShift(repos, tx.ShiftID); err != nil {
+			return err
+		}
+		// Re-read the sale under a lock so a racing refund or second void cannot
+		// reverse it again
+		locked, err := repos.Transactions.LockByID(tx.ID)
+		if err != nil {
+			return fmt.Errorf("failed to lock transaction: %w", err)
+		}
+		switch locked.Status {
+		case domain.TransactionStatusCompleted:
+		case domain.TransactionStatusVoided:
+			return errors.New("transaction already voided")
+		default:
+			return fmt.Errorf("cannot void a %s transaction", locked.Status)
+		}
+		tx = locked
+		tx.Status = domain.TransactionStatusVoided
+		tx.VoidReason = req.Reason
+		tx.VoidedBy = &userID
+		tx.VoidApprovedBy = &approverID
+		tx.VoidedAt = &now
+
+		if err := restoreSaleStock(repos, tx, req.Reason, &userID); err != nil {
+			return err
+		}
+
+		if err := repos.Transactions.Update(tx); err != nil {
+			return fmt.Errorf("failed to void transaction: %w", err)
+		}
+		// Dishes not yet cooked are taken off the kitchen queue
+		if kitchenEvents, err = u.kitchen.fire(repos, tx); err != nil {
+			return err
+		}
+		if err := u.loyalty.reverse(repos, tx, tx.TotalAmount, true, &userID, "Void of "+tx.TransactionNumber); err != nil {
+			return err
+		}
+		if err := u.giftCards.reverse(repos, tx, &userID); err != nil {
+			return err
+		}
+
+		// Reverse the sale journal (Void → Journal)
+		return u.reverseSaleJournal(repos, tenantID, tx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	u.kitchen.publish(kitchenEvents)
+
+	return tx, nil
+}
+
+// resolveVoidApprover authenticates the manager approving a cashier's void,
+// either by email and password or by the override PIN of the chosen manager.
+// Wrong PINs are counted per approver and per device, which are locked out for a
+// while after maxPINFailures in a row.
+func (u *POSUsecase) resolveVoidApprover(tenantID, outletID uuid.UUID, req domain.VoidRequest) (*domain.User, error) {
+	var approver *domain.User
+	switch {
+	case req.ApproverEmail != "" && req.ApproverPassword != "":
+		user, err := u.userRepo.FindByEmail(tenantID, req.ApproverEmail)
+		if err != nil || bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.ApproverPassword)) != nil {
+			return nil, errors.New("invalid approver credentials")
+		}
+		approver = user
+	case req.ApproverPIN != "":
+		if req.ApproverID == nil {
+			return nil, errors.New("choose the approving manager to use a PIN")
+		}
+		subjects := []string{
+			"approver:" + req.ApproverID.String(),
+			"device:" + tenantID.String() + ":" + req.Device,
+		}
+		now := time.Now()
+		for _, subject := range subjects {
+			if attempt, err := u.userRepo.FindPINAttempt(subject); err == nil && attempt.LockedUntil != nil && now.Before(*attempt.LockedUntil) {
+				return nil, ErrPINLocked
+			}
+		}
+		user, err := u.userRepo.FindByID(*req.ApproverID)
+		if err != nil || user.PinHash == "" || bcrypt.CompareHashAndPassword([]byte(user.PinHash), []byte(req.ApproverPIN)) != nil {
+			if err := u.failPIN(subjects, now); err != nil {
+				return nil, err
+			}
+			return nil, errors.New("invalid approver credentials")
+		}
+		if err := u.userRepo.ClearPINAttempts(subjects...); err != nil {
+			return nil, fmt.Errorf("failed to reset PIN attempts: %w", err)
+		}
+		approver = user
+	default:
+		return nil, errors.New("manager approval is required to void a transaction")
+	}
+
+	if approver.TenantID != tenantID || !approver.IsActive {
+		return nil, errors.New("invalid approver credentials")
+	}
+	switch approver.Role {
+	case domain.RoleOwner:
+	case domain.RoleOutletManager:
+		if approver.OutletID != nil && *approver.OutletID != outletID {
+			return nil, errors.New("approver does not manage this outlet")
+		}
+	default:
+		return nil, errors.New("only an outlet manager or owner can approve a void")
+	}
+	return approver, nil
+}
+
+// failPIN counts a wrong PIN against the approver and the device, locking PIN
+// approval for whichever reaches maxPINFailures
+func (u *POSUsecase) failPIN(subjects []string, now time.Time) error {
+	for _, subject := range subjects {
+		failures, err := u.userRepo.RecordPINFailure(subject)
+		if err != nil {
+			return fmt.Errorf("failed to record PIN attempt: %w", err)
+		}
+		if failures >= maxPINFailures {
+			if err := u.userRepo.LockPIN(subject, now.Add(pinLockout)); err != nil {
+				return fmt.Errorf("failed to lock PIN approval: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// SplitBill divides an open bill into shares by item, by seat or into equal parts.
+// Splitting again replaces the previous shares as long as none has been paid.
+func (u *POSUsecase) SplitBill(tenantID, transactionID uuid.UUID, req domain.SplitBillRequest) (*domain.Transaction, error) {
+	tx, err := u.transactionRepo.FindByID(transactionID)
+	if err != nil || tx.TenantID != tenantID {
+		return nil, errors.New("transaction not found")
+	}
+	if tx.Status != domain.TransactionStatusPending {
+		return nil, errors.New("only open bills can be split")
+	}
+	for _, split := range tx.Splits {
+		if split.Status == domain.SplitStatusPaid {
+			return nil, errors.New("bill already has paid splits and cannot be split again")
+		}
+	}
+
+	// What each line contributes to the bill total
+	lineTotals := make(map[uuid.UUID]domain.Money, len(tx.Items))
+	for _, item := range tx.Items {
+		lineTotals[item.ID] = item.Subtotal - item.DiscountAmount + item.TaxAmount - item.TaxIncluded
+	}
+
+	var splits []domain.SplitBill
+	switch req.Mode {
+	case domain.SplitModeItem:
+		if len(req.Groups) < 2 {
+			return nil, errors.New("split by item requires at least two groups")
+		}
+		assigned := make(map[uuid.UUID]bool)
+		for i, group := range req.Groups {
+			if len(group) == 0 {
+				return nil, fmt.Errorf("split %d has no items", i+1)
+			}
+			var amount domain.Money
+			for _, itemID := range group {
+				lineTotal, ok := lineTotals[itemID]
+				if !ok {
+					return nil, errors.New("split contains items that do not belong to this transaction")
+				}
+				if assigned[itemID] {
+					return nil, errors.New("an item can only be assigned to one split")
+				}
+				assigned[itemID] = true
+				amount += lineTotal
+			}
+			splits = append(splits, newSplit(domain.SplitModeItem, fmt.Sprintf("Split %d", i+1), amount, group))
+		}
+		if len(assigned) != len(lineTotals) {
+			return nil, errors.New("every item must be assigned to a split")
+		}
+
+	case domain.SplitModeSeat:
+		// Items without a seat are shared equally by every seat
+		seatItems := make(map[int][]uuid.UUID)
+		seatAmounts := make(map[int]domain.Money)
+		var seats []int
+		var shared []uuid.UUID
+		var sharedAmount domain.Money
+		for _, item := range tx.Items {
+			if item.SeatNumber <= 0 {
+				shared = append(shared, item.ID)
+				sharedAmount += lineTotals[item.ID]
+				continue
+			}
+			if _, ok := seatItems[item.SeatNumber]; !ok {
+				seats = append(seats, item.SeatNumber)
+			}
+			seatItems[item.SeatNumber] = append(seatItems[item.SeatNumber], item.ID)
+			seatAmounts[item.SeatNumber] += lineTotals[item.ID]
+		}
+		if len(seats) < 2 {
+			return nil, errors.New("split by seat requires items on at least two seats")
+		}
+		sort.Ints(seats)
+		sharedParts := sharedAmount.Split(len(seats))
+		for i, seat := range seats {
+			itemIDs := append(append([]uuid.UUID{}, seatItems[seat]...), shared...)
+			splits = append(splits, newSplit(domain.SplitModeSeat, fmt.Sprintf("Seat %d", seat), seatAmounts[seat]+sharedParts[i], itemIDs))
+		}
+
+	case domain.SplitModeEqual:
+		if req.Shares < 2 {
+			return nil, errors.New("split into equal shares requires at least two shares")
+		}
+		if req.Shares > 100 {
+			return nil, errors.New("a bill can be split into at most 100 shares")
+		}
+		for i, amount := range tx.TotalAmount.Split(req.Shares) {
+			splits = append(splits, newSplit(domain.SplitModeEqual, fmt.Sprintf("Share %d/%d", i+1, req.Shares), amount, nil))
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown split mode: %s", req.Mode)
+	}
+
+	// Service charge and tip belong to the whole bill and are shared in proportion
+	// to the items of each split; the last split absorbs the rounding
+	if req.Mode != domain.SplitModeEqual {
+		var lineSum domain.Money
+		for _, total := range lineTotals {
+			lineSum += total
+		}
+		extra := tx.TotalAmount - lineSum
+		var assigned domain.Money
+		for i := range splits {
+			share := extra.MulRatio(splits[i].Amount, lineSum)
+			if i == len(splits)-1 {
+				share = extra - assigned
+			}
+			assigned += share
+			splits[i].Amount += share
+		}
+	}
+
+	// Line totals and equal shares are exact, so the shares always sum to the bill total
+	for i := range splits {
+		splits[i].TransactionID = tx.ID
+		splits[i].SplitNumber = i + 1
+	}
+
+	err = u.uow.Do(func(repos domain.Repositories) error {
+		// Re-checked under the bill's lock so a share paid meanwhile is not thrown away
+		locked, err := repos.Transactions.LockByID(tx.ID)
+		if err != nil {
+			return fmt.Errorf("failed to lock transaction: %w", err)
+		}
+		if locked.Status != domain.TransactionStatusPending {
+			return errors.New("only open bills can be split")
+		}
+		for _, split := range locked.Splits {
+			if split.Status == domain.SplitStatusPaid {
+				return errors.New("bill already has paid splits and cannot be split again")
+			}
+		}
+		if err := repos.SplitBills.DeleteByTransactionID(tx.ID); err != nil {
+			return fmt.Errorf("failed to clear previous splits: %w", err)
+		}
+		if err := repos.SplitBills.CreateBatch(splits); err != nil {
+			return fmt.Errorf("failed to create splits: %w", err)
+		}
+		// The split bill is presented to the table
+		return repos.Tables.SetStatusByTransaction(tx.ID, domain.TableStatusBilling)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return u.transactionRepo.FindByID(tx.ID)
+}
+
+// PaySplit pays one share of a split bill with its own payment method and MDR.
+// The bill is completed, and stock and journal are booked, once every share is paid.
+func (u *POSUsecase) PaySplit(tenantID, cashierID, transactionID, splitID uuid.UUID, req domain.PaymentRequest) (*domain.Transaction, error) {
+	tx, err := u.transactionRepo.FindByID(transactionID)
+	if err != nil || tx.TenantID != tenantID {
+		return nil, errors.New("transaction not found")
+	}
+	if tx.Status != domain.TransactionStatusPending {
+		return nil, errors.New("bill is not open")
+	}
+
+	split, err := u.splitBillRepo.FindByID(splitID)
+	if err != nil || split.TransactionID != tx.ID {
+		return nil, errors.New("split not found")
+	}
+	shiftID, err := u.openShiftAt(cashierID, tx.OutletID)
+	if err != nil {
+		return nil, err
+	}
+	if split.Status == domain.SplitStatusPaid {
+		return nil, errors.New("split already paid")
+	}
+	if req.PaymentMethod == "" {
+		return nil, errors.New("payment method is required")
+	}
+	if req.PaymentMethod == domain.PaymentLoyalty {
+		return nil, errors.New("loyalty points cannot pay a split bill")
+	}
+	if req.Amount < split.Amount {
+		return nil, errors.New("payment amount is less than split amount")
+	}
+	payments := []domain.TransactionPayment{{
+		TransactionID:   tx.ID,
+		PaymentMethod:   req.PaymentMethod,
+		Amount:          req.Amount,
+		ReferenceNumber: req.ReferenceNumber,
+		ShiftID:         shiftID,
+		SplitID:         &splitID,
+		Status:          "completed",
+	}}
+	// A gift card pays the share exactly; it gives no change
+	if err := u.giftCards.attach(tenantID, tx, split.Amount, []domain.PaymentRequest{req}, payments); err != nil {
+		return nil, err
+	}
+	payment := &payments[0]
+
+	// The split's share of the tip is left out of its MDR base, as at checkout
+	tipShare := tx.TipAmount.MulRatio(split.Amount, tx.TotalAmount)
+	feeMidtrans, feeCodapos, mdrPercent, mdrFlat := calculateMDR(req.PaymentMethod, split.Amount-tipShare)
+	now := time.Now()
+
+	err = u.uow.Do(func(repos domain.Repositories) error {
+		if err := holdShift(repos, shiftID); err != nil {
+			return err
+		}
+		// The bill is locked and its shares re-read, so two devices paying the last
+		// shares at once cannot both miss (or both run) the completion below
+		locked, err := repos.Transactions.LockByID(tx.ID)
+		if err != nil {
+			return fmt.Errorf("failed to lock transaction: %w", err)
+		}
+		if locked.Status != domain.TransactionStatusPending {
+			return errors.New("bill is not open")
+		}
+		tx = locked
+		var split *domain.SplitBill
+		for i := range tx.Splits {
+			if tx.Splits[i].ID == splitID {
+				split = &tx.Splits[i]
+			}
+		}
+		if split == nil {
+			return errors.New("split not found")
+		}
+		if split.Status == domain.SplitStatusPaid {
+			return errors.New("split already paid")
+		}
+
+		split.PaymentMethod = req.PaymentMethod
+		split.ReferenceNumber = req.ReferenceNumber
+		split.MDRRatePercentage = mdrPercent
+		split.MDRRateFlat = mdrFlat
+		split.FeeMidtrans = feeMidtrans
+		split.FeeCodapos = feeCodapos
+		split.TotalMDRMerchant = feeMidtrans + feeCodapos
+		split.Status = domain.SplitStatusPaid
+		split.PaidBy = &cashierID
+		split.PaidAt = &now
+		if err := repos.SplitBills.Update(split); err != nil {
+			return fmt.Errorf("failed to update split: %w", err)
+		}
+
+		if err := repos.Transactions.CreatePayment(payment); err != nil {
+			return fmt.Errorf("failed to record payment: %w", err)
+		}
+		if err := u.giftCards.book(repos, tx, payments); err != nil {
+			return err
+		}
+		tx.Payments = append(tx.Payments, *payment)
+
+		splits := tx.Splits
+		for _, s := range splits {
+			if s.Status != domain.SplitStatusPaid {
+				return nil
+			}
+		}
+
+		// Every share is paid: the parent takes the combined MDR of its splits
+		tx.PaymentMethod = splits[0].PaymentMethod
+		tx.MDRRatePercentage = splits[0].MDRRatePercentage
+		tx.MDRRateFlat = splits[0].MDRRateFlat
+		tx.FeeMidtrans, tx.FeeCodapos, tx.TotalMDRMerchant = 0, 0, 0
+		for _, s := range splits {
+			if s.PaymentMethod != tx.PaymentMethod {
+				tx.PaymentMethod = domain.PaymentSplit
+				tx.MDRRatePercentage, tx.MDRRateFlat = 0, 0
+			}
+			tx.FeeMidtrans += s.FeeMidtrans
+			tx.FeeCodapos += s.FeeCodapos
+			tx.TotalMDRMerchant += s.TotalMDRMerchant
+		}
+		tx.NetProfit = tx.TotalAmount - tx.TipAmount - tx.TotalMDRMerchant
+		tx.Status = domain.TransactionStatusCompleted
+		// The sale is reported in the shift that took its last payment; the cash of each
+		// share stays with the shift that took it
+		if shiftID != nil {
+			tx.ShiftID = shiftID
+		}
+		tx.ExpiresAt = nil
+		if err := repos.Transactions.Update(tx); err != nil {
+			return fmt.Errorf("failed to complete transaction: %w", err)
+		}
+		if err := repos.Tables.ReleaseByTransaction(tx.ID); err != nil {
+			return fmt.Errorf("failed to release table: %w", err)
+		}
+
+		return u.settleSale(repos, tenantID, cashierID, tx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return u.transactionRepo.FindByID(tx.ID)
+}
+
+// openShiftAt returns the ID of the cashier's open shift, which must be at the outlet.
+// Shifts are optional: a cashier who has not opened one takes payments outside any
+// shift, and nil is returned.
+func (u *POSUsecase) openShiftAt(cashierID, outletID uuid.UUID) (*uuid.UUID, error) {
+	shift, err := u.shiftRepo.FindOpenByCashier(cashierID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load open shift: %w", err)
+	}
+	if shift.OutletID != outletID {
+		return nil, errors.New("cashier's open shift is at another outlet")
+	}
+	return &shift.ID, nil
+}
+
+func newSplit(mode, label string, amount domain.Money, itemIDs []uuid.UUID) domain.SplitBill {
+	if itemIDs == nil {
+		itemIDs = []uuid.UUID{}
+	}
+	raw, _ := json.Marshal(itemIDs)
+	return domain.SplitBill{
+		Mode:    mode,
+		Label:   label,
+		ItemIDs: domain.JSON(raw),
+		Amount:  amount,
+		Status:  domain.SplitStatusPending,
+	}
 }
 
 // GetTransactions returns transactions with pagination
@@ -339,10 +1473,10 @@ func (u *POSUsecase) GetTransaction(id uuid.UUID) (*domain.Transaction, error) {
 	return u.transactionRepo.FindByID(id)
 }
 
-// IncrementReprint processes a receipt reprint logging
-func (u *POSUsecase) IncrementReprint(id uuid.UUID) (*domain.Transaction, error) {
+// IncrementReprint logs a receipt reprint; receipts rendered afterwards carry the reprint mark
+func (u *POSUsecase) IncrementReprint(tenantID, id uuid.UUID) (*domain.Transaction, error) {
 	tx, err := u.transactionRepo.FindByID(id)
-	if err != nil {
+	if err != nil || tx.TenantID != tenantID {
 		return nil, errors.New("transaction not found")
 	}
 
@@ -430,7 +1564,7 @@ func (u *POSUsecase) PayTenantBilling(tenantID uuid.UUID, billingID uuid.UUID) (
 	// To simplify: if Status == "past_due" or date>7
 	now := time.Now()
 	if now.Day() > 7 && bill.Status != "paid" {
-		bill.PenaltyFee = bill.TotalMDR * 0.10 // 10% Late Fee
+		bill.PenaltyFee = bill.TotalMDR.Percent(10) // 10% Late Fee
 		bill.Status = "past_due"
 	}
 
@@ -1,17 +1,23 @@
 package usecase
 
 import (
+	"errors"
+	"fmt"
+
 	"github.com/codapos/backend/internal/domain"
 	"github.com/google/uuid"
 )
 
 type ProductUsecase struct {
-	productRepo  domain.ProductRepository
-	categoryRepo domain.CategoryRepository
+	productRepo     domain.ProductRepository
+	categoryRepo    domain.CategoryRepository
+	outletRepo      domain.OutletRepository
+	outletPriceRepo domain.OutletPriceRepository
+	inventory       *InventoryUsecase
 }
 
-func NewProductUsecase(pr domain.ProductRepository, cr domain.CategoryRepository) *ProductUsecase {
-	return &ProductUsecase{productRepo: pr, categoryRepo: cr}
+func NewProductUsecase(pr domain.ProductRepository, cr domain.CategoryRepository, or domain.OutletRepository, opr domain.OutletPriceRepository, inventory *InventoryUsecase) *ProductUsecase {
+	return &ProductUsecase{productRepo: pr, categoryRepo: cr, outletRepo: or, outletPriceRepo: opr, inventory: inventory}
 }
 
 // CreateProduct creates a new product
@@ -19,9 +25,55 @@ func (u *ProductUsecase) CreateProduct(product *domain.Product) error {
 	return u.productRepo.Create(product)
 }
 
-// GetProducts returns products for a tenant with optional search and category filter
-func (u *ProductUsecase) GetProducts(tenantID uuid.UUID, search string, categoryID *uuid.UUID) ([]domain.Product, error) {
-	return u.productRepo.FindByTenantID(tenantID, search, categoryID)
+// GetProducts returns products for a tenant with optional search and category filter.
+// When an outlet is given, each product and variant carries its effective price there,
+// and stock-tracked ones what is left to sell after reservations for unpaid online orders.
+func (u *ProductUsecase) GetProducts(tenantID uuid.UUID, search string, categoryID *uuid.UUID, outletID *uuid.UUID) ([]domain.Product, error) {
+	products, err := u.productRepo.FindByTenantID(tenantID, search, categoryID)
+	if err != nil || outletID == nil {
+		return products, err
+	}
+
+	if _, err := u.getOutlet(tenantID, *outletID); err != nil {
+		return nil, err
+	}
+	prices, err := u.outletPriceRepo.FindByOutletID(*outletID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load outlet prices: %w", err)
+	}
+	book := newOutletPriceBook(prices)
+	for i := range products {
+		book.apply(&products[i])
+	}
+
+	stock, err := u.inventory.GetStockByOutlet(*outletID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load stock: %w", err)
+	}
+	available := make(map[string]float64, len(stock))
+	for _, inv := range stock {
+		available[stockKey(inv.ProductID, inv.VariantID)] = inv.Available
+	}
+	for i := range products {
+		setAvailable(&products[i], available)
+	}
+	return products, nil
+}
+
+// setAvailable sets the stock left to sell of a stock-tracked product and its variants; a
+// product with variants has what its variants have between them
+func setAvailable(product *domain.Product, available map[string]float64) {
+	if !product.TrackStock {
+		return
+	}
+	total := available[stockKey(product.ID, nil)]
+	for i := range product.Variants {
+		v := &product.Variants[i]
+		quantity := available[stockKey(product.ID, &v.ID)]
+		v.Available = &quantity
+		total += quantity
+	}
+	product.Available = &total
 }
 
 // GetProduct returns a single product
@@ -55,3 +107,126 @@ func (u *ProductUsecase) UpdateCategory(category *domain.Category) error {
 func (u *ProductUsecase) DeleteCategory(id uuid.UUID) error {
 	return u.categoryRepo.Delete(id)
 }
+
+// GetOutletPrices returns the price overrides of an outlet
+func (u *ProductUsecase) GetOutletPrices(tenantID, outletID uuid.UUID) ([]domain.OutletPrice, error) {
+	if _, err := u.getOutlet(tenantID, outletID); err != nil {
+		return nil, err
+	}
+	return u.outletPriceRepo.FindByOutletID(outletID)
+}
+
+// SetOutletPrices creates or updates several price overrides of an outlet at once
+func (u *ProductUsecase) SetOutletPrices(tenantID, outletID uuid.UUID, req domain.OutletPriceBulkRequest) ([]domain.OutletPrice, error) {
+	if _, err := u.getOutlet(tenantID, outletID); err != nil {
+		return nil, err
+	}
+	if len(req.Prices) == 0 {
+		return nil, errors.New("prices are required")
+	}
+
+	prices := make([]domain.OutletPrice, 0, len(req.Prices))
+	for _, p := range req.Prices {
+		if p.Price < 0 {
+			return nil, errors.New("price cannot be negative")
+		}
+		if err := u.checkPriceTarget(tenantID, p); err != nil {
+			return nil, err
+		}
+		prices = append(prices, domain.OutletPrice{
+			OutletID:  outletID,
+			ProductID: p.ProductID,
+			VariantID: p.VariantID,
+			Price:     p.Price,
+		})
+	}
+
+	if err := u.outletPriceRepo.Upsert(prices); err != nil {
+		return nil, fmt.Errorf("failed to save outlet prices: %w", err)
+	}
+	return prices, nil
+}
+
+// DeleteOutletPrices removes several price overrides of an outlet, restoring the default price
+func (u *ProductUsecase) DeleteOutletPrices(tenantID, outletID uuid.UUID, req domain.OutletPriceBulkRequest) error {
+	if _, err := u.getOutlet(tenantID, outletID); err != nil {
+		return err
+	}
+	for _, p := range req.Prices {
+		if err := u.outletPriceRepo.Delete(outletID, p.ProductID, p.VariantID); err != nil {
+			return fmt.Errorf("failed to delete outlet price: %w", err)
+		}
+	}
+	return nil
+}
+
+func (u *ProductUsecase) getOutlet(tenantID, outletID uuid.UUID) (*domain.Outlet, error) {
+	outlet, err := u.outletRepo.FindByID(outletID)
+	if err != nil || outlet.TenantID != tenantID {
+		return nil, errors.New("outlet not found")
+	}
+	return outlet, nil
+}
+
+// checkPriceTarget verifies that the product (and variant) of an override belong to the tenant
+func (u *ProductUsecase) checkPriceTarget(tenantID uuid.UUID, p domain.OutletPriceRequest) error {
+	product, err := u.productRepo.FindByID(p.ProductID)
+	if err != nil || product.TenantID != tenantID {
+		return fmt.Errorf("product not found: %s", p.ProductID)
+	}
+	if p.VariantID == nil {
+		return nil
+	}
+	for _, v := range product.Variants {
+		if v.ID == *p.VariantID {
+			return nil
+		}
+	}
+	return fmt.Errorf("variant not found: %s", *p.VariantID)
+}
+
+// outletPriceBook indexes the price overrides of one outlet.
+// Product-level overrides are stored under uuid.Nil as variant.
+type outletPriceBook map[[2]uuid.UUID]domain.Money
+
+func newOutletPriceBook(prices []domain.OutletPrice) outletPriceBook {
+	book := make(outletPriceBook, len(prices))
+	for _, p := range prices {
+		variantID := uuid.Nil
+		if p.VariantID != nil {
+			variantID = *p.VariantID
+		}
+		book[[2]uuid.UUID{p.ProductID, variantID}] = p.Price
+	}
+	return book
+}
+
+// basePrice returns the product's price at the outlet before variants
+func (b outletPriceBook) basePrice(product *domain.Product) domain.Money {
+	if price, ok := b[[2]uuid.UUID{product.ID, uuid.Nil}]; ok {
+		return price
+	}
+	return product.BasePrice
+}
+
+// unitPrice returns the price of a product, or of one of its variants, at the outlet.
+// A variant-level override wins over a product-level override plus the variant's additional price.
+func (b outletPriceBook) unitPrice(product *domain.Product, variant *domain.ProductVariant) domain.Money {
+	if variant == nil {
+		return b.basePrice(product)
+	}
+	if price, ok := b[[2]uuid.UUID{product.ID, variant.ID}]; ok {
+		return price
+	}
+	return b.basePrice(product) + variant.AdditionalPrice
+}
+
+// apply fills the EffectivePrice of a product and its variants
+func (b outletPriceBook) apply(product *domain.Product) {
+	price := b.unitPrice(product, nil)
+	product.EffectivePrice = &price
+	for i := range product.Variants {
+		variantPrice := b.unitPrice(product, &product.Variants[i])
+		product.Variants[i].EffectivePrice = &variantPrice
+	}
+}
@@ -0,0 +1,296 @@
+package usecase
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/codapos/backend/internal/domain"
+	"github.com/google/uuid"
+)
+
+type PromotionUsecase struct {
+	promotionRepo domain.PromotionRepository
+}
+
+func NewPromotionUsecase(pr domain.PromotionRepository) *PromotionUsecase {
+	return &PromotionUsecase{promotionRepo: pr}
+}
+
+// CreatePromotion validates and stores a new promotion for a tenant
+func (u *PromotionUsecase) CreatePromotion(tenantID uuid.UUID, promo *domain.Promotion) error {
+	promo.TenantID = tenantID
+	if err := validatePromotion(promo); err != nil {
+		return err
+	}
+	return u.promotionRepo.Create(promo)
+}
+
+// GetPromotions returns the promotions of a tenant
+func (u *PromotionUsecase) GetPromotions(tenantID uuid.UUID, activeOnly bool) ([]domain.Promotion, error) {
+	return u.promotionRepo.FindByTenantID(tenantID, activeOnly)
+}
+
+// GetPromotion returns a single promotion owned by the tenant
+func (u *PromotionUsecase) GetPromotion(tenantID, id uuid.UUID) (*domain.Promotion, error) {
+	promo, err := u.promotionRepo.FindByID(id)
+	if err != nil || promo.TenantID != tenantID {
+		return nil, errors.New("promotion not found")
+	}
+	return promo, nil
+}
+
+// UpdatePromotion replaces the editable fields of an existing promotion
+func (u *PromotionUsecase) UpdatePromotion(tenantID, id uuid.UUID, req *domain.Promotion) (*domain.Promotion, error) {
+	promo, err := u.GetPromotion(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	promo.Name = req.Name
+	promo.Type = req.Type
+	promo.Value = req.Value
+	promo.Rate = req.Rate
+	promo.MinPurchase = req.MinPurchase
+	promo.MaxDiscount = req.MaxDiscount
+	promo.BuyQuantity = req.BuyQuantity
+	promo.GetQuantity = req.GetQuantity
+	promo.StartDate = req.StartDate
+	promo.EndDate = req.EndDate
+	promo.IsActive = req.IsActive
+	promo.ApplicableOutlets = req.ApplicableOutlets
+	promo.ApplicableProducts = req.ApplicableProducts
+
+	if err := validatePromotion(promo); err != nil {
+		return nil, err
+	}
+	if err := u.promotionRepo.Update(promo); err != nil {
+		return nil, fmt.Errorf("failed to update promotion: %w", err)
+	}
+	return promo, nil
+}
+
+// DeletePromotion removes a promotion owned by the tenant
+func (u *PromotionUsecase) DeletePromotion(tenantID, id uuid.UUID) error {
+	if _, err := u.GetPromotion(tenantID, id); err != nil {
+		return err
+	}
+	return u.promotionRepo.Delete(id)
+}
+
+// validatePromotion checks that a promotion is internally consistent before it is saved
+func validatePromotion(promo *domain.Promotion) error {
+	if promo.Name == "" {
+		return errors.New("promotion name is required")
+	}
+	if promo.Value < 0 || promo.Rate < 0 || promo.MinPurchase < 0 {
+		return errors.New("promotion value and minimum purchase cannot be negative")
+	}
+	if promo.MaxDiscount != nil && *promo.MaxDiscount <= 0 {
+		return errors.New("max_discount must be greater than zero")
+	}
+	if promo.StartDate != nil && promo.EndDate != nil && promo.EndDate.Before(*promo.StartDate) {
+		return errors.New("end_date must be after start_date")
+	}
+
+	if len(promo.ApplicableOutlets) == 0 {
+		promo.ApplicableOutlets = domain.JSON("[]")
+	}
+	if len(promo.ApplicableProducts) == 0 {
+		promo.ApplicableProducts = domain.JSON("[]")
+	}
+	if _, err := parseUUIDList(promo.ApplicableOutlets); err != nil {
+		return errors.New("applicable_outlets must be a list of outlet IDs")
+	}
+	products, err := parseUUIDList(promo.ApplicableProducts)
+	if err != nil {
+		return errors.New("applicable_products must be a list of product IDs")
+	}
+
+	switch promo.Type {
+	case domain.PromotionTypePercentage:
+		if promo.Rate <= 0 || promo.Rate > 100 {
+			return errors.New("percentage promotion rate must be between 0 and 100")
+		}
+	case domain.PromotionTypeFixed:
+		if promo.Value <= 0 {
+			return errors.New("fixed promotion value must be greater than zero")
+		}
+	case domain.PromotionTypeBuyXGetY:
+		if promo.BuyQuantity <= 0 || promo.GetQuantity <= 0 {
+			return errors.New("buy_x_get_y promotion requires buy_quantity and get_quantity")
+		}
+	case domain.PromotionTypeBundle:
+		if promo.Value <= 0 {
+			return errors.New("bundle promotion value (bundle price) must be greater than zero")
+		}
+		if len(products) < 2 {
+			return errors.New("bundle promotion requires at least two applicable products")
+		}
+	default:
+		return fmt.Errorf("unknown promotion type: %s", promo.Type)
+	}
+	return nil
+}
+
+// applyPromotion validates a promotion against the cart and spreads the resulting
+// discount across items[i].DiscountAmount. It returns the total discount.
+func applyPromotion(promo *domain.Promotion, outletID uuid.UUID, items []domain.TransactionItem, now time.Time) (domain.Money, error) {
+	if !promo.IsActive {
+		return 0, errors.New("promotion is not active")
+	}
+	if promo.StartDate != nil && now.Before(*promo.StartDate) {
+		return 0, errors.New("promotion has not started yet")
+	}
+	if promo.EndDate != nil && now.After(*promo.EndDate) {
+		return 0, errors.New("promotion has expired")
+	}
+
+	outlets, _ := parseUUIDList(promo.ApplicableOutlets)
+	if len(outlets) > 0 && !containsUUID(outlets, outletID) {
+		return 0, errors.New("promotion is not valid at this outlet")
+	}
+
+	var cartSubtotal domain.Money
+	for _, item := range items {
+		cartSubtotal += item.Subtotal
+	}
+	if cartSubtotal < promo.MinPurchase {
+		return 0, fmt.Errorf("minimum purchase for this promotion is Rp%.0f", promo.MinPurchase.Float64())
+	}
+
+	// Eligible lines: every item when ApplicableProducts is empty
+	products, _ := parseUUIDList(promo.ApplicableProducts)
+	var eligible []int
+	var eligibleSubtotal domain.Money
+	for i, item := range items {
+		if len(products) == 0 || containsUUID(products, item.ProductID) {
+			eligible = append(eligible, i)
+			eligibleSubtotal += item.Subtotal
+		}
+	}
+	if len(eligible) == 0 {
+		return 0, errors.New("no items in the cart qualify for this promotion")
+	}
+
+	// alloc holds the discount per cart line
+	alloc := make([]domain.Money, len(items))
+
+	switch promo.Type {
+	case domain.PromotionTypePercentage:
+		spreadByValue(alloc, items, eligible, eligibleSubtotal.Percent(promo.Rate))
+
+	case domain.PromotionTypeFixed:
+		spreadByValue(alloc, items, eligible, min(promo.Value, eligibleSubtotal))
+
+	case domain.PromotionTypeBuyXGetY:
+		var totalUnits float64
+		for _, i := range eligible {
+			totalUnits += math.Floor(items[i].Quantity)
+		}
+		groupSize := float64(promo.BuyQuantity + promo.GetQuantity)
+		freeUnits := math.Floor(totalUnits/groupSize) * float64(promo.GetQuantity)
+		if freeUnits == 0 {
+			return 0, fmt.Errorf("buy %d to get %d free", promo.BuyQuantity, promo.GetQuantity)
+		}
+		// The cheapest units are given away first
+		sorted := append([]int(nil), eligible...)
+		sort.SliceStable(sorted, func(a, b int) bool {
+			return items[sorted[a]].UnitPrice < items[sorted[b]].UnitPrice
+		})
+		for _, i := range sorted {
+			if freeUnits <= 0 {
+				break
+			}
+			take := math.Min(math.Floor(items[i].Quantity), freeUnits)
+			alloc[i] = items[i].UnitPrice.Mul(take)
+			freeUnits -= take
+		}
+
+	case domain.PromotionTypeBundle:
+		bundles := math.Inf(1)
+		var regularPrice domain.Money
+		for _, productID := range products {
+			var units float64
+			unitPrice := domain.Money(math.MaxInt64)
+			for _, i := range eligible {
+				if items[i].ProductID == productID {
+					units += math.Floor(items[i].Quantity)
+					unitPrice = min(unitPrice, items[i].UnitPrice)
+				}
+			}
+			if units == 0 {
+				return 0, errors.New("cart does not contain every product of the bundle")
+			}
+			bundles = math.Min(bundles, units)
+			regularPrice += unitPrice
+		}
+		spreadByValue(alloc, items, eligible, (regularPrice - promo.Value).Mul(bundles))
+	}
+
+	var total domain.Money
+	for _, a := range alloc {
+		total += a
+	}
+	if total <= 0 {
+		return 0, errors.New("promotion does not reduce the price of this cart")
+	}
+	if promo.MaxDiscount != nil && total > *promo.MaxDiscount {
+		for i := range alloc {
+			alloc[i] = alloc[i].MulRatio(*promo.MaxDiscount, total)
+		}
+		total = *promo.MaxDiscount
+	}
+
+	// Cap each line at its subtotal and push the rounding remainder onto the largest line
+	var assigned domain.Money
+	largest := 0
+	for i := range items {
+		items[i].DiscountAmount = min(alloc[i], items[i].Subtotal)
+		assigned += items[i].DiscountAmount
+		if alloc[i] > alloc[largest] {
+			largest = i
+		}
+	}
+	items[largest].DiscountAmount += total - assigned
+
+	return total, nil
+}
+
+// spreadByValue distributes amount over the given lines in proportion to their subtotal
+func spreadByValue(alloc []domain.Money, items []domain.TransactionItem, lines []int, amount domain.Money) {
+	var base domain.Money
+	for _, i := range lines {
+		base += items[i].Subtotal
+	}
+	if base <= 0 || amount <= 0 {
+		return
+	}
+	for _, i := range lines {
+		alloc[i] += amount.MulRatio(items[i].Subtotal, base)
+	}
+}
+
+// parseUUIDList decodes a JSONB array of UUID strings
+func parseUUIDList(raw domain.JSON) ([]uuid.UUID, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var ids []uuid.UUID
+	if err := json.Unmarshal(raw, &ids); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func containsUUID(ids []uuid.UUID, id uuid.UUID) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
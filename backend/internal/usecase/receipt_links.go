@@ -0,0 +1,158 @@
+package usecase
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/codapos/backend/internal/domain"
+	"github.com/google/uuid"
+)
+
+// Errors of opening a public receipt link
+var (
+	ErrReceiptLinkNotFound = errors.New("receipt not found")
+	ErrReceiptLinkExpired  = errors.New("receipt link has expired")
+	ErrReceiptLinkRevoked  = errors.New("receipt link has been revoked")
+)
+
+const (
+	defaultReceiptLinkDays = 30
+	maxReceiptLinkDays     = 365
+)
+
+// CreateReceiptLink creates a public link to the digital receipt of a transaction
+func (u *ReceiptUsecase) CreateReceiptLink(tenantID, userID, transactionID uuid.UUID, req domain.ReceiptLinkRequest) (*domain.ReceiptLink, error) {
+	tx, err := u.transactionRepo.FindByID(transactionID)
+	if err != nil || tx.TenantID != tenantID {
+		return nil, errors.New("transaction not found")
+	}
+	if !domain.IsReportableStatus(tx.Status) && tx.Status != domain.TransactionStatusVoided {
+		return nil, errors.New("only paid transactions have a receipt to share")
+	}
+	days := req.ExpiresInDays
+	if days == 0 {
+		days = defaultReceiptLinkDays
+	}
+	if days < 0 || days > maxReceiptLinkDays {
+		return nil, fmt.Errorf("link expiry must be between 1 and %d days", maxReceiptLinkDays)
+	}
+
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, fmt.Errorf("failed to generate link token: %w", err)
+	}
+	expires := time.Now().AddDate(0, 0, days)
+	link := &domain.ReceiptLink{
+		TenantID:      tenantID,
+		TransactionID: tx.ID,
+		Token:         base64.RawURLEncoding.EncodeToString(raw),
+		ExpiresAt:     &expires,
+		CreatedBy:     userID,
+	}
+	if err := u.linkRepo.Create(link); err != nil {
+		return nil, fmt.Errorf("failed to create receipt link: %w", err)
+	}
+	u.withURL(link)
+	return link, nil
+}
+
+// GetReceiptLinks returns the links shared for a transaction with their view counts
+func (u *ReceiptUsecase) GetReceiptLinks(tenantID, transactionID uuid.UUID) ([]domain.ReceiptLink, error) {
+	tx, err := u.transactionRepo.FindByID(transactionID)
+	if err != nil || tx.TenantID != tenantID {
+		return nil, errors.New("transaction not found")
+	}
+	links, err := u.linkRepo.FindByTransaction(tx.ID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range links {
+		u.withURL(&links[i])
+	}
+	return links, nil
+}
+
+// RevokeReceiptLink stops a link from working, e.g. when it was sent to the wrong customer
+func (u *ReceiptUsecase) RevokeReceiptLink(tenantID, linkID uuid.UUID) (*domain.ReceiptLink, error) {
+	link, err := u.linkRepo.FindByID(linkID)
+	if err != nil || link.TenantID != tenantID {
+		return nil, errors.New("receipt link not found")
+	}
+	if link.RevokedAt == nil {
+		now := time.Now()
+		link.RevokedAt = &now
+		if err := u.linkRepo.Update(link); err != nil {
+			return nil, fmt.Errorf("failed to revoke receipt link: %w", err)
+		}
+	}
+	u.withURL(link)
+	return link, nil
+}
+
+// ViewReceipt renders the digital receipt behind a public link as HTML and counts the view.
+// The receipt shows the current state of the transaction, so a later refund or void shows on it.
+func (u *ReceiptUsecase) ViewReceipt(publicToken string) ([]byte, error) {
+	token, signature, ok := strings.Cut(publicToken, ".")
+	if !ok || token == "" {
+		return nil, ErrReceiptLinkNotFound
+	}
+	link, err := u.linkRepo.FindByToken(token)
+	if err != nil || !hmac.Equal([]byte(signature), []byte(u.signLink(link))) {
+		return nil, ErrReceiptLinkNotFound
+	}
+	now := time.Now()
+	if link.RevokedAt != nil {
+		return nil, ErrReceiptLinkRevoked
+	}
+	if link.ExpiresAt != nil && now.After(*link.ExpiresAt) {
+		return nil, ErrReceiptLinkExpired
+	}
+
+	tx, err := u.transactionRepo.FindByID(link.TransactionID)
+	if err != nil || tx.TenantID != link.TenantID {
+		return nil, ErrReceiptLinkNotFound
+	}
+	tenant, err := u.tenantRepo.FindByID(link.TenantID)
+	if err != nil || !tenant.IsEnabled {
+		return nil, ErrReceiptLinkNotFound
+	}
+
+	r := u.newReceipt(tenant, tx)
+	// The customer's digital copy is not a reprint
+	copied := *tx
+	copied.ReprintCount = 0
+	r.Transaction = &copied
+	var settings struct {
+		CustomColor string `json:"custom_color"`
+	}
+	_ = json.Unmarshal(tenant.Settings, &settings)
+	r.AccentColor = settings.CustomColor
+
+	body, err := r.Render(domain.ReceiptFormatHTML)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.linkRepo.RecordView(link.ID, now); err != nil {
+		return nil, fmt.Errorf("failed to record receipt view: %w", err)
+	}
+	return body, nil
+}
+
+// signLink signs the token of a link together with its transaction, so a public
+// address only opens the receipt it was issued for
+func (u *ReceiptUsecase) signLink(link *domain.ReceiptLink) string {
+	mac := hmac.New(sha256.New, u.linkKey)
+	mac.Write([]byte(link.Token + ":" + link.TransactionID.String()))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (u *ReceiptUsecase) withURL(link *domain.ReceiptLink) {
+	link.URL = fmt.Sprintf("%s/api/v1/receipts/%s.%s", u.baseURL, link.Token, u.signLink(link))
+}
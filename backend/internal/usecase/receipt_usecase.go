@@ -0,0 +1,270 @@
+package usecase
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // logo formats
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/codapos/backend/internal/domain"
+	"github.com/codapos/backend/internal/receipt"
+	"github.com/google/uuid"
+)
+
+// ErrFeatureNotInPlan is returned when a tenant uses a feature its plan does not include
+var ErrFeatureNotInPlan = errors.New("feature is not included in your plan")
+
+type ReceiptUsecase struct {
+	templateRepo    domain.ReceiptTemplateRepository
+	linkRepo        domain.ReceiptLinkRepository
+	transactionRepo domain.TransactionRepository
+	tenantRepo      domain.TenantRepository
+	outletRepo      domain.OutletRepository
+	featureRepo     domain.FeatureFlagRepository
+	logos           *logoCache
+	linkKey         []byte // signs public receipt links
+	baseURL         string // public address of the API, used in receipt links
+}
+
+func NewReceiptUsecase(
+	rtr domain.ReceiptTemplateRepository,
+	rlr domain.ReceiptLinkRepository,
+	tr domain.TransactionRepository,
+	tnr domain.TenantRepository,
+	or domain.OutletRepository,
+	fr domain.FeatureFlagRepository,
+	secret string,
+	baseURL string,
+) *ReceiptUsecase {
+	key := sha256.Sum256([]byte("receipt-link:" + secret))
+	return &ReceiptUsecase{
+		templateRepo:    rtr,
+		linkRepo:        rlr,
+		transactionRepo: tr,
+		tenantRepo:      tnr,
+		outletRepo:      or,
+		featureRepo:     fr,
+		logos:           newLogoCache(),
+		linkKey:         key[:],
+		baseURL:         strings.TrimRight(baseURL, "/"),
+	}
+}
+
+// GetTemplates returns the receipt templates of a tenant, the tenant default first
+func (u *ReceiptUsecase) GetTemplates(tenantID uuid.UUID) ([]domain.ReceiptTemplate, error) {
+	return u.templateRepo.FindByTenant(tenantID)
+}
+
+// SaveTemplate sets the receipt template of an outlet, or the tenant default when no
+// outlet is given. Custom templates are a plan feature.
+func (u *ReceiptUsecase) SaveTemplate(tenantID uuid.UUID, req domain.ReceiptTemplateRequest) (*domain.ReceiptTemplate, error) {
+	if !u.featureEnabled(tenantID, domain.FeatureCustomTemplate) {
+		return nil, fmt.Errorf("custom receipt templates: %w", ErrFeatureNotInPlan)
+	}
+	if req.OutletID != nil {
+		outlet, err := u.outletRepo.FindByID(*req.OutletID)
+		if err != nil || outlet.TenantID != tenantID {
+			return nil, errors.New("outlet not found")
+		}
+	}
+	if req.PaperSize == "" {
+		req.PaperSize = domain.Paper58mm
+	}
+	if !receipt.ValidPaperSize(req.PaperSize) {
+		return nil, fmt.Errorf("unknown paper size: %s", req.PaperSize)
+	}
+	if len(req.QRContent) > 500 {
+		return nil, errors.New("QR content is too long")
+	}
+	if req.LogoURL != "" && !strings.HasPrefix(req.LogoURL, "https://") && !strings.HasPrefix(req.LogoURL, "http://") && !strings.HasPrefix(req.LogoURL, "/uploads/") {
+		return nil, errors.New("logo URL must be an http(s) address")
+	}
+
+	template, err := u.templateRepo.FindByOutlet(tenantID, req.OutletID)
+	isNew := err != nil
+	if isNew {
+		template = &domain.ReceiptTemplate{TenantID: tenantID, OutletID: req.OutletID}
+	}
+	template.PaperSize = req.PaperSize
+	template.ShowLogo = req.ShowLogo == nil || *req.ShowLogo
+	template.ShowCashier = req.ShowCashier == nil || *req.ShowCashier
+	template.LogoURL = strings.TrimSpace(req.LogoURL)
+	template.Header = req.Header
+	template.Footer = req.Footer
+	template.QRContent = strings.TrimSpace(req.QRContent)
+
+	if isNew {
+		err = u.templateRepo.Create(template)
+	} else {
+		err = u.templateRepo.Update(template)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to save receipt template: %w", err)
+	}
+	return template, nil
+}
+
+// DeleteTemplate removes a template; its outlet falls back to the tenant default
+func (u *ReceiptUsecase) DeleteTemplate(tenantID, id uuid.UUID) error {
+	template, err := u.templateRepo.FindByID(id)
+	if err != nil || template.TenantID != tenantID {
+		return errors.New("receipt template not found")
+	}
+	return u.templateRepo.Delete(id)
+}
+
+// RenderReceipt renders the receipt of a transaction in the given format. paper, when
+// set, overrides the paper size of the template. Printer output is a plan feature.
+func (u *ReceiptUsecase) RenderReceipt(tenantID, transactionID uuid.UUID, format, paper string) ([]byte, error) {
+	switch format {
+	case domain.ReceiptFormatESCPOS:
+		if !u.featureEnabled(tenantID, domain.FeaturePrinterBluetooth) {
+			return nil, fmt.Errorf("thermal printer receipts: %w", ErrFeatureNotInPlan)
+		}
+	case domain.ReceiptFormatText, domain.ReceiptFormatHTML:
+	default:
+		return nil, fmt.Errorf("unknown receipt format: %s", format)
+	}
+
+	tx, err := u.transactionRepo.FindByID(transactionID)
+	if err != nil || tx.TenantID != tenantID {
+		return nil, errors.New("transaction not found")
+	}
+	tenant, err := u.tenantRepo.FindByID(tenantID)
+	if err != nil {
+		return nil, errors.New("tenant not found")
+	}
+	if paper != "" && !receipt.ValidPaperSize(paper) {
+		return nil, fmt.Errorf("unknown paper size: %s", paper)
+	}
+
+	r := u.newReceipt(tenant, tx)
+	if paper != "" {
+		r.Template.PaperSize = paper
+	}
+	return r.Render(format)
+}
+
+// newReceipt prepares the receipt of a transaction with the layout and logo of its outlet
+func (u *ReceiptUsecase) newReceipt(tenant *domain.Tenant, tx *domain.Transaction) *receipt.Receipt {
+	template := u.templateFor(tenant.ID, tx.OutletID)
+	r := &receipt.Receipt{Transaction: tx, Template: template, BusinessName: tenant.Name}
+	if template.ShowLogo {
+		logoURL := template.LogoURL
+		if logoURL == "" {
+			logoURL = tenant.LogoURL
+		}
+		r.Logo = u.logos.get(logoURL)
+	}
+	return r
+}
+
+// templateFor returns the template of an outlet, falling back to the tenant default and
+// then the built-in layout. Tenants without custom templates get the built-in layout.
+func (u *ReceiptUsecase) templateFor(tenantID, outletID uuid.UUID) domain.ReceiptTemplate {
+	if !u.featureEnabled(tenantID, domain.FeatureCustomTemplate) {
+		return domain.DefaultReceiptTemplate(tenantID)
+	}
+	if template, err := u.templateRepo.FindByOutlet(tenantID, &outletID); err == nil {
+		return *template
+	}
+	if template, err := u.templateRepo.FindByOutlet(tenantID, nil); err == nil {
+		return *template
+	}
+	return domain.DefaultReceiptTemplate(tenantID)
+}
+
+func (u *ReceiptUsecase) featureEnabled(tenantID uuid.UUID, feature string) bool {
+	enabled, err := u.featureRepo.IsEnabled(tenantID, feature)
+	return err == nil && enabled
+}
+
+const (
+	logoTTL        = time.Hour
+	logoFailureTTL = 5 * time.Minute
+	maxLogoBytes   = 2 << 20
+)
+
+// logoCache keeps decoded logos so printing a receipt does not download the logo each time.
+// Logos that cannot be loaded are remembered for a short while and the receipt prints without one.
+type logoCache struct {
+	mu     sync.Mutex
+	client *http.Client
+	logos  map[string]cachedLogo
+}
+
+type cachedLogo struct {
+	image   image.Image
+	expires time.Time
+}
+
+func newLogoCache() *logoCache {
+	return &logoCache{
+		client: &http.Client{Timeout: 5 * time.Second},
+		logos:  make(map[string]cachedLogo),
+	}
+}
+
+func (c *logoCache) get(url string) image.Image {
+	if url == "" {
+		return nil
+	}
+	c.mu.Lock()
+	cached, ok := c.logos[url]
+	c.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.image
+	}
+
+	img, err := c.load(url)
+	ttl := logoTTL
+	if err != nil {
+		ttl = logoFailureTTL
+	}
+	c.mu.Lock()
+	c.logos[url] = cachedLogo{image: img, expires: time.Now().Add(ttl)}
+	c.mu.Unlock()
+	return img
+}
+
+// load reads a logo from the upload folder or downloads it
+func (c *logoCache) load(url string) (image.Image, error) {
+	var body io.Reader
+	switch {
+	case strings.HasPrefix(url, "/uploads/"):
+		name := path.Clean(url)
+		if !strings.HasPrefix(name, "/uploads/") {
+			return nil, errors.New("invalid logo path")
+		}
+		f, err := os.Open("." + name)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		body = f
+	case strings.HasPrefix(url, "https://"), strings.HasPrefix(url, "http://"):
+		resp, err := c.client.Get(url)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("logo download failed with status %d", resp.StatusCode)
+		}
+		body = resp.Body
+	default:
+		return nil, errors.New("unsupported logo URL")
+	}
+	img, _, err := image.Decode(io.LimitReader(body, maxLogoBytes))
+	return img, err
+}
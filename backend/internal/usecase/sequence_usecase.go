@@ -0,0 +1,149 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/codapos/backend/internal/domain"
+	"github.com/google/uuid"
+)
+
+// defaultSequenceConfigs apply until a tenant configures its own numbering
+var defaultSequenceConfigs = map[string]domain.SequenceConfig{
+	domain.SequenceTransaction: {DocumentType: domain.SequenceTransaction, Prefix: "TXN", ResetPeriod: domain.SequenceResetDaily, Padding: 4},
+	domain.SequenceRefund:      {DocumentType: domain.SequenceRefund, Prefix: "REF", ResetPeriod: domain.SequenceResetDaily, Padding: 4},
+	domain.SequenceDelivery:    {DocumentType: domain.SequenceDelivery, Prefix: "DLV", ResetPeriod: domain.SequenceResetDaily, Padding: 4},
+	domain.SequenceJournal:     {DocumentType: domain.SequenceJournal, Prefix: "JRN", ResetPeriod: domain.SequenceResetMonthly, Padding: 5},
+}
+
+var sequencePrefixPattern = regexp.MustCompile(`^[A-Z0-9]{1,10}$`)
+
+type SequenceUsecase struct {
+	sequenceRepo domain.SequenceRepository
+	outletRepo   domain.OutletRepository
+}
+
+func NewSequenceUsecase(sr domain.SequenceRepository, or domain.OutletRepository) *SequenceUsecase {
+	return &SequenceUsecase{sequenceRepo: sr, outletRepo: or}
+}
+
+// GetConfigs returns the effective numbering of every document type
+func (u *SequenceUsecase) GetConfigs(tenantID uuid.UUID) ([]domain.SequenceConfig, error) {
+	configs, err := u.sequenceRepo.FindConfigs(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	saved := make(map[string]domain.SequenceConfig, len(configs))
+	for _, c := range configs {
+		saved[c.DocumentType] = c
+	}
+
+	result := make([]domain.SequenceConfig, 0, len(defaultSequenceConfigs))
+	for _, docType := range []string{domain.SequenceTransaction, domain.SequenceRefund, domain.SequenceDelivery, domain.SequenceJournal} {
+		config, ok := saved[docType]
+		if !ok {
+			config = defaultSequenceConfigs[docType]
+			config.TenantID = tenantID
+		}
+		result = append(result, config)
+	}
+	return result, nil
+}
+
+// UpdateConfig changes the prefix, reset period or padding of a document type.
+// Counters are kept per period, so a change takes effect with the next number.
+func (u *SequenceUsecase) UpdateConfig(tenantID uuid.UUID, documentType string, req domain.SequenceConfigRequest) (*domain.SequenceConfig, error) {
+	if _, ok := defaultSequenceConfigs[documentType]; !ok {
+		return nil, fmt.Errorf("unknown document type: %s", documentType)
+	}
+	prefix := strings.ToUpper(strings.TrimSpace(req.Prefix))
+	if !sequencePrefixPattern.MatchString(prefix) {
+		return nil, errors.New("prefix must be 1-10 letters or digits")
+	}
+	switch req.ResetPeriod {
+	case domain.SequenceResetDaily, domain.SequenceResetMonthly, domain.SequenceResetNever:
+	default:
+		return nil, errors.New("reset_period must be daily, monthly or never")
+	}
+	if req.Padding < 1 || req.Padding > 10 {
+		return nil, errors.New("padding must be between 1 and 10")
+	}
+	// Refunds share the transaction number column with sales, so equal prefixes would
+	// issue clashing numbers; every document type keeps its own prefix
+	configs, err := u.GetConfigs(tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load sequence configs: %w", err)
+	}
+	for _, other := range configs {
+		if other.DocumentType != documentType && other.Prefix == prefix {
+			return nil, fmt.Errorf("prefix %s is already used for %s numbers", prefix, other.DocumentType)
+		}
+	}
+
+	config, err := u.sequenceRepo.FindConfig(tenantID, documentType)
+	if err != nil {
+		config = &domain.SequenceConfig{TenantID: tenantID, DocumentType: documentType}
+	}
+	config.Prefix = prefix
+	config.ResetPeriod = req.ResetPeriod
+	config.Padding = req.Padding
+	if err := u.sequenceRepo.SaveConfig(config); err != nil {
+		return nil, fmt.Errorf("failed to save sequence config: %w", err)
+	}
+	return config, nil
+}
+
+// Next issues the next document number on its own connection
+func (u *SequenceUsecase) Next(tenantID uuid.UUID, outletID *uuid.UUID, documentType string) (string, error) {
+	return u.NextWith(u.sequenceRepo, tenantID, outletID, documentType)
+}
+
+// NextWith issues the next document number through repo, typically the sequence
+// repository of a unit of work so the number is only consumed when the document commits.
+// Numbers look like PREFIX-SCOPE-PERIOD-0001, where SCOPE is the outlet code (or a short
+// tenant code for tenant-wide sequences) so that they are unique across tenants.
+func (u *SequenceUsecase) NextWith(repo domain.SequenceRepository, tenantID uuid.UUID, outletID *uuid.UUID, documentType string) (string, error) {
+	config, err := repo.FindConfig(tenantID, documentType)
+	if err != nil {
+		defaults, ok := defaultSequenceConfigs[documentType]
+		if !ok {
+			return "", fmt.Errorf("unknown document type: %s", documentType)
+		}
+		config = &defaults
+	}
+
+	scopeID := uuid.Nil
+	scope := strings.ToUpper(strings.ReplaceAll(tenantID.String(), "-", "")[:8])
+	if outletID != nil {
+		outlet, err := u.outletRepo.FindByID(*outletID)
+		if err != nil || outlet.TenantID != tenantID {
+			return "", errors.New("outlet not found")
+		}
+		scopeID = outlet.ID
+		scope = strings.ToUpper(strings.ReplaceAll(outlet.Code, " ", ""))
+	}
+
+	var period string
+	now := time.Now()
+	switch config.ResetPeriod {
+	case domain.SequenceResetDaily:
+		period = now.Format("20060102")
+	case domain.SequenceResetMonthly:
+		period = now.Format("200601")
+	}
+
+	value, err := repo.Next(tenantID, scopeID, documentType, period)
+	if err != nil {
+		return "", fmt.Errorf("failed to issue %s number: %w", documentType, err)
+	}
+
+	parts := []string{config.Prefix, scope}
+	if period != "" {
+		parts = append(parts, period)
+	}
+	parts = append(parts, fmt.Sprintf("%0*d", config.Padding, value))
+	return strings.Join(parts, "-"), nil
+}
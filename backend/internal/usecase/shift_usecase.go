@@ -0,0 +1,353 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/codapos/backend/internal/domain"
+	"github.com/google/uuid"
+)
+
+type ShiftUsecase struct {
+	shiftRepo       domain.ShiftRepository
+	transactionRepo domain.TransactionRepository
+	outletRepo      domain.OutletRepository
+	uow             domain.UnitOfWork
+}
+
+func NewShiftUsecase(sr domain.ShiftRepository, tr domain.TransactionRepository, or domain.OutletRepository, uow domain.UnitOfWork) *ShiftUsecase {
+	return &ShiftUsecase{shiftRepo: sr, transactionRepo: tr, outletRepo: or, uow: uow}
+}
+
+// OpenShift starts a cashier's session at an outlet with the float put in the drawer
+func (u *ShiftUsecase) OpenShift(tenantID, cashierID uuid.UUID, req domain.OpenShiftRequest) (*domain.Shift, error) {
+	outlet, err := u.outletRepo.FindByID(req.OutletID)
+	if err != nil || outlet.TenantID != tenantID {
+		return nil, errors.New("outlet not found")
+	}
+	if req.OpeningFloat < 0 {
+		return nil, errors.New("opening float cannot be negative")
+	}
+	if _, err := u.shiftRepo.FindOpenByCashier(cashierID); err == nil {
+		return nil, errors.New("cashier already has an open shift, close it first")
+	}
+
+	shift := &domain.Shift{
+		TenantID:     tenantID,
+		OutletID:     outlet.ID,
+		CashierID:    cashierID,
+		Status:       domain.ShiftStatusOpen,
+		OpeningFloat: req.OpeningFloat,
+		OpenedAt:     time.Now(),
+		Notes:        req.Notes,
+	}
+	if err := u.shiftRepo.Create(shift); err != nil {
+		return nil, fmt.Errorf("failed to open shift: %w", err)
+	}
+	return u.shiftRepo.FindByID(shift.ID)
+}
+
+// CurrentShift returns the open shift of a cashier. Expected cash is not disclosed
+// until the drawer has been counted.
+func (u *ShiftUsecase) CurrentShift(tenantID, cashierID uuid.UUID) (*domain.Shift, error) {
+	open, err := u.shiftRepo.FindOpenByCashier(cashierID)
+	if err != nil || open.TenantID != tenantID {
+		return nil, errors.New("no open shift")
+	}
+	return u.shiftRepo.FindByID(open.ID)
+}
+
+// GetShifts returns shifts with pagination
+func (u *ShiftUsecase) GetShifts(tenantID uuid.UUID, outletID *uuid.UUID, page, perPage int) ([]domain.Shift, int64, error) {
+	offset := (page - 1) * perPage
+	return u.shiftRepo.FindByTenantID(tenantID, outletID, perPage, offset)
+}
+
+// GetShift returns a single shift owned by the tenant
+func (u *ShiftUsecase) GetShift(tenantID, id uuid.UUID) (*domain.Shift, error) {
+	shift, err := u.shiftRepo.FindByID(id)
+	if err != nil || shift.TenantID != tenantID {
+		return nil, errors.New("shift not found")
+	}
+	return shift, nil
+}
+
+// AddCashMovement records petty cash put into or taken out of the drawer
+func (u *ShiftUsecase) AddCashMovement(tenantID, userID uuid.UUID, role string, shiftID uuid.UUID, req domain.CashMovementRequest) (*domain.ShiftCashMovement, error) {
+	shift, err := u.openShift(tenantID, userID, role, shiftID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Type != domain.CashMovementIn && req.Type != domain.CashMovementOut {
+		return nil, errors.New("type must be cash_in or cash_out")
+	}
+	if req.Amount <= 0 {
+		return nil, errors.New("amount must be greater than zero")
+	}
+	if strings.TrimSpace(req.Reason) == "" {
+		return nil, errors.New("reason is required")
+	}
+
+	movement := &domain.ShiftCashMovement{
+		ShiftID:   shift.ID,
+		Type:      req.Type,
+		Amount:    req.Amount,
+		Reason:    req.Reason,
+		CreatedBy: userID,
+	}
+	err = u.uow.Do(func(repos domain.Repositories) error {
+		if err := holdShift(repos, &shift.ID); err != nil {
+			return err
+		}
+		if err := repos.Shifts.CreateCashMovement(movement); err != nil {
+			return fmt.Errorf("failed to record cash movement: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return movement, nil
+}
+
+// CloseShift records the blind cash count, closes the shift and returns its Z-report
+func (u *ShiftUsecase) CloseShift(tenantID, userID uuid.UUID, role string, shiftID uuid.UUID, req domain.CloseShiftRequest) (*domain.ZReport, error) {
+	shift, err := u.openShift(tenantID, userID, role, shiftID)
+	if err != nil {
+		return nil, err
+	}
+	if req.CountedCash < 0 {
+		return nil, errors.New("counted cash cannot be negative")
+	}
+
+	var report *domain.ZReport
+	err = u.uow.Do(func(repos domain.Repositories) error {
+		// Payments hold a share lock on the shift, so once this lock is granted every
+		// payment taken in it is committed and none can be added before it closes
+		shift, err := repos.Shifts.LockByID(shift.ID)
+		if err != nil {
+			return fmt.Errorf("failed to lock shift: %w", err)
+		}
+		if shift.Status != domain.ShiftStatusOpen {
+			return errors.New("shift is already closed")
+		}
+
+		now := time.Now()
+		shift.Status = domain.ShiftStatusClosed
+		shift.ClosedAt = &now
+		shift.ClosedBy = &userID
+		shift.CountedCash = req.CountedCash
+		if req.Notes != "" {
+			shift.Notes = strings.TrimSpace(shift.Notes + "\n" + req.Notes)
+		}
+
+		if report, err = buildZReport(repos.Transactions, shift); err != nil {
+			return err
+		}
+		shift.ExpectedCash = report.ExpectedCash
+		shift.CashVariance = report.Variance
+
+		if err := repos.Shifts.Update(shift); err != nil {
+			return fmt.Errorf("failed to close shift: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// GetZReport returns the Z-report of a closed shift
+func (u *ShiftUsecase) GetZReport(tenantID, shiftID uuid.UUID) (*domain.ZReport, error) {
+	shift, err := u.GetShift(tenantID, shiftID)
+	if err != nil {
+		return nil, err
+	}
+	if shift.Status != domain.ShiftStatusClosed {
+		return nil, errors.New("the z-report is available once the shift is closed")
+	}
+	return buildZReport(u.transactionRepo, shift)
+}
+
+// holdShift share-locks the shift a payment or cash movement goes into and checks it is
+// still open, so the shift cannot close, and its Z-report be drawn up, in between. A
+// payment taken outside any shift (nil) holds nothing.
+func holdShift(repos domain.Repositories, shiftID *uuid.UUID) error {
+	if shiftID == nil {
+		return nil
+	}
+	shift, err := repos.Shifts.LockSharedByID(*shiftID)
+	if err != nil {
+		return fmt.Errorf("failed to lock shift: %w", err)
+	}
+	if shift.Status != domain.ShiftStatusOpen {
+		return errors.New("shift was closed meanwhile, open a new shift")
+	}
+	return nil
+}
+
+// openShift loads an open shift the user may work on: cashiers only their own,
+// managers and owners any shift of the tenant
+func (u *ShiftUsecase) openShift(tenantID, userID uuid.UUID, role string, shiftID uuid.UUID) (*domain.Shift, error) {
+	shift, err := u.GetShift(tenantID, shiftID)
+	if err != nil {
+		return nil, err
+	}
+	if shift.Status != domain.ShiftStatusOpen {
+		return nil, errors.New("shift is already closed")
+	}
+	if role == domain.RoleCashier && shift.CashierID != userID {
+		return nil, errors.New("shift belongs to another cashier")
+	}
+	return shift, nil
+}
+
+// buildZReport aggregates the transactions and cash movements of a shift. Voided
+// sales were handed back in full, so only their count and value are reported; open
+// bills are reported unpaid. Payments, and so the cash in the drawer, are counted in
+// the shift that took them, which for split bills need not be the bill's own shift.
+func buildZReport(transactionRepo domain.TransactionRepository, shift *domain.Shift) (*domain.ZReport, error) {
+	transactions, err := transactionRepo.FindByShiftID(shift.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load shift transactions: %w", err)
+	}
+
+	report := &domain.ZReport{Shift: shift, OpeningFloat: shift.OpeningFloat, CountedCash: shift.CountedCash}
+	payments := make(map[string]*domain.ZReportPayment)
+	tally := func(method string, amount domain.Money) {
+		p, ok := payments[method]
+		if !ok {
+			p = &domain.ZReportPayment{PaymentMethod: method}
+			payments[method] = p
+		}
+		p.Count++
+		p.Amount += amount
+	}
+	orderTypes := make(map[string]*domain.ZReportOrderType)
+
+	for _, tx := range transactions {
+		ownShift := tx.ShiftID != nil && *tx.ShiftID == shift.ID
+		switch {
+		case !ownShift:
+			// Only shares of the bill were paid in this shift
+		case tx.Type == domain.TransactionTypeRefund:
+			report.RefundCount++
+			report.RefundAmount -= tx.TotalAmount
+			orderType(orderTypes, tx.OrderType).Amount += tx.TotalAmount
+		case tx.Status == domain.TransactionStatusVoided:
+			report.VoidCount++
+			report.VoidAmount += tx.TotalAmount
+		case tx.Status == domain.TransactionStatusPending:
+			report.OpenBillCount++
+			report.OpenBillAmount += tx.TotalAmount
+		default:
+			report.SalesCount++
+			report.GrossSales += tx.Subtotal
+			report.Discounts += tx.DiscountAmount
+			report.Tax += tx.TaxAmount
+			report.ServiceCharge += tx.ServiceChargeAmount
+			report.Tips += tx.TipAmount
+			report.NetSales += tx.TotalAmount
+			ot := orderType(orderTypes, tx.OrderType)
+			ot.Count++
+			ot.Amount += tx.TotalAmount
+		}
+		if tx.Status == domain.TransactionStatusVoided {
+			continue
+		}
+
+		// Overpayment is handed back as change from the drawer: per share for split
+		// bills, and on the whole bill otherwise
+		var paid, change domain.Money
+		for _, p := range tx.Payments {
+			if !takenInShift(p, &tx, shift.ID) {
+				continue
+			}
+			tally(p.PaymentMethod, p.Amount)
+			if split := findSplit(tx.Splits, p.SplitID); split != nil {
+				change += max(p.Amount-split.Amount, 0)
+			} else {
+				paid += p.Amount
+			}
+			if p.PaymentMethod != domain.PaymentCash {
+				continue
+			}
+			if p.Amount < 0 {
+				report.CashRefunds -= p.Amount
+			} else {
+				report.CashSales += p.Amount
+			}
+		}
+		if tx.Type == domain.TransactionTypeSale && paid > tx.TotalAmount {
+			change += paid - tx.TotalAmount
+		}
+		if cash, ok := payments[domain.PaymentCash]; ok && change > 0 {
+			cash.Amount -= change
+			report.CashSales -= change
+		}
+	}
+
+	for _, m := range shift.CashMovements {
+		if m.Type == domain.CashMovementIn {
+			report.CashIn += m.Amount
+		} else {
+			report.CashOut += m.Amount
+		}
+	}
+
+	report.Payments = make([]domain.ZReportPayment, 0, len(payments))
+	for _, p := range payments {
+		report.Payments = append(report.Payments, *p)
+	}
+	sort.Slice(report.Payments, func(i, j int) bool {
+		return report.Payments[i].PaymentMethod < report.Payments[j].PaymentMethod
+	})
+
+	report.OrderTypes = make([]domain.ZReportOrderType, 0, len(orderTypes))
+	for _, ot := range orderTypes {
+		report.OrderTypes = append(report.OrderTypes, *ot)
+	}
+	sort.Slice(report.OrderTypes, func(i, j int) bool {
+		return report.OrderTypes[i].OrderType < report.OrderTypes[j].OrderType
+	})
+
+	report.ExpectedCash = report.OpeningFloat + report.CashSales - report.CashRefunds + report.CashIn - report.CashOut
+	report.Variance = report.CountedCash - report.ExpectedCash
+	return report, nil
+}
+
+// takenInShift reports whether a payment went into the shift's drawer. Payments
+// recorded before payments carried their shift belong to the shift of their bill.
+func takenInShift(p domain.TransactionPayment, tx *domain.Transaction, shiftID uuid.UUID) bool {
+	if p.ShiftID != nil {
+		return *p.ShiftID == shiftID
+	}
+	return tx.ShiftID != nil && *tx.ShiftID == shiftID
+}
+
+// findSplit returns the share of a split bill a payment paid, if any
+func findSplit(splits []domain.SplitBill, id *uuid.UUID) *domain.SplitBill {
+	if id == nil {
+		return nil
+	}
+	for i := range splits {
+		if splits[i].ID == *id {
+			return &splits[i]
+		}
+	}
+	return nil
+}
+
+// orderType returns the running tally of an order type
+func orderType(tallies map[string]*domain.ZReportOrderType, name string) *domain.ZReportOrderType {
+	t, ok := tallies[name]
+	if !ok {
+		t = &domain.ZReportOrderType{OrderType: name}
+		tallies[name] = t
+	}
+	return t
+}
@@ -0,0 +1,410 @@
+package usecase
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codapos/backend/internal/domain"
+	"github.com/google/uuid"
+)
+
+// ErrStoreNotFound is returned for a storefront that does not exist or is switched off
+var ErrStoreNotFound = errors.New("store not found")
+
+const maxStoreOrderItems = 50
+
+// storeReservationTTL is how long the stock of an unpaid storefront order is held, and
+// how long the customer has to pay
+const storeReservationTTL = 30 * time.Minute
+
+// StorefrontUsecase takes orders from a merchant's public storefront. Orders are priced
+// from the catalog like a POS sale, delivered by the merchant's couriers and paid
+// through the merchant's payment gateway before the merchant sees them.
+type StorefrontUsecase struct {
+	tenantRepo   domain.TenantRepository
+	outletRepo   domain.OutletRepository
+	deliveryRepo domain.DeliveryRepository
+	customers    *CustomerUsecase
+	deliveries   *DeliveryUsecase
+	pos          *POSUsecase
+	payments     *PaymentUsecase
+}
+
+func NewStorefrontUsecase(
+	tnr domain.TenantRepository,
+	or domain.OutletRepository,
+	dr domain.DeliveryRepository,
+	customers *CustomerUsecase,
+	deliveries *DeliveryUsecase,
+	pos *POSUsecase,
+	payments *PaymentUsecase,
+) *StorefrontUsecase {
+	return &StorefrontUsecase{
+		tenantRepo:   tnr,
+		outletRepo:   or,
+		deliveryRepo: dr,
+		customers:    customers,
+		deliveries:   deliveries,
+		pos:          pos,
+		payments:     payments,
+	}
+}
+
+// storeSettings are the storefront keys of the tenant settings
+type storeSettings struct {
+	Address   string  `json:"store_address"`
+	Phone     string  `json:"store_phone"`
+	Latitude  float64 `json:"store_latitude"`
+	Longitude float64 `json:"store_longitude"`
+}
+
+// Quote prices a storefront order without placing it, so the customer sees the delivery
+// fee and total before paying, and finds out early when something is sold out
+func (u *StorefrontUsecase) Quote(slug string, req domain.StoreCheckoutRequest) (*domain.StoreQuote, error) {
+	tenant, err := u.store(slug)
+	if err != nil {
+		return nil, err
+	}
+	quote, _, err := u.price(tenant, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.pos.CheckOnlineStock(quote.OutletID, quote.Items); err != nil {
+		return nil, err
+	}
+	return quote, nil
+}
+
+// FulfillingOutlet returns the outlet the storefront sells from when the customer does
+// not pick one, so the catalog shows what that outlet has left
+func (u *StorefrontUsecase) FulfillingOutlet(tenantID uuid.UUID) (*domain.Outlet, error) {
+	return u.outlet(tenantID, nil)
+}
+
+// Checkout places a storefront order. The order is priced by the server, waits for
+// payment and becomes pending for the merchant once the gateway confirms the payment.
+// A retried checkout with the same idempotency key returns the original order and
+// payment instead of placing another.
+func (u *StorefrontUsecase) Checkout(slug string, req domain.StoreCheckoutRequest) (*domain.StoreCheckoutResult, error) {
+	tenant, err := u.store(slug)
+	if err != nil {
+		return nil, err
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	req.Phone = strings.TrimSpace(req.Phone)
+	req.FullAddress = strings.TrimSpace(req.FullAddress)
+	if req.Name == "" || req.Phone == "" || req.FullAddress == "" {
+		return nil, errors.New("name, phone and address are required")
+	}
+	if len(req.IdempotencyKey) > 100 {
+		return nil, errors.New("idempotency key is too long")
+	}
+	if req.IdempotencyKey != "" {
+		if existing, err := u.deliveryRepo.FindOrderByIdempotencyKey(tenant.ID, req.IdempotencyKey); err == nil {
+			return u.resume(tenant, existing, req)
+		}
+	}
+
+	quote, settings, err := u.price(tenant, req)
+	if err != nil {
+		return nil, err
+	}
+	customer, err := u.customers.CreateCustomer(tenant.ID, domain.CreateCustomerRequest{
+		Name:        req.Name,
+		Phone:       req.Phone,
+		FullAddress: req.FullAddress,
+		Latitude:    req.Latitude,
+		Longitude:   req.Longitude,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save customer: %w", err)
+	}
+
+	order, err := u.deliveries.CreateOrder(tenant.ID, domain.CreateDeliveryRequest{
+		CustomerID:     customer.ID.String(),
+		PickupAddress:  settings.Address,
+		PickupLat:      settings.Latitude,
+		PickupLng:      settings.Longitude,
+		PickupContact:  tenant.Name,
+		PickupPhone:    settings.Phone,
+		DropoffAddress: req.FullAddress,
+		DropoffLat:     req.Latitude,
+		DropoffLng:     req.Longitude,
+		DropoffContact: req.Name,
+		DropoffPhone:   req.Phone,
+		PackageDesc:    truncate(itemsSummary(quote.Items), 255),
+		Notes:          req.Notes,
+		IdempotencyKey: req.IdempotencyKey,
+		OutletID:       &quote.OutletID,
+		AwaitPayment:   true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if order.MidtransOrderID != "" {
+		// A concurrent retry placed the order first
+		return u.resume(tenant, order, req)
+	}
+
+	if err := u.place(order, quote); err != nil {
+		return nil, err
+	}
+	if err := u.charge(order, req); err != nil {
+		return nil, err
+	}
+	return checkoutResult(order, *quote), nil
+}
+
+// place records the priced sale on an order and reserves its stock while the customer
+// pays. An order the outlet cannot fill is cancelled.
+func (u *StorefrontUsecase) place(order *domain.DeliveryOrder, quote *domain.StoreQuote) error {
+	if err := setOrderSale(order, quote); err != nil {
+		return err
+	}
+	if err := u.deliveryRepo.UpdateOrder(order); err != nil {
+		return fmt.Errorf("failed to update delivery order: %w", err)
+	}
+	err := u.pos.ReserveOnlineOrder(order, time.Now().Add(storeReservationTTL))
+	if errors.Is(err, ErrOutOfStock) {
+		now := time.Now()
+		order.Status = domain.DeliveryStatusCancelled
+		order.CancelledAt = &now
+		order.CancelReason = err.Error()
+		if cancelErr := u.pos.CancelOnlineOrder(order, domain.DeliveryStatusWaitingPayment, nil); cancelErr != nil {
+			log.Printf("⚠️ Failed to cancel delivery order %s: %v", order.OrderNumber, cancelErr)
+		}
+	}
+	return err
+}
+
+// resume returns an order placed by an earlier attempt of the same checkout, creating its
+// payment if that attempt did not get that far
+func (u *StorefrontUsecase) resume(tenant *domain.Tenant, order *domain.DeliveryOrder, req domain.StoreCheckoutRequest) (*domain.StoreCheckoutResult, error) {
+	quote := domain.StoreQuote{
+		DeliveryQuote: domain.DeliveryQuote{
+			DistanceKm:    order.DistanceKm,
+			DeliveryFee:   order.DeliveryFee,
+			EstimatedTime: order.EstimatedTime,
+		},
+		TotalAmount: order.TotalAmount,
+	}
+	if order.OutletID != nil {
+		quote.OutletID = *order.OutletID
+	}
+	if sale, err := orderSale(order); err == nil && sale != nil {
+		quote.Items = sale.Items
+		quote.Subtotal = sale.Subtotal
+		quote.DiscountAmount = sale.DiscountAmount
+		quote.TaxAmount = sale.TaxAmount
+		quote.ServiceChargeAmount = sale.ServiceChargeAmount
+	}
+	if order.MidtransOrderID == "" {
+		// The earlier attempt stopped before the order was priced; price it now
+		priced, _, err := u.price(tenant, req)
+		if err != nil {
+			return nil, err
+		}
+		quote = *priced
+		if err := u.place(order, &quote); err != nil {
+			return nil, err
+		}
+	}
+	if order.Status == domain.DeliveryStatusWaitingPayment && order.PaymentToken == "" {
+		if err := u.charge(order, req); err != nil {
+			return nil, err
+		}
+	}
+	return checkoutResult(order, quote), nil
+}
+
+// charge creates the gateway payment of an order for exactly its total
+func (u *StorefrontUsecase) charge(order *domain.DeliveryOrder, req domain.StoreCheckoutRequest) error {
+	charge, err := u.payments.CreateCharge(&order.TenantID, domain.ChargeRequest{
+		OrderID:       order.MidtransOrderID,
+		Amount:        order.TotalAmount,
+		CustomerName:  req.Name,
+		Email:         storeCustomerEmail(req.Phone),
+		Phone:         req.Phone,
+		ExpiryMinutes: int(storeReservationTTL / time.Minute),
+	})
+	if err != nil {
+		// The order keeps waiting; retrying the checkout tries the payment again
+		return err
+	}
+	order.PaymentProvider = charge.Provider
+	order.PaymentToken = charge.Token
+	order.PaymentURL = charge.RedirectURL
+	if err := u.deliveryRepo.UpdateOrder(order); err != nil {
+		log.Printf("⚠️ Failed to save payment of delivery order %s: %v", order.OrderNumber, err)
+	}
+	return nil
+}
+
+// price prices the items at the outlet the order is fulfilled from and adds the delivery fee
+func (u *StorefrontUsecase) price(tenant *domain.Tenant, req domain.StoreCheckoutRequest) (*domain.StoreQuote, storeSettings, error) {
+	var settings storeSettings
+	_ = json.Unmarshal(tenant.Settings, &settings)
+	if settings.Address == "" {
+		settings.Address = tenant.Name
+	}
+	if len(req.Items) == 0 {
+		return nil, settings, errors.New("order has no items")
+	}
+	if len(req.Items) > maxStoreOrderItems {
+		return nil, settings, fmt.Errorf("an order has at most %d items", maxStoreOrderItems)
+	}
+	for _, item := range req.Items {
+		if item.Quantity != float64(int64(item.Quantity)) {
+			return nil, settings, errors.New("quantity must be a whole number")
+		}
+		if item.SeatNumber != 0 {
+			return nil, settings, errors.New("seat numbers are for dine-in orders")
+		}
+	}
+
+	outlet, err := u.outlet(tenant.ID, req.OutletID)
+	if err != nil {
+		return nil, settings, err
+	}
+	tx, err := u.pos.priceOrder(tenant.ID, outlet, req.Items, nil, nil)
+	if err != nil {
+		return nil, settings, err
+	}
+	delivery := u.deliveries.Quote(settings.Latitude, settings.Longitude, req.Latitude, req.Longitude)
+	tx.OrderType = domain.OrderTypeDelivery
+	tx.Channel = domain.ChannelOnline
+	tx.DeliveryFee = delivery.DeliveryFee
+	tx.TotalAmount += delivery.DeliveryFee
+	return &domain.StoreQuote{
+		OutletID:            outlet.ID,
+		Items:               tx.Items,
+		Subtotal:            tx.Subtotal,
+		DiscountAmount:      tx.DiscountAmount,
+		TaxAmount:           tx.TaxAmount,
+		ServiceChargeAmount: tx.ServiceChargeAmount,
+		DeliveryQuote:       delivery,
+		TotalAmount:         tx.TotalAmount,
+		Sale:                tx,
+	}, settings, nil
+}
+
+// outlet returns the outlet an order is fulfilled from: the chosen one, else the first
+// active outlet of the tenant
+func (u *StorefrontUsecase) outlet(tenantID uuid.UUID, outletID *uuid.UUID) (*domain.Outlet, error) {
+	if outletID != nil {
+		outlet, err := u.outletRepo.FindByID(*outletID)
+		if err != nil || outlet.TenantID != tenantID || outlet.Status != domain.OutletStatusActive {
+			return nil, errors.New("outlet not found")
+		}
+		return outlet, nil
+	}
+	outlets, err := u.outletRepo.FindByTenantID(tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load outlets: %w", err)
+	}
+	for i := range outlets {
+		if outlets[i].Status == domain.OutletStatusActive {
+			return &outlets[i], nil
+		}
+	}
+	return nil, errors.New("store has no open outlet")
+}
+
+func (u *StorefrontUsecase) store(slug string) (*domain.Tenant, error) {
+	tenant, err := u.tenantRepo.FindBySlug(slug)
+	if err != nil || !tenant.IsEnabled {
+		return nil, ErrStoreNotFound
+	}
+	return tenant, nil
+}
+
+// setOrderSale prices a storefront order: it gets its gateway order ID, its total and
+// the sale booked once it is paid
+func setOrderSale(order *domain.DeliveryOrder, quote *domain.StoreQuote) error {
+	sale, err := json.Marshal(quote.Sale)
+	if err != nil {
+		return fmt.Errorf("failed to encode sale: %w", err)
+	}
+	order.MidtransOrderID = storeGatewayOrderID(order)
+	order.ItemsSummary = itemsSummary(quote.Items)
+	order.TotalAmount = quote.TotalAmount
+	order.Sale = domain.JSON(sale)
+	return nil
+}
+
+// orderSale decodes the sale of a storefront order; orders placed before sales were
+// recorded have none
+func orderSale(order *domain.DeliveryOrder) (*domain.Transaction, error) {
+	if len(order.Sale) == 0 || string(order.Sale) == "{}" || string(order.Sale) == "null" {
+		return nil, nil
+	}
+	var sale domain.Transaction
+	if err := json.Unmarshal(order.Sale, &sale); err != nil {
+		return nil, fmt.Errorf("failed to decode sale of delivery order %s: %w", order.OrderNumber, err)
+	}
+	return &sale, nil
+}
+
+func checkoutResult(order *domain.DeliveryOrder, quote domain.StoreQuote) *domain.StoreCheckoutResult {
+	result := &domain.StoreCheckoutResult{
+		StoreQuote:      quote,
+		TenantID:        order.TenantID,
+		DeliveryOrderID: order.ID,
+		OrderNumber:     order.OrderNumber,
+		Status:          order.Status,
+		Message:         "Pesanan dibuat. Selesaikan pembayaran agar pesanan diproses.",
+	}
+	if order.CustomerID != nil {
+		result.CustomerID = *order.CustomerID
+	}
+	switch {
+	case order.Status == domain.DeliveryStatusWaitingPayment && order.PaymentToken != "":
+		result.Payment = &domain.Charge{
+			Provider:    order.PaymentProvider,
+			OrderID:     order.MidtransOrderID,
+			Token:       order.PaymentToken,
+			RedirectURL: order.PaymentURL,
+		}
+	case order.Status == domain.DeliveryStatusCancelled:
+		result.Message = "Pesanan dibatalkan."
+	case order.Status != domain.DeliveryStatusWaitingPayment:
+		result.Message = "Pembayaran diterima. Pesanan sedang diproses."
+	}
+	return result
+}
+
+// storeGatewayOrderID is the gateway order ID of a storefront order; it is unique across
+// tenants and within the 50 characters gateways accept
+func storeGatewayOrderID(order *domain.DeliveryOrder) string {
+	return "ORD-" + strings.ReplaceAll(order.ID.String(), "-", "")
+}
+
+// storeCustomerEmail is the address given to the gateway for customers who only leave a phone number
+func storeCustomerEmail(phone string) string {
+	return phone + "@customer.codapos.com"
+}
+
+// itemsSummary describes the items of an order on one line, e.g. "2x Kopi Susu (Large), 1x Roti"
+func itemsSummary(items []domain.TransactionItem) string {
+	parts := make([]string, 0, len(items))
+	for _, item := range items {
+		name := item.ProductName
+		if item.VariantName != "" {
+			name += " (" + item.VariantName + ")"
+		}
+		parts = append(parts, strconv.FormatFloat(item.Quantity, 'f', -1, 64)+"x "+name)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func truncate(s string, max int) string {
+	if len([]rune(s)) <= max {
+		return s
+	}
+	return string([]rune(s)[:max-1]) + "…"
+}
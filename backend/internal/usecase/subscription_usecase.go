@@ -154,7 +154,7 @@ func (u *SubscriptionUsecase) enableFeaturesForPlan(tenantID uuid.UUID, planSlug
 
 // createInvoice generates a billing invoice (placeholder for payment integration)
 func (u *SubscriptionUsecase) createInvoice(tenantID uuid.UUID, subID uuid.UUID, plan *domain.SubscriptionPlan, billingCycle string) {
-	var amount float64
+	var amount domain.Money
 	if billingCycle == "yearly" && plan.PriceYearly != nil {
 		amount = *plan.PriceYearly
 	} else {
@@ -0,0 +1,252 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/codapos/backend/internal/domain"
+	"github.com/google/uuid"
+)
+
+type TableUsecase struct {
+	tableRepo       domain.TableRepository
+	transactionRepo domain.TransactionRepository
+	outletRepo      domain.OutletRepository
+	uow             domain.UnitOfWork
+	kitchen         *KitchenUsecase
+}
+
+func NewTableUsecase(tbr domain.TableRepository, tr domain.TransactionRepository, or domain.OutletRepository, uow domain.UnitOfWork, kitchen *KitchenUsecase) *TableUsecase {
+	return &TableUsecase{tableRepo: tbr, transactionRepo: tr, outletRepo: or, uow: uow, kitchen: kitchen}
+}
+
+// GetFloorPlan returns the floors of an outlet with their tables and current status
+func (u *TableUsecase) GetFloorPlan(tenantID, outletID uuid.UUID) ([]domain.Floor, error) {
+	if err := u.checkOutlet(tenantID, outletID); err != nil {
+		return nil, err
+	}
+	if err := sweepTables(u.uow, u.kitchen, u.tableRepo, tenantID); err != nil {
+		return nil, err
+	}
+	return u.tableRepo.FindFloorsByOutlet(outletID)
+}
+
+// CreateFloor adds a floor to an outlet's floor plan
+func (u *TableUsecase) CreateFloor(tenantID uuid.UUID, floor *domain.Floor) error {
+	if err := u.checkOutlet(tenantID, floor.OutletID); err != nil {
+		return err
+	}
+	floor.Name = strings.TrimSpace(floor.Name)
+	if floor.Name == "" {
+		return errors.New("floor name is required")
+	}
+	floor.ID = uuid.Nil
+	floor.TenantID = tenantID
+	floor.Tables = nil
+	return u.tableRepo.CreateFloor(floor)
+}
+
+// UpdateFloor renames or reorders a floor
+func (u *TableUsecase) UpdateFloor(tenantID, id uuid.UUID, req *domain.Floor) (*domain.Floor, error) {
+	floor, err := u.floor(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, errors.New("floor name is required")
+	}
+	floor.Name = name
+	floor.SortOrder = req.SortOrder
+	if err := u.tableRepo.UpdateFloor(floor); err != nil {
+		return nil, fmt.Errorf("failed to update floor: %w", err)
+	}
+	return floor, nil
+}
+
+// DeleteFloor removes an empty floor
+func (u *TableUsecase) DeleteFloor(tenantID, id uuid.UUID) error {
+	floor, err := u.floor(tenantID, id)
+	if err != nil {
+		return err
+	}
+	count, err := u.tableRepo.CountByFloor(floor.ID)
+	if err != nil {
+		return fmt.Errorf("failed to count tables: %w", err)
+	}
+	if count > 0 {
+		return errors.New("floor still has tables, move or delete them first")
+	}
+	return u.tableRepo.DeleteFloor(floor.ID)
+}
+
+// GetTables returns the tables of an outlet, optionally with one status
+func (u *TableUsecase) GetTables(tenantID, outletID uuid.UUID, status string) ([]domain.DiningTable, error) {
+	if err := u.checkOutlet(tenantID, outletID); err != nil {
+		return nil, err
+	}
+	if err := sweepTables(u.uow, u.kitchen, u.tableRepo, tenantID); err != nil {
+		return nil, err
+	}
+	return u.tableRepo.FindByOutlet(outletID, status)
+}
+
+// GetTable returns a single table with its open order
+func (u *TableUsecase) GetTable(tenantID, id uuid.UUID) (*domain.DiningTable, error) {
+	if err := sweepTables(u.uow, u.kitchen, u.tableRepo, tenantID); err != nil {
+		return nil, err
+	}
+	table, err := u.tableRepo.FindByID(id)
+	if err != nil || table.TenantID != tenantID {
+		return nil, errors.New("table not found")
+	}
+	return table, nil
+}
+
+// CreateTable places a new table on a floor
+func (u *TableUsecase) CreateTable(tenantID uuid.UUID, table *domain.DiningTable) error {
+	floor, err := u.floor(tenantID, table.FloorID)
+	if err != nil {
+		return err
+	}
+	if err := validateTable(table); err != nil {
+		return err
+	}
+	table.ID = uuid.Nil
+	table.TenantID = tenantID
+	table.OutletID = floor.OutletID
+	table.IsActive = true
+	table.Status = domain.TableStatusFree
+	table.TransactionID = nil
+	table.OccupiedAt = nil
+	table.Transaction = nil
+	return u.tableRepo.Create(table)
+}
+
+// UpdateTable changes a table's layout; its status is changed through the POS
+func (u *TableUsecase) UpdateTable(tenantID, id uuid.UUID, req *domain.DiningTable) (*domain.DiningTable, error) {
+	table, err := u.GetTable(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if req.FloorID != uuid.Nil && req.FloorID != table.FloorID {
+		floor, err := u.floor(tenantID, req.FloorID)
+		if err != nil {
+			return nil, err
+		}
+		if floor.OutletID != table.OutletID {
+			return nil, errors.New("table cannot move to a floor of another outlet")
+		}
+		table.FloorID = floor.ID
+	}
+	if err := validateTable(req); err != nil {
+		return nil, err
+	}
+	if !req.IsActive && table.Status != domain.TableStatusFree {
+		return nil, errors.New("table is in use and cannot be deactivated")
+	}
+	table.Name = req.Name
+	table.Capacity = req.Capacity
+	table.Shape = req.Shape
+	table.PosX = req.PosX
+	table.PosY = req.PosY
+	table.Width = req.Width
+	table.Height = req.Height
+	table.IsActive = req.IsActive
+	if err := u.tableRepo.Update(table); err != nil {
+		return nil, fmt.Errorf("failed to update table: %w", err)
+	}
+	return table, nil
+}
+
+// DeleteTable removes a free table
+func (u *TableUsecase) DeleteTable(tenantID, id uuid.UUID) error {
+	table, err := u.GetTable(tenantID, id)
+	if err != nil {
+		return err
+	}
+	if table.Status != domain.TableStatusFree {
+		return errors.New("table is in use and cannot be deleted")
+	}
+	return u.tableRepo.Delete(table.ID)
+}
+
+// SetTableStatus changes a table's status by hand: seating walk-in guests before they
+// order, presenting the bill, or freeing a table whose guests left without an open order
+func (u *TableUsecase) SetTableStatus(tenantID, id uuid.UUID, req domain.TableStatusRequest) (*domain.DiningTable, error) {
+	table, err := u.GetTable(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	switch req.Status {
+	case domain.TableStatusFree:
+		if table.TransactionID != nil {
+			return nil, errors.New("table has an open order, settle or move it first")
+		}
+		table.OccupiedAt = nil
+	case domain.TableStatusOccupied:
+		if !table.IsActive {
+			return nil, errors.New("table is not in use")
+		}
+		if table.OccupiedAt == nil {
+			now := time.Now()
+			table.OccupiedAt = &now
+		}
+	case domain.TableStatusBilling:
+		if table.TransactionID == nil {
+			return nil, errors.New("table has no open order to bill")
+		}
+	default:
+		return nil, fmt.Errorf("unknown table status: %s", req.Status)
+	}
+	table.Status = req.Status
+	if err := u.tableRepo.Update(table); err != nil {
+		return nil, fmt.Errorf("failed to update table status: %w", err)
+	}
+	return table, nil
+}
+
+func (u *TableUsecase) checkOutlet(tenantID, outletID uuid.UUID) error {
+	outlet, err := u.outletRepo.FindByID(outletID)
+	if err != nil || outlet.TenantID != tenantID {
+		return errors.New("outlet not found")
+	}
+	return nil
+}
+
+func (u *TableUsecase) floor(tenantID, id uuid.UUID) (*domain.Floor, error) {
+	floor, err := u.tableRepo.FindFloorByID(id)
+	if err != nil || floor.TenantID != tenantID {
+		return nil, errors.New("floor not found")
+	}
+	return floor, nil
+}
+
+func validateTable(table *domain.DiningTable) error {
+	table.Name = strings.TrimSpace(table.Name)
+	if table.Name == "" {
+		return errors.New("table name is required")
+	}
+	if table.Capacity < 1 {
+		return errors.New("capacity must be at least 1")
+	}
+	switch table.Shape {
+	case "":
+		table.Shape = domain.TableShapeSquare
+	case domain.TableShapeSquare, domain.TableShapeRound:
+	default:
+		return fmt.Errorf("unknown table shape: %s", table.Shape)
+	}
+	if table.PosX < 0 || table.PosY < 0 {
+		return errors.New("table position cannot be negative")
+	}
+	if table.Width < 1 {
+		table.Width = 1
+	}
+	if table.Height < 1 {
+		table.Height = 1
+	}
+	return nil
+}
@@ -0,0 +1,397 @@
+package usecase
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/codapos/backend/internal/domain"
+	"github.com/google/uuid"
+)
+
+type TaxUsecase struct {
+	taxRateRepo    domain.TaxRateRepository
+	accountingRepo domain.AccountingRepository
+	outletRepo     domain.OutletRepository
+}
+
+func NewTaxUsecase(tr domain.TaxRateRepository, ar domain.AccountingRepository, or domain.OutletRepository) *TaxUsecase {
+	return &TaxUsecase{taxRateRepo: tr, accountingRepo: ar, outletRepo: or}
+}
+
+// GetTaxRates returns the tax rates of a tenant
+func (u *TaxUsecase) GetTaxRates(tenantID uuid.UUID, activeOnly bool) ([]domain.TaxRate, error) {
+	return u.taxRateRepo.FindByTenantID(tenantID, activeOnly)
+}
+
+// GetTaxRate returns a single tax rate owned by the tenant
+func (u *TaxUsecase) GetTaxRate(tenantID, id uuid.UUID) (*domain.TaxRate, error) {
+	rate, err := u.taxRateRepo.FindByID(id)
+	if err != nil || rate.TenantID != tenantID {
+		return nil, errors.New("tax rate not found")
+	}
+	return rate, nil
+}
+
+// CreateTaxRate validates and stores a tax rate. Without an AccountID a liability
+// account of its own is opened under the tax payable account.
+func (u *TaxUsecase) CreateTaxRate(tenantID uuid.UUID, rate *domain.TaxRate) error {
+	rate.ID = uuid.Nil
+	rate.TenantID = tenantID
+	if err := u.validateTaxRate(rate); err != nil {
+		return err
+	}
+	if rate.AccountID == nil {
+		account, err := u.openTaxAccount(tenantID, rate.Name)
+		if err != nil {
+			return err
+		}
+		if account != nil {
+			rate.AccountID = &account.ID
+		}
+	}
+	if err := u.taxRateRepo.Create(rate); err != nil {
+		return fmt.Errorf("failed to create tax rate: %w", err)
+	}
+	return nil
+}
+
+// UpdateTaxRate replaces the editable fields of a tax rate. Past transactions keep
+// the tax they were charged.
+func (u *TaxUsecase) UpdateTaxRate(tenantID, id uuid.UUID, req *domain.TaxRate) (*domain.TaxRate, error) {
+	rate, err := u.GetTaxRate(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	rate.Name = req.Name
+	rate.Rate = req.Rate
+	rate.Type = req.Type
+	rate.IsInclusive = req.IsInclusive
+	rate.IsCompound = req.IsCompound
+	rate.SortOrder = req.SortOrder
+	rate.IsDefault = req.IsDefault
+	rate.IsActive = req.IsActive
+	if req.AccountID != nil {
+		rate.AccountID = req.AccountID
+	}
+
+	if err := u.validateTaxRate(rate); err != nil {
+		return nil, err
+	}
+	if err := u.taxRateRepo.Update(rate); err != nil {
+		return nil, fmt.Errorf("failed to update tax rate: %w", err)
+	}
+	return u.taxRateRepo.FindByID(rate.ID)
+}
+
+// DeleteTaxRate removes a tax rate and its outlet assignments
+func (u *TaxUsecase) DeleteTaxRate(tenantID, id uuid.UUID) error {
+	if _, err := u.GetTaxRate(tenantID, id); err != nil {
+		return err
+	}
+	return u.taxRateRepo.Delete(id)
+}
+
+// GetOutletTaxRates returns the rates charged at an outlet
+func (u *TaxUsecase) GetOutletTaxRates(tenantID, outletID uuid.UUID) ([]domain.TaxRate, error) {
+	outlet, err := u.outletRepo.FindByID(outletID)
+	if err != nil || outlet.TenantID != tenantID {
+		return nil, errors.New("outlet not found")
+	}
+	return u.RatesForOutlet(tenantID, outletID)
+}
+
+// SetOutletTaxRates chooses the rates of an outlet; an empty list restores the defaults
+func (u *TaxUsecase) SetOutletTaxRates(tenantID, outletID uuid.UUID, req domain.OutletTaxRatesRequest) ([]domain.TaxRate, error) {
+	outlet, err := u.outletRepo.FindByID(outletID)
+	if err != nil || outlet.TenantID != tenantID {
+		return nil, errors.New("outlet not found")
+	}
+	seen := make(map[uuid.UUID]bool, len(req.TaxRateIDs))
+	var ids []uuid.UUID
+	for _, id := range req.TaxRateIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if _, err := u.GetTaxRate(tenantID, id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err := u.taxRateRepo.SetOutletTaxRates(outletID, ids); err != nil {
+		return nil, fmt.Errorf("failed to set outlet tax rates: %w", err)
+	}
+	return u.RatesForOutlet(tenantID, outletID)
+}
+
+// RatesForOutlet returns the active rates charged at an outlet: its own rates when
+// it has any, otherwise the tenant's default rates
+func (u *TaxUsecase) RatesForOutlet(tenantID, outletID uuid.UUID) ([]domain.TaxRate, error) {
+	assigned, err := u.taxRateRepo.FindByOutletID(outletID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load outlet tax rates: %w", err)
+	}
+	candidates := assigned
+	if len(assigned) == 0 {
+		if candidates, err = u.taxRateRepo.FindByTenantID(tenantID, true); err != nil {
+			return nil, fmt.Errorf("failed to load tax rates: %w", err)
+		}
+	}
+
+	var rates []domain.TaxRate
+	for _, rate := range candidates {
+		if rate.IsActive && (len(assigned) > 0 || rate.IsDefault) {
+			rates = append(rates, rate)
+		}
+	}
+	return rates, nil
+}
+
+func (u *TaxUsecase) validateTaxRate(rate *domain.TaxRate) error {
+	rate.Name = strings.TrimSpace(rate.Name)
+	if rate.Name == "" {
+		return errors.New("tax name is required")
+	}
+	if rate.Rate <= 0 || rate.Rate > 100 {
+		return errors.New("tax rate must be between 0 and 100")
+	}
+	switch rate.Type {
+	case "":
+		rate.Type = domain.TaxTypePPN
+	case domain.TaxTypePPN, domain.TaxTypePB1, domain.TaxTypeOther:
+	default:
+		return fmt.Errorf("unknown tax type: %s", rate.Type)
+	}
+	if rate.IsInclusive && rate.IsCompound {
+		return errors.New("an inclusive tax cannot be compound")
+	}
+	if rate.AccountID != nil {
+		account, err := u.accountingRepo.FindAccountByID(*rate.AccountID)
+		if err != nil || account.TenantID != rate.TenantID {
+			return errors.New("tax account not found")
+		}
+		if account.Type != domain.AccountTypeLiability {
+			return errors.New("tax account must be a liability account")
+		}
+	}
+	return nil
+}
+
+// openTaxAccount adds a liability account for one tax under the tax payable
+// account (2200 → 2210, 2220, ...). Tenants without a tax payable account get none
+// and their taxes are posted to the generic tax account, if any.
+func (u *TaxUsecase) openTaxAccount(tenantID uuid.UUID, taxName string) (*domain.ChartOfAccount, error) {
+	accounts, err := u.accountingRepo.FindAccountsByTenantID(tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chart of accounts: %w", err)
+	}
+	var parent *domain.ChartOfAccount
+	used := make(map[string]bool, len(accounts))
+	for i := range accounts {
+		used[accounts[i].Code] = true
+		if parent == nil && accounts[i].SubType == domain.AccountSubTypeTax && accounts[i].ParentID == nil {
+			parent = &accounts[i]
+		}
+	}
+	if parent == nil {
+		return nil, nil
+	}
+	base, err := strconv.Atoi(parent.Code)
+	if err != nil {
+		return nil, nil
+	}
+	for next := base + 10; next < base+100; next += 10 {
+		code := strconv.Itoa(next)
+		if used[code] {
+			continue
+		}
+		account := &domain.ChartOfAccount{
+			TenantID: tenantID,
+			ParentID: &parent.ID,
+			Code:     code,
+			Name:     parent.Name + " - " + taxName,
+			Type:     domain.AccountTypeLiability,
+			SubType:  domain.AccountSubTypeTax,
+			IsActive: true,
+		}
+		if err := u.accountingRepo.CreateAccount(account); err != nil {
+			return nil, fmt.Errorf("failed to create tax account: %w", err)
+		}
+		return account, nil
+	}
+	return nil, nil
+}
+
+// applyTaxes charges the outlet's tax rates on every item, after discounts. Without
+// outlet rates the legacy per-product rate is charged on top of the price, as before
+// the tax engine.
+func applyTaxes(items []domain.TransactionItem, rates []domain.TaxRate, productRates []float64) {
+	for i := range items {
+		gross := items[i].Subtotal - items[i].DiscountAmount
+
+		var taxes []domain.ItemTax
+		switch {
+		case items[i].IsGiftCard:
+			// Stored value is taxed when it is spent on goods, not when it is sold
+		case len(rates) > 0:
+			taxes = chargeTaxes(gross, rates)
+		case productRates[i] > 0:
+			taxes = []domain.ItemTax{{
+				Name:          fmt.Sprintf("Pajak %g%%", productRates[i]),
+				Type:          domain.TaxTypeOther,
+				Rate:          productRates[i],
+				TaxableAmount: gross,
+				Amount:        gross.Percent(productRates[i]),
+			}}
+		}
+		setItemTaxes(&items[i], taxes)
+	}
+}
+
+// chargeTaxes computes the taxes of one line. Inclusive taxes are carved out of the
+// gross amount, the last one absorbing the rounding; exclusive taxes are added on
+// the net base, or on the base plus the earlier taxes when compound.
+func chargeTaxes(gross domain.Money, rates []domain.TaxRate) []domain.ItemTax {
+	var inclusiveRate float64
+	lastInclusive := -1
+	for i, rate := range rates {
+		if rate.IsInclusive {
+			inclusiveRate += rate.Rate
+			lastInclusive = i
+		}
+	}
+	base := gross
+	if inclusiveRate > 0 {
+		base = gross.Mul(100 / (100 + inclusiveRate))
+	}
+
+	taxes := make([]domain.ItemTax, len(rates))
+	charged := base
+	for i, rate := range rates {
+		id := rate.ID
+		taxes[i] = domain.ItemTax{
+			TaxRateID:     &id,
+			Name:          rate.Name,
+			Type:          rate.Type,
+			Rate:          rate.Rate,
+			IsInclusive:   rate.IsInclusive,
+			AccountID:     rate.AccountID,
+			TaxableAmount: base,
+		}
+		if rate.IsInclusive {
+			if i == lastInclusive {
+				taxes[i].Amount = gross - charged
+			} else {
+				taxes[i].Amount = base.Percent(rate.Rate)
+			}
+			charged += taxes[i].Amount
+		}
+	}
+
+	// Exclusive taxes in order; compound ones include every tax charged before them
+	running := gross
+	for i, rate := range rates {
+		if rate.IsInclusive {
+			continue
+		}
+		if rate.IsCompound {
+			taxes[i].TaxableAmount = running
+		}
+		taxes[i].Amount = taxes[i].TaxableAmount.Percent(rate.Rate)
+		running += taxes[i].Amount
+	}
+	return taxes
+}
+
+// refundTaxes scales the taxes charged on an amount (gross, with tax in total) down
+// to the refunded part of it and negates them; the last tax absorbs the rounding so
+// they sum to -refundTax
+func refundTaxes(raw domain.JSON, gross, refundGross, tax, refundTax domain.Money) []domain.ItemTax {
+	var taxes []domain.ItemTax
+	_ = json.Unmarshal(raw, &taxes)
+	if len(taxes) == 0 {
+		return nil
+	}
+	var assigned domain.Money
+	for i := range taxes {
+		amount := taxes[i].Amount.MulRatio(refundTax, tax)
+		if i == len(taxes)-1 {
+			amount = refundTax - assigned
+		}
+		assigned += amount
+		taxes[i].Amount = -amount
+		taxes[i].TaxableAmount = -taxes[i].TaxableAmount.MulRatio(refundGross, gross)
+	}
+	return taxes
+}
+
+// serviceChargeTaxes charges the outlet's rates on a service charge. The charge is
+// computed on net sales, so every rate is added on top of it.
+func serviceChargeTaxes(amount domain.Money, rates []domain.TaxRate) []domain.ItemTax {
+	exclusive := make([]domain.TaxRate, len(rates))
+	for i, rate := range rates {
+		rate.IsInclusive = false
+		exclusive[i] = rate
+	}
+	return chargeTaxes(amount, exclusive)
+}
+
+// setItemTaxes stores the taxes of an item with their totals
+func setItemTaxes(item *domain.TransactionItem, taxes []domain.ItemTax) {
+	item.TaxAmount, item.TaxIncluded = 0, 0
+	for _, t := range taxes {
+		item.TaxAmount += t.Amount
+		if t.IsInclusive {
+			item.TaxIncluded += t.Amount
+		}
+	}
+	if taxes == nil {
+		taxes = []domain.ItemTax{}
+	}
+	raw, _ := json.Marshal(taxes)
+	item.Taxes = domain.JSON(raw)
+}
+
+// summarizeTaxes adds up the taxes of every item and of the service charge, one row
+// per tax in the order they first appear
+func summarizeTaxes(items []domain.TransactionItem, serviceCharge []domain.ItemTax) []domain.TransactionTax {
+	var summary []domain.TransactionTax
+	index := make(map[string]int)
+	lines := make([][]domain.ItemTax, 0, len(items)+1)
+	for _, item := range items {
+		var taxes []domain.ItemTax
+		_ = json.Unmarshal(item.Taxes, &taxes)
+		lines = append(lines, taxes)
+	}
+	lines = append(lines, serviceCharge)
+
+	for _, taxes := range lines {
+		for _, t := range taxes {
+			key := fmt.Sprintf("%g|%s", t.Rate, t.Name)
+			if t.TaxRateID != nil {
+				key = t.TaxRateID.String()
+			}
+			key += fmt.Sprintf("|%t", t.IsInclusive)
+			i, ok := index[key]
+			if !ok {
+				i = len(summary)
+				index[key] = i
+				summary = append(summary, domain.TransactionTax{
+					TaxRateID:   t.TaxRateID,
+					Name:        t.Name,
+					Type:        t.Type,
+					Rate:        t.Rate,
+					IsInclusive: t.IsInclusive,
+					AccountID:   t.AccountID,
+				})
+			}
+			summary[i].TaxableAmount += t.TaxableAmount
+			summary[i].Amount += t.Amount
+		}
+	}
+	return summary
+}
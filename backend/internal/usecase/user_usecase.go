@@ -2,6 +2,7 @@ package usecase
 
 import (
 	"errors"
+	"strings"
 
 	"github.com/codapos/backend/internal/domain"
 	"github.com/google/uuid"
@@ -190,6 +191,30 @@ func (u *UserUsecase) ChangePassword(userID uuid.UUID, req domain.ChangePassword
 	return u.userRepo.Update(user)
 }
 
+// SetPIN sets the user's manager override PIN after re-checking their password
+func (u *UserUsecase) SetPIN(userID uuid.UUID, req domain.SetPINRequest) error {
+	if len(req.PIN) < 4 || len(req.PIN) > 6 || strings.Trim(req.PIN, "0123456789") != "" {
+		return errors.New("PIN must be 4 to 6 digits")
+	}
+
+	user, err := u.userRepo.FindByID(userID)
+	if err != nil {
+		return errors.New("user not found")
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		return errors.New("password is incorrect")
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(req.PIN), bcrypt.DefaultCost)
+	if err != nil {
+		return errors.New("failed to hash PIN")
+	}
+
+	user.PinHash = string(hashed)
+	return u.userRepo.Update(user)
+}
+
 // UpdateMerchant updates the tenant/merchant info for the authenticated user
 func (u *UserUsecase) UpdateMerchant(tenantID uuid.UUID, req domain.UpdateMerchantRequest) (*domain.Tenant, error) {
 	tenant, err := u.tenantRepo.FindByID(tenantID)
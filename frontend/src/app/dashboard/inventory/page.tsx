@@ -251,6 +251,9 @@ export default function InventoryPage() {
                                                 <span className={`text-lg font-bold ${item.quantity <= 0 ? "text-red-400" : item.min_stock > 0 && item.quantity <= item.min_stock ? "text-yellow-400" : "text-white"}`}>
                                                     {item.quantity.toLocaleString("id-ID")}
                                                 </span>
+                                                {item.reserved > 0 && (
+                                                    <p className="text-[11px] text-white/40">{item.reserved.toLocaleString("id-ID")} dipesan online · sisa {item.available.toLocaleString("id-ID")}</p>
+                                                )}
                                             </td>
                                             <td className="text-right text-white/40 mobile-hide">
                                                 {item.min_stock > 0 ? item.min_stock.toLocaleString("id-ID") : "—"}
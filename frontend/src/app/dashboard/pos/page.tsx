@@ -2,7 +2,7 @@
 
 import { useState, useEffect, useCallback, useRef } from "react";
 import { useCartStore } from "@/store";
-import { Product } from "@/types";
+import { CartItem, Product, Transaction } from "@/types";
 import { productAPI, posAPI, outletAPI, paymentAPI, tenantAPI } from "@/lib/api";
 import { generateESCPOS, printReceiptInBrowser, type ReceiptData, type ReceiptTenant } from "@/lib/receipt";
 import { printerService } from "@/lib/bluetooth-printer";
@@ -34,8 +34,19 @@ declare global {
 
 const categories = ["Semua", "Makanan", "Minuman", "Snack"];
 
+const checkoutItems = (items: CartItem[]) => items.map((item) => ({
+    product_id: item.product.id,
+    variant_id: item.variant?.id,
+    quantity: item.quantity,
+    modifiers: item.modifiers.map((m) => ({ modifier_id: m.id })),
+    notes: item.notes,
+}));
+
+const apiError = (err: unknown, fallback: string) =>
+    (err as { response?: { data?: { error?: string } } })?.response?.data?.error || fallback;
+
 export default function POSPage() {
-    const { items, addItem, removeItem, updateQuantity, updateNote, clearCart, getSubtotal, getTax, getTotal, getItemCount } = useCartStore();
+    const { items, addItem, removeItem, updateQuantity, updateNote, clearCart, getItemCount } = useCartStore();
     const [products, setProducts] = useState<Product[]>([]);
     const [loading, setLoading] = useState(true);
     const [search, setSearch] = useState("");
@@ -52,21 +63,50 @@ export default function POSPage() {
     const snapLoaded = useRef(false);
     const midtransConfig = useRef<{ clientKey: string; mode: string }>({ clientKey: "", mode: "sandbox" });
     const [mobileCartOpen, setMobileCartOpen] = useState(false);
+    const [quote, setQuote] = useState<Transaction | null>(null);
+    const [quoting, setQuoting] = useState(false);
 
     const fetchProducts = useCallback(async () => {
         try {
             setLoading(true);
-            const res = await productAPI.getAll(search || undefined);
+            const res = await productAPI.getAll(search || undefined, undefined, defaultOutletId || undefined);
             setProducts((res.data.data || []).filter((p: Product) => p.is_active));
         } catch {
             // silently fail
         } finally {
             setLoading(false);
         }
-    }, [search]);
+    }, [search, defaultOutletId]);
 
     useEffect(() => { fetchProducts(); }, [fetchProducts]);
 
+    // Prices, promotions, taxes and service charge are worked out by the server; the
+    // quoted total is what the payment must cover
+    const fetchQuote = useCallback(async (): Promise<Transaction> => {
+        const res = await posAPI.quote({ outlet_id: defaultOutletId, items: checkoutItems(items) });
+        return res.data.data;
+    }, [items, defaultOutletId]);
+
+    useEffect(() => {
+        if (items.length === 0 || !defaultOutletId) {
+            setQuote(null);
+            return;
+        }
+        let cancelled = false;
+        setQuoting(true);
+        const timer = setTimeout(async () => {
+            try {
+                const q = await fetchQuote();
+                if (!cancelled) { setQuote(q); setCheckoutError(""); }
+            } catch (err) {
+                if (!cancelled) { setQuote(null); setCheckoutError(apiError(err, "Gagal menghitung total. Coba lagi.")); }
+            } finally {
+                if (!cancelled) setQuoting(false);
+            }
+        }, 300);
+        return () => { cancelled = true; clearTimeout(timer); };
+    }, [fetchQuote, items.length, defaultOutletId]);
+
     // Load outlet + Midtrans config + tenant info on mount
     useEffect(() => {
         const init = async () => {
@@ -141,6 +181,8 @@ export default function POSPage() {
                 subtotal: tx.subtotal,
                 discount_amount: tx.discount_amount,
                 tax_amount: tx.tax_amount,
+                service_charge_amount: tx.service_charge_amount,
+                tip_amount: tx.tip_amount,
                 total_amount: tx.total_amount,
                 notes: tx.notes,
             };
@@ -184,6 +226,17 @@ export default function POSPage() {
 
         const methodLower = method.toLowerCase();
 
+        // Charge what the server prices the cart at right now, not what was last shown
+        let total: number;
+        try {
+            const q = await fetchQuote();
+            setQuote(q);
+            total = q.total_amount;
+        } catch (err) {
+            setCheckoutError(apiError(err, "Gagal menghitung total. Coba lagi."));
+            return;
+        }
+
         // For QRIS or Card, use Midtrans Snap
         if (methodLower === "qris" || methodLower === "card") {
             if (!midtransConfig.current.clientKey) {
@@ -193,9 +246,9 @@ export default function POSPage() {
             setProcessingPayment(true);
             try {
                 const orderId = `POS-${Date.now()}-${Math.random().toString(36).slice(2, 7)}`;
-                const snapRes = await paymentAPI.createSnap({
+                const snapRes = await paymentAPI.createCharge({
                     order_id: orderId,
-                    gross_amount: getTotal(),
+                    gross_amount: total,
                     first_name: "Pelanggan",
                     email: "pos@codapos.com",
                     phone: "08000000000",
@@ -227,16 +280,10 @@ export default function POSPage() {
                         try {
                             const checkoutData = {
                                 outlet_id: defaultOutletId,
-                                items: items.map((item) => ({
-                                    product_id: item.product.id,
-                                    variety_id: item.variant?.id,
-                                    quantity: item.quantity,
-                                    modifiers: item.modifiers,
-                                    notes: item.notes,
-                                })),
+                                items: checkoutItems(items),
                                 payments: [{
                                     payment_method: methodLower,
-                                    amount: getTotal(),
+                                    amount: total,
                                     reference_number: orderId,
                                 }],
                             };
@@ -280,16 +327,10 @@ export default function POSPage() {
         try {
             const checkoutData = {
                 outlet_id: defaultOutletId,
-                items: items.map((item) => ({
-                    product_id: item.product.id,
-                    variant_id: item.variant?.id,
-                    quantity: item.quantity,
-                    modifiers: item.modifiers,
-                    notes: item.notes,
-                })),
+                items: checkoutItems(items),
                 payments: [{
                     payment_method: methodLower,
-                    amount: getTotal(),
+                    amount: total,
                 }],
             };
 
@@ -305,8 +346,8 @@ export default function POSPage() {
                 setTxNumber("");
                 setLastTxId("");
             }, 8000);
-        } catch {
-            setCheckoutError("Gagal memproses pembayaran. Coba lagi.");
+        } catch (err) {
+            setCheckoutError(apiError(err, "Gagal memproses pembayaran. Coba lagi."));
         }
     };
 
@@ -370,6 +411,11 @@ export default function POSPage() {
                                 </div>
                                 <h3 className="text-sm font-semibold text-white truncate">{product.name}</h3>
                                 <p className="text-base font-bold text-[#C40000] mt-1">{formatCurrency(product.base_price)}</p>
+                                {product.available !== undefined && (
+                                    <p className={`text-[11px] mt-1 ${product.available > 0 ? "text-white/40" : "text-amber-400"}`}>
+                                        {product.available > 0 ? `Stok ${product.available}` : "Stok habis"}
+                                    </p>
+                                )}
                             </button>
                         ))}
                     </div>
@@ -484,16 +530,31 @@ export default function POSPage() {
                     <div className="p-5 pb-8 border-t border-white/5 space-y-3">
                         <div className="flex justify-between text-sm">
                             <span className="text-white/40">Subtotal</span>
-                            <span className="text-white">{formatCurrency(getSubtotal())}</span>
+                            <span className="text-white">{quote ? formatCurrency(quote.subtotal) : "…"}</span>
                         </div>
+                        {!!quote?.discount_amount && (
+                            <div className="flex justify-between text-sm">
+                                <span className="text-white/40">Diskon</span>
+                                <span className="text-white">-{formatCurrency(quote.discount_amount)}</span>
+                            </div>
+                        )}
+                        {!!quote?.service_charge_amount && (
+                            <div className="flex justify-between text-sm">
+                                <span className="text-white/40">Service Charge</span>
+                                <span className="text-white">{formatCurrency(quote.service_charge_amount)}</span>
+                            </div>
+                        )}
                         <div className="flex justify-between text-sm">
-                            <span className="text-white/40">PPN (11%)</span>
-                            <span className="text-white">{formatCurrency(getTax())}</span>
+                            <span className="text-white/40">Pajak</span>
+                            <span className="text-white">{quote ? formatCurrency(quote.tax_amount) : "…"}</span>
                         </div>
                         <div className="h-px bg-white/5" />
                         <div className="flex justify-between">
                             <span className="text-white font-semibold">Total</span>
-                            <span className="text-xl font-bold text-[#C40000]">{formatCurrency(getTotal())}</span>
+                            <span className="text-xl font-bold text-[#C40000]">
+                                {quoting && <Loader2 className="inline w-4 h-4 mr-2 animate-spin text-white/40" />}
+                                {quote ? formatCurrency(quote.total_amount) : "…"}
+                            </span>
                         </div>
 
                         {checkoutError && (
@@ -506,7 +567,8 @@ export default function POSPage() {
                         {!showPayment ? (
                             <button
                                 onClick={() => setShowPayment(true)}
-                                className="btn-primary w-full mt-3"
+                                disabled={!quote}
+                                className="btn-primary w-full mt-3 disabled:opacity-50"
                             >
                                 Bayar Sekarang
                             </button>
@@ -84,6 +84,8 @@ export default function TransactionsPage() {
                 subtotal: updatedTx.subtotal,
                 discount_amount: updatedTx.discount_amount,
                 tax_amount: updatedTx.tax_amount,
+                service_charge_amount: updatedTx.service_charge_amount,
+                tip_amount: updatedTx.tip_amount,
                 total_amount: updatedTx.total_amount,
                 notes: updatedTx.notes,
             };
@@ -248,7 +250,9 @@ export default function TransactionsPage() {
                             <div className="border-t border-white/10 pt-4 space-y-2 text-sm">
                                 <div className="flex justify-between text-white/60"><span>Subtotal (*Gross*)</span><span>{formatCurrency(selectedTx.subtotal)}</span></div>
                                 {selectedTx.discount_amount > 0 && <div className="flex justify-between text-red-400"><span>Diskon</span><span>-{formatCurrency(selectedTx.discount_amount)}</span></div>}
+                                {!!selectedTx.service_charge_amount && <div className="flex justify-between text-white/60"><span>Service Charge</span><span>{formatCurrency(selectedTx.service_charge_amount)}</span></div>}
                                 {selectedTx.tax_amount > 0 && <div className="flex justify-between text-white/60"><span>PPN</span><span>{formatCurrency(selectedTx.tax_amount)}</span></div>}
+                                {!!selectedTx.tip_amount && <div className="flex justify-between text-white/60"><span>Tip</span><span>{formatCurrency(selectedTx.tip_amount)}</span></div>}
 
                                 <div className="flex justify-between text-white font-bold text-base pt-2 border-t border-white/5">
                                     <span>Total Pembayaran (Customer)</span>
@@ -15,15 +15,20 @@ interface Tenant {
     merchant_type?: { name: string; slug: string; icon: string };
 }
 interface Category { id: string; name: string; slug?: string; icon?: string; }
-interface Variant { id: string; name: string; additional_price: number; }
+interface Variant { id: string; name: string; additional_price: number; available?: number; }
 interface Product {
     id: string; name: string; description?: string; image_url?: string;
     base_price: number; category_id?: string; category?: Category;
     variants?: Variant[];
+    available?: number; // left to sell, only for products that track stock
 }
 interface CartItem {
     product: Product; variant?: Variant; quantity: number; unitPrice: number;
 }
+interface StoreQuote {
+    subtotal: number; discount_amount: number; tax_amount: number; service_charge_amount: number;
+    delivery_fee: number; distance_km: number; estimated_time: number; total_amount: number;
+}
 interface BannerSlide {
     image_url: string; title?: string; link?: string;
 }
@@ -101,9 +106,11 @@ export default function StorefrontPage() {
     const [customerLng, setCustomerLng] = useState(0);
     const [customerNotes, setCustomerNotes] = useState("");
     const [paymentLoading, setPaymentLoading] = useState(false);
-    const [snapLoaded, setSnapLoaded] = useState(false);
     const [deliveryOrderNumber, setDeliveryOrderNumber] = useState("");
     const [deliveryOrderId, setDeliveryOrderId] = useState("");
+    const [quote, setQuote] = useState<StoreQuote | null>(null);
+    const [stockError, setStockError] = useState("");
+    const clientOrderIdRef = useRef("");
     const [mapLoaded, setMapLoaded] = useState(false);
     const mapRef = useRef<HTMLDivElement>(null);
     const leafletMapRef = useRef<unknown>(null);
@@ -141,13 +148,12 @@ export default function StorefrontPage() {
                 const { mode, client_key } = res.data.data;
                 if (!client_key || cancelled) return;
                 const existingScript = document.getElementById('midtrans-snap');
-                if (existingScript) { setSnapLoaded(true); return; }
+                if (existingScript) return;
                 const snapUrl = mode === 'production' ? 'https://app.midtrans.com/snap/snap.js' : 'https://app.sandbox.midtrans.com/snap/snap.js';
                 const script = document.createElement('script');
                 script.id = 'midtrans-snap'; script.src = snapUrl;
                 script.setAttribute('data-client-key', client_key);
-                script.onload = () => { if (!cancelled) setSnapLoaded(true); };
-                document.head.appendChild(script);
+                                document.head.appendChild(script);
             } catch (e) { console.error('Failed to load Midtrans Snap', e); }
         };
         loadSnap();
@@ -195,6 +201,11 @@ export default function StorefrontPage() {
     const cartTotal = useMemo(() => cart.reduce((s, i) => s + i.unitPrice * i.quantity, 0), [cart]);
     const cartCount = useMemo(() => cart.reduce((s, i) => s + i.quantity, 0), [cart]);
 
+    /* ── Stock: products that track stock carry what is left to sell ── */
+    const soldOut = (available?: number) => available !== undefined && available <= 0;
+    const baseAvailable = (product: Product) => product.available === undefined ? undefined
+        : product.available - (product.variants || []).reduce((s, v) => s + (v.available || 0), 0);
+
     const handleAddClick = (product: Product) => {
         if (product.variants && product.variants.length > 0) setShowVariantPicker(product);
         else addToCart(product);
@@ -273,64 +284,70 @@ export default function StorefrontPage() {
         }
     }, [checkoutStep]);
 
-    /* ── Checkout with Midtrans ── */
+    /* ── Server-side price of the cart ── */
+    const orderPayload = useMemo(() => ({
+        items: cart.map(i => ({ product_id: i.product.id, variant_id: i.variant?.id, quantity: i.quantity })),
+        latitude: customerLat, longitude: customerLng,
+    }), [cart, customerLat, customerLng]);
+    useEffect(() => {
+        if (checkoutStep !== 'form' || cart.length === 0) return;
+        let cancelled = false;
+        storeAPI.quote(slug, orderPayload)
+            .then(res => { if (!cancelled) { setQuote(res.data.data); setStockError(""); } })
+            .catch((err) => {
+                if (cancelled) return;
+                setQuote(null);
+                setStockError(err?.response?.status === 409 ? `Stok tidak cukup (${err.response.data?.error || 'habis'})` : "");
+            });
+        return () => { cancelled = true; };
+    }, [slug, checkoutStep, orderPayload, cart.length]);
+    // A changed cart is a new order
+    useEffect(() => { clientOrderIdRef.current = ''; }, [cart]);
+
+    /* ── Checkout: the server prices the order and creates its payment ── */
     const handleCheckout = async () => {
         if (!customerName.trim() || !customerPhone.trim() || !customerAddress.trim()) return;
         setPaymentLoading(true); setCheckoutStep("processing");
+        if (!clientOrderIdRef.current) clientOrderIdRef.current = `${slug}-${Date.now()}-${Math.random().toString(36).slice(2, 10)}`;
         try {
-            const orderId = `ORD-${slug}-${Date.now()}`;
-            const itemsSummary = cart.map(i => `${i.product.name}${i.variant ? ` (${i.variant.name})` : ''} x${i.quantity}`).join(', ');
-            const checkoutPayload = {
+            const res = await storeAPI.checkout(slug, {
+                ...orderPayload,
                 name: customerName, phone: customerPhone,
-                full_address: customerAddress,
-                latitude: customerLat, longitude: customerLng,
-                notes: customerNotes, midtrans_order_id: orderId,
-                total_amount: cartTotal, items_summary: itemsSummary,
-            };
-
-            // 1) Try to create order first — but don't block payment if this fails
-            try {
-                const checkoutRes = await storeAPI.checkout(slug, checkoutPayload);
-                const orderData = checkoutRes.data.data;
-                setDeliveryOrderNumber(orderData?.order_number || '');
-                setDeliveryOrderId(orderData?.delivery_order_id || '');
-            } catch (e) {
-                console.warn('Order pre-creation failed, will retry after payment:', e);
+                full_address: customerAddress, notes: customerNotes,
+                client_order_id: clientOrderIdRef.current,
+            });
+            const order = res.data.data;
+            setDeliveryOrderNumber(order?.order_number || '');
+            setDeliveryOrderId(order?.delivery_order_id || '');
+            setQuote(order);
+            const done = () => { setCheckoutStep("success"); setCart([]); };
+            const payment = order?.payment;
+            if (!payment) {
+                // Already paid on an earlier attempt
+                if (order?.status === 'cancelled') setCheckoutStep("error"); else done();
+                return;
             }
-
-            // 2) Create Midtrans Snap token & open payment popup
-            const res = await paymentAPI.createSnap({ order_id: orderId, gross_amount: cartTotal, first_name: customerName, email: `${customerPhone}@customer.codapos.com`, phone: customerPhone });
-            const snapToken = res.data.data.token;
-            if (window.snap && snapToken) {
-                window.snap.pay(snapToken, {
-                    onSuccess: async () => {
-                        // Retry order creation if it failed earlier
-                        if (!deliveryOrderId) {
-                            try {
-                                const retryRes = await storeAPI.checkout(slug, checkoutPayload);
-                                const od = retryRes.data.data;
-                                setDeliveryOrderNumber(od?.order_number || '');
-                                setDeliveryOrderId(od?.delivery_order_id || '');
-                            } catch { /* ignore */ }
-                        }
-                        setCheckoutStep("success"); setCart([]);
-                    },
-                    onPending: async () => {
-                        if (!deliveryOrderId) {
-                            try {
-                                const retryRes = await storeAPI.checkout(slug, checkoutPayload);
-                                const od = retryRes.data.data;
-                                setDeliveryOrderNumber(od?.order_number || '');
-                                setDeliveryOrderId(od?.delivery_order_id || '');
-                            } catch { /* ignore */ }
-                        }
-                        setCheckoutStep("success"); setCart([]);
-                    },
+            if (payment.provider === 'midtrans' && window.snap && payment.token) {
+                // The order is only passed to the store once Midtrans notifies the payment
+                window.snap.pay(payment.token, {
+                    onSuccess: done,
+                    onPending: done,
                     onError: () => setCheckoutStep("error"),
-                    onClose: () => { if (checkoutStep === "processing") setCheckoutStep("form"); },
+                    onClose: () => setCheckoutStep("form"),
                 });
+            } else if (payment.redirect_url) {
+                window.location.href = payment.redirect_url;
+            } else setCheckoutStep("error");
+        } catch (err) {
+            const e = err as { response?: { status?: number; data?: { error?: string } } };
+            if (e?.response?.status === 409) {
+                // Sold out while the customer was checking out
+                clientOrderIdRef.current = '';
+                setQuote(null);
+                setStockError(`Stok tidak cukup (${e.response.data?.error || 'habis'})`);
+                setCheckoutStep("form");
             } else setCheckoutStep("error");
-        } catch { setCheckoutStep("error"); } finally { setPaymentLoading(false); }
+        } finally { setPaymentLoading(false); }
     };
 
     /* ── Bottom nav handler ── */
@@ -520,11 +537,15 @@ export default function StorefrontPage() {
                                     {product.description && <p className={`text-[11px] mt-0.5 line-clamp-2 ${t.textMuted}`}>{product.description}</p>}
                                     <div className="mt-2 flex items-center justify-between">
                                         <span className={`text-sm font-bold`} style={{ color: accent }}>{formatPrice(product.base_price)}</span>
-                                        <button onClick={() => handleAddClick(product)}
-                                            className="shrink-0 w-8 h-8 rounded-xl flex items-center justify-center transition-all active:scale-90 shadow-lg text-white"
-                                            style={{ background: accentGradient }}>
-                                            <Plus className="w-4 h-4" />
-                                        </button>
+                                        {soldOut(product.available) ? (
+                                            <span className={`shrink-0 text-[11px] font-semibold px-2 py-1 rounded-lg ${isDark ? 'text-white/40 bg-white/5' : 'text-gray-400 bg-gray-100'}`}>Habis</span>
+                                        ) : (
+                                            <button onClick={() => handleAddClick(product)}
+                                                className="shrink-0 w-8 h-8 rounded-xl flex items-center justify-center transition-all active:scale-90 shadow-lg text-white"
+                                                style={{ background: accentGradient }}>
+                                                <Plus className="w-4 h-4" />
+                                            </button>
+                                        )}
                                     </div>
                                     {product.variants && product.variants.length > 0 && (
                                         <span className={`text-[10px] px-1.5 py-0.5 rounded-full mt-1 inline-block ${isDark ? 'text-white/30 bg-white/5' : 'text-gray-400 bg-gray-100'}`}>
@@ -550,16 +571,16 @@ export default function StorefrontPage() {
                             <p className={`text-sm mt-1 ${t.textMuted}`}>{showVariantPicker.name}</p>
                         </div>
                         <div className="p-4 space-y-2 max-h-[60vh] overflow-y-auto">
-                            <button onClick={() => addToCart(showVariantPicker)} className={`w-full text-left p-3 rounded-xl border transition-all ${isDark ? 'bg-white/5 border-white/10 hover:bg-white/10' : 'bg-gray-50 border-gray-200 hover:bg-gray-100'}`}>
+                            <button onClick={() => addToCart(showVariantPicker)} disabled={soldOut(baseAvailable(showVariantPicker))} className={`w-full text-left p-3 rounded-xl border transition-all disabled:opacity-40 ${isDark ? 'bg-white/5 border-white/10 hover:bg-white/10' : 'bg-gray-50 border-gray-200 hover:bg-gray-100'}`}>
                                 <div className="flex items-center justify-between">
-                                    <span className={`text-sm font-medium ${t.textPrimary}`}>Original</span>
+                                    <span className={`text-sm font-medium ${t.textPrimary}`}>Original{soldOut(baseAvailable(showVariantPicker)) && ' · Habis'}</span>
                                     <span className="text-sm font-bold" style={{ color: accent }}>{formatPrice(showVariantPicker.base_price)}</span>
                                 </div>
                             </button>
                             {showVariantPicker.variants?.map(v => (
-                                <button key={v.id} onClick={() => addToCart(showVariantPicker, v)} className={`w-full text-left p-3 rounded-xl border transition-all ${isDark ? 'bg-white/5 border-white/10 hover:bg-white/10' : 'bg-gray-50 border-gray-200 hover:bg-gray-100'}`}>
+                                <button key={v.id} onClick={() => addToCart(showVariantPicker, v)} disabled={soldOut(v.available)} className={`w-full text-left p-3 rounded-xl border transition-all disabled:opacity-40 ${isDark ? 'bg-white/5 border-white/10 hover:bg-white/10' : 'bg-gray-50 border-gray-200 hover:bg-gray-100'}`}>
                                     <div className="flex items-center justify-between">
-                                        <span className={`text-sm font-medium ${t.textPrimary}`}>{v.name}</span>
+                                        <span className={`text-sm font-medium ${t.textPrimary}`}>{v.name}{soldOut(v.available) && ' · Habis'}</span>
                                         <span className="text-sm font-bold" style={{ color: accent }}>{formatPrice(showVariantPicker.base_price + v.additional_price)}</span>
                                     </div>
                                     {v.additional_price > 0 && <p className={`text-[10px] mt-0.5 ${t.textMuted}`}>+{formatPrice(v.additional_price)}</p>}
@@ -619,7 +640,7 @@ export default function StorefrontPage() {
                         {checkoutStep === 'processing' && (
                             <div className="flex-1 flex flex-col items-center justify-center p-8 text-center">
                                 <Loader2 className="w-12 h-12 animate-spin mb-4" style={{ color: accent }} />
-                                <p className={`text-sm ${t.textMuted}`}>Menghubungkan ke Midtrans...</p>
+                                <p className={`text-sm ${t.textMuted}`}>Menghubungkan ke pembayaran...</p>
                             </div>
                         )}
 
@@ -774,8 +795,16 @@ export default function StorefrontPage() {
                                     <div className={`p-5 border-t ${isDark ? 'border-white/10 bg-[#0f172a]' : 'border-gray-100 bg-gray-50'} shrink-0`}>
                                         <div className="flex items-center justify-between mb-3">
                                             <span className={`text-sm ${t.textMuted}`}>Total</span>
-                                            <span className="text-xl font-bold" style={{ color: accent }}>{formatPrice(cartTotal)}</span>
+                                            <span className="text-xl font-bold" style={{ color: accent }}>{formatPrice(checkoutStep === 'form' && quote ? quote.total_amount : cartTotal)}</span>
                                         </div>
+                                        {checkoutStep === 'form' && stockError && (
+                                            <p className="text-xs mb-3 text-right text-red-500">{stockError}</p>
+                                        )}
+                                        {checkoutStep === 'form' && quote && (
+                                            <p className={`text-xs mb-3 text-right ${t.textMuted}`}>
+                                                termasuk ongkir {formatPrice(quote.delivery_fee)} ({quote.distance_km} km){quote.tax_amount + quote.service_charge_amount > 0 ? ` dan pajak/layanan ${formatPrice(quote.tax_amount + quote.service_charge_amount)}` : ''}
+                                            </p>
+                                        )}
                                         {checkoutStep === 'cart' ? (
                                             <button onClick={() => setCheckoutStep('form')}
                                                 className="w-full py-3.5 rounded-xl text-white font-semibold text-sm flex items-center justify-center gap-2 shadow-lg active:scale-[0.98]"
@@ -785,11 +814,11 @@ export default function StorefrontPage() {
                                         ) : (
                                             <div className="space-y-2">
                                                 <button onClick={handleCheckout}
-                                                    disabled={!customerName.trim() || !customerPhone.trim() || !customerAddress.trim() || paymentLoading || !snapLoaded}
+                                                    disabled={!customerName.trim() || !customerPhone.trim() || !customerAddress.trim() || paymentLoading || !quote}
                                                     className="w-full py-3.5 rounded-xl text-white font-semibold text-sm flex items-center justify-center gap-2 shadow-lg disabled:opacity-50"
                                                     style={{ background: accentGradient, boxShadow: `0 4px 20px ${accent}30` }}>
                                                     {paymentLoading ? <Loader2 className="w-4 h-4 animate-spin" /> : <CreditCard className="w-4 h-4" />}
-                                                    {paymentLoading ? 'Memproses...' : `Bayar ${formatPrice(cartTotal)}`}
+                                                    {paymentLoading ? 'Memproses...' : `Bayar ${formatPrice(quote?.total_amount ?? cartTotal)}`}
                                                 </button>
                                                 <button onClick={() => setCheckoutStep('cart')} className={`w-full py-2 rounded-xl text-sm ${isDark ? 'text-white/50' : 'text-gray-400'}`}>← Kembali</button>
                                             </div>
@@ -62,18 +62,25 @@ export const tenantAPI = {
 // ======= PUBLIC STORE =======
 export const storeAPI = {
     getBySlug: (slug: string) => api.get(`/store/${slug}`),
-    checkout: (slug: string, data: {
-        name: string; phone: string; full_address: string;
-        latitude: number; longitude: number; notes: string;
-        midtrans_order_id: string; total_amount: number; items_summary: string;
+    // Prices the cart on the server, delivery fee included
+    quote: (slug: string, data: StoreOrderPayload) => api.post(`/store/${slug}/quote`, data),
+    // Places the order and starts its payment; the same client_order_id returns the same order
+    checkout: (slug: string, data: StoreOrderPayload & {
+        name: string; phone: string; full_address: string; notes: string; client_order_id: string;
     }) => api.post(`/store/${slug}/checkout`, data),
 };
 
+export interface StoreOrderPayload {
+    items: { product_id: string; variant_id?: string; quantity: number; modifiers?: { modifier_id: string }[] }[];
+    latitude: number; longitude: number; outlet_id?: string;
+}
+
 // ======= PAYMENT (Midtrans) =======
 export const paymentAPI = {
     getConfig: () => api.get('/payment/config'),
-    createSnap: (data: { order_id: string; gross_amount: number; first_name: string; email: string; phone: string }) =>
-        api.post('/payment/create', data),
+    // Cashier payment through the gateway of the tenant
+    createCharge: (data: { order_id: string; gross_amount: number; first_name: string; email: string; phone: string }) =>
+        api.post('/payment/charges', data),
 };
 
 // ======= OUTLETS =======
@@ -87,10 +94,11 @@ export const outletAPI = {
 
 // ======= PRODUCTS =======
 export const productAPI = {
-    getAll: (search?: string, categoryId?: string) => {
+    getAll: (search?: string, categoryId?: string, outletId?: string) => {
         const params = new URLSearchParams();
         if (search) params.set('search', search);
         if (categoryId) params.set('category_id', categoryId);
+        if (outletId) params.set('outlet_id', outletId);
         return api.get(`/products?${params.toString()}`);
     },
     getById: (id: string) => api.get(`/products/${id}`),
@@ -117,6 +125,7 @@ export const categoryAPI = {
 // ======= POS =======
 export const posAPI = {
     checkout: (data: import('@/types').CheckoutRequest) => api.post('/pos/checkout', data),
+    quote: (data: import('@/types').QuoteRequest) => api.post('/pos/quote', data),
     refund: (id: string, reason: string) => api.post(`/pos/refund/${id}`, { reason }),
     getTransactions: (page?: number, outletId?: string) => {
         const params = new URLSearchParams();
@@ -158,6 +167,29 @@ export const customerAPI = {
     deleteAddress: (addressId: string) => api.delete(`/customers/addresses/${addressId}`),
 };
 
+// ======= LOYALTY =======
+export const loyaltyAPI = {
+    getProgram: () => api.get('/loyalty/program'),
+    saveProgram: (data: import('@/types').LoyaltyProgram) => api.put('/loyalty/program', data),
+    getRules: () => api.get('/loyalty/rules'),
+    createRule: (data: Partial<import('@/types').LoyaltyEarnRule>) => api.post('/loyalty/rules', data),
+    updateRule: (id: string, data: Partial<import('@/types').LoyaltyEarnRule>) => api.put(`/loyalty/rules/${id}`, data),
+    deleteRule: (id: string) => api.delete(`/loyalty/rules/${id}`),
+    getTiers: () => api.get('/loyalty/tiers'),
+    createTier: (data: Partial<import('@/types').LoyaltyTier>) => api.post('/loyalty/tiers', data),
+    updateTier: (id: string, data: Partial<import('@/types').LoyaltyTier>) => api.put(`/loyalty/tiers/${id}`, data),
+    deleteTier: (id: string) => api.delete(`/loyalty/tiers/${id}`),
+    getBalance: (phone: string) => api.get(`/loyalty/balance?phone=${encodeURIComponent(phone)}`),
+    getCustomerBalance: (customerId: string) => api.get(`/loyalty/customers/${customerId}`),
+    getLedger: (customerId: string, page = 1) => api.get(`/loyalty/customers/${customerId}/ledger?page=${page}`),
+    adjust: (customerId: string, points: number, notes: string) => api.post(`/loyalty/customers/${customerId}/adjust`, { points, notes }),
+};
+
+export const giftCardAPI = {
+    list: (status = '', page = 1) => api.get(`/gift-cards?status=${status}&page=${page}`),
+    lookup: (code: string) => api.get(`/gift-cards/${encodeURIComponent(code)}`),
+};
+
 // ======= SUPER ADMIN (Phase 1) =======
 export const superAdminAPI = {
     // Merchants
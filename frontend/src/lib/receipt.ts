@@ -38,6 +38,8 @@ export interface ReceiptData {
     subtotal: number;
     discount_amount: number;
     tax_amount: number;
+    service_charge_amount?: number;
+    tip_amount?: number;
     total_amount: number;
     notes?: string;
 }
@@ -128,7 +130,9 @@ ${receiptType === "Kasir" ? `
 <div class="divider"></div>
 <div class="row"><span>Subtotal</span><span>${formatCurrency(data.subtotal)}</span></div>
 ${data.discount_amount > 0 ? `<div class="row" style="color:#C40000"><span>Diskon</span><span>-${formatCurrency(data.discount_amount)}</span></div>` : ""}
+${data.service_charge_amount ? `<div class="row"><span>Service Charge</span><span>${formatCurrency(data.service_charge_amount)}</span></div>` : ""}
 ${data.tax_amount > 0 ? `<div class="row"><span>PPN 11%</span><span>${formatCurrency(data.tax_amount)}</span></div>` : ""}
+${data.tip_amount ? `<div class="row"><span>Tip</span><span>${formatCurrency(data.tip_amount)}</span></div>` : ""}
 <div class="divider-bold"></div>
 <div class="total-row"><span>TOTAL</span><span>${formatCurrency(data.total_amount)}</span></div>
 <div class="divider-bold"></div>
@@ -241,7 +245,9 @@ export function generateESCPOS(data: ReceiptData, tenant: ReceiptTenant, paper:
     if (receiptType === "Kasir") {
         leftRight("Subtotal", formatCurrency(data.subtotal));
         if (data.discount_amount > 0) leftRight("Diskon", `-${formatCurrency(data.discount_amount)}`);
+        if (data.service_charge_amount) leftRight("Service Charge", formatCurrency(data.service_charge_amount));
         if (data.tax_amount > 0) leftRight("PPN 11%", formatCurrency(data.tax_amount));
+        if (data.tip_amount) leftRight("Tip", formatCurrency(data.tip_amount));
         boldLine();
 
         push(...BOLD_ON);
@@ -54,15 +54,12 @@ export const useAuthStore = create<AuthState>((set) => ({
 interface CartState {
     items: CartItem[];
     selectedOutletId: string | null;
-    addItem: (product: Product, variant?: ProductVariant, modifiers?: { name: string; price: number }[]) => void;
+    addItem: (product: Product, variant?: ProductVariant, modifiers?: { id: string; name: string; price: number }[]) => void;
     removeItem: (index: number) => void;
     updateQuantity: (index: number, quantity: number) => void;
     updateNote: (index: number, notes: string) => void;
     clearCart: () => void;
     setOutlet: (outletId: string) => void;
-    getSubtotal: () => number;
-    getTax: () => number;
-    getTotal: () => number;
     getItemCount: () => number;
 }
 
@@ -114,21 +111,6 @@ export const useCartStore = create<CartState>((set, get) => ({
 
     setOutlet: (outletId) => set({ selectedOutletId: outletId }),
 
-    getSubtotal: () => {
-        return get().items.reduce((sum, item) => sum + item.unitPrice * item.quantity, 0);
-    },
-
-    getTax: () => {
-        return get().items.reduce((sum, item) => {
-            const itemTotal = item.unitPrice * item.quantity;
-            return sum + itemTotal * (item.product.tax_rate / 100);
-        }, 0);
-    },
-
-    getTotal: () => {
-        return get().getSubtotal() + get().getTax();
-    },
-
     getItemCount: () => {
         return get().items.reduce((sum, item) => sum + item.quantity, 0);
     },
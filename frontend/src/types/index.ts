@@ -129,7 +129,11 @@ export interface Product {
     is_locked: boolean;
     sort_order: number;
     unit: string;
+    // A gift card product issues a card worth its price per unit sold (0 days = no expiry)
+    is_gift_card: boolean;
+    gift_card_valid_days?: number;
     stock_quantity?: number;
+    available?: number; // left to sell at the outlet asked for, after online reservations
     category?: Category;
     variants?: ProductVariant[];
     modifier_groups?: ModifierGroup[];
@@ -143,6 +147,7 @@ export interface ProductVariant {
     additional_price: number;
     cost_price: number;
     is_active: boolean;
+    available?: number;
 }
 
 export interface ModifierGroup {
@@ -175,10 +180,20 @@ export interface Transaction {
     customer_id?: string;
     transaction_number: string;
     type: 'sale' | 'refund' | 'void';
-    status: 'pending' | 'completed' | 'voided' | 'refunded';
+    status: 'pending' | 'completed' | 'voided' | 'refunded' | 'partially_refunded' | 'expired' | 'merged';
+    order_type?: 'dine_in' | 'takeaway' | 'delivery';
+    channel?: 'pos' | 'online';
+    table_id?: string;
+    guest_count?: number;
+    tab_name?: string;
+    expires_at?: string;
     subtotal: number;
     discount_amount: number;
     tax_amount: number;
+    service_charge_amount?: number;
+    tip_amount?: number;
+    delivery_fee?: number;
+    tip_staff_id?: string;
     total_amount: number;
     notes?: string;
     refund_reason?: string;
@@ -197,6 +212,9 @@ export interface Transaction {
     fee_codapos?: number;
     total_mdr_merchant?: number;
     net_profit?: number;
+    points_earned?: number;
+    points_redeemed?: number;
+    gift_cards?: GiftCard[];
 }
 
 export interface TransactionItem {
@@ -213,6 +231,7 @@ export interface TransactionItem {
     subtotal: number;
     modifiers?: { name: string; price: number }[];
     notes?: string;
+    is_gift_card?: boolean;
 }
 
 export interface TransactionPayment {
@@ -221,6 +240,7 @@ export interface TransactionPayment {
     payment_method: string;
     amount: number;
     reference_number?: string;
+    gift_card_id?: string;
     status: string;
 }
 
@@ -231,13 +251,25 @@ export interface CheckoutRequest {
     payments: PaymentInput[];
     promotion_id?: string;
     notes?: string;
+    // Loyalty points spent as a discount; points can also pay through a loyalty_points payment
+    redeem_points?: number;
+}
+
+// The server prices a cart; the total of the quote is what the checkout payments must cover
+export interface QuoteRequest {
+    outlet_id: string;
+    customer_id?: string;
+    items: CheckoutItem[];
+    promotion_id?: string;
+    tip_amount?: number;
+    redeem_points?: number;
 }
 
 export interface CheckoutItem {
     product_id: string;
     variant_id?: string;
     quantity: number;
-    modifiers?: { name: string; price: number }[];
+    modifiers?: { modifier_id: string }[];
     notes?: string;
 }
 
@@ -245,6 +277,8 @@ export interface PaymentInput {
     payment_method: string;
     amount: number;
     reference_number?: string;
+    // Code of the card a gift_card payment is taken from
+    gift_card_code?: string;
 }
 
 export interface TenantBilling {
@@ -328,7 +362,7 @@ export interface CartItem {
     product: Product;
     variant?: ProductVariant;
     quantity: number;
-    modifiers: { name: string; price: number }[];
+    modifiers: { id: string; name: string; price: number }[];
     notes?: string;
     unitPrice: number;
 }
@@ -358,6 +392,83 @@ export interface Customer {
     created_at: string;
 }
 
+export interface LoyaltyProgram {
+    id?: string;
+    is_active: boolean;
+    spend_per_point: number;
+    point_value: number;
+    min_redeem_points: number;
+    expiry_days: number;
+}
+
+export interface LoyaltyEarnRule {
+    id: string;
+    name: string;
+    category_id?: string;
+    weekday?: number; // 0 = Sunday
+    multiplier: number;
+    is_active: boolean;
+    category?: Category;
+}
+
+export interface LoyaltyTier {
+    id: string;
+    name: string;
+    min_points: number;
+    earn_multiplier: number;
+    discount_percent: number;
+}
+
+export interface LoyaltyBalance {
+    customer: Customer;
+    balance: number;
+    value: number;
+    lifetime_points: number;
+    tier?: LoyaltyTier;
+    next_tier?: LoyaltyTier;
+    expiring_points: number;
+    expiring_at?: string;
+}
+
+export interface LoyaltyEntry {
+    id: string;
+    customer_id: string;
+    transaction_id?: string;
+    type: 'earn' | 'redeem' | 'clawback' | 'return' | 'expire' | 'adjust';
+    points: number;
+    remaining: number;
+    expires_at?: string;
+    notes?: string;
+    created_at: string;
+}
+
+export interface GiftCard {
+    id: string;
+    tenant_id: string;
+    code: string;
+    initial_amount: number;
+    balance: number;
+    status: 'active' | 'spent' | 'expired' | 'voided';
+    expires_at?: string;
+    expired_at?: string;
+    product_id?: string;
+    outlet_id?: string;
+    customer_id?: string;
+    transaction_id?: string;
+    movements?: GiftCardMovement[];
+    created_at: string;
+}
+
+export interface GiftCardMovement {
+    id: string;
+    gift_card_id: string;
+    transaction_id?: string;
+    type: 'issue' | 'redeem' | 'refund' | 'expire' | 'void';
+    amount: number;
+    balance_after: number;
+    created_at: string;
+}
+
 export interface CustomerAddress {
     id: string;
     customer_id: string;
@@ -400,6 +511,8 @@ export interface InventoryItem {
     variant_id?: string;
     quantity: number;
     min_stock: number;
+    reserved: number; // held for unpaid online orders
+    available: number;
     product?: Product;
     variant?: { id: string; name: string; sku?: string };
 }